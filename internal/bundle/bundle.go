@@ -0,0 +1,262 @@
+// Package bundle saves an analysis, the raw shell trace and optionally the
+// config files it came from into one archive, so it can be browsed later or
+// on another machine (e.g. attached to a support ticket).
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"lspath/internal/model"
+	"lspath/internal/trace"
+)
+
+// Archive member names
+const (
+	metaFile     = "meta.json"
+	analysisFile = "analysis.json"
+	traceFile    = "trace.txt"
+	configDir    = "configs/"
+)
+
+// Meta describes where and when a bundle was made.
+type Meta struct {
+	Version  string    // lspath version
+	Created  time.Time // Export time
+	OS       string    // runtime.GOOS
+	Arch     string    // runtime.GOARCH
+	Shell    string    // $SHELL
+	Home     string    // Home directory, for reading ~ paths
+	Variable string    // Analyzed variable
+	Session  string    // Value of the variable in the exporting session
+}
+
+// Bundle is the contents of a .lspath archive.
+type Bundle struct {
+	Meta    Meta
+	Result  model.AnalysisResult
+	Trace   []byte            // Raw shell trace output
+	Configs map[string][]byte // Config file copies by absolute path (opt-in)
+}
+
+// Collect runs the analysis, capturing the raw trace, and copies the config
+// files behind it if includeConfigs is set.
+func Collect(opts trace.Options, includeConfigs bool) (Bundle, error) {
+	var raw bytes.Buffer
+	opts.RawTrace = &raw
+	res, err := trace.RunAnalysis(opts)
+	if err != nil {
+		return Bundle{}, err
+	}
+
+	home, _ := os.UserHomeDir()
+	b := Bundle{
+		Meta: Meta{
+			Version:  model.Version,
+			Created:  time.Now(),
+			OS:       runtime.GOOS,
+			Arch:     runtime.GOARCH,
+			Shell:    os.Getenv("SHELL"),
+			Home:     home,
+			Variable: res.VariableName(),
+			Session:  os.Getenv(res.VariableName()),
+		},
+		Result: res,
+		Trace:  raw.Bytes(),
+	}
+	if opts.SessionPath != "" {
+		b.Meta.Session = opts.SessionPath
+	}
+	if includeConfigs {
+		b.Configs = make(map[string][]byte)
+		for _, f := range trace.ConfigFiles(res) {
+			if data, err := os.ReadFile(f); err == nil {
+				b.Configs[f] = data
+			}
+		}
+	}
+	return b, nil
+}
+
+// configFiles returns the paths in Configs sorted, so archives and extracted
+// trees come out the same on every run.
+func (b Bundle) configFiles() []string {
+	files := make([]string, 0, len(b.Configs))
+	for file := range b.Configs {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Export writes b to path as a zip archive.
+func Export(path string, b Bundle) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(f)
+
+	write := func(name string, data []byte) error {
+		w, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+	writeJSON := func(name string, v any) error {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		return write(name, data)
+	}
+
+	err = writeJSON(metaFile, b.Meta)
+	if err == nil {
+		err = writeJSON(analysisFile, model.NewDocument(b.Result))
+	}
+	if err == nil {
+		err = write(traceFile, b.Trace)
+	}
+	for _, file := range b.configFiles() {
+		if err != nil {
+			break
+		}
+		err = write(configDir+strings.TrimPrefix(filepath.ToSlash(file), "/"), b.Configs[file])
+	}
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Open reads a bundle written by Export.
+func Open(path string) (*Bundle, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not an lspath bundle: %w", path, err)
+	}
+	defer zr.Close()
+
+	b := &Bundle{Configs: make(map[string][]byte)}
+	found := false
+	for _, zf := range zr.File {
+		rc, err := zf.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case zf.Name == metaFile:
+			err = json.Unmarshal(data, &b.Meta)
+		case zf.Name == analysisFile:
+			var doc model.Document
+			if err = json.Unmarshal(data, &doc); err == nil {
+				err = doc.Supported()
+			}
+			b.Result = doc.AnalysisResult
+			found = true
+		case zf.Name == traceFile:
+			b.Trace = data
+		case strings.HasPrefix(zf.Name, configDir):
+			b.Configs["/"+strings.TrimPrefix(zf.Name, configDir)] = data
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", path, zf.Name, err)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%s is not an lspath bundle: no %s", path, analysisFile)
+	}
+	return b, nil
+}
+
+// Extract writes the bundled config files under dir and returns the result
+// with its file references pointing at those copies, so previews and source
+// quotes show the files as they were when the bundle was made. Files that
+// were not bundled keep their original paths.
+func (b *Bundle) Extract(dir string) (model.AnalysisResult, error) {
+	res := b.Result
+	res.Diagnostics = append([]string{
+		fmt.Sprintf("INFO: Opened from a bundle made %s on %s/%s by lspath %s. Directory listings show this machine, not the original.",
+			b.Meta.Created.Format("2006-01-02 15:04"), b.Meta.OS, b.Meta.Arch, b.Meta.Version),
+	}, res.Diagnostics...)
+	if len(b.Configs) == 0 {
+		res.Diagnostics = append(res.Diagnostics, "INFO: The bundle has no config file copies; previews show this machine's files, if any.")
+		return res, nil
+	}
+
+	moved := make(map[string]string)
+	for _, file := range b.configFiles() {
+		data := b.Configs[file]
+		dest := filepath.Join(dir, filepath.FromSlash(file))
+		if !strings.HasPrefix(dest, filepath.Clean(dir)+string(filepath.Separator)) {
+			continue // A crafted archive must not write outside dir
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return res, err
+		}
+		if err := os.WriteFile(dest, data, 0644); err != nil {
+			return res, err
+		}
+		moved[file] = dest
+	}
+	relocate := func(p string) string {
+		if dest, ok := moved[expandHome(p, b.Meta.Home)]; ok {
+			return dest
+		}
+		return p
+	}
+
+	res.PathEntries = append([]model.PathEntry(nil), res.PathEntries...)
+	for i := range res.PathEntries {
+		res.PathEntries[i].SourceFile = relocate(res.PathEntries[i].SourceFile)
+	}
+	res.FlowNodes = append([]model.ConfigNode(nil), res.FlowNodes...)
+	for i := range res.FlowNodes {
+		res.FlowNodes[i].FilePath = relocate(res.FlowNodes[i].FilePath)
+	}
+	res.SideEffects = append([]model.SideEffect(nil), res.SideEffects...)
+	for i := range res.SideEffects {
+		res.SideEffects[i].File = relocate(res.SideEffects[i].File)
+	}
+	res.Definitions = append([]model.Definition(nil), res.Definitions...)
+	for i := range res.Definitions {
+		res.Definitions[i].File = relocate(res.Definitions[i].File)
+	}
+	res.Diagnostics = append(res.Diagnostics, fmt.Sprintf("INFO: Bundled config files were extracted to %s.", dir))
+	return res, nil
+}
+
+// expandHome expands ~ against the exporting machine's home directory.
+func expandHome(p, home string) string {
+	if home == "" {
+		return p
+	}
+	if p == "~" {
+		return home
+	}
+	if rest, ok := strings.CutPrefix(p, "~/"); ok {
+		return filepath.Join(home, rest)
+	}
+	return p
+}
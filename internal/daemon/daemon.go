@@ -0,0 +1,229 @@
+// Package daemon keeps an analysis warm and answers queries about it over a
+// Unix socket, so editor plugins and shell prompts get PATH information in
+// milliseconds instead of tracing the shell on every call. Requests are
+// JSON-RPC 2.0, one per line; see the methods in handle.
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"lspath/internal/jsonrpc"
+	"lspath/internal/model"
+	"lspath/internal/trace"
+)
+
+// SocketPath returns where the daemon listens unless told otherwise, e.g.
+// ~/.cache/lspath/daemon.sock.
+func SocketPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "lspath", "daemon.sock")
+}
+
+// Daemon holds the current analysis and replaces it whenever one of its
+// config files changes.
+type Daemon struct {
+	opts trace.Options
+
+	mu      sync.RWMutex
+	result  model.AnalysisResult
+	startup time.Duration // How long the traced shell took to start
+	at      time.Time     // When result was made
+	err     error         // Why the last re-analysis failed, if it did
+}
+
+// New analyzes with opts and returns a daemon holding the result.
+func New(opts trace.Options) (*Daemon, error) {
+	d := &Daemon{opts: opts}
+	if err := d.analyze(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// analyze replaces the analysis with a new one. On failure the old one is
+// kept and the error recorded for status.
+func (d *Daemon) analyze() error {
+	var startup time.Duration
+	opts := d.opts
+	opts.StartupTime = &startup
+	res, err := trace.RunAnalysis(opts)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.err = err
+	if err != nil {
+		return err
+	}
+	d.result, d.startup, d.at = res, startup, time.Now()
+	return nil
+}
+
+// current returns the analysis and how long its shell took to start.
+func (d *Daemon) current() (model.AnalysisResult, time.Duration) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.result, d.startup
+}
+
+// watch re-analyzes whenever a config file behind the analysis changes,
+// until ctx is done.
+func (d *Daemon) watch(ctx context.Context) {
+	for {
+		res, _ := d.current()
+		_, err := trace.WaitForChange(ctx, trace.ConfigFiles(res))
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			d.mu.Lock()
+			d.err = fmt.Errorf("watch stopped: %v", err)
+			d.mu.Unlock()
+			return
+		}
+		d.analyze()
+	}
+}
+
+// Listen creates the socket at path, readable only by this user. A socket
+// left behind by a daemon that is gone is replaced; one that answers is an
+// error.
+func Listen(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("a daemon is already listening on %s", path)
+	}
+	os.Remove(path)
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// Serve answers the connections to ln, each in its own goroutine, and
+// keeps the analysis up to date until ctx is done.
+func (d *Daemon) Serve(ctx context.Context, ln net.Listener) error {
+	go d.watch(ctx)
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			jsonrpc.Serve(conn, conn, d.handle)
+		}()
+	}
+}
+
+// whichReply is the answer to which: the executables in priority order
+// (the first runs) and the text `lspath which` prints.
+type whichReply struct {
+	Hits []trace.CommandHit
+	Text string
+}
+
+// statusReply is the answer to status.
+type statusReply struct {
+	Version  string
+	PID      int
+	Variable string
+	Entries  int
+	Analyzed time.Time
+	Error    string `json:",omitempty"` // Why the last re-analysis failed
+}
+
+// handle answers one request. Methods:
+//
+//	analysis {"format": "json"|"report", "verbose": bool}  the --json document (default) or report text
+//	oneline {}                                             the --oneline summary
+//	which {"name": "python3"}                              every executable called name
+//	preview {"file": "~/.zshrc", "line": 12}              a traced config file's line and its neighbours
+//	refresh {}                                             trace again now; answers like status
+//	status {}                                              what the daemon holds
+func (d *Daemon) handle(req jsonrpc.Request) (any, *jsonrpc.Error) {
+	var params struct {
+		Format  string `json:"format"`
+		Verbose bool   `json:"verbose"`
+		Name    string `json:"name"`
+		File    string `json:"file"`
+		Line    int    `json:"line"`
+	}
+	if err := jsonrpc.ParseParams(req, &params); err != nil {
+		return nil, err
+	}
+	res, startup := d.current()
+
+	switch req.Method {
+	case "analysis":
+		switch params.Format {
+		case "", "json":
+			data, err := trace.MarshalDocument(res, 0)
+			if err != nil {
+				return nil, jsonrpc.Errorf(jsonrpc.CodeInternalError, "%v", err)
+			}
+			return json.RawMessage(data), nil
+		case "report":
+			return trace.GenerateReport(res, params.Verbose), nil
+		}
+		return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "unknown format %q (use json or report)", params.Format)
+	case "oneline":
+		return trace.NewOneline(res, d.opts, startup).String(), nil
+	case "which":
+		if params.Name == "" {
+			return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "name is required")
+		}
+		hits := trace.FindCommand(res.PathEntries, params.Name)
+		return whichReply{Hits: hits, Text: trace.FormatWhich(res, params.Name, hits)}, nil
+	case "preview":
+		// Only files the analysis traced, so the socket is no general file reader
+		if !slices.Contains(trace.ConfigFiles(res), model.ExpandTilde(params.File)) {
+			return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "%s is not a traced config file", params.File)
+		}
+		return model.GetLineContext(params.File, params.Line), nil
+	case "refresh":
+		if err := d.analyze(); err != nil {
+			return nil, jsonrpc.Errorf(jsonrpc.CodeInternalError, "%v", err)
+		}
+		return d.status(), nil
+	case "status":
+		return d.status(), nil
+	}
+	return nil, jsonrpc.Errorf(jsonrpc.CodeMethodNotFound, "method %q not found", req.Method)
+}
+
+func (d *Daemon) status() statusReply {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	s := statusReply{Version: model.Version, PID: os.Getpid(), Variable: d.result.VariableName(), Entries: len(d.result.PathEntries), Analyzed: d.at}
+	if d.err != nil {
+		s.Error = d.err.Error()
+	}
+	return s
+}
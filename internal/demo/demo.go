@@ -0,0 +1,236 @@
+// Package demo provides a made-up but realistic analysis for `lspath demo`:
+// a macOS zsh user's PATH with Homebrew, pyenv, nvm, an active virtualenv,
+// a duplicate and two missing directories. It lets the TUI and Web Mode be
+// tried, taught or screenshotted without tracing anything on this machine.
+package demo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"lspath/internal/model"
+	"lspath/internal/trace"
+)
+
+// home is the demo user's home directory.
+const home = "/Users/demo"
+
+// startupFiles are the demo user's startup files, by path. Entries below
+// refer to their line numbers.
+var startupFiles = map[string]string{
+	"/etc/zprofile": `# System-wide profile for interactive zsh(1) login shells.
+
+# Setup user specific overrides for this in ~/.zprofile. See zshbuiltins(1)
+# and zshoptions(1) for more details.
+
+if [ -x /usr/libexec/path_helper ]; then
+	eval ` + "`/usr/libexec/path_helper -s`" + `
+fi
+`,
+	"/etc/zshrc": `# System-wide profile for interactive zsh(1) shells.
+
+# Correctly display UTF-8 with combining characters.
+if [[ "$(locale LC_CTYPE)" == "UTF-8" ]]; then
+    setopt COMBINING_CHARS
+fi
+
+# Disable the log builtin, so we don't conflict with /usr/bin/log
+disable log
+
+HISTFILE=${ZDOTDIR:-$HOME}/.zsh_history
+HISTSIZE=2000
+SAVEHIST=1000
+`,
+	home + "/.zprofile": `eval "$(/opt/homebrew/bin/brew shellenv)"
+`,
+	home + "/.zshrc": `# ~/.zshrc
+
+export PATH="$HOME/.local/bin:$PATH"
+
+# Python
+export PYENV_ROOT="$HOME/.pyenv"
+export PATH="$PYENV_ROOT/bin:$PATH"
+eval "$(pyenv init -)"
+
+# Node
+export NVM_DIR="$HOME/.nvm"
+[ -s "$NVM_DIR/nvm.sh" ] && . "$NVM_DIR/nvm.sh"
+
+# Homebrew first (copied from a blog post)
+export PATH="/opt/homebrew/bin:$PATH"
+
+export GOPATH="$HOME/go"
+export PATH="$PATH:$GOPATH/bin"
+
+export PATH="$PATH:/usr/local/mysql/bin"
+`,
+	home + "/.nvm/nvm.sh": `# Node Version Manager (abridged for the demo)
+NVM_SCRIPT_SOURCE="$_"
+
+nvm_use_default() {
+  local VERSION_PATH="$NVM_DIR/versions/node/v20.11.1/bin"
+  export PATH="$VERSION_PATH:$PATH"
+}
+
+nvm_use_default
+`,
+}
+
+// entry is one directory of the demo PATH, in final PATH order.
+type entry struct {
+	Dir     string
+	File    string // Startup file that added it; "" for the system default, "session" for session-only
+	Line    int
+	Node    string // Flow node that added it
+	Note    string // ConfidenceReason, if the attribution is a guess
+	Missing bool
+}
+
+var entries = []entry{
+	{Dir: home + "/projects/webapp/.venv/bin", File: "session", Node: "session-node"},
+	{Dir: "/opt/homebrew/bin", File: home + "/.zshrc", Line: 15, Node: "node-6"},
+	{Dir: home + "/.nvm/versions/node/v20.11.1/bin", File: home + "/.nvm/nvm.sh", Line: 6, Node: "node-5"},
+	{Dir: home + "/.pyenv/shims", File: home + "/.zshrc", Line: 8, Node: "node-4", Note: "Attributed to the eval on line 8, which ran before the change was seen on line 2"},
+	{Dir: home + "/.pyenv/bin", File: home + "/.zshrc", Line: 7, Node: "node-4"},
+	{Dir: home + "/.local/bin", File: home + "/.zshrc", Line: 3, Node: "node-4"},
+	{Dir: "/opt/homebrew/bin", File: home + "/.zprofile", Line: 1, Node: "node-2"},
+	{Dir: "/opt/homebrew/sbin", File: home + "/.zprofile", Line: 1, Node: "node-2"},
+	{Dir: "/usr/local/bin", File: "/etc/zprofile", Line: 7, Node: "node-1"},
+	{Dir: "/System/Cryptexes/App/usr/bin", File: "/etc/zprofile", Line: 7, Node: "node-1"},
+	{Dir: "/usr/bin", Node: "node-0"},
+	{Dir: "/bin", Node: "node-0"},
+	{Dir: "/usr/sbin", Node: "node-0"},
+	{Dir: "/sbin", Node: "node-0"},
+	{Dir: "/Library/Apple/usr/bin", File: "/etc/zprofile", Line: 7, Node: "node-1"},
+	{Dir: home + "/go/bin", File: home + "/.zshrc", Line: 18, Node: "node-6", Missing: true},
+	{Dir: "/usr/local/mysql/bin", File: home + "/.zshrc", Line: 20, Node: "node-6", Missing: true},
+}
+
+// nodes is the demo startup flow, in the order zsh ran it, including the
+// standard files it did not run.
+var nodes = []model.ConfigNode{
+	{ID: "node-0", FilePath: "System (Default)", Description: "Initial environment PATH"},
+	{ID: "session-node", FilePath: "Session (Manual/Runtime)", Description: "Paths added in this terminal session"},
+	{ID: "ghost-1", FilePath: "/etc/zshenv", NotExecuted: true, Description: "(system-wide env)"},
+	{ID: "ghost-2", FilePath: "~/.zshenv", NotExecuted: true, Description: "(user-specific)"},
+	{ID: "node-1", FilePath: "/etc/zprofile", Description: "(system-wide profile)"},
+	{ID: "node-2", FilePath: home + "/.zprofile", Description: "(user-specific)"},
+	{ID: "node-3", FilePath: "/etc/zshrc", Description: "(system-wide rc)"},
+	{ID: "node-4", FilePath: home + "/.zshrc", Description: "(user-specific)"},
+	{ID: "node-5", FilePath: home + "/.nvm/nvm.sh", Depth: 1},
+	{ID: "node-6", FilePath: home + "/.zshrc", Description: "(user-specific)"},
+	{ID: "ghost-7", FilePath: "/etc/zlogin", NotExecuted: true, Description: "(system-wide)"},
+	{ID: "ghost-8", FilePath: "~/.zlogin", NotExecuted: true, Description: "(user-specific)"},
+}
+
+// shadows are the demo's commands found in more than one directory, as
+// indices into entries.
+var shadows = []model.Shadow{
+	{Name: "git", Winner: 1, Losers: []int{10}},
+	{Name: "node", Winner: 1, Losers: []int{2}},
+	{Name: "pip3", Winner: 0, Losers: []int{3, 10}},
+	{Name: "python3", Winner: 0, Losers: []int{3, 10}},
+}
+
+// Result writes the demo startup files under dir, so file previews work,
+// and returns the demo analysis with its file references pointing at them.
+func Result(dir string) (model.AnalysisResult, error) {
+	for file, content := range startupFiles {
+		dest := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return model.AnalysisResult{}, err
+		}
+		if err := os.WriteFile(dest, []byte(content), 0644); err != nil {
+			return model.AnalysisResult{}, err
+		}
+	}
+	relocate := func(file string) string {
+		if _, ok := startupFiles[file]; ok {
+			return filepath.Join(dir, filepath.FromSlash(file))
+		}
+		return file
+	}
+
+	res := model.AnalysisResult{
+		Diagnostics: []string{
+			"INFO: Demo mode - a made-up PATH for a macOS zsh user, not this machine's. Nothing was traced; directory listings and command lookups still show this machine.",
+			fmt.Sprintf("INFO: The demo startup files were written to %s; edits and fixes only change those copies.", dir),
+			"INFO: Unified view - showing your actual PATH with full attribution.",
+			"INFO: Entries marked as 'Session' were added manually or by tools (not from shell config files).",
+		},
+		Shadows: shadows,
+		Environment: model.TraceEnvironment{
+			User:         "demo (uid 501)",
+			Umask:        "0022",
+			Shell:        "/bin/zsh",
+			ShellVersion: "zsh 5.9 (arm64-apple-darwin23.0)",
+			OS:           "darwin/arm64",
+			OSRelease:    "macOS 14.5",
+			Lspath:       model.Version,
+		},
+	}
+
+	flowIndex := make(map[string]int)
+	for i, n := range nodes {
+		n.Order = i + 1
+		n.FilePath = relocate(n.FilePath)
+		n.Entries = []int{}
+		res.FlowNodes = append(res.FlowNodes, n)
+		flowIndex[n.ID] = i
+	}
+
+	seen := make(map[string]int)
+	for i, d := range entries {
+		e := model.PathEntry{
+			Value:            d.Dir,
+			SourceFile:       relocate(d.File),
+			LineNumber:       d.Line,
+			Mode:             trace.GuessShellMode(d.File),
+			FlowID:           d.Node,
+			SymlinkPointsTo:  -1,
+			Confidence:       model.ConfidenceHigh,
+			ConfidenceReason: "Assigned on this line",
+		}
+		switch d.File {
+		case "":
+			e.SourceFile, e.Mode = "System (Default)", "System"
+			e.ConfidenceReason = "Part of the baseline PATH the trace starts from"
+		case "session":
+			e.SourceFile, e.Mode = "Session (Manual/Runtime)", "Session"
+			e.IsSessionOnly = true
+			e.SessionNote = trace.SessionNote(d.Dir)
+			e.Confidence = model.ConfidenceLow
+			e.ConfidenceReason = "Not seen in the trace; assumed added in this terminal session"
+		}
+		if d.Note != "" {
+			e.Confidence, e.ConfidenceReason = model.ConfidenceMedium, d.Note
+		}
+		if first, ok := seen[d.Dir]; ok {
+			e.IsDuplicate = true
+			e.DuplicateOf = first
+			e.DuplicateMessage = fmt.Sprintf("Duplicates PATH entry #%d (%s)", first+1, d.Dir)
+		} else {
+			seen[d.Dir] = i
+		}
+		if d.Missing {
+			e.Diagnostics = append(e.Diagnostics, "Directory does not exist on disk.")
+		}
+		n := &res.FlowNodes[flowIndex[d.Node]]
+		n.Entries = append(n.Entries, i)
+		res.PathEntries = append(res.PathEntries, e)
+	}
+
+	for _, sh := range shadows {
+		var hidden []string
+		for _, l := range sh.Losers {
+			hidden = append(hidden, entries[l].Dir)
+			e := &res.PathEntries[l]
+			e.ShadowedBy = append(e.ShadowedBy, fmt.Sprintf("%s (by %s)", sh.Name, entries[sh.Winner].Dir))
+		}
+		e := &res.PathEntries[sh.Winner]
+		e.Shadows = append(e.Shadows, fmt.Sprintf("%s (hides %s)", sh.Name, strings.Join(hidden, ", ")))
+	}
+	return res, nil
+}
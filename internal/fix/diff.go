@@ -0,0 +1,98 @@
+package fix
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+type diffLine struct {
+	op   byte // ' ', '-' or '+'
+	text string
+	old  int // 1-based line number in the original (0 for added lines)
+	new  int // 1-based line number in the result (0 for removed lines)
+}
+
+// Diff renders the change as a unified diff.
+func (c Change) Diff() string {
+	var lines []diffLine
+	oldNo, newNo := 0, 0
+	for i, l := range c.Before {
+		oldNo++
+		if c.remove[i] {
+			lines = append(lines, diffLine{op: '-', text: l, old: oldNo})
+			continue
+		}
+		newNo++
+		if c.comment[i] {
+			lines = append(lines, diffLine{op: '-', text: l, old: oldNo}, diffLine{op: '+', text: commentOut(l), new: newNo})
+			continue
+		}
+		lines = append(lines, diffLine{op: ' ', text: l, old: oldNo, new: newNo})
+	}
+	for _, l := range c.append {
+		newNo++
+		lines = append(lines, diffLine{op: '+', text: l, new: newNo})
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", c.File, c.File))
+
+	for start := 0; start < len(lines); {
+		// Find the next change
+		first := start
+		for first < len(lines) && lines[first].op == ' ' {
+			first++
+		}
+		if first == len(lines) {
+			break
+		}
+
+		// Extend the hunk while changes are within 2*diffContext of each other
+		last := first
+		for j := first; j < len(lines); j++ {
+			if lines[j].op != ' ' {
+				last = j
+			} else if j-last > 2*diffContext {
+				break
+			}
+		}
+
+		from := max(first-diffContext, start)
+		to := min(last+diffContext, len(lines)-1)
+		writeHunk(&sb, lines[from:to+1])
+		start = to + 1
+	}
+	return sb.String()
+}
+
+func writeHunk(sb *strings.Builder, hunk []diffLine) {
+	oldStart, newStart, oldLen, newLen := 0, 0, 0, 0
+	for _, l := range hunk {
+		if l.op != '+' {
+			if oldStart == 0 {
+				oldStart = l.old
+			}
+			oldLen++
+		}
+		if l.op != '-' {
+			if newStart == 0 {
+				newStart = l.new
+			}
+			newLen++
+		}
+	}
+	// Empty ranges start at the line before, per the unified diff format
+	if oldLen == 0 {
+		oldStart = max(hunk[0].new-1, 0)
+	}
+	if newLen == 0 {
+		newStart = max(hunk[0].old-1, 0)
+	}
+	sb.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", oldStart, oldLen, newStart, newLen))
+	for _, l := range hunk {
+		sb.WriteString(string(l.op) + l.text + "\n")
+	}
+}
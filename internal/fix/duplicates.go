@@ -0,0 +1,83 @@
+package fix
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// DuplicateRemovals proposes removing config lines that only add PATH
+// entries already present earlier in PATH. Lines that also add a needed
+// entry, or that re-add an entry from the same line (the shell re-running
+// its own config), are left alone and explained in notes instead.
+func DuplicateRemovals(res model.AnalysisResult) (edits []Edit, notes []string) {
+	for _, sl := range res.SourceLines() {
+		var dups []int
+		var needed []string
+		for _, i := range sl.Entries {
+			e := res.PathEntries[i]
+			if !e.IsDuplicate {
+				needed = append(needed, e.Value)
+				continue
+			}
+			orig := res.PathEntries[e.DuplicateOf]
+			if orig.SourceFile == e.SourceFile && orig.LineNumber == e.LineNumber {
+				needed = append(needed, e.Value)
+				continue
+			}
+			dups = append(dups, i)
+		}
+		if len(dups) == 0 {
+			continue
+		}
+		if len(needed) > 0 {
+			notes = append(notes, fmt.Sprintf("%s:%d adds duplicates but also %s; edit it by hand.",
+				sl.File, sl.Line, strings.Join(needed, ", ")))
+			continue
+		}
+
+		text, ok := readLine(sl.File, sl.Line)
+		if !ok {
+			notes = append(notes, fmt.Sprintf("%s:%d could not be read.", sl.File, sl.Line))
+			continue
+		}
+		if !strings.Contains(text, "PATH") && !strings.Contains(text, "fish_add_path") {
+			// e.g. eval "$(brew shellenv)" - removing it would lose more than PATH
+			notes = append(notes, fmt.Sprintf("%s:%d adds duplicates indirectly (%s); edit it by hand.",
+				sl.File, sl.Line, strings.TrimSpace(text)))
+			continue
+		}
+		var reasons []string
+		for _, i := range dups {
+			e := res.PathEntries[i]
+			reason := fmt.Sprintf("%s duplicates PATH entry #%d", e.Value, e.DuplicateOf+1)
+			if e.Confidence != "" && e.Confidence != model.ConfidenceHigh {
+				reason += fmt.Sprintf(" (tentative: %s)", e.ConfidenceReason)
+			}
+			reasons = append(reasons, reason)
+		}
+		edits = append(edits, Edit{
+			File:   sl.File,
+			Action: ActionRemoveLine,
+			Text:   text,
+			Line:   sl.Line,
+			Reason: strings.Join(reasons, "; "),
+		})
+	}
+	return edits, notes
+}
+
+// readLine returns line n (1-based) of file.
+func readLine(file string, n int) (string, bool) {
+	content, err := os.ReadFile(model.ExpandTilde(file))
+	if err != nil {
+		return "", false
+	}
+	lines := splitLines(string(content))
+	if n < 1 || n > len(lines) {
+		return "", false
+	}
+	return lines[n-1], true
+}
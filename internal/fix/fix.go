@@ -0,0 +1,157 @@
+// Package fix applies edits to shell configuration files.
+// Every file is backed up before it is modified.
+package fix
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"lspath/internal/model"
+)
+
+// Edit actions
+const (
+	ActionAppend     = "append"      // Append Text to the end of the file
+	ActionRemoveLine = "remove-line" // Remove line Line, which must still read Text
+	ActionCommentOut = "comment-out" // Comment out line Line, which must still read Text
+)
+
+// Edit describes a single change to a config file.
+type Edit struct {
+	File   string // Config file to modify (may start with ~)
+	Action string // One of the Action* constants
+	Text   string // Text to insert (for append) or the expected line (for remove-line, comment-out)
+	Line   int    // 1-based line number (for remove-line, comment-out)
+	Reason string // Human-readable explanation shown before applying
+}
+
+// Change is the combined effect of one or more edits on a single file.
+type Change struct {
+	File    string   // Expanded path of the file
+	Edits   []Edit   // Edits that make up the change
+	Before  []string // Original lines
+	remove  map[int]bool
+	comment map[int]bool
+	append  []string
+}
+
+// Backup copies path to a timestamped sibling and returns the backup path.
+// The copy has the original's permissions, so a private file stays private.
+// A missing file is not an error; there is simply nothing to back up.
+func Backup(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	backup := fmt.Sprintf("%s.lspath-%s.bak", path, time.Now().Format("20060102-150405"))
+	if err := os.WriteFile(backup, content, filePerm(path)); err != nil {
+		return "", err
+	}
+	return backup, nil
+}
+
+// Plan groups edits by file and checks each one against the file's current
+// contents, so nothing is written if a file changed since it was analyzed.
+func Plan(edits []Edit) ([]Change, error) {
+	var changes []Change
+	byFile := make(map[string]int)
+
+	for _, e := range edits {
+		path := model.ExpandTilde(e.File)
+		ci, ok := byFile[path]
+		if !ok {
+			content, err := os.ReadFile(path)
+			if err != nil && !(os.IsNotExist(err) && e.Action == ActionAppend) {
+				return nil, err
+			}
+			changes = append(changes, Change{File: path, Before: splitLines(string(content)), remove: make(map[int]bool), comment: make(map[int]bool)})
+			ci = len(changes) - 1
+			byFile[path] = ci
+		}
+		c := &changes[ci]
+
+		switch e.Action {
+		case ActionAppend:
+			c.append = append(c.append, splitLines(e.Text)...)
+		case ActionRemoveLine, ActionCommentOut:
+			if e.Line < 1 || e.Line > len(c.Before) || strings.TrimSpace(c.Before[e.Line-1]) != strings.TrimSpace(e.Text) {
+				return nil, fmt.Errorf("line %d of %s has changed since the trace; re-run lspath", e.Line, path)
+			}
+			if e.Action == ActionRemoveLine {
+				c.remove[e.Line-1] = true
+			} else {
+				c.comment[e.Line-1] = true
+			}
+		default:
+			return nil, fmt.Errorf("unknown edit action %q", e.Action)
+		}
+		c.Edits = append(c.Edits, e)
+	}
+	return changes, nil
+}
+
+// After returns the file contents once the change is applied.
+func (c Change) After() string {
+	var lines []string
+	for i, l := range c.Before {
+		switch {
+		case c.remove[i]:
+		case c.comment[i]:
+			lines = append(lines, commentOut(l))
+		default:
+			lines = append(lines, l)
+		}
+	}
+	lines = append(lines, c.append...)
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// ApplyChange backs up the file and writes the changed contents, returning
+// the path of the backup it made.
+func ApplyChange(c Change) (string, error) {
+	backup, err := Backup(c.File)
+	if err != nil {
+		return "", fmt.Errorf("backing up %s: %w", c.File, err)
+	}
+	return backup, os.WriteFile(c.File, []byte(c.After()), filePerm(c.File))
+}
+
+// filePerm returns the permissions of the file at path, or 0644 for a file
+// that is yet to be created.
+func filePerm(path string) os.FileMode {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0644
+	}
+	return info.Mode().Perm()
+}
+
+// Apply performs a single edit, returning the path of the backup it made.
+func Apply(e Edit) (string, error) {
+	changes, err := Plan([]Edit{e})
+	if err != nil {
+		return "", err
+	}
+	return ApplyChange(changes[0])
+}
+
+// commentOut disables a shell line, marking who did it.
+func commentOut(line string) string {
+	return "# " + line + " # disabled by lspath"
+}
+
+// splitLines splits text into lines, ignoring the final newline.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
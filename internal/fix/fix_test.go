@@ -0,0 +1,36 @@
+package fix
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestApplyKeepsPermissions checks that a private file and its backup stay
+// private after a fix.
+func TestApplyKeepsPermissions(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".zshrc")
+	if err := os.WriteFile(file, []byte("export TOKEN=secret\nexport PATH=\"/opt/bin:$PATH\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(file, 0600); err != nil { // In case the umask is unusual
+		t.Fatal(err)
+	}
+
+	backup, err := Apply(Edit{File: file, Action: ActionRemoveLine, Line: 2, Text: `export PATH="/opt/bin:$PATH"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backup == "" {
+		t.Fatal("no backup was made")
+	}
+	for _, path := range []string{file, backup} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm != 0600 {
+			t.Errorf("%s has permissions %#o, want 0600", filepath.Base(path), perm)
+		}
+	}
+}
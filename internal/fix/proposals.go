@@ -0,0 +1,30 @@
+package fix
+
+import (
+	"strings"
+
+	"lspath/internal/model"
+	"lspath/internal/trace"
+)
+
+// Proposals returns every edit --fix and the TUI's fix dialog offer for res:
+// removing the config lines that add nothing but duplicates (see
+// DuplicateRemovals) and, when res's pins are out of order, appending a
+// snippet that restores them to the startup file shellName reads.
+func Proposals(res model.AnalysisResult, shellName string) (edits []Edit, notes []string) {
+	edits, notes = DuplicateRemovals(res)
+	if broken := res.BrokenPins(); len(broken) > 0 {
+		file, snippet := trace.PinAdvice(shellName, res)
+		var reasons []string
+		for _, p := range broken {
+			reasons = append(reasons, p.Broken)
+		}
+		edits = append(edits, Edit{
+			File:   file,
+			Action: ActionAppend,
+			Text:   "\n# Added by lspath: restore the pinned order\n" + snippet,
+			Reason: strings.Join(reasons, "; "),
+		})
+	}
+	return edits, notes
+}
@@ -0,0 +1,286 @@
+// Package fleet aggregates analyses saved on many machines (--json
+// snapshots and .lspath bundles) into one report of environment drift:
+// problems several hosts share, hosts that differ most from the rest and
+// entries only one host has.
+package fleet
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"lspath/internal/bundle"
+	"lspath/internal/model"
+	"lspath/internal/trace"
+)
+
+// Host is one saved analysis, named after its file.
+type Host struct {
+	Name   string
+	File   string
+	Result model.AnalysisResult `json:"-"`
+}
+
+// Load reads every .json and .lspath file in dir, in name order. Files that
+// cannot be read are returned as errors alongside the hosts that could.
+func Load(dir string) ([]Host, []error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, []error{err}
+	}
+	var hosts []Host
+	var errs []error
+	for _, f := range files {
+		ext := filepath.Ext(f.Name())
+		if f.IsDir() || (ext != ".json" && ext != ".lspath") {
+			continue
+		}
+		h := Host{Name: strings.TrimSuffix(f.Name(), ext), File: filepath.Join(dir, f.Name())}
+		if ext == ".lspath" {
+			b, err := bundle.Open(h.File)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			h.Result = b.Result
+		} else {
+			h.Result, err = trace.LoadSnapshot(h.File)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		hosts = append(hosts, h)
+	}
+	return hosts, errs
+}
+
+// Problems a fleet report counts, taken from what each analysis recorded on
+// its own machine (this machine's filesystem says nothing about theirs).
+var problemOrder = []string{model.IgnoreMissing, model.IgnoreBroken, model.IgnoreDuplicate, model.IgnoreRelative, model.IgnoreSession}
+
+// Issue is a problem with one directory, and the hosts that have it.
+type Issue struct {
+	Problem string
+	Dir     string // Directory key, with home directories written as ~
+	Hosts   []string
+}
+
+// Outlier is a host whose entries differ most from the rest of the fleet.
+type Outlier struct {
+	Host    string
+	Unique  []string // Entries no other host has
+	Lacking []string // Entries most other hosts have
+}
+
+// Report is the aggregate of a fleet of analyses.
+type Report struct {
+	Hosts    []Host
+	Issues   []Issue             // Problems on more than one host, most widespread first
+	Totals   map[string]int      // Hosts with each problem, by problem
+	Unique   map[string][]string // Entries only one host has, by host
+	Outliers []Outlier           // Most drifted first
+}
+
+// homePrefix matches a home directory, so ~/.local/bin on alice's machine
+// and bob's count as the same entry.
+var homePrefix = regexp.MustCompile(`^(?:/home/[^/]+|/Users/[^/]+|/root|(?i:[a-z]:\\Users\\[^\\]+))([/\\]|$)`)
+
+// dirKey is the fleet-wide key of a PATH value.
+func dirKey(value string) string {
+	if value == "" {
+		return model.DisplayPath(value)
+	}
+	key := value
+	if len(key) > 1 {
+		key = strings.TrimRight(key, `/\`)
+	}
+	return homePrefix.ReplaceAllString(key, "~${1}")
+}
+
+// problems lists the problems the analysis recorded for e, leaving out the
+// ones its owner ignored.
+func problems(e model.PathEntry, pol model.DuplicatePolicy) []string {
+	var found []string
+	for _, p := range problemOrder {
+		has := false
+		switch p {
+		case model.IgnoreMissing:
+			for _, d := range e.Diagnostics {
+				has = has || d == "Directory does not exist on disk."
+			}
+		case model.IgnoreBroken:
+			has = e.SymlinkBroken != ""
+		case model.IgnoreDuplicate:
+			has = pol.Flagged(e)
+		case model.IgnoreRelative:
+			has = model.IsRelativePath(e.Value)
+		case model.IgnoreSession:
+			has = e.IsSessionOnly
+		}
+		if has && !e.IsIgnored(p) {
+			found = append(found, p)
+		}
+	}
+	return found
+}
+
+// Aggregate compares the hosts' analyses.
+func Aggregate(hosts []Host) Report {
+	r := Report{Hosts: hosts, Totals: make(map[string]int), Unique: make(map[string][]string)}
+
+	type issueKey struct{ problem, dir string }
+	issueHosts := make(map[issueKey][]string)
+	entryHosts := make(map[string][]string) // Directory key -> hosts with it
+	entrySets := make([]map[string]bool, len(hosts))
+	for i, h := range hosts {
+		entrySets[i] = make(map[string]bool)
+		hostProblems := make(map[string]bool)
+		for _, e := range h.Result.PathEntries {
+			key := dirKey(e.Value)
+			if !entrySets[i][key] {
+				entrySets[i][key] = true
+				entryHosts[key] = append(entryHosts[key], h.Name)
+			}
+			for _, p := range problems(e, h.Result.DuplicatePolicy) {
+				k := issueKey{p, key}
+				if n := len(issueHosts[k]); n == 0 || issueHosts[k][n-1] != h.Name {
+					issueHosts[k] = append(issueHosts[k], h.Name)
+				}
+				hostProblems[p] = true
+			}
+		}
+		for p := range hostProblems {
+			r.Totals[p]++
+		}
+	}
+
+	for k, names := range issueHosts {
+		if len(names) > 1 {
+			r.Issues = append(r.Issues, Issue{Problem: k.problem, Dir: k.dir, Hosts: names})
+		}
+	}
+	sort.Slice(r.Issues, func(i, j int) bool {
+		a, b := r.Issues[i], r.Issues[j]
+		if len(a.Hosts) != len(b.Hosts) {
+			return len(a.Hosts) > len(b.Hosts)
+		}
+		if a.Problem != b.Problem {
+			return a.Problem < b.Problem
+		}
+		return a.Dir < b.Dir
+	})
+
+	if len(hosts) < 2 {
+		return r
+	}
+	for key, names := range entryHosts {
+		if len(names) == 1 {
+			r.Unique[names[0]] = append(r.Unique[names[0]], key)
+		}
+	}
+	for _, keys := range r.Unique {
+		sort.Strings(keys)
+	}
+
+	// A host drifts by what only it has and by what most others have but
+	// it lacks; outliers drift well beyond the typical host.
+	drift := make([]int, len(hosts))
+	lacking := make([][]string, len(hosts))
+	for i, h := range hosts {
+		for key, names := range entryHosts {
+			if !entrySets[i][key] && len(names)*2 > len(hosts)-1 {
+				lacking[i] = append(lacking[i], key)
+			}
+		}
+		sort.Strings(lacking[i])
+		drift[i] = len(r.Unique[h.Name]) + len(lacking[i])
+	}
+	sorted := append([]int(nil), drift...)
+	sort.Ints(sorted)
+	limit := max(2, 2*sorted[len(sorted)/2])
+	for i, h := range hosts {
+		if drift[i] > limit {
+			r.Outliers = append(r.Outliers, Outlier{Host: h.Name, Unique: r.Unique[h.Name], Lacking: lacking[i]})
+		}
+	}
+	sort.SliceStable(r.Outliers, func(i, j int) bool {
+		return len(r.Outliers[i].Unique)+len(r.Outliers[i].Lacking) > len(r.Outliers[j].Unique)+len(r.Outliers[j].Lacking)
+	})
+	return r
+}
+
+// Format renders r as a text report.
+func Format(r Report) string {
+	var sb strings.Builder
+	title := fmt.Sprintf("FLEET REPORT (%d HOSTS)", len(r.Hosts))
+	sb.WriteString(title + "\n" + strings.Repeat("=", len(title)) + "\n\n")
+
+	width := 0
+	for _, h := range r.Hosts {
+		width = max(width, len(h.Name))
+	}
+	sb.WriteString("HOSTS\n-----\n")
+	for _, h := range r.Hosts {
+		issues := 0
+		for _, e := range h.Result.PathEntries {
+			issues += len(problems(e, h.Result.DuplicatePolicy))
+		}
+		line := fmt.Sprintf("%-*s  %d %s entries, %d problems", width, h.Name, len(h.Result.PathEntries), h.Result.VariableName(), issues)
+		if env := h.Result.Environment.Summary(); env != "" {
+			line += " · " + env
+		}
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString("\n")
+
+	sb.WriteString("PROBLEMS BY HOST COUNT\n----------------------\n")
+	for _, p := range problemOrder {
+		sb.WriteString(fmt.Sprintf("%-10s %d/%d hosts\n", p, r.Totals[p], len(r.Hosts)))
+	}
+	sb.WriteString("\n")
+
+	sb.WriteString("COMMON ISSUES\n-------------\n")
+	if len(r.Issues) == 0 {
+		sb.WriteString("No problem is shared by more than one host.\n")
+	}
+	dirWidth := 0
+	for _, is := range r.Issues {
+		dirWidth = max(dirWidth, len(is.Dir))
+	}
+	for _, is := range r.Issues {
+		sb.WriteString(fmt.Sprintf("%-10s %-*s  %d/%d hosts (%s)\n", is.Problem, dirWidth, is.Dir, len(is.Hosts), len(r.Hosts), strings.Join(is.Hosts, ", ")))
+	}
+	sb.WriteString("\n")
+
+	if len(r.Hosts) < 2 {
+		sb.WriteString("Add more hosts to compare their entries.\n")
+		return sb.String()
+	}
+
+	sb.WriteString("OUTLIER HOSTS\n-------------\n")
+	if len(r.Outliers) == 0 {
+		sb.WriteString("No host stands out from the rest.\n")
+	}
+	for _, o := range r.Outliers {
+		sb.WriteString(fmt.Sprintf("%s: %d entries no other host has, lacks %d entries most hosts have\n", o.Host, len(o.Unique), len(o.Lacking)))
+		for _, key := range o.Lacking {
+			sb.WriteString("  - " + key + "\n")
+		}
+	}
+	sb.WriteString("\n")
+
+	sb.WriteString("ENTRIES UNIQUE TO ONE HOST\n--------------------------\n")
+	if len(r.Unique) == 0 {
+		sb.WriteString("Every entry is on at least two hosts.\n")
+	}
+	for _, h := range r.Hosts {
+		for _, key := range r.Unique[h.Name] {
+			sb.WriteString(fmt.Sprintf("%-*s  + %s\n", width, h.Name, key))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,225 @@
+// Package help renders lspath's help, written once in help.md, for the
+// TUI's help dialog, the web page's, and the man page and plain-text long
+// help that --man prints.
+package help
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"lspath/internal/model"
+
+	"github.com/spf13/pflag"
+)
+
+//go:embed help.md
+var source string
+
+// Audiences the help is rendered for. Lines of help.md that start with
+// "[tui] " or "[web] " are only for that front end; the man page and long
+// help describe the TUI, lspath's default mode.
+const (
+	TUI = "tui"
+	Web = "web"
+	CLI = "cli"
+)
+
+// Synopsis lists the ways to run lspath, for --help and the man page.
+var Synopsis = []string{
+	"lspath [options]",
+	"lspath which <command>...",
+	"lspath --diff <old.json> [new.json]",
+	"lspath bundle export|open <file.lspath>",
+	"lspath prompt-init zsh|bash|fish",
+	"lspath fleet --inputs <dir>",
+	"lspath demo [--web]",
+}
+
+// Text returns the help for audience, with {{VERSION}} filled in.
+func Text(audience string) string {
+	var sb strings.Builder
+	for _, line := range strings.SplitAfter(source, "\n") {
+		if rest, ok := strings.CutPrefix(line, "[tui] "); ok {
+			if audience == Web {
+				continue
+			}
+			line = rest
+		} else if rest, ok := strings.CutPrefix(line, "[web] "); ok {
+			if audience != Web {
+				continue
+			}
+			line = rest
+		}
+		sb.WriteString(line)
+	}
+	return strings.ReplaceAll(sb.String(), "{{VERSION}}", model.Version)
+}
+
+// section is a titled part of the help, e.g. "FLOW MODE".
+type section struct {
+	Title string
+	Lines []string
+}
+
+// sections splits text at its titles, which are underlined with - or =.
+// The first is the title of the whole text.
+func sections(text string) []section {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	var out []section
+	for i := 0; i < len(lines); i++ {
+		if i+1 < len(lines) && isUnderline(lines[i+1]) && strings.TrimSpace(lines[i]) != "" {
+			out = append(out, section{Title: strings.TrimSpace(lines[i])})
+			i++
+			continue
+		}
+		if len(out) == 0 {
+			out = append(out, section{})
+		}
+		out[len(out)-1].Lines = append(out[len(out)-1].Lines, lines[i])
+	}
+	return out
+}
+
+func isUnderline(line string) bool {
+	line = strings.TrimSpace(line)
+	return len(line) >= 3 && (strings.Trim(line, "-") == "" || strings.Trim(line, "=") == "")
+}
+
+// optionalDefaults are the defaults not worth printing.
+var optionalDefaults = map[string]bool{"": true, "false": true, "0": true, "0s": true, "[]": true}
+
+// flagDefault describes f's default for the manual, e.g. " (default 10s)".
+func flagDefault(f *pflag.Flag) string {
+	if optionalDefaults[f.DefValue] {
+		return ""
+	}
+	if f.Value.Type() == "string" {
+		return fmt.Sprintf(" (default %q)", f.DefValue)
+	}
+	return " (default " + f.DefValue + ")"
+}
+
+// Long returns the plain-text long help: how to run lspath, the help text
+// and every option in flags.
+func Long(flags *pflag.FlagSet) string {
+	var sb strings.Builder
+	for i, s := range sections(Text(CLI)) {
+		if s.Title != "" {
+			sb.WriteString(s.Title + "\n" + strings.Repeat(underline(i), len(s.Title)) + "\n")
+		}
+		sb.WriteString(strings.Join(s.Lines, "\n") + "\n")
+		if i == 0 {
+			sb.WriteString("USAGE\n-----\n")
+			for _, s := range Synopsis {
+				sb.WriteString("  " + s + "\n")
+			}
+			sb.WriteString("\n")
+		}
+		if i == 1 {
+			sb.WriteString("OPTIONS\n-------\n" + flags.FlagUsages() + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// underline is the character under the title of the i'th section.
+func underline(i int) string {
+	if i == 0 {
+		return "="
+	}
+	return "-"
+}
+
+var (
+	keyRe      = regexp.MustCompile(`^• (.{12}): (.*)$`) // "• q / Ctrl+C  : Quit application", aligned
+	bulletRe   = regexp.MustCompile(`^• (.*)$`)
+	numberedRe = regexp.MustCompile(`^(\d+)\. (.*)$`)
+	subheadRe  = regexp.MustCompile(`^[A-Z][A-Z-]+( [A-Z-]+)+$`) // "COMMON NAVIGATION"
+)
+
+// roff escapes text for troff.
+func roff(text string) string {
+	text = strings.ReplaceAll(text, `\`, `\e`)
+	text = strings.ReplaceAll(text, "-", `\-`)
+	if strings.HasPrefix(text, ".") || strings.HasPrefix(text, "'") {
+		text = `\&` + text
+	}
+	return text
+}
+
+// manBody turns the lines of a help section into troff: blank lines break
+// paragraphs, • lines become bulleted items (keyboard shortcuts a tagged
+// list), numbered lines numbered items and unindented capitalized lines
+// subsections. Indented lines continue the item above.
+func manBody(lines []string) string {
+	var sb strings.Builder
+	para := false // Text written since the last paragraph break
+	for _, line := range lines {
+		text := strings.TrimSpace(line)
+		if text == "" {
+			if para {
+				sb.WriteString(".PP\n")
+				para = false
+			}
+			continue
+		}
+		para = true
+		if m := keyRe.FindStringSubmatch(text); m != nil {
+			sb.WriteString(".TP 14\n\\fB" + roff(strings.TrimSpace(m[1])) + "\\fR\n" + roff(m[2]) + "\n")
+		} else if m := bulletRe.FindStringSubmatch(text); m != nil {
+			sb.WriteString(".IP \\(bu 2\n" + roff(m[1]) + "\n")
+		} else if m := numberedRe.FindStringSubmatch(text); m != nil {
+			sb.WriteString(".IP " + m[1] + ". 4\n" + roff(m[2]) + "\n")
+		} else if subheadRe.MatchString(text) && line == text {
+			sb.WriteString(".SS " + text + "\n")
+		} else {
+			sb.WriteString(roff(text) + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// Man returns the man page: the help text with a synopsis and every option
+// in flags, in troff (view it with `man -l -`).
+func Man(flags *pflag.FlagSet) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(".TH LSPATH 1 \"\" \"lspath %s\" \"User Commands\"\n", model.Version))
+	sb.WriteString(".SH NAME\nlspath \\- see where every PATH entry comes from, and fix it\n")
+	sb.WriteString(".SH SYNOPSIS\n.nf\n")
+	for _, s := range Synopsis {
+		sb.WriteString(roff(s) + "\n")
+	}
+	sb.WriteString(".fi\n")
+	for i, s := range sections(Text(CLI)) {
+		switch i {
+		case 0:
+			continue // The title, given by .TH
+		case 1:
+			sb.WriteString(".SH DESCRIPTION\n" + manBody(s.Lines))
+			sb.WriteString(".SH OPTIONS\n")
+			flags.VisitAll(func(f *pflag.Flag) {
+				if f.Hidden {
+					return
+				}
+				name := `\fB\-\-` + roff(f.Name) + `\fR`
+				if f.Shorthand != "" {
+					name = `\fB\-` + f.Shorthand + `\fR, ` + name
+				}
+				arg, usage := pflag.UnquoteUsage(f)
+				if arg != "" {
+					if f.NoOptDefVal != "" {
+						name += `[=\fI` + arg + `\fR]`
+					} else {
+						name += ` \fI` + arg + `\fR`
+					}
+				}
+				sb.WriteString(".TP\n" + name + "\n" + roff(usage+flagDefault(f)) + "\n")
+			})
+			continue
+		}
+		sb.WriteString(".SH " + s.Title + "\n" + manBody(s.Lines))
+	}
+	return sb.String()
+}
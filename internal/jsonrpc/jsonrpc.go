@@ -0,0 +1,100 @@
+// Package jsonrpc reads JSON-RPC 2.0 requests one per line and writes the
+// answers the same way, the framing both --mcp (on stdin/stdout) and
+// --daemon (on a Unix socket) use.
+package jsonrpc
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Error codes defined by JSON-RPC.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// maxMessage is the longest request line read.
+const maxMessage = 16 * 1024 * 1024
+
+// Error is a failed request's answer.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Errorf returns an error with code and a formatted message.
+func Errorf(code int, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Request is a call; one without an ID is a notification, which gets no
+// answer.
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Handler answers a request with a result or an error.
+type Handler func(req Request) (any, *Error)
+
+// ParseParams decodes req's params into v. Absent params leave v as it is.
+func ParseParams(req Request, v any) *Error {
+	if len(req.Params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(req.Params, v); err != nil {
+		return Errorf(CodeInvalidParams, "%v", err)
+	}
+	return nil
+}
+
+// Serve answers the requests read from in on out, in order, until in ends.
+func Serve(in io.Reader, out io.Writer, handle Handler) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), maxMessage)
+	enc := json.NewEncoder(out) // Encode ends each message with a newline
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var req Request
+		if err := json.Unmarshal(line, &req); err != nil {
+			if err := enc.Encode(response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: Errorf(CodeParseError, "%v", err)}); err != nil {
+				return err
+			}
+			continue
+		}
+		if len(req.ID) == 0 {
+			continue
+		}
+		resp := response{JSONRPC: "2.0", ID: req.ID}
+		if req.JSONRPC != "2.0" {
+			resp.Error = Errorf(CodeInvalidRequest, `jsonrpc must be "2.0"`)
+		} else {
+			resp.Result, resp.Error = handle(req)
+		}
+		if resp.Result == nil && resp.Error == nil {
+			resp.Result = struct{}{} // A result is required, even if empty
+		}
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,119 @@
+// Package mcp serves the analysis over the Model Context Protocol, so AI
+// coding assistants can ask where PATH comes from and which binary a
+// command runs when they diagnose "command not found". Messages are
+// JSON-RPC 2.0, one per line on stdin and stdout (MCP's stdio transport).
+package mcp
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"lspath/internal/jsonrpc"
+	"lspath/internal/model"
+	"lspath/internal/trace"
+)
+
+// ProtocolVersion is the MCP revision the server speaks.
+const ProtocolVersion = "2024-11-05"
+
+// ResultTTL is how long tools answer from the last analysis before tracing
+// the shell again, so a conversation's tool calls share one trace but see
+// config files the assistant has since edited.
+const ResultTTL = 30 * time.Second
+
+// content is a tool's answer: text for the assistant to read.
+type content struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type toolResult struct {
+	Content []content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// Server answers MCP requests from the analysis opts configure.
+type Server struct {
+	opts trace.Options
+
+	mu       sync.Mutex // Held while tracing, so calls share a trace
+	result   *model.AnalysisResult
+	resultAt time.Time
+}
+
+// NewServer returns a server that analyzes with opts.
+func NewServer(opts trace.Options) *Server {
+	return &Server{opts: opts}
+}
+
+// Serve answers requests read from in on out until in ends.
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	return jsonrpc.Serve(in, out, s.handle)
+}
+
+// handle answers one request.
+func (s *Server) handle(req jsonrpc.Request) (any, *jsonrpc.Error) {
+	switch req.Method {
+	case "initialize":
+		return map[string]any{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]string{"name": "lspath", "version": model.Version},
+			"instructions":    "Use these tools when a command is not found, the wrong version of a command runs, or PATH differs between shells: they show where each PATH entry was added (file and line) and which binary wins.",
+		}, nil
+	case "ping":
+		return nil, nil
+	case "tools/list":
+		return map[string]any{"tools": tools}, nil
+	case "tools/call":
+		var params struct {
+			Name      string   `json:"name"`
+			Arguments toolArgs `json:"arguments"`
+		}
+		if err := jsonrpc.ParseParams(req, &params); err != nil {
+			return nil, err
+		}
+		t, ok := toolsByName[params.Name]
+		if !ok {
+			return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "unknown tool %q", params.Name)
+		}
+		text, err := t.run(s, params.Arguments)
+		if err != nil {
+			// Tool failures go to the assistant, which may retry or explain
+			return toolResult{Content: []content{{"text", err.Error()}}, IsError: true}, nil
+		}
+		return toolResult{Content: []content{{"text", text}}}, nil
+	}
+	return nil, jsonrpc.Errorf(jsonrpc.CodeMethodNotFound, "method %q not found", req.Method)
+}
+
+// analysis returns the last analysis if it is younger than ResultTTL and
+// refresh is false, else a new one.
+func (s *Server) analysis(refresh bool) (model.AnalysisResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.result != nil && !refresh && time.Since(s.resultAt) < ResultTTL {
+		return *s.result, nil
+	}
+	res, err := trace.RunAnalysis(s.opts)
+	if err != nil {
+		return res, err
+	}
+	s.result, s.resultAt = &res, time.Now()
+	return res, nil
+}
+
+// explain describes one entry, scanning PATH within DefaultScanBudget for
+// the commands that would break without it.
+func (s *Server) explain(res model.AnalysisResult, ref string) (string, error) {
+	idx, err := trace.FindEntry(res, ref)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), trace.DefaultScanBudget)
+	defer cancel()
+	bins := trace.ScanBinaryIndex(ctx, res.PathEntries, nil)
+	return trace.GenerateExplanation(res, idx, bins), nil
+}
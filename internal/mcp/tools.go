@@ -0,0 +1,117 @@
+package mcp
+
+import (
+	"fmt"
+
+	"lspath/internal/trace"
+)
+
+// toolArgs holds the arguments of every tool; each reads the ones it
+// declares in its schema.
+type toolArgs struct {
+	Name    string `json:"name"`
+	Entry   string `json:"entry"`
+	Verbose bool   `json:"verbose"`
+	Refresh bool   `json:"refresh"`
+}
+
+// tool is what tools/list describes, and how tools/call runs it.
+type tool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+
+	run func(s *Server, args toolArgs) (string, error)
+}
+
+// Schemas of the arguments tools share.
+var (
+	verboseArg = map[string]any{"type": "boolean", "description": "Include every entry's details"}
+	refreshArg = map[string]any{"type": "boolean", "description": "Trace the shell again even if the last analysis is recent, e.g. after editing a startup file"}
+)
+
+var tools = []tool{
+	{
+		Name:        "get_path_analysis",
+		Description: "Analyze PATH as the user's shell sets it up: every entry in priority order with the startup file and line that added it, plus duplicates, missing directories, entries others can write to and shadowed binaries.",
+		InputSchema: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"verbose": verboseArg, "refresh": refreshArg},
+		},
+		run: func(s *Server, args toolArgs) (string, error) {
+			res, err := s.analysis(args.Refresh)
+			if err != nil {
+				return "", err
+			}
+			return trace.GenerateReport(res, args.Verbose), nil
+		},
+	},
+	{
+		Name:        "which_binary",
+		Description: "Find every PATH entry with an executable called name, in priority order: the first is what the shell runs, the rest are shadowed. Use it when a command is not found or the wrong version runs.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"name":    map[string]any{"type": "string", "description": "Command name, e.g. python3"},
+				"refresh": refreshArg,
+			},
+			"required": []string{"name"},
+		},
+		run: func(s *Server, args toolArgs) (string, error) {
+			if args.Name == "" {
+				return "", fmt.Errorf("name is required")
+			}
+			res, err := s.analysis(args.Refresh)
+			if err != nil {
+				return "", err
+			}
+			return trace.FormatWhich(res, args.Name, trace.FindCommand(res.PathEntries, args.Name)), nil
+		},
+	},
+	{
+		Name:        "get_config_flow",
+		Description: "List the shell startup files in the order they ran, nested by which file sourced which, with how many PATH entries each added (and, if verbose, the lines that added them).",
+		InputSchema: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"verbose": verboseArg, "refresh": refreshArg},
+		},
+		run: func(s *Server, args toolArgs) (string, error) {
+			res, err := s.analysis(args.Refresh)
+			if err != nil {
+				return "", err
+			}
+			return trace.GenerateFlow(res, args.Verbose), nil
+		},
+	},
+	{
+		Name:        "explain_entry",
+		Description: "Explain one PATH entry, given by number or directory: where it was added, its problems, and which commands would break if it were removed.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"entry":   map[string]any{"type": "string", "description": "Entry number (1 is first) or directory, e.g. ~/go/bin"},
+				"refresh": refreshArg,
+			},
+			"required": []string{"entry"},
+		},
+		run: func(s *Server, args toolArgs) (string, error) {
+			if args.Entry == "" {
+				return "", fmt.Errorf("entry is required")
+			}
+			res, err := s.analysis(args.Refresh)
+			if err != nil {
+				return "", err
+			}
+			return s.explain(res, args.Entry)
+		},
+	},
+}
+
+// toolsByName indexes tools for tools/call.
+var toolsByName = func() map[string]tool {
+	m := make(map[string]tool, len(tools))
+	for _, t := range tools {
+		m[t.Name] = t
+	}
+	return m
+}()
@@ -0,0 +1,183 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// CanonOptions controls how PATH values are normalized before comparison.
+// The zero value expands ~/$HOME and collapses trailing slashes and //
+// runs only.
+type CanonOptions struct {
+	ResolveSymlinks bool // Resolve the full symlink chain (e.g. /bin -> /usr/bin)
+	CaseInsensitive bool // Compare paths case-insensitively (e.g. macOS APFS)
+	DetectCase      bool // Compare case-insensitively on volumes that are (see CaseInsensitiveVolume)
+	FoldPrivate     bool // Treat macOS's /private/var, /private/tmp and /private/etc as /var, /tmp and /etc
+}
+
+// How the case of PATH values is compared (--path-case).
+const (
+	CaseAuto        = "auto"        // Ignored on volumes that ignore it, detected per directory
+	CaseSensitive   = "sensitive"   // Always significant
+	CaseInsensitive = "insensitive" // Always ignored
+)
+
+// ParseCaseMode validates a --path-case value.
+func ParseCaseMode(s string) (string, error) {
+	switch s {
+	case "", CaseAuto:
+		return CaseAuto, nil
+	case CaseSensitive, CaseInsensitive:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown path case %q (use auto, sensitive or insensitive)", s)
+}
+
+// CanonOptionsFor returns the options that compare case as mode says (one
+// of the Case* constants; empty means CaseAuto), folding /private on macOS.
+func CanonOptionsFor(mode string) CanonOptions {
+	opts := CanonOptions{FoldPrivate: runtime.GOOS == "darwin"}
+	switch mode {
+	case CaseInsensitive:
+		opts.CaseInsensitive = true
+	case CaseSensitive:
+	default:
+		opts.DetectCase = true
+	}
+	return opts
+}
+
+// caseVolumes caches CaseInsensitiveVolume's answer for each directory it
+// looked a name up in.
+var caseVolumes sync.Map
+
+// CaseInsensitiveVolume reports whether path is on a volume that ignores
+// case, such as macOS's default APFS: whether the nearest existing
+// directory on it is found under its name with the case of the letters
+// swapped. A path with no letters, or none of whose directories exist, is
+// taken to be case-sensitive.
+func CaseInsensitiveVolume(path string) bool {
+	p := ExpandTilde(path)
+	if !filepath.IsAbs(p) {
+		return false
+	}
+	for p = filepath.Clean(p); ; p = filepath.Dir(p) {
+		if folds, ok := caseVolumes.Load(p); ok {
+			return folds.(bool)
+		}
+		base := filepath.Base(p)
+		if swapped := swapCase(base); swapped != base {
+			if info, err := os.Stat(p); err == nil {
+				other, err := os.Stat(filepath.Join(filepath.Dir(p), swapped))
+				folds := err == nil && os.SameFile(info, other)
+				caseVolumes.Store(p, folds)
+				return folds
+			}
+		}
+		if filepath.Dir(p) == p {
+			return false
+		}
+	}
+}
+
+func swapCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}, s)
+}
+
+// privateDirs are the macOS directories whose real location is under
+// /private, reached through a symlink at the root.
+var privateDirs = []string{"/private/var", "/private/tmp", "/private/etc"}
+
+// ExpandTilde expands a leading ~ or $HOME to the user's home directory.
+func ExpandTilde(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	switch {
+	case path == "~" || path == "$HOME" || path == "${HOME}":
+		return home
+	case strings.HasPrefix(path, "~/"):
+		return filepath.Join(home, path[2:])
+	case strings.HasPrefix(path, "$HOME/"):
+		return filepath.Join(home, path[6:])
+	case strings.HasPrefix(path, "${HOME}/"):
+		return filepath.Join(home, path[8:])
+	}
+	return path
+}
+
+// SplitPathList splits a PATH-like value on ':', keeping empty segments
+// ("a::b", a trailing ':'), which the shell treats as the current directory.
+// An empty value has no segments.
+func SplitPathList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ":")
+}
+
+// IsRelativePath reports whether a PATH segment is empty or relative, so it
+// is resolved against the current directory each time it is searched.
+func IsRelativePath(path string) bool {
+	return path == "" || !filepath.IsAbs(ExpandTilde(path))
+}
+
+// DisplayPath returns path for display, naming the otherwise invisible
+// empty segment.
+func DisplayPath(path string) string {
+	if path == "" {
+		return "(empty: current directory)"
+	}
+	return path
+}
+
+// CanonicalPath returns the comparison key for a PATH value. Two entries that
+// refer to the same directory under the given options share the same key.
+// The result is only used for matching; display code keeps the raw value.
+func CanonicalPath(path string, opts CanonOptions) string {
+	if path == "" {
+		return ""
+	}
+	p := ExpandTilde(path)
+
+	// Collapse trailing slashes and // runs, but keep relative entries relative
+	if len(p) > 1 {
+		p = filepath.Clean(p)
+	}
+
+	if opts.ResolveSymlinks {
+		if resolved, err := filepath.EvalSymlinks(p); err == nil {
+			p = resolved
+		}
+	}
+
+	if opts.CaseInsensitive || (opts.DetectCase && CaseInsensitiveVolume(p)) {
+		p = strings.ToLower(p)
+	}
+
+	if opts.FoldPrivate {
+		for _, d := range privateDirs {
+			if p == d || strings.HasPrefix(p, d+"/") {
+				p = strings.TrimPrefix(p, "/private")
+				break
+			}
+		}
+	}
+	return p
+}
+
+// SamePath reports whether two PATH values refer to the same directory.
+func SamePath(a, b string, opts CanonOptions) bool {
+	return CanonicalPath(a, opts) == CanonicalPath(b, opts)
+}
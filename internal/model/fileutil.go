@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"fmt"
 	"os"
-	"strings"
 )
 
 // LineContext represents a line from a file with surrounding context
@@ -29,12 +28,7 @@ func GetLineContext(filePath string, lineNumber int) LineContext {
 	}
 
 	// Expand tilde in file path
-	if strings.HasPrefix(filePath, "~/") {
-		home, err := os.UserHomeDir()
-		if err == nil {
-			filePath = strings.Replace(filePath, "~", home, 1)
-		}
-	}
+	filePath = ExpandTilde(filePath)
 
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -12,4 +12,6 @@ const (
 	IconMissing      = "✗" // Thin X (missing)
 	IconOK           = " " // Space (OK - no icon to reduce noise)
 	IconSession      = "◆" // Diamond for session-only paths
+	IconShadow       = "◐" // Half-shaded circle (binary shadowed by another entry)
+	IconRelative     = "!" // Exclamation (empty or relative segment, searched in the current directory)
 )
@@ -1,20 +1,29 @@
 package model
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
 // PathEntry represents a single directory in the system PATH.
 type PathEntry struct {
 	Value       string   // The directory path (e.g., /usr/bin)
 	SourceFile  string   // File where it was added (e.g., .zshrc)
 	LineNumber  int      // Line number in the source file
 	Mode        string   // "Login" or "Interactive" or "Unknown"
-	Shadows     []string // List of paths that this entry shadows (if applicable)
+	Shadows     []string // Binaries in this entry that hide copies in later entries (e.g., "python3 (hides /usr/bin)")
+	ShadowedBy  []string // Binaries in this entry hidden by an earlier entry (e.g., "python3 (by /opt/homebrew/bin)")
 	IsDuplicate bool     // True if this is a duplicate entry
 	DuplicateOf int      // Index of the original entry if this is a duplicate
 	Remediation string   // Advice on how to fix/remove if duplicate (HTML format for web)
 
 	// Symlink tracking
-	IsSymlink       bool   // True if this path is a symlink
-	SymlinkTarget   string // Resolved target of the symlink
-	SymlinkPointsTo int    // Index of PATH entry that this symlink resolves to (-1 if none)
+	IsSymlink       bool     // True if this path is a symlink
+	SymlinkTarget   string   // Where the chain of symlinks ends
+	SymlinkChain    []string // Each link's target in turn, ending with SymlinkTarget
+	SymlinkBroken   string   // Why the chain leads nowhere (e.g. "/opt/x does not exist"), if it does
+	SymlinkPointsTo int      // Index of PATH entry that this symlink resolves to (-1 if none)
 
 	// Standardized human-readable messages (DRY principle)
 	DuplicateMessage string // User-friendly duplicate message (plain text)
@@ -24,11 +33,77 @@ type PathEntry struct {
 	IsSessionOnly bool   // True if this path was added manually/runtime (not from shell config)
 	SessionNote   string // Explanation of session-only status (e.g., "Virtual environment")
 
+	// Package attribution
+	Package string // System package that installed this entry (e.g., "XQuartz (/etc/paths.d/40-XQuartz)")
+
+	// Version managers
+	Kind           string // KindShims or KindManagedVersion if a version manager owns the directory
+	VersionManager string // The version manager, e.g. "pyenv"
+
+	// Hook is the tool whose eval'd shell code added the entry, if one did
+	Hook *EvalHook
+
 	// Flow Attribution
 	FlowID      string   // ID of the ConfigNode this belongs to
 	Diagnostics []string // List of issues (e.g., missing directory)
+
+	// Attribution confidence
+	Confidence       string // One of the Confidence* constants
+	ConfidenceReason string // Which heuristic, if any, the attribution relies on
+
+	Ignored []string // Problems of this entry the user's ignore rules silence (Ignore* constants)
+}
+
+// IsIgnored reports whether the user's ignore rules silence problem for e.
+func (e PathEntry) IsIgnored(problem string) bool {
+	for _, p := range e.Ignored {
+		if p == problem {
+			return true
+		}
+	}
+	return false
+}
+
+// EvalHook is a tool that sets up the shell by printing shell code for a
+// startup file to run, e.g. `eval "$(direnv hook zsh)"`.
+type EvalHook struct {
+	Tool    string // e.g. "direnv", or the command's name for tools lspath does not know
+	Command string // The command whose output was run, e.g. "direnv hook zsh"
+	Binary  string // The tool's executable, if found
+}
+
+// Kinds of directory a version manager puts on PATH.
+const (
+	KindShims          = "shims"           // Scripts that hand each command to the version manager
+	KindManagedVersion = "managed version" // One installed version's bin, swapped when the version changes
+)
+
+// Problems an ignore rule can silence.
+const (
+	IgnoreMissing   = "missing"   // Directory does not exist
+	IgnoreBroken    = "broken"    // Symlink whose chain leads nowhere
+	IgnoreDuplicate = "duplicate" // Duplicate, or symlink to another entry
+	IgnoreRelative  = "relative"  // Empty or relative segment
+	IgnoreSession   = "session"   // Added in this session, not by a startup file
+	IgnoreUnsafe    = "unsafe"    // Writable by other users
+)
+
+// IgnoreRule silences a problem the user knowingly accepts, for one
+// directory or for every entry.
+type IgnoreRule struct {
+	Problem string // One of the Ignore* constants; empty for all of them
+	Dir     string // Directory the rule applies to (may start with ~); empty for every entry
+	Source  string // Where the rule came from, e.g. "--ignore" or "line 3 of ~/.config/lspath/ignore"
 }
 
+// Attribution confidence levels. Advice based on a medium or low confidence
+// attribution (e.g. "remove line N") is tentative.
+const (
+	ConfidenceHigh   = "high"   // Seen assigned on the attributed line
+	ConfidenceMedium = "medium" // Inferred (eval output, normalization, system-path guess)
+	ConfidenceLow    = "low"    // A guess (session-only, repeated entry)
+)
+
 // TraceEvent represents a single line of debug output from the shell.
 type TraceEvent struct {
 	Directory  string // Directory context of execution
@@ -41,18 +116,321 @@ type TraceEvent struct {
 
 // ConfigNode represents a file in the config loading flow.
 type ConfigNode struct {
-	ID          string // e.g. "node-1"
-	FilePath    string // e.g. "/etc/zshenv"
-	Order       int    // Sequence order (1, 2, 3...)
-	Depth       int    // Stack depth (indentation level)
-	Entries     []int  // Indices of PathEntries contributed by this node
-	NotExecuted bool   // True if this file was inserted as a placeholder
-	Description string // Descriptive label (e.g., "(system-wide)")
+	ID          string   // e.g. "node-1"
+	FilePath    string   // e.g. "/etc/zshenv"
+	Order       int      // Sequence order (1, 2, 3...)
+	Depth       int      // Stack depth (indentation level)
+	Entries     []int    // Indices of PathEntries contributed by this node
+	NotExecuted bool     // True if this file was inserted as a placeholder
+	Description string   // Descriptive label (e.g., "(system-wide)")
+	Note        string   // Longer explanation of what the file does (e.g., never modifies PATH)
+	Skipped     []string // Why the file, or guarded parts of it, did not run
 }
 
 // AnalysisResult contains the processed data from a trace.
 type AnalysisResult struct {
+	Variable    string // Analyzed variable (e.g. "MANPATH"); empty means PATH
 	PathEntries []PathEntry
 	FlowNodes   []ConfigNode
 	Diagnostics []string
+	Shadows     []Shadow        // Executables provided by more than one PATH entry, sorted by name
+	SideEffects []SideEffect    // Commands run during the trace that changed the system
+	Definitions []Definition    // Aliases and functions the startup files define, in the order they ran
+	HashTable   []HashedCommand // Commands the traced shell remembered the location of, where it can list them
+
+	DuplicatePolicy DuplicatePolicy // How duplicates are reported
+
+	Pins []Pin // Ordering the user pinned in their pins file, checked against PathEntries
+
+	Heuristics []HeuristicUse // How the analyzer's guesses shaped the result, for --verbose
+	Parser     ParserStats    // How much of the raw trace the parser understood
+
+	PluginFindings []PluginFinding // What the user's plugins found, also written into Diagnostics
+
+	Environment TraceEnvironment // Who, where and with what the trace ran
+}
+
+// PluginFinding is a problem an analysis plugin reported, such as an
+// in-house rule the PATH breaks.
+type PluginFinding struct {
+	Plugin   string // File name of the plugin
+	Severity string // "error", "warning" or "info"
+	Entry    int    // PathEntries index, or -1 for the result as a whole
+	Message  string
+}
+
+// TraceEnvironment describes the context a trace ran in, so a shared report
+// can be read on another machine and version-specific shell behavior spotted.
+type TraceEnvironment struct {
+	User         string // e.g. "alice (uid 501)"
+	Umask        string // e.g. "0022"; empty where there is none (Windows)
+	Shell        string // Shell whose startup was traced, e.g. "/bin/zsh"
+	ShellVersion string // First line of the shell's --version output
+	OS           string // GOOS/GOARCH, e.g. "darwin/arm64"
+	OSRelease    string // e.g. "macOS 14.5", "Ubuntu 24.04 LTS"
+	Lspath       string // lspath version that made the analysis
+}
+
+// Summary formats e for one line of a report, e.g. "alice (uid 501), umask
+// 0022 · zsh 5.9 (arm64-apple-darwin23.0) · macOS 14.5 (darwin/arm64) ·
+// lspath 1.3.6". Unknown parts are left out.
+func (e TraceEnvironment) Summary() string {
+	var parts []string
+	who := e.User
+	if e.Umask != "" {
+		who = strings.TrimPrefix(who+", umask "+e.Umask, ", ")
+	}
+	shell := e.ShellVersion
+	if shell == "" {
+		shell = e.Shell
+	}
+	system := strings.TrimSpace(fmt.Sprintf("%s (%s)", e.OSRelease, e.OS))
+	if e.OS == "" {
+		system = e.OSRelease
+	}
+	lspath := ""
+	if e.Lspath != "" {
+		lspath = "lspath " + e.Lspath
+	}
+	for _, p := range []string{who, shell, system, lspath} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, " · ")
+}
+
+// DuplicatePolicy controls how duplicate entries affect the summary, icons
+// and exit codes. The zero value reports them as warnings, including
+// symlinks to another entry (e.g. /bin -> /usr/bin).
+type DuplicatePolicy struct {
+	Severity       string // One of the Duplicates* constants; empty means DuplicatesWarn
+	IgnoreSymlinks bool   // Don't count symlinks to another entry as duplicates
+}
+
+// Duplicate severities.
+const (
+	DuplicatesWarn     = "warn"     // Reported, but not serious
+	DuplicatesError    = "error"    // First wins: later copies are dead weight and fail --report
+	DuplicatesHarmless = "harmless" // Noted in details only; counted as OK
+)
+
+// ParseDuplicateSeverity validates a --duplicates value.
+func ParseDuplicateSeverity(s string) (string, error) {
+	switch s {
+	case "", DuplicatesWarn:
+		return DuplicatesWarn, nil
+	case DuplicatesError, DuplicatesHarmless:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown duplicate policy %q (use warn, error or harmless)", s)
+}
+
+// IsDuplicate reports whether e counts as a duplicate under p.
+func (p DuplicatePolicy) IsDuplicate(e PathEntry) bool {
+	return e.IsDuplicate || (e.SymlinkPointsTo >= 0 && !p.IgnoreSymlinks)
+}
+
+// Flagged reports whether e is shown and counted as a duplicate problem,
+// rather than as OK (harmless, or ignored by the user).
+func (p DuplicatePolicy) Flagged(e PathEntry) bool {
+	return p.Severity != DuplicatesHarmless && p.IsDuplicate(e) && !e.IsIgnored(IgnoreDuplicate)
+}
+
+// DuplicateCount returns the number of entries flagged as duplicates under
+// the result's policy.
+func (r AnalysisResult) DuplicateCount() int {
+	n := 0
+	for _, e := range r.PathEntries {
+		if r.DuplicatePolicy.Flagged(e) {
+			n++
+		}
+	}
+	return n
+}
+
+// DuplicateGroup is a directory that appears more than once: the copy the
+// shell searches and the later ones it never reaches. Each entry's
+// SourceFile and LineNumber say where that copy was added.
+type DuplicateGroup struct {
+	First  int   // Index of the PathEntry that is searched
+	Copies []int // Indices of the later PathEntries for the same directory, in PATH order
+}
+
+// Entries returns the indices of every copy, the searched one first.
+func (g DuplicateGroup) Entries() []int {
+	return append([]int{g.First}, g.Copies...)
+}
+
+// DuplicateGroups groups the duplicate entries by the entry they
+// duplicate, in PATH order, so a directory added four times is one group
+// of four rather than three pairs. Symlinks to another entry are not
+// included.
+func (r AnalysisResult) DuplicateGroups() []DuplicateGroup {
+	var groups []DuplicateGroup
+	groupOf := make(map[int]int) // First -> index in groups
+	for i, e := range r.PathEntries {
+		if !e.IsDuplicate {
+			continue
+		}
+		g, ok := groupOf[e.DuplicateOf]
+		if !ok {
+			groups = append(groups, DuplicateGroup{First: e.DuplicateOf})
+			g = len(groups) - 1
+			groupOf[e.DuplicateOf] = g
+		}
+		groups[g].Copies = append(groups[g].Copies, i)
+	}
+	sort.Slice(groups, func(a, b int) bool { return groups[a].First < groups[b].First })
+	return groups
+}
+
+// DuplicateGroupOf returns the group entry idx is a copy in, whether the
+// searched one or a later one.
+func (r AnalysisResult) DuplicateGroupOf(idx int) (DuplicateGroup, bool) {
+	for _, g := range r.DuplicateGroups() {
+		for _, i := range g.Entries() {
+			if i == idx {
+				return g, true
+			}
+		}
+	}
+	return DuplicateGroup{}, false
+}
+
+// Pin is a directory from the user's pins file. Pinned directories must be
+// in PATH, in the order the file lists them.
+type Pin struct {
+	Dir    string // As written in the pins file (may start with ~)
+	Line   int    // Line number in the pins file
+	Entry  int    // Index of the first PathEntry for Dir, or -1 if it is not in PATH
+	Broken string // Why the pin does not hold; empty if it does
+}
+
+// HeuristicUse records how often one of the analyzer's heuristics changed
+// the result, or that it was turned off.
+type HeuristicUse struct {
+	Name     string
+	Fired    int // Entries or flow nodes it changed
+	Disabled bool
+}
+
+// ParserStats counts what the trace parser made of the shell's trace output,
+// so a trace it could not read (a startup file that overrides PS4, say) can
+// be told apart from startup files that really do nothing.
+type ParserStats struct {
+	LinesRead      int
+	LinesMatched   int      // Lines in the shell's trace format
+	PathEvents     int      // Matched lines that assigned the variable
+	LinesSkipped   int      // Lines not in the trace format, e.g. output of commands the startup files ran
+	SkippedSamples []string // The first few skipped lines, shortened
+	ShellErrors    []string // The first few errors the shell reported, as "file:line: message"
+}
+
+// BrokenPins returns the pins the result does not satisfy.
+func (r AnalysisResult) BrokenPins() []Pin {
+	var broken []Pin
+	for _, p := range r.Pins {
+		if p.Broken != "" {
+			broken = append(broken, p)
+		}
+	}
+	return broken
+}
+
+// SideEffect is a traced startup command that does more than set up the
+// environment, so running lspath (or any new shell) repeats it.
+type SideEffect struct {
+	File    string // Config file containing the command
+	Line    int    // Line number in File
+	Command string // The command as traced (e.g. "ssh-agent -s")
+	Kind    string // One of the SideEffect* constants
+}
+
+// Side effect kinds.
+const (
+	SideEffectDaemon = "starts a background process"
+	SideEffectWrite  = "modifies files"
+)
+
+// Definition is an alias or function a startup file defines. The shell
+// runs it instead of searching PATH for a command of the same name.
+type Definition struct {
+	Name string // Command name (e.g. "ll")
+	Kind string // One of the Definition* constants
+	Body string // What an alias expands to (e.g. "ls -l"); empty for functions
+	File string // Config file containing the definition
+	Line int    // Line number in File
+}
+
+// Definition kinds.
+const (
+	DefinitionAlias    = "alias"
+	DefinitionFunction = "function"
+)
+
+// HashedCommand is an entry in a shell's hash table: where the shell will
+// run a command from without searching PATH again.
+type HashedCommand struct {
+	Name string // Command name (e.g. "python3")
+	Path string // Executable the shell remembered (e.g. "/usr/bin/python3")
+}
+
+// Shadow records an executable name found in more than one PATH directory.
+type Shadow struct {
+	Name   string // Executable name (e.g. "python3")
+	Winner int    // Index of the PathEntry that provides it (earliest in PATH)
+	Losers []int  // Indices of later PathEntries whose copies are hidden
+}
+
+// VariableName returns the analyzed variable, defaulting to PATH.
+func (r AnalysisResult) VariableName() string {
+	if r.Variable == "" {
+		return "PATH"
+	}
+	return r.Variable
+}
+
+// SourceLine is a config file line and the entries it added.
+type SourceLine struct {
+	File    string
+	Line    int
+	Entries []int // Indices of PathEntries, in PATH order
+}
+
+// SourceLines groups entries by the config line that added them, in order
+// of first appearance. Entries without a line (system defaults, session-only)
+// are left out.
+func (r AnalysisResult) SourceLines() []SourceLine {
+	type fileLine struct {
+		file string
+		line int
+	}
+	var lines []SourceLine
+	index := make(map[fileLine]int)
+	for i, e := range r.PathEntries {
+		if e.LineNumber == 0 || e.IsSessionOnly {
+			continue
+		}
+		key := fileLine{e.SourceFile, e.LineNumber}
+		li, ok := index[key]
+		if !ok {
+			lines = append(lines, SourceLine{File: e.SourceFile, Line: e.LineNumber})
+			li = len(lines) - 1
+			index[key] = li
+		}
+		lines[li].Entries = append(lines[li].Entries, i)
+	}
+	return lines
+}
+
+// SourceLineOf returns the group containing entry idx, if it has a line.
+func (r AnalysisResult) SourceLineOf(idx int) (SourceLine, bool) {
+	e := r.PathEntries[idx]
+	for _, sl := range r.SourceLines() {
+		if sl.File == e.SourceFile && sl.Line == e.LineNumber {
+			return sl, true
+		}
+	}
+	return SourceLine{}, false
 }
@@ -0,0 +1,30 @@
+package model
+
+import "fmt"
+
+// SchemaVersion is the version of the JSON lspath writes (--json,
+// --snapshot, bundles). Adding a field keeps it; removing, renaming or
+// retyping one bumps it, and --json-version keeps writing the old shape.
+const SchemaVersion = 1
+
+// Document is an AnalysisResult as lspath saves it, stamped with the schema
+// version it was written in. Its fields sit alongside the result's, so
+// readers that predate the stamp still see the same object.
+type Document struct {
+	SchemaVersion int // 0 in files saved before the stamp, which are version 1
+	AnalysisResult
+}
+
+// NewDocument stamps res with the current SchemaVersion.
+func NewDocument(res AnalysisResult) Document {
+	return Document{SchemaVersion: SchemaVersion, AnalysisResult: res}
+}
+
+// Supported returns an error if d was written by a newer lspath whose
+// schema this one does not know.
+func (d Document) Supported() error {
+	if d.SchemaVersion > SchemaVersion {
+		return fmt.Errorf("written by a newer lspath (JSON schema version %d; this one reads up to %d)", d.SchemaVersion, SchemaVersion)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+// Package pager shows output taller than the terminal through the user's
+// pager, the way git does.
+package pager
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
+)
+
+// DefaultPager is used when neither LSPATH_PAGER nor PAGER is set. -R
+// passes the report's colors through.
+const DefaultPager = "less -R"
+
+// Command returns the pager to use: LSPATH_PAGER, else PAGER, else
+// DefaultPager. An empty LSPATH_PAGER or "cat" means none.
+func Command() string {
+	if p, ok := os.LookupEnv("LSPATH_PAGER"); ok {
+		return normalize(p)
+	}
+	if p := os.Getenv("PAGER"); p != "" {
+		return normalize(p)
+	}
+	return DefaultPager
+}
+
+func normalize(p string) string {
+	if p = strings.TrimSpace(p); p == "cat" {
+		return ""
+	}
+	return p
+}
+
+// Print writes text to stdout, through the pager if stdout is a terminal
+// that text does not fit on. If the pager cannot be started, text is
+// written directly.
+func Print(text string) {
+	pager := Command()
+	if pager == "" || !tooTall(text) {
+		fmt.Print(text)
+		return
+	}
+	if err := run(pager, text); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: pager %q failed (%v); set LSPATH_PAGER=cat or use --no-pager to turn it off\n", pager, err)
+		fmt.Print(text)
+	}
+}
+
+// tooTall reports whether stdout is a terminal and text, with long lines
+// wrapped, has more lines than it.
+func tooTall(text string) bool {
+	fd := os.Stdout.Fd()
+	if !term.IsTerminal(fd) {
+		return false
+	}
+	width, height, err := term.GetSize(fd)
+	if err != nil || width <= 0 || height <= 0 {
+		return false
+	}
+	rows := 0
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		rows += 1 + (lipgloss.Width(line)-1)/width
+		if rows >= height { // Leave a line for the shell's prompt
+			return true
+		}
+	}
+	return false
+}
+
+// run feeds text to pager, a command line, and waits for the user to quit
+// it. It returns an error only if the pager could not be run, so text was
+// not shown; how the user quits is up to them. less is told to keep the text on screen afterwards (LESS=FRX, as git
+// sets it) unless LESS is already set.
+func run(pager, text string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		fields := strings.Fields(pager)
+		cmd = exec.Command(fields[0], fields[1:]...)
+	} else {
+		cmd = exec.Command("sh", "-c", pager) // PAGER may have arguments and quoting
+	}
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	cmd.Env = os.Environ()
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		cmd.Env = append(cmd.Env, "LESS=FRX")
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	io.WriteString(stdin, text) // Fails harmlessly if the user quits early
+	stdin.Close()
+	var exitErr *exec.ExitError
+	if err := cmd.Wait(); errors.As(err, &exitErr) && exitErr.ExitCode() == 127 {
+		return fmt.Errorf("command not found") // From sh
+	}
+	return nil
+}
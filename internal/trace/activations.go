@@ -0,0 +1,142 @@
+package trace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// manualSessionNote describes a session-only entry nothing more is known of.
+const manualSessionNote = "Added manually or by runtime tool (not in shell config)"
+
+// activation is a kind of environment a command run in the shell puts on
+// PATH, such as a Python venv or the Node version `nvm use` picked.
+type activation struct {
+	// Match returns the note for dir if it belongs to such an environment,
+	// e.g. "Activated Python venv: /home/me/proj/.venv"
+	Match func(dir string) (string, bool)
+	Undo  string // How to take it off PATH again, or make it permanent
+}
+
+// envRoot returns the environment dir is the bin directory of: its parent
+// for bin or Scripts (Windows), else "".
+func envRoot(dir string) string {
+	switch strings.ToLower(filepath.Base(dir)) {
+	case "bin", "scripts":
+		return filepath.Dir(dir)
+	}
+	return ""
+}
+
+var activations = []activation{
+	{
+		// conda activate: <root>/bin, or on Windows <root>, Scripts or Library\bin
+		Match: func(dir string) (string, bool) {
+			p := model.ExpandTilde(dir)
+			roots := []string{envRoot(p), p}
+			if strings.EqualFold(filepath.Base(filepath.Dir(p)), "Library") {
+				roots = append(roots, filepath.Dir(filepath.Dir(p)))
+			}
+			for _, root := range roots {
+				if root == "" || (!fileExists(filepath.Join(root, "conda-meta")) && root != os.Getenv("CONDA_PREFIX")) {
+					continue
+				}
+				name := "base"
+				if filepath.Base(filepath.Dir(root)) == "envs" {
+					name = filepath.Base(root)
+				}
+				return fmt.Sprintf("Activated conda env %q: %s", name, root), true
+			}
+			return "", false
+		},
+		Undo: "run `conda deactivate` to remove it",
+	},
+	{
+		Match: func(dir string) (string, bool) {
+			root := envRoot(model.ExpandTilde(dir))
+			if !strings.Contains(filepath.ToSlash(root), "/pypoetry/virtualenvs/") {
+				return "", false
+			}
+			return "Activated Poetry env: " + root, true
+		},
+		Undo: "run `deactivate`, or `exit` the `poetry shell`, to remove it",
+	},
+	{
+		Match: func(dir string) (string, bool) {
+			root := envRoot(model.ExpandTilde(dir))
+			if root == "" {
+				return "", false
+			}
+			switch {
+			case fileExists(filepath.Join(root, "pyvenv.cfg")), root == os.Getenv("VIRTUAL_ENV"):
+			case filepath.Base(root) == ".venv" || filepath.Base(root) == "venv":
+				// Not on this machine, e.g. in a saved analysis; named like one
+			default:
+				return "", false
+			}
+			return "Activated Python venv: " + root, true
+		},
+		Undo: "run `deactivate` to remove it",
+	},
+	{
+		Match: func(dir string) (string, bool) {
+			p := filepath.ToSlash(model.ExpandTilde(dir))
+			_, rest, ok := strings.Cut(p, "/.nvm/versions/node/")
+			if !ok || !strings.HasSuffix(rest, "/bin") {
+				return "", false
+			}
+			return fmt.Sprintf("Node %s, switched to with `nvm use`", strings.TrimSuffix(rest, "/bin")), true
+		},
+		Undo: "run `nvm use default` to go back, or `nvm deactivate` to remove it",
+	},
+	{
+		Match: func(dir string) (string, bool) {
+			if !strings.Contains(filepath.ToSlash(dir), "/fnm_multishells/") {
+				return "", false
+			}
+			return "Node version switched to with `fnm use`", true
+		},
+		Undo: "run `fnm use default` to go back",
+	},
+	{
+		Match: func(dir string) (string, bool) {
+			home, _ := os.UserHomeDir()
+			pipxBin := os.Getenv("PIPX_BIN_DIR")
+			if pipxBin == "" && home != "" {
+				pipxBin = filepath.Join(home, ".local", "bin")
+			}
+			if pipxBin == "" || !model.SamePath(dir, pipxBin, model.CanonOptions{}) {
+				return "", false
+			}
+			if !fileExists(filepath.Join(home, ".local", "pipx", "venvs")) && !fileExists(filepath.Join(home, ".local", "share", "pipx", "venvs")) {
+				return "", false
+			}
+			return "pipx's app directory, added in this shell rather than by a startup file", true
+		},
+		Undo: "run `pipx ensurepath` so every new shell gets it",
+	},
+}
+
+// activationNote describes the environment activated in this shell that
+// dir belongs to and how to remove it, e.g. "Activated Python venv:
+// /home/me/proj/.venv (run `deactivate` to remove it)", if it belongs to one.
+func activationNote(dir string) (string, bool) {
+	for _, a := range activations {
+		if note, ok := a.Match(dir); ok {
+			return note + " (" + a.Undo + ")", true
+		}
+	}
+	return "", false
+}
+
+// SessionNote describes a session-only entry: as activationNote does, or
+// with a generic note if it belongs to no activated environment.
+func SessionNote(dir string) string {
+	if note, ok := activationNote(dir); ok {
+		return note
+	}
+	return manualSessionNote
+}
@@ -0,0 +1,166 @@
+package trace
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// LocationAdvice recommends which startup file a new PATH export belongs in.
+type LocationAdvice struct {
+	Shell         string   // Shell the advice applies to (zsh, bash, fish, pwsh)
+	File          string   // Recommended file (e.g. ~/.zprofile)
+	Reason        string   // Why this file was chosen
+	Avoid         []string // Files that look plausible but are wrong, with reasons
+	Snippet       string   // Ready-to-paste export line
+	AlreadyInPath bool     // True if the directory is already in PATH
+}
+
+// AdviseLocation works out where an export for dir should go, based on the
+// shell, whether it starts as a login shell, and which files already exist.
+func AdviseLocation(shellName string, res model.AnalysisResult, dir string) LocationAdvice {
+	advice := LocationAdvice{
+		Shell:   shellName,
+		Snippet: exportSnippet(shellName, dir),
+	}
+	for _, e := range res.PathEntries {
+		if model.SamePath(e.Value, dir, model.CanonOptions{}) {
+			advice.AlreadyInPath = true
+			break
+		}
+	}
+
+	login := IsLoginShell(res.FlowNodes)
+
+	switch shellName {
+	case "fish":
+		advice.File = "~/.config/fish/config.fish"
+		advice.Reason = "fish reads config.fish for every shell. fish_add_path skips directories already in PATH, so it is safe in nested shells."
+		advice.Avoid = append(advice.Avoid, "set -U fish_user_paths by hand - universal variables persist invisibly outside your config files")
+	case "pwsh":
+		advice.File = "~/.config/powershell/profile.ps1"
+		advice.Reason = "pwsh runs profile.ps1 (CurrentUserAllHosts) for every session, in any host, including the VS Code terminal."
+		advice.Avoid = append(advice.Avoid, "~/.config/powershell/Microsoft.PowerShell_profile.ps1 - only read by the console host")
+		advice.Avoid = append(advice.Avoid, "~/.profile or ~/.zshrc - pwsh does not read POSIX shell startup files")
+	case "tcsh", "csh":
+		rc := "~/.cshrc"
+		if shellName == "tcsh" && fileExists("~/.tcshrc") {
+			rc = "~/.tcshrc"
+		}
+		if login {
+			advice.File = "~/.login"
+			advice.Reason = fmt.Sprintf("Your terminal starts %s as a LOGIN shell, which reads ~/.login once, after %s, "+
+				"so your entry is set up once and inherited by every subshell.", shellName, rc)
+			advice.Avoid = append(advice.Avoid, rc+" - runs for every csh, scripts included, so additions there pile up duplicates in nested shells")
+		} else {
+			advice.File = rc
+			advice.Reason = fmt.Sprintf("Your terminal starts %s as an INTERACTIVE (non-login) shell, which reads %s.", shellName, rc)
+			advice.Avoid = append(advice.Avoid, "~/.login - not read by non-login terminals")
+		}
+		advice.Avoid = append(advice.Avoid, "~/.profile or ~/.bashrc - csh does not read POSIX shell startup files")
+	case "sh", "dash", "ash":
+		advice.File = "~/.profile"
+		if login {
+			advice.Reason = fmt.Sprintf("Your terminal starts %s as a LOGIN shell, which reads /etc/profile and then ~/.profile, "+
+				"so your entry is set up once and inherited by every subshell.", shellName)
+		} else {
+			advice.Reason = fmt.Sprintf("%s reads ~/.profile only as a login shell; a non-login %s reads just the file $ENV names, "+
+				"so put the export in ~/.profile and start the shell with -l.", shellName, shellName)
+		}
+		advice.Avoid = append(advice.Avoid, "~/.bashrc or ~/.zshrc - a plain sh reads neither")
+	case "ksh", "ksh93", "mksh", "oksh":
+		rc := "~/.kshrc"
+		if shellName == "mksh" {
+			rc = "~/.mkshrc"
+		}
+		if login {
+			advice.File = "~/.profile"
+			advice.Reason = fmt.Sprintf("Your terminal starts %s as a LOGIN shell, which reads /etc/profile and then ~/.profile once, "+
+				"so your entry is set up once and inherited by every subshell.", shellName)
+			advice.Avoid = append(advice.Avoid, rc+" - read by every interactive shell (as $ENV), so additions there pile up duplicates in nested shells")
+		} else {
+			advice.File = rc
+			advice.Reason = fmt.Sprintf("Your terminal starts %s as an INTERACTIVE (non-login) shell, which reads only the file $ENV names, %s by default.", shellName, rc)
+			advice.Avoid = append(advice.Avoid, "~/.profile - not read by non-login terminals")
+		}
+	case "bash":
+		if login {
+			// bash reads only the first of these that exists
+			advice.File = "~/.profile"
+			for _, f := range []string{"~/.bash_profile", "~/.bash_login"} {
+				if fileExists(f) {
+					advice.File = f
+					break
+				}
+			}
+			advice.Reason = fmt.Sprintf("Your terminal starts bash as a LOGIN shell, which reads %s once at startup. "+
+				"bash stops at the first of ~/.bash_profile, ~/.bash_login, ~/.profile that exists.", advice.File)
+			advice.Avoid = append(advice.Avoid, "~/.bashrc - only read by login shells if your profile sources it")
+		} else {
+			advice.File = "~/.bashrc"
+			advice.Reason = "Your terminal starts bash as an INTERACTIVE (non-login) shell, which reads ~/.bashrc."
+			advice.Avoid = append(advice.Avoid, "~/.bash_profile / ~/.profile - not read by non-login terminals")
+		}
+	default:
+		if login {
+			advice.File = "~/.zprofile"
+			advice.Reason = "Your terminal starts zsh as a LOGIN shell. ~/.zprofile runs once, after /etc/zprofile, " +
+				"so your entry keeps its position and is inherited by every subshell."
+		} else {
+			advice.File = "~/.zshrc"
+			advice.Reason = "Your terminal starts zsh as an INTERACTIVE (non-login) shell, which reads ~/.zshrc."
+		}
+		if runtime.GOOS == "darwin" {
+			advice.Avoid = append(advice.Avoid, "~/.zshenv - on macOS, /etc/zprofile runs path_helper afterwards and moves your entries behind the system ones")
+		} else {
+			advice.Avoid = append(advice.Avoid, "~/.zshenv - runs for every zsh, including scripts; only use it if non-interactive scripts need the entry")
+		}
+		if login {
+			advice.Avoid = append(advice.Avoid, "~/.zshrc - runs for every new interactive shell, so exports there pile up duplicates in nested shells")
+		}
+	}
+
+	return advice
+}
+
+// exportSnippet builds the export line for dir, writing paths under the home
+// directory relative to $HOME so the snippet stays portable.
+func exportSnippet(shellName, dir string) string {
+	value := portablePath(dir)
+	switch shellName {
+	case "fish":
+		return fmt.Sprintf("fish_add_path \"%s\"", value)
+	case "pwsh":
+		return fmt.Sprintf("$env:PATH = \"%s\" + [IO.Path]::PathSeparator + $env:PATH", value)
+	case "tcsh", "csh":
+		return fmt.Sprintf("set path = ( \"%s\" $path )", value)
+	}
+	return fmt.Sprintf("export PATH=\"%s:$PATH\"", value)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(model.ExpandTilde(path))
+	return err == nil
+}
+
+// FormatLocationAdvice renders advice as plain text for the CLI.
+func FormatLocationAdvice(a LocationAdvice) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Shell:       %s\n", a.Shell))
+	sb.WriteString(fmt.Sprintf("Put it in:   %s\n", a.File))
+	sb.WriteString(fmt.Sprintf("Snippet:     %s\n\n", a.Snippet))
+	sb.WriteString("Why: " + a.Reason + "\n")
+	if len(a.Avoid) > 0 {
+		sb.WriteString("\nAvoid:\n")
+		for _, f := range a.Avoid {
+			sb.WriteString("  • " + f + "\n")
+		}
+	}
+	if a.AlreadyInPath {
+		sb.WriteString("\nNote: this directory is already in your PATH. Run 'lspath --explain <dir>' to see where it comes from.\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,74 @@
+package trace
+
+import "strings"
+
+// alignEntries matches each position of next, a new value of the variable,
+// to the entry of prev it continues, so every occurrence keeps its own
+// attribution even when a directory appears more than once. Entries are
+// matched in order where possible (a longest common subsequence); a copy
+// that is out of order is matched to an unused occurrence of the same
+// directory, as when a line moves an entry. Positions left over are new and
+// get -1.
+//
+// When a directory occurs more often in next than in prev, either copy
+// could be the new one. appended says the change added to the end
+// (PATH="$PATH:dir"), so the earlier copies continue prev; otherwise the
+// later ones do, as after the usual PATH="dir:$PATH".
+func alignEntries(prev, next []string, appended bool) []int {
+	n, m := len(prev), len(next)
+	// lcs[i][j] is the length of the longest common subsequence of prev[i:]
+	// and next[j:]
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if prev[i] == next[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	match := make([]int, m)
+	for j := range match {
+		match[j] = -1
+	}
+	used := make([]bool, n)
+	for i, j := 0, 0; i < n && j < m; {
+		switch {
+		case prev[i] == next[j] && lcs[i][j] == lcs[i+1][j+1]+1 && (appended || lcs[i][j+1] < lcs[i][j]):
+			match[j], used[i] = i, true
+			i++
+			j++
+		case lcs[i][j+1] == lcs[i][j]:
+			j++
+		default:
+			i++
+		}
+	}
+
+	// Moved entries: reuse unmatched occurrences of the same directory, in order
+	unused := make(map[string][]int)
+	for i, key := range prev {
+		if !used[i] {
+			unused[key] = append(unused[key], i)
+		}
+	}
+	for j, key := range next {
+		if match[j] < 0 && len(unused[key]) > 0 {
+			match[j] = unused[key][0]
+			unused[key] = unused[key][1:]
+		}
+	}
+	return match
+}
+
+// appendsTo reports whether a traced command adds to the end of variable,
+// e.g. PATH="$PATH:dir" or path+=(dir).
+func appendsTo(command, variable string) bool {
+	return strings.Contains(command, "$"+variable+":") || strings.Contains(command, "${"+variable+"}:") ||
+		strings.Contains(command, variable+"+=") || strings.Contains(command, strings.ToLower(variable)+"+=")
+}
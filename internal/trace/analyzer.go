@@ -2,61 +2,19 @@ package trace
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"lspath/internal/model"
 )
 
-// expandTilde expands ~ to the user's home directory for path normalization
-func expandTilde(path string) string {
-	if strings.HasPrefix(path, "~/") {
-		home, err := os.UserHomeDir()
-		if err == nil {
-			return filepath.Join(home, path[2:])
-		}
-	} else if path == "~" {
-		home, err := os.UserHomeDir()
-		if err == nil {
-			return home
-		}
-	}
-	return path
-}
-
-// isLikelySystemPath returns true if the path looks like it should be part
-// of the system default PATH rather than a session-specific addition.
-// Common system paths that might be added by /etc/bash.bashrc or /etc/environment
-// but missed in the trace due to the minimal SandboxInitialPath baseline.
-func isLikelySystemPath(path string) bool {
-	commonSystemPaths := []string{
-		"/usr/local/sbin",
-		"/usr/local/bin",
-		"/usr/sbin",
-		"/usr/bin",
-		"/sbin",
-		"/bin",
-		"/usr/games",
-		"/usr/local/games",
-		"/snap/bin",
-		"/opt/local/bin",
-		"/opt/local/sbin",
-	}
-
-	for _, sysPath := range commonSystemPaths {
-		if path == sysPath {
-			return true
-		}
-	}
-
-	return false
-}
-
 // getLineFromFile reads a specific line number from a file
 func getLineFromFile(filePath string, lineNum int) string {
-	f, err := os.Open(expandTilde(filePath))
+	f, err := os.Open(model.ExpandTilde(filePath))
 	if err != nil {
 		return ""
 	}
@@ -76,6 +34,26 @@ func getLineFromFile(filePath string, lineNum int) string {
 // Analyzer processes trace events to reconstruct the PATH evolution.
 type Analyzer struct {
 	events []model.TraceEvent
+
+	// Canon controls how PATH values are normalized when matching duplicates
+	// and merging trace entries with the session PATH.
+	Canon model.CanonOptions
+
+	// Variable is the PATH-like variable being analyzed. Empty means PATH.
+	Variable string
+
+	// DisabledHeuristics holds the Heuristic* names not to apply.
+	DisabledHeuristics map[string]bool
+
+	// Baseline is the PATH the trace started from. Defaults to
+	// DetectBaseline's.
+	Baseline string
+}
+
+// analyzesPath reports whether the analyzer is looking at PATH itself, which
+// enables the system-default heuristics that only make sense for PATH.
+func (a *Analyzer) analyzesPath() bool {
+	return a.Variable == "" || a.Variable == DefaultVariable
 }
 
 func NewAnalyzer() *Analyzer {
@@ -99,11 +77,7 @@ func (a *Analyzer) AnalyzeSessionPath(currentPath string) model.AnalysisResult {
 	}
 
 	// Parse the PATH
-	parts := strings.Split(currentPath, ":")
-	for _, p := range parts {
-		if p == "" {
-			continue
-		}
+	for _, p := range model.SplitPathList(currentPath) {
 		entries = append(entries, model.PathEntry{
 			Value:           p,
 			SourceFile:      "Current Session",
@@ -111,69 +85,19 @@ func (a *Analyzer) AnalyzeSessionPath(currentPath string) model.AnalysisResult {
 			Mode:            "Session",
 			FlowID:          "node-0",
 			SymlinkPointsTo: -1,
+
+			Confidence:       model.ConfidenceLow,
+			ConfidenceReason: "Session view; no trace was run",
 		})
 	}
 
 	// Post-process for duplicates and disk existence
-	seen := make(map[string]int)
-	resolvedPaths := make(map[string]int)
-
+	a.markDuplicates(entries)
+	a.flagRelativeSegments(entries)
+	if a.analyzesPath() {
+		labelVersionManagers(entries)
+	}
 	for i := range entries {
-		e := &entries[i]
-		normalizedPath := expandTilde(e.Value)
-
-		// Check if this path is a symlink
-		fileInfo, err := os.Lstat(normalizedPath)
-		var resolvedPath string
-		if err == nil && fileInfo.Mode()&os.ModeSymlink != 0 {
-			target, err := os.Readlink(normalizedPath)
-			if err == nil {
-				var absTarget string
-				if filepath.IsAbs(target) {
-					absTarget = target
-				} else {
-					parent := filepath.Dir(normalizedPath)
-					absTarget = filepath.Join(parent, target)
-				}
-				absTarget = filepath.Clean(absTarget)
-				e.IsSymlink = true
-				e.SymlinkTarget = absTarget
-				resolvedPath = absTarget
-			}
-		}
-
-		if resolvedPath == "" {
-			resolvedPath = normalizedPath
-		}
-
-		// Duplicate check
-		if firstIdx, ok := seen[normalizedPath]; ok {
-			e.IsDuplicate = true
-			e.DuplicateOf = firstIdx
-			e.DuplicateMessage = fmt.Sprintf(
-				"Duplicates PATH entry #%d (%s)",
-				firstIdx+1, entries[firstIdx].Value,
-			)
-		} else if e.IsSymlink {
-			if firstIdx, ok := resolvedPaths[resolvedPath]; ok {
-				e.SymlinkPointsTo = firstIdx
-				e.SymlinkMessage = fmt.Sprintf(
-					"Symlink resolves to PATH entry #%d (%s)",
-					firstIdx+1, e.SymlinkTarget,
-				)
-			}
-		}
-
-		if !e.IsDuplicate {
-			seen[normalizedPath] = i
-			resolvedPaths[resolvedPath] = i
-		}
-
-		// Disk existence check
-		if _, err := os.Stat(normalizedPath); os.IsNotExist(err) {
-			e.Diagnostics = append(e.Diagnostics, "Directory does not exist on disk.")
-		}
-
 		// Add to session node's entries
 		sessionNode.Entries = append(sessionNode.Entries, i)
 	}
@@ -193,45 +117,70 @@ func (a *Analyzer) AnalyzeSessionPath(currentPath string) model.AnalysisResult {
 // Session PATH entries that don't appear in trace are marked as session-only.
 // This provides the most complete view: actual PATH with full attribution.
 func (a *Analyzer) AnalyzeUnified(sessionPath string, events []model.TraceEvent) model.AnalysisResult {
-	// First, run the trace analysis to get config-based attribution and full flow structure
-	traceResult := a.Analyze(events, SandboxInitialPath)
-
-	// Build a map of traced paths for quick lookup (path value -> entry)
-	tracedPaths := make(map[string]*model.PathEntry)
-	for i := range traceResult.PathEntries {
-		entry := &traceResult.PathEntries[i]
-		if _, exists := tracedPaths[entry.Value]; !exists {
-			tracedPaths[entry.Value] = entry
+	// First, run the trace analysis to get config-based attribution and full flow structure.
+	// Other variables are traced from empty, so there is no system baseline.
+	initialPath := ""
+	if a.analyzesPath() {
+		initialPath = a.Baseline
+		if initialPath == "" {
+			initialPath = DetectBaseline()
 		}
 	}
+	traceResult := a.Analyze(events, initialPath)
+
+	// Match each session entry to the traced occurrence it is, so a
+	// directory the trace added twice gets both sources. Copies beyond the
+	// traced ones fall back to the first traced occurrence.
+	sessionPaths := model.SplitPathList(sessionPath)
+	tracedKeys := make([]string, len(traceResult.PathEntries))
+	firstTraced := make(map[string]int) // canonical value -> first traced index
+	for i, entry := range traceResult.PathEntries {
+		tracedKeys[i] = model.CanonicalPath(entry.Value, a.Canon)
+		if _, exists := firstTraced[tracedKeys[i]]; !exists {
+			firstTraced[tracedKeys[i]] = i
+		}
+	}
+	sessionKeys := make([]string, len(sessionPaths))
+	for i, p := range sessionPaths {
+		sessionKeys[i] = model.CanonicalPath(p, a.Canon)
+	}
+	tracedOf := alignEntries(tracedKeys, sessionKeys, false)
+	unifiedOf := make(map[int]int) // traced index -> unified index, for the flow nodes
 
 	// Process the actual session PATH in order
-	sessionParts := strings.Split(sessionPath, ":")
 	var unifiedEntries []model.PathEntry
 	var sessionOnlyEntries []int // indices of session-only entries
 
-	for _, pathValue := range sessionParts {
-		if pathValue == "" {
-			continue
-		}
-
+	for si, pathValue := range sessionPaths {
 		entryIdx := len(unifiedEntries)
 
 		// Check if this path was in the trace
-		tracedEntry, inTrace := tracedPaths[pathValue]
+		var tracedEntry *model.PathEntry
+		if t := tracedOf[si]; t >= 0 {
+			tracedEntry = &traceResult.PathEntries[t]
+			unifiedOf[t] = entryIdx
+		} else if t, ok := firstTraced[sessionKeys[si]]; ok {
+			tracedEntry = &traceResult.PathEntries[t]
+		}
+		inTrace := tracedEntry != nil
 
 		var entry model.PathEntry
 		if inTrace {
-			// Use trace attribution - copy the entry
+			// Use trace attribution - copy the entry, but keep the session's spelling
 			entry = *tracedEntry
+			entry.Value = pathValue
 			entry.SymlinkPointsTo = -1 // Will be recalculated
 			entry.IsDuplicate = false  // Will be recalculated
 			entry.DuplicateOf = 0
 			entry.DuplicateMessage = ""
+			if pathValue != tracedEntry.Value && entry.Confidence == model.ConfidenceHigh {
+				entry.Confidence = model.ConfidenceMedium
+				entry.ConfidenceReason = fmt.Sprintf("Matched to traced entry %s after normalization", tracedEntry.Value)
+			}
 		} else {
 			// Not in trace - could be session-only OR could be a system path
-			// that the trace missed due to starting with minimal SandboxInitialPath
-			if isLikelySystemPath(pathValue) {
+			// that the trace missed due to starting with the minimal baseline
+			if pkg, isPkg := lookupSystemPackage(pathValue); a.analyzesPath() && (isPkg || isLikelySystemPath(pathValue)) {
 				// Attribute to System (Default) rather than marking as session-only
 				entry = model.PathEntry{
 					Value:           pathValue,
@@ -241,6 +190,12 @@ func (a *Analyzer) AnalyzeUnified(sessionPath string, events []model.TraceEvent)
 					IsSessionOnly:   false,
 					SymlinkPointsTo: -1,
 					FlowID:          "node-0",
+
+					Confidence:       model.ConfidenceMedium,
+					ConfidenceReason: "Not seen in the trace; assumed part of the system default PATH",
+				}
+				if isPkg {
+					entry.ConfidenceReason = fmt.Sprintf("Not seen in the trace; assumed installed by %s", pkg.Name)
 				}
 				// Don't add to sessionOnlyEntries, add to System node instead
 			} else {
@@ -251,9 +206,12 @@ func (a *Analyzer) AnalyzeUnified(sessionPath string, events []model.TraceEvent)
 					LineNumber:      0,
 					Mode:            "Session",
 					IsSessionOnly:   true,
-					SessionNote:     "Added manually or by runtime tool (not in shell config)",
+					SessionNote:     SessionNote(pathValue),
 					SymlinkPointsTo: -1,
 					FlowID:          "session-node",
+
+					Confidence:       model.ConfidenceLow,
+					ConfidenceReason: "Not seen in the trace; assumed added in this terminal session",
 				}
 				sessionOnlyEntries = append(sessionOnlyEntries, entryIdx)
 			}
@@ -265,57 +223,26 @@ func (a *Analyzer) AnalyzeUnified(sessionPath string, events []model.TraceEvent)
 	// Use the trace's flow nodes as base (preserves shell startup order, depth, all config files)
 	flowNodes := traceResult.FlowNodes
 
-	// Remap flow node entries to point to unified entry indices
-	// Build a map from old trace path value -> new unified index
-	// Also track system paths that need to be added to the System (Default) node
-	pathToUnifiedIdx := make(map[string][]int)
+	// Remap flow node entries to point to unified entry indices. The System
+	// (Default) node gets every system entry, including the paths detected
+	// from the session that weren't in the trace.
 	var systemNodeEntries []int
-
 	for i, entry := range unifiedEntries {
-		if entry.IsSessionOnly {
-			continue // Skip session-only entries
-		}
-
 		if entry.FlowID == "node-0" && entry.SourceFile == "System (Default)" {
-			// This is a system path that wasn't in the trace but we attributed to system
 			systemNodeEntries = append(systemNodeEntries, i)
-		} else {
-			// Normal traced entry
-			pathToUnifiedIdx[entry.Value] = append(pathToUnifiedIdx[entry.Value], i)
 		}
 	}
-
-	// Update each flow node's entries to point to unified indices
 	for i := range flowNodes {
-		var newEntries []int
-
-		// Special handling for System (Default) node - add the extra system paths
 		if flowNodes[i].FilePath == "System (Default)" {
-			// First add the originally traced entries
-			for _, oldIdx := range flowNodes[i].Entries {
-				if oldIdx < len(traceResult.PathEntries) {
-					pathValue := traceResult.PathEntries[oldIdx].Value
-					if indices, ok := pathToUnifiedIdx[pathValue]; ok && len(indices) > 0 {
-						newEntries = append(newEntries, indices[0])
-						pathToUnifiedIdx[pathValue] = indices[1:]
-					}
-				}
-			}
-			// Then add any system paths we detected from session but weren't in trace
-			newEntries = append(newEntries, systemNodeEntries...)
-		} else {
-			// For other nodes, just remap the entries
-			for _, oldIdx := range flowNodes[i].Entries {
-				if oldIdx < len(traceResult.PathEntries) {
-					pathValue := traceResult.PathEntries[oldIdx].Value
-					if indices, ok := pathToUnifiedIdx[pathValue]; ok && len(indices) > 0 {
-						newEntries = append(newEntries, indices[0])
-						pathToUnifiedIdx[pathValue] = indices[1:]
-					}
-				}
+			flowNodes[i].Entries = systemNodeEntries
+			continue
+		}
+		var newEntries []int
+		for _, oldIdx := range flowNodes[i].Entries {
+			if idx, ok := unifiedOf[oldIdx]; ok {
+				newEntries = append(newEntries, idx)
 			}
 		}
-
 		flowNodes[i].Entries = newEntries
 	}
 
@@ -354,44 +281,62 @@ func (a *Analyzer) AnalyzeUnified(sessionPath string, events []model.TraceEvent)
 	// before and after sourcing nvm.sh), so we keep the original FlowID.
 
 	// Post-process for duplicates, symlinks, and disk existence
-	seen := make(map[string]int)
-	resolvedPaths := make(map[string]int)
-
+	a.markDuplicates(unifiedEntries)
+	a.flagRelativeSegments(unifiedEntries)
 	for i := range unifiedEntries {
 		e := &unifiedEntries[i]
-		normalizedPath := expandTilde(e.Value)
-
-		// Check if this path is a symlink
-		fileInfo, err := os.Lstat(normalizedPath)
-		var resolvedPath string
-		if err == nil && fileInfo.Mode()&os.ModeSymlink != 0 {
-			target, err := os.Readlink(normalizedPath)
-			if err == nil {
-				var absTarget string
-				if filepath.IsAbs(target) {
-					absTarget = target
-				} else {
-					parent := filepath.Dir(normalizedPath)
-					absTarget = filepath.Join(parent, target)
-				}
-				absTarget = filepath.Clean(absTarget)
-				e.IsSymlink = true
-				e.SymlinkTarget = absTarget
-				resolvedPath = absTarget
-			}
+		if !e.IsDuplicate || e.LineNumber == 0 {
+			continue
 		}
-
-		if resolvedPath == "" {
-			resolvedPath = normalizedPath
+		// Both copies map to the same traced entry, so the line is a guess
+		if orig := unifiedEntries[e.DuplicateOf]; orig.SourceFile == e.SourceFile && orig.LineNumber == e.LineNumber {
+			e.Confidence = model.ConfidenceLow
+			e.ConfidenceReason = fmt.Sprintf("Repeats entry #%d; the trace cannot tell which line added it again", e.DuplicateOf+1)
 		}
+	}
+
+	shadows := a.analyzeShadowing(unifiedEntries)
+
+	globalDiagnostics := []string{
+		"INFO: Unified view - showing your actual PATH with full attribution.",
+		"INFO: Entries marked as 'Session' were added manually or by tools (not from shell config files).",
+	}
+	globalDiagnostics = append(globalDiagnostics, attributePackages(unifiedEntries)...)
+	if a.analyzesPath() {
+		labelVersionManagers(unifiedEntries)
+		globalDiagnostics = append(globalDiagnostics, a.checkInstallRoots(unifiedEntries, flowNodes)...)
+	}
+
+	return model.AnalysisResult{
+		PathEntries: unifiedEntries,
+		FlowNodes:   flowNodes,
+		Diagnostics: globalDiagnostics,
+		Shadows:     shadows,
+		Heuristics:  traceResult.Heuristics,
+	}
+}
+
+// markDuplicates flags duplicate entries, symlinks that resolve to an earlier
+// entry, broken symlinks and directories missing from disk.
+func (a *Analyzer) markDuplicates(entries []model.PathEntry) {
+	seen := make(map[string]int)
+	resolvedPaths := make(map[string]int)
+
+	for i := range entries {
+		e := &entries[i]
+		normalizedPath := model.ExpandTilde(e.Value)
+		key := model.CanonicalPath(e.Value, a.Canon)
+
+		// Follow the chain if this path is a symlink
+		resolvedPath := linkEntry(e, normalizedPath)
 
 		// Duplicate check
-		if firstIdx, ok := seen[normalizedPath]; ok {
+		if firstIdx, ok := seen[key]; ok {
 			e.IsDuplicate = true
 			e.DuplicateOf = firstIdx
 			e.DuplicateMessage = fmt.Sprintf(
 				"Duplicates PATH entry #%d (%s)",
-				firstIdx+1, unifiedEntries[firstIdx].Value,
+				firstIdx+1, entries[firstIdx].Value,
 			)
 		} else if e.IsSymlink {
 			if firstIdx, ok := resolvedPaths[resolvedPath]; ok {
@@ -404,26 +349,134 @@ func (a *Analyzer) AnalyzeUnified(sessionPath string, events []model.TraceEvent)
 		}
 
 		if !e.IsDuplicate {
-			seen[normalizedPath] = i
+			seen[key] = i
 			resolvedPaths[resolvedPath] = i
 		}
 
 		// Disk existence check
-		if _, err := os.Stat(normalizedPath); os.IsNotExist(err) {
-			e.Diagnostics = append(e.Diagnostics, "Directory does not exist on disk.")
+		if d := existenceDiagnostic(*e, normalizedPath); d != "" {
+			e.Diagnostics = append(e.Diagnostics, d)
 		}
 	}
+}
 
-	globalDiagnostics := []string{
-		"INFO: Unified view - showing your actual PATH with full attribution.",
-		"INFO: Entries marked as 'Session' were added manually or by tools (not from shell config files).",
+// flagRelativeSegments adds a diagnostic and advice to empty and relative
+// entries. Both are resolved against the current directory when searched,
+// which for PATH lets any directory you cd into supply commands.
+func (a *Analyzer) flagRelativeSegments(entries []model.PathEntry) {
+	name := a.Variable
+	if name == "" {
+		name = DefaultVariable
 	}
+	for i := range entries {
+		e := &entries[i]
+		if !model.IsRelativePath(e.Value) || relativeDiagnostic(*e) != "" {
+			continue // Absolute, or already flagged in the trace analysis
+		}
 
-	return model.AnalysisResult{
-		PathEntries: unifiedEntries,
-		FlowNodes:   flowNodes,
-		Diagnostics: globalDiagnostics,
+		var diag, fix string
+		if e.Value == "" {
+			diag = fmt.Sprintf("Empty segment: %s searches the current directory here.", name)
+			fix = "remove the stray ':' (leading, trailing or doubled)"
+			if e.LineNumber == 0 {
+				fix += "; it often comes from appending an unset variable, e.g. PATH=$PATH:$EXTRA"
+			}
+		} else {
+			diag = fmt.Sprintf("Relative segment: %s resolves %q against the current directory.", name, e.Value)
+			fix = fmt.Sprintf("replace %s with an absolute path (e.g. $HOME/%s)", e.Value, strings.TrimPrefix(e.Value, "./"))
+		}
+		if a.analyzesPath() {
+			diag += " Any directory you cd into can replace commands."
+		}
+		if e.LineNumber > 0 {
+			fix += fmt.Sprintf(" on line %d of %s", e.LineNumber, e.SourceFile)
+		}
+		e.Diagnostics = append(e.Diagnostics, diag, "Advice: "+fix)
+	}
+}
+
+// relativeDiagnostic returns the empty/relative segment diagnostic of e, if any.
+func relativeDiagnostic(e model.PathEntry) string {
+	d, _ := relativeDiagnostics(e)
+	return d
+}
+
+// relativeDiagnostics returns the empty/relative segment diagnostic of e and
+// the advice flagRelativeSegments added after it, if any.
+func relativeDiagnostics(e model.PathEntry) (diag, advice string) {
+	for i, d := range e.Diagnostics {
+		if strings.HasPrefix(d, "Empty segment:") || strings.HasPrefix(d, "Relative segment:") {
+			if i+1 < len(e.Diagnostics) && strings.HasPrefix(e.Diagnostics[i+1], "Advice: ") {
+				advice = e.Diagnostics[i+1]
+			}
+			return d, advice
+		}
 	}
+	return "", ""
+}
+
+// analyzeShadowing scans every PATH directory (within DefaultScanBudget) and
+// records executables that appear in more than one entry. The first entry in
+// PATH wins; copies in later entries are shadowed. Duplicate and symlinked
+// entries are skipped, as are later copies that are the same file.
+func (a *Analyzer) analyzeShadowing(entries []model.PathEntry) []model.Shadow {
+	if !a.analyzesPath() {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultScanBudget)
+	defer cancel()
+	bins := ScanBinaryIndex(ctx, entries, nil)
+
+	var shadows []model.Shadow
+	byName := make(map[string]int) // executable name -> index into shadows
+	first := make(map[string]int)  // executable name -> entry that provides it
+	for i := 0; i < bins.Scanned; i++ {
+		if entries[i].IsDuplicate || entries[i].SymlinkPointsTo >= 0 || model.IsRelativePath(entries[i].Value) {
+			continue
+		}
+		for _, name := range bins.ByEntry[i] {
+			w, ok := first[name]
+			if !ok {
+				first[name] = i
+				continue
+			}
+			if sameFile(filepath.Join(model.ExpandTilde(entries[w].Value), name), filepath.Join(model.ExpandTilde(entries[i].Value), name)) {
+				continue
+			}
+			si, ok := byName[name]
+			if !ok {
+				shadows = append(shadows, model.Shadow{Name: name, Winner: w})
+				si = len(shadows) - 1
+				byName[name] = si
+			}
+			shadows[si].Losers = append(shadows[si].Losers, i)
+		}
+	}
+	// Byte order rather than the locale's collation, so reports match across machines
+	sort.SliceStable(shadows, func(i, j int) bool { return shadows[i].Name < shadows[j].Name })
+
+	for _, sh := range shadows {
+		var hidden []string
+		for _, l := range sh.Losers {
+			hidden = append(hidden, entries[l].Value)
+			entries[l].ShadowedBy = append(entries[l].ShadowedBy, fmt.Sprintf("%s (by %s)", sh.Name, entries[sh.Winner].Value))
+		}
+		entries[sh.Winner].Shadows = append(entries[sh.Winner].Shadows, fmt.Sprintf("%s (hides %s)", sh.Name, strings.Join(hidden, ", ")))
+	}
+	return shadows
+}
+
+// sameFile reports whether two paths resolve to the same file.
+func sameFile(a, b string) bool {
+	ia, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	ib, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(ia, ib)
 }
 
 func (a *Analyzer) Analyze(events []model.TraceEvent, initialPath string) model.AnalysisResult {
@@ -447,6 +500,11 @@ func (a *Analyzer) Analyze(events []model.TraceEvent, initialPath string) model.
 	// Initialize lastPathStr with the baseline so diffs work correctly
 	lastPathStr = initialPath
 
+	variable := a.Variable
+	if variable == "" {
+		variable = DefaultVariable
+	}
+
 	// Maintain current list of `[]*model.PathEntry`.
 	var currentEntries []*model.PathEntry
 
@@ -462,17 +520,16 @@ func (a *Analyzer) Analyze(events []model.TraceEvent, initialPath string) model.
 			Entries:     []int{},
 		})
 
-		parts := strings.Split(initialPath, ":")
-		for _, p := range parts {
-			if p == "" {
-				continue
-			}
+		for _, p := range model.SplitPathList(initialPath) {
 			currentEntries = append(currentEntries, &model.PathEntry{
 				Value:      p,
 				SourceFile: "System (Default)", // Or "(initial environment)"
 				LineNumber: 0,
 				Mode:       "System",
 				FlowID:     "node-0", // Assign to the system node
+
+				Confidence:       model.ConfidenceHigh,
+				ConfidenceReason: "Part of the baseline PATH the trace starts from",
 			})
 		}
 	}
@@ -487,23 +544,38 @@ func (a *Analyzer) Analyze(events []model.TraceEvent, initialPath string) model.
 		return len(fileStack) - 1
 	}
 
-	// Track eval contexts to properly attribute PATH changes
-	// Map: file -> evalLine
-	// We track evals that contain command substitution ($(...) or backticks)
-	// because these can produce output that modifies PATH
-	evalContext := make(map[string]int)
-	// Track which files have had a PATH change attributed to an eval
-	evalUsed := make(map[string]bool)
+	// The last eval of a tool's output in each file (eval "$(tool init)"),
+	// whose changes to PATH are attributed to the tool
+	type evalSite struct {
+		line, depth int
+		hook        *model.EvalHook // nil if the command is unknown
+		traced      bool            // The eval'd code showed up in the trace
+		used        bool            // A change has been attributed to it
+	}
+	evals := make(map[string]*evalSite)
+	fileLines := make(map[string][]string)
+	var prev model.TraceEvent
+
+	// Times each heuristic changed the result, and the files coalesced so far
+	fired := make(map[string]int)
+	coalesced := make(map[string]bool)
 
 	nodeCounter := 0
 
 	for _, ev := range events {
-		// Detect eval commands with command substitution and track their line numbers
-		if strings.Contains(ev.RawCommand, "eval ") &&
-			(strings.Contains(ev.RawCommand, "$(") || strings.Contains(ev.RawCommand, "`")) {
-			evalContext[ev.File] = ev.Line
-			evalUsed[ev.File] = false
+		if _, ok := fileLines[ev.File]; !ok {
+			fileLines[ev.File] = readLines(ev.File)
 		}
+		toolPath := lastPathStr
+		if !a.analyzesPath() {
+			toolPath = os.Getenv("PATH")
+		}
+		if hook, ok := detectEvalHook(ev, prev, fileLines[ev.File], toolPath); ok {
+			evals[ev.File] = &evalSite{line: ev.Line, depth: ev.Depth, hook: hook}
+		} else if site := evals[ev.File]; site != nil && ev.Line == site.line && ev.Depth > site.depth {
+			site.traced = true
+		}
+		prev = ev
 		// Flow Graph Construction
 		if ev.File != lastFile {
 			// Check if this file is "noisy" (system functions)
@@ -511,10 +583,14 @@ func (a *Analyzer) Analyze(events []model.TraceEvent, initialPath string) model.
 			// and attribute events to the previous node (effectively coalecsing).
 			// However, if it changes PATH, we MUST record it.
 
-			isSystem := strings.HasPrefix(ev.File, "/usr/share/zsh") || strings.HasPrefix(ev.File, "/etc/zshrc_Apple_Terminal")
+			isSystem := strings.HasPrefix(ev.File, "/usr/share/zsh")
 			isPathChange := (ev.PathChange != "")
 
-			if isSystem && !isPathChange {
+			if isSystem && !isPathChange && a.enabled(HeuristicCoalesce) {
+				if !coalesced[ev.File] {
+					coalesced[ev.File] = true
+					fired[HeuristicCoalesce]++
+				}
 				// Skip creating a new node, stay on current.
 				// But update lastFile so we don't check this every event?
 				// No, if we update lastFile, next event will think we are in context.
@@ -587,29 +663,53 @@ func (a *Analyzer) Analyze(events []model.TraceEvent, initialPath string) model.
 		// Check if this event changes PATH
 		if ev.PathChange != "" && ev.PathChange != lastPathStr {
 			// Parse the new PATH string
-			newPaths := strings.Split(ev.PathChange, ":")
+			newPaths := model.SplitPathList(ev.PathChange)
 			var newEntries []*model.PathEntry
 
-			// Build a pool of existing entries to reuse
-			// To handle duplicates and reordering correctly is tricky.
-			// Heuristic: If we see path "P" and we had "P" before, keep the old one.
-			// If we had multiple "P"s, which one? The first one?
-
-			// Optimization: Map[Value] -> *Entry (last seen or list?)
-			// Let's iterate.
-
-			for _, p := range newPaths {
-				if p == "" {
-					continue
+			// Match each new position to the occurrence it continues, so a
+			// directory added twice keeps both sources
+			prevKeys := make([]string, len(currentEntries))
+			for i, curr := range currentEntries {
+				prevKeys[i] = model.CanonicalPath(curr.Value, a.Canon)
+			}
+			newKeys := make([]string, len(newPaths))
+			for i, p := range newPaths {
+				newKeys[i] = model.CanonicalPath(p, a.Canon)
+			}
+			continues := alignEntries(prevKeys, newKeys, appendsTo(ev.RawCommand, variable))
+
+			// Where the new entries came from
+			lineNum := ev.Line
+			confidence, reason := model.ConfidenceHigh, "Assigned on this line"
+			var hook *model.EvalHook
+			site := evals[ev.File]
+			switch {
+			case site != nil && ev.Line == site.line && ev.Depth > site.depth:
+				// bash traces the eval'd code on the eval's line, one level down
+				hook = site.hook
+				site.used = true
+				if hook != nil {
+					reason = fmt.Sprintf("Assigned by the code `%s` printed, run on this line", hook.Command)
 				}
+			case site != nil && a.enabled(HeuristicEval) && !site.used && !site.traced && site.line > 0 && ev.Line > site.line:
+				// This PATH change is happening after an eval on an earlier line
+				// Attribute it to the eval's line instead
+				lineNum = site.line
+				hook = site.hook
+				// Mark this eval as used so subsequent PATH changes get their real line numbers
+				site.used = true
+				confidence = model.ConfidenceMedium
+				reason = fmt.Sprintf("Attributed to the eval on line %d, which ran before the change was seen on line %d", site.line, ev.Line)
+				fired[HeuristicEval]++
+			case !strings.Contains(strings.ToUpper(ev.RawCommand), variable):
+				confidence = model.ConfidenceMedium
+				reason = fmt.Sprintf("Line does not mention %s; the change was inferred from the value before and after it", variable)
+			}
 
-				// Is this p in currentEntries?
+			for i, p := range newPaths {
 				var existing *model.PathEntry
-				for _, curr := range currentEntries {
-					if curr.Value == p {
-						existing = curr
-						break
-					}
+				if continues[i] >= 0 {
+					existing = currentEntries[continues[i]]
 				}
 
 				if existing != nil {
@@ -622,22 +722,16 @@ func (a *Analyzer) Analyze(events []model.TraceEvent, initialPath string) model.
 					newEntries = append(newEntries, &e)
 				} else {
 					// New Entry
-					// Check if we're in an eval context
-					lineNum := ev.Line
-					if evalLine, inEval := evalContext[ev.File]; inEval && !evalUsed[ev.File] && ev.Line > evalLine {
-						// This PATH change is happening after an eval on an earlier line
-						// Attribute it to the eval's line instead
-						lineNum = evalLine
-						// Mark this eval as used so subsequent PATH changes get their real line numbers
-						evalUsed[ev.File] = true
-					}
-
 					e := model.PathEntry{
 						Value:      p,
 						SourceFile: ev.File,
 						LineNumber: lineNum,
 						FlowID:     currentNode.ID,
 						Mode:       GuessShellMode(ev.File),
+						Hook:       hook,
+
+						Confidence:       confidence,
+						ConfidenceReason: reason,
 					}
 					newEntries = append(newEntries, &e)
 				}
@@ -653,47 +747,25 @@ func (a *Analyzer) Analyze(events []model.TraceEvent, initialPath string) model.
 		// Initialize SymlinkPointsTo to -1 to indicate "not a symlink" or "doesn't point to another entry"
 		entries[i].SymlinkPointsTo = -1
 	}
+	flowNodes = a.expandPathHelper(entries, flowNodes)
 
 	// Post-process for Duplicates and Disk existence
-	seen := make(map[string]int)          // normalized value -> index
+	seen := make(map[string]int)          // canonical value -> index
 	resolvedPaths := make(map[string]int) // resolved symlink path -> index
 
 	for i, e := range entries {
-		// Normalize path for comparison (expand ~)
-		normalizedPath := expandTilde(e.Value)
-
-		// Check if THIS path itself (not parent directories) is a symlink
-		fileInfo, err := os.Lstat(normalizedPath)
-		var resolvedPath string
-		if err == nil && fileInfo.Mode()&os.ModeSymlink != 0 {
-			// It's a direct symlink - read the immediate target
-			target, err := os.Readlink(normalizedPath)
-			if err == nil {
-				// Convert relative symlink target to absolute path
-				var absTarget string
-				if filepath.IsAbs(target) {
-					absTarget = target
-				} else {
-					// Relative symlink - resolve relative to parent directory
-					parent := filepath.Dir(normalizedPath)
-					absTarget = filepath.Join(parent, target)
-				}
-				// Clean the path to normalize it
-				absTarget = filepath.Clean(absTarget)
-
-				entries[i].IsSymlink = true
-				entries[i].SymlinkTarget = absTarget
-				resolvedPath = absTarget
-				entries[i].SymlinkPointsTo = -1 // Will be set below if it matches another entry
-			}
-		}
-
-		if resolvedPath == "" {
-			resolvedPath = normalizedPath
+		// Normalize path for comparison (expand ~, trailing slashes, case policy)
+		normalizedPath := model.ExpandTilde(e.Value)
+		key := model.CanonicalPath(e.Value, a.Canon)
+
+		// Follow the chain if THIS path itself (not parent directories) is a symlink
+		resolvedPath := linkEntry(&entries[i], normalizedPath)
+		if entries[i].IsSymlink {
+			entries[i].SymlinkPointsTo = -1 // Will be set below if it matches another entry
 		}
 
 		// 1. Duplicate check - check both normalized path and resolved path
-		if firstIdx, ok := seen[normalizedPath]; ok {
+		if firstIdx, ok := seen[key]; ok {
 			entries[i].IsDuplicate = true
 			entries[i].DuplicateOf = firstIdx
 
@@ -732,15 +804,17 @@ func (a *Analyzer) Analyze(events []model.TraceEvent, initialPath string) model.
 
 		// Always add to maps for future comparisons
 		if !entries[i].IsDuplicate {
-			seen[normalizedPath] = i
+			seen[key] = i
 			resolvedPaths[resolvedPath] = i
 		}
 
-		// 2. Disk existence check (use normalized path)
-		if _, err := os.Stat(normalizedPath); os.IsNotExist(err) {
-			entries[i].Diagnostics = append(entries[i].Diagnostics, "Directory does not exist on disk.")
+		// 2. Disk existence check (use normalized path); relative entries
+		// depend on the current directory, so flagRelativeSegments covers them
+		if d := existenceDiagnostic(entries[i], normalizedPath); d != "" {
+			entries[i].Diagnostics = append(entries[i].Diagnostics, d)
 		}
 	}
+	a.flagRelativeSegments(entries)
 
 	// Post-process Flow Graph: Clean up noise
 	// 1. Attribute entries to nodes (reverse mapping)
@@ -757,18 +831,32 @@ func (a *Analyzer) Analyze(events []model.TraceEvent, initialPath string) model.
 	// 2. Filter and Merge
 	var cleanNodes []model.ConfigNode
 	for _, node := range flowNodes {
-		isImportant := isImportantConfig(node.FilePath)
-		if len(node.Entries) == 0 && !isImportant {
-			continue
+		if a.enabled(HeuristicNoisyFiles) {
+			isImportant := isImportantConfig(node.FilePath)
+			if len(node.Entries) == 0 && !isImportant && !isAppleTerminal(node.FilePath) {
+				fired[HeuristicNoisyFiles]++
+				continue
+			}
+
+			// Returning to /etc/zshrc after the Apple Terminal hooks adds nothing
+			if len(cleanNodes) > 0 && len(node.Entries) == 0 && isAppleTerminal(cleanNodes[len(cleanNodes)-1].FilePath) {
+				fired[HeuristicNoisyFiles]++
+				continue
+			}
+		}
+
+		if isAppleTerminal(node.FilePath) {
+			node.Note = describeAppleTerminal(events)
 		}
 
-		if len(cleanNodes) > 0 {
+		if len(cleanNodes) > 0 && a.enabled(HeuristicCoalesce) {
 			last := &cleanNodes[len(cleanNodes)-1]
 			if last.FilePath == node.FilePath {
 				last.Entries = append(last.Entries, node.Entries...)
 				for _, entryIdx := range node.Entries {
 					entries[entryIdx].FlowID = last.ID
 				}
+				fired[HeuristicCoalesce]++
 				continue
 			}
 		}
@@ -782,15 +870,20 @@ func (a *Analyzer) Analyze(events []model.TraceEvent, initialPath string) model.
 			cleanNodes[i].Description = getPathDescription(cleanNodes[i].FilePath)
 		}
 	}
-	cleanNodes = injectMissingNodes(cleanNodes)
+	if a.enabled(HeuristicGhostNodes) {
+		before := len(cleanNodes)
+		cleanNodes = injectMissingNodes(cleanNodes)
+		fired[HeuristicGhostNodes] = len(cleanNodes) - before
+	}
 	for i := range cleanNodes {
 		cleanNodes[i].Order = i + 1
 	}
+	explainSkipped(cleanNodes, events)
 
 	globalDiagnostics := []string{}
 
 	// Shell Mode Advice
-	if isLoginShell(cleanNodes) {
+	if IsLoginShell(cleanNodes) {
 		globalDiagnostics = append(globalDiagnostics, "INFO: Detected as a LOGIN shell. This is typical for terminal startups on macOS.")
 	} else {
 		globalDiagnostics = append(globalDiagnostics, "INFO: Detected as an INTERACTIVE (non-login) shell.")
@@ -815,18 +908,67 @@ func (a *Analyzer) Analyze(events []model.TraceEvent, initialPath string) model.
 	if brewIdx != -1 && usrLocalIdx != -1 && usrLocalIdx < brewIdx {
 		globalDiagnostics = append(globalDiagnostics, "ADVICE: /usr/local/bin appears before Homebrew in PATH. Brew packages may be shadowed by system-installed ones.")
 	}
+	globalDiagnostics = append(globalDiagnostics, attributePackages(entries)...)
+	if a.analyzesPath() {
+		labelVersionManagers(entries)
+	}
 
 	return model.AnalysisResult{
 		PathEntries: entries,
 		FlowNodes:   cleanNodes,
 		Diagnostics: globalDiagnostics,
+		Heuristics:  a.heuristicUses(fired),
 	}
 }
 
+// isAppleTerminal reports whether path is macOS Terminal.app's zsh integration file.
+func isAppleTerminal(path string) bool {
+	return strings.HasSuffix(path, "/etc/zshrc_Apple_Terminal")
+}
+
+// describeAppleTerminal explains what /etc/zshrc_Apple_Terminal did during the
+// trace. It only sets up Terminal.app integration and never touches PATH.
+func describeAppleTerminal(events []model.TraceEvent) string {
+	var features []string
+	seen := make(map[string]bool)
+	add := func(f string) {
+		if !seen[f] {
+			seen[f] = true
+			features = append(features, f)
+		}
+	}
+	for _, ev := range events {
+		if !isAppleTerminal(ev.File) {
+			continue
+		}
+		cmd := ev.RawCommand
+		switch {
+		case strings.Contains(cmd, "update_terminal_cwd"):
+			add("reports the working directory to Terminal.app so new tabs/windows restore it")
+		case strings.Contains(cmd, "SHELL_SESSION") || strings.Contains(cmd, "shell_session"):
+			add("saves and restores per-window history (~/.zsh_sessions)")
+		case strings.Contains(cmd, "bracketed") || strings.Contains(cmd, "zle_bracketed_paste"):
+			add("configures bracketed paste")
+		}
+	}
+
+	note := "Apple Terminal integration (session restoration)"
+	if len(features) > 0 {
+		note += ": " + strings.Join(features, "; ")
+	}
+	return note + ". This file never modifies PATH."
+}
+
 func getPathDescription(path string) string {
 	if path == "System (Default)" {
 		return "Initial environment PATH"
 	}
+	if path == SystemRegistrySource || path == UserRegistrySource {
+		return "(registry)"
+	}
+	if isAppleTerminal(path) {
+		return "(Apple Terminal)"
+	}
 	if strings.HasPrefix(path, "/etc/") {
 		if strings.Contains(path, "env") {
 			return "(system-wide env)"
@@ -840,15 +982,21 @@ func getPathDescription(path string) string {
 		return "(system-wide)"
 	}
 	if strings.Contains(path, "/.zshrc") || strings.Contains(path, "/.zprofile") || strings.Contains(path, "/.zshenv") ||
-		strings.Contains(path, "/.zlogin") || strings.Contains(path, "/.profile") || strings.HasPrefix(path, "~") {
+		strings.Contains(path, "/.zlogin") || strings.Contains(path, "/.profile") || strings.Contains(path, "/.config/fish/") ||
+		strings.Contains(path, "/.config/powershell/") || strings.Contains(path, "/.tcshrc") || strings.Contains(path, "/.cshrc") ||
+		strings.HasSuffix(path, "/.login") || strings.HasSuffix(path, "/.kshrc") || strings.HasSuffix(path, "/.mkshrc") ||
+		strings.HasPrefix(path, "~") {
 		return "(user-specific)"
 	}
 	return ""
 }
 
-func isLoginShell(nodes []model.ConfigNode) bool {
+// IsLoginShell reports whether the traced shell ran as a login shell, judged
+// by whether it read a login-only file such as ~/.zprofile.
+func IsLoginShell(nodes []model.ConfigNode) bool {
 	for _, n := range nodes {
-		if strings.Contains(n.FilePath, "zprofile") || strings.Contains(n.FilePath, "zlogin") || strings.Contains(n.FilePath, "bash_profile") {
+		if strings.Contains(n.FilePath, "zprofile") || strings.Contains(n.FilePath, "zlogin") || strings.Contains(n.FilePath, "bash_profile") ||
+			strings.HasSuffix(n.FilePath, "csh.login") || strings.HasSuffix(n.FilePath, "/.login") {
 			if !n.NotExecuted {
 				return true
 			}
@@ -857,11 +1005,29 @@ func isLoginShell(nodes []model.ConfigNode) bool {
 	return false
 }
 
+// symlinkLabel describes an entry that is a symlink to another entry, which
+// only counts as a duplicate if the policy says so.
+func symlinkLabel(pol model.DuplicatePolicy) string {
+	if pol.IgnoreSymlinks {
+		return "symlink"
+	}
+	return "duplicate, symlink"
+}
+
 // GenerateReport creates a human-readable text report of the analysis.
 func GenerateReport(res model.AnalysisResult, verbose bool) string {
 	var sb strings.Builder
+	name := res.VariableName()
+	pol := res.DuplicatePolicy
 	sb.WriteString("LS-PATH ANALYSIS REPORT\n")
-	sb.WriteString("========================\n\n")
+	sb.WriteString("========================\n")
+	if name != DefaultVariable {
+		sb.WriteString(fmt.Sprintf("Variable: %s\n", name))
+	}
+	if env := res.Environment.Summary(); env != "" {
+		sb.WriteString(fmt.Sprintf("Traced as: %s\n", env))
+	}
+	sb.WriteString("\n")
 
 	sb.WriteString("GLOBAL DIAGNOSTICS\n")
 	sb.WriteString("------------------\n")
@@ -873,22 +1039,30 @@ func GenerateReport(res model.AnalysisResult, verbose bool) string {
 		}
 	}
 	sb.WriteString("\n")
+	sb.WriteString(GenerateSideEffects(res))
 
 	if verbose {
-		sb.WriteString(fmt.Sprintf("PATH ENTRIES (%d ENTRIES) - PRIORITY ORDER\n", len(res.PathEntries)))
+		sb.WriteString(GenerateHeuristics(res))
+		sb.WriteString(GenerateParserStats(res))
+
+		sb.WriteString(fmt.Sprintf("%s ENTRIES (%d ENTRIES) - PRIORITY ORDER\n", name, len(res.PathEntries)))
 		sb.WriteString("--------------------------------------------\n\n")
+		dirStats := StatDirs(res.PathEntries)
 		for i, e := range res.PathEntries {
-			cat := getPathCategory(e.Value)
-			pathMissing := isMissing(e.Value)
+			cat := PathCategory(e.Value)
+			pathMissing := isMissing(e.Value) || e.SymlinkBroken != ""
+			missingIgnored := e.IsIgnored(missingProblem(e))
 
 			// Determine status icon (same as non-verbose mode)
 			statusIcon := model.IconOK
 			if e.IsSessionOnly {
 				statusIcon = model.IconSession
-			} else if e.IsDuplicate || e.SymlinkPointsTo >= 0 {
+			} else if pol.Flagged(e) {
 				statusIcon = model.IconDuplicate
-			} else if pathMissing {
+			} else if pathMissing && !missingIgnored {
 				statusIcon = model.IconMissing
+			} else if model.IsRelativePath(e.Value) && !e.IsIgnored(model.IgnoreRelative) {
+				statusIcon = model.IconRelative
 			}
 
 			// Build suffix labels (same as non-verbose mode)
@@ -898,9 +1072,16 @@ func GenerateReport(res model.AnalysisResult, verbose bool) string {
 				suffixLabel = fmt.Sprintf(" [duplicate → #%d: %s]", e.DuplicateOf+1, origPath)
 			} else if e.SymlinkPointsTo >= 0 {
 				targetPath := res.PathEntries[e.SymlinkPointsTo].Value
-				suffixLabel = fmt.Sprintf(" [duplicate, symlink → #%d: %s]", e.SymlinkPointsTo+1, targetPath)
-			} else if pathMissing {
-				suffixLabel = " (missing)"
+				suffixLabel = fmt.Sprintf(" [%s → #%d: %s]", symlinkLabel(pol), e.SymlinkPointsTo+1, targetPath)
+			} else {
+				suffixLabel = missingLabel(e)
+			}
+
+			if l := VersionManagerLabel(e); l != "" {
+				suffixLabel += " [" + l + "]"
+			}
+			if l := HookLabel(e); l != "" {
+				suffixLabel += " [" + l + "]"
 			}
 
 			// Priority indicators
@@ -910,7 +1091,7 @@ func GenerateReport(res model.AnalysisResult, verbose bool) string {
 				suffixLabel += " (lowest priority " + model.IconPriorityLow + ")"
 			}
 
-			sb.WriteString(fmt.Sprintf("%2d. %s %s%s\n", i+1, statusIcon, e.Value, suffixLabel))
+			sb.WriteString(fmt.Sprintf("%2d. %s %s%s\n", i+1, statusIcon, model.DisplayPath(e.Value), suffixLabel))
 
 			// Source line
 			if e.LineNumber == 0 {
@@ -918,10 +1099,26 @@ func GenerateReport(res model.AnalysisResult, verbose bool) string {
 			} else {
 				sb.WriteString(fmt.Sprintf("      - Source: %s:%d\n", e.SourceFile, e.LineNumber))
 			}
+			if chain := SymlinkChain(e); chain != "" {
+				if e.SymlinkBroken != "" {
+					chain += " (broken: " + e.SymlinkBroken + ")"
+				}
+				sb.WriteString(fmt.Sprintf("      - Symlink: %s\n", chain))
+			}
+			if e.IsSessionOnly && e.SessionNote != "" {
+				sb.WriteString(fmt.Sprintf("      - Note: %s\n", e.SessionNote))
+			}
+			if e.Confidence != "" && e.Confidence != model.ConfidenceHigh {
+				sb.WriteString(fmt.Sprintf("      - Confidence: %s (%s)\n", e.Confidence, e.ConfidenceReason))
+			}
 
 			// Path Contains line
-			if !pathMissing {
-				sb.WriteString(fmt.Sprintf("      - Path Contains: %s\n", getDirStats(e.Value)))
+			if model.IsRelativePath(e.Value) {
+				sb.WriteString("      - Path Contains: depends on the current directory\n")
+			} else if dirStats[i] != nil {
+				sb.WriteString(fmt.Sprintf("      - Path Contains: %s\n", dirStats[i].Summary()))
+			} else if !pathMissing {
+				sb.WriteString("      - Path Contains: unknown\n")
 			} else {
 				sb.WriteString("      - Path Contains: does not exist\n")
 			}
@@ -933,19 +1130,40 @@ func GenerateReport(res model.AnalysisResult, verbose bool) string {
 
 			// Category line
 			sb.WriteString(fmt.Sprintf("      - Category: %s\n", cat))
+			if e.Package != "" {
+				sb.WriteString(fmt.Sprintf("      - Installed By: %s\n", e.Package))
+			}
+			if vm, ok := lookupVersionManager(e.Value); ok {
+				sb.WriteString(fmt.Sprintf("      - Version Manager: %s %s, selected by %s\n", vm.Name, vm.Kind, vm.Selects))
+			}
+			if e.Hook != nil {
+				tool := e.Hook.Binary
+				if tool == "" {
+					tool = "tool not found on PATH"
+				}
+				sb.WriteString(fmt.Sprintf("      - Hook: added by the code `%s` prints (%s)\n", e.Hook.Command, tool))
+			}
+			if len(e.Shadows) > 0 {
+				sb.WriteString(fmt.Sprintf("      - Shadows: %d binaries - %s\n", len(e.Shadows), summarizeNames(e.Shadows, 3)))
+			}
+			if len(e.ShadowedBy) > 0 {
+				sb.WriteString(fmt.Sprintf("      - Shadowed: %d binaries - %s\n", len(e.ShadowedBy), summarizeNames(e.ShadowedBy, 3)))
+			}
 		}
 	} else {
-		sb.WriteString(fmt.Sprintf("PATH (%d ENTRIES) - Use --verbose (or 'v' in TUI) for details\n", len(res.PathEntries)))
+		sb.WriteString(fmt.Sprintf("%s (%d ENTRIES) - Use --verbose (or 'v' in TUI) for details\n", name, len(res.PathEntries)))
 		sb.WriteString("-----------------------------------------------------------\n\n")
 		for i, e := range res.PathEntries {
 			// Determine status icon
 			statusIcon := model.IconOK
 			if e.IsSessionOnly {
 				statusIcon = model.IconSession
-			} else if e.IsDuplicate || e.SymlinkPointsTo >= 0 {
+			} else if pol.Flagged(e) {
 				statusIcon = model.IconDuplicate
-			} else if isMissing(e.Value) {
+			} else if reportedMissing(e) || reportedBroken(e) {
 				statusIcon = model.IconMissing
+			} else if model.IsRelativePath(e.Value) && !e.IsIgnored(model.IgnoreRelative) {
+				statusIcon = model.IconRelative
 			}
 
 			// Build suffix labels
@@ -955,9 +1173,16 @@ func GenerateReport(res model.AnalysisResult, verbose bool) string {
 				suffixLabel = fmt.Sprintf(" [duplicate → #%d: %s]", e.DuplicateOf+1, origPath)
 			} else if e.SymlinkPointsTo >= 0 {
 				targetPath := res.PathEntries[e.SymlinkPointsTo].Value
-				suffixLabel = fmt.Sprintf(" [duplicate, symlink → #%d: %s]", e.SymlinkPointsTo+1, targetPath)
-			} else if isMissing(e.Value) {
-				suffixLabel = " (missing)"
+				suffixLabel = fmt.Sprintf(" [%s → #%d: %s]", symlinkLabel(pol), e.SymlinkPointsTo+1, targetPath)
+			} else {
+				suffixLabel = missingLabel(e)
+			}
+
+			if l := VersionManagerLabel(e); l != "" {
+				suffixLabel += " [" + l + "]"
+			}
+			if l := HookLabel(e); l != "" {
+				suffixLabel += " [" + l + "]"
 			}
 
 			// Priority indicators
@@ -967,7 +1192,7 @@ func GenerateReport(res model.AnalysisResult, verbose bool) string {
 				suffixLabel += " (lowest priority " + model.IconPriorityLow + ")"
 			}
 
-			displayPath := e.Value
+			displayPath := model.DisplayPath(e.Value)
 			if len(displayPath) > 60 {
 				displayPath = displayPath[:57] + "..."
 			}
@@ -979,32 +1204,44 @@ func GenerateReport(res model.AnalysisResult, verbose bool) string {
 	// Summary Section
 	sb.WriteString("SUMMARY\n")
 	sb.WriteString("-------\n")
-	okCount, dupCount, missCount := 0, 0, 0
-	sources := make(map[string]int)
+	okCount, dupCount, missCount, brokenCount, relativeCount, harmlessCount, ignoredCount := 0, 0, 0, 0, 0, 0, 0
 	for _, e := range res.PathEntries {
-		if e.IsDuplicate || e.SymlinkPointsTo >= 0 {
+		if pol.Flagged(e) {
 			dupCount++
-		} else if isMissing(e.Value) {
+		} else if reportedMissing(e) {
 			missCount++
+		} else if reportedBroken(e) {
+			brokenCount++
+		} else if relativeDiagnostic(e) != "" && !e.IsIgnored(model.IgnoreRelative) {
+			relativeCount++
 		} else {
 			okCount++
-		}
-		src := e.SourceFile
-		if strings.HasPrefix(src, "/Users/") {
-			parts := strings.Split(src, "/")
-			if len(parts) > 3 {
-				src = "~/" + strings.Join(parts[3:], "/")
+			if e.IsIgnored(model.IgnoreDuplicate) || e.IsIgnored(model.IgnoreMissing) || e.IsIgnored(model.IgnoreBroken) || e.IsIgnored(model.IgnoreRelative) {
+				ignoredCount++
+			} else if pol.IsDuplicate(e) {
+				harmlessCount++
 			}
 		}
-		sources[src]++
 	}
 
 	total := len(res.PathEntries)
-	sb.WriteString(fmt.Sprintf("Total PATH Entries: %d\n", total))
+	sb.WriteString(fmt.Sprintf("Total %s Entries: %d\n", name, total))
 	if total > 0 {
 		sb.WriteString(fmt.Sprintf("├─ %-13s %2d (%3d%%)\n", "OK:", okCount, okCount*100/total))
 		sb.WriteString(fmt.Sprintf("├─ %-13s %2d (%3d%%)\n", fmt.Sprintf("Missing %s:", model.IconMissing), missCount, missCount*100/total))
+		if brokenCount > 0 {
+			sb.WriteString(fmt.Sprintf("├─ %-13s %2d (%3d%%)\n", fmt.Sprintf("Broken %s:", model.IconSymlink), brokenCount, brokenCount*100/total))
+		}
+		if relativeCount > 0 {
+			sb.WriteString(fmt.Sprintf("├─ %-13s %2d (%3d%%)\n", fmt.Sprintf("Relative %s:", model.IconRelative), relativeCount, relativeCount*100/total))
+		}
 		sb.WriteString(fmt.Sprintf("└─ %-13s %2d (%3d%%)\n", fmt.Sprintf("Duplicates %s:", model.IconDuplicate), dupCount, dupCount*100/total))
+		if harmlessCount > 0 {
+			sb.WriteString(fmt.Sprintf("   (%d harmless duplicates counted as OK)\n", harmlessCount))
+		}
+		if ignoredCount > 0 {
+			sb.WriteString(fmt.Sprintf("   (%d entries with ignored problems counted as OK)\n", ignoredCount))
+		}
 	}
 
 	sb.WriteString("\n")
@@ -1017,33 +1254,16 @@ func GenerateReport(res model.AnalysisResult, verbose bool) string {
 	// Duplicates
 	if dupCount > 0 {
 		foundAny = true
-		sb.WriteString(fmt.Sprintf("%s DUPLICATES (%d) [NOT SERIOUS]\n", model.IconDuplicate, dupCount))
+		seriousness := "NOT SERIOUS"
+		if pol.Severity == model.DuplicatesError {
+			seriousness = "SERIOUS: only the first copy is ever searched"
+		}
+		sb.WriteString(fmt.Sprintf("%s DUPLICATES (%d) [%s]\n", model.IconDuplicate, dupCount, seriousness))
+		for _, g := range res.DuplicateGroups() {
+			writeDuplicateGroup(&sb, res, g)
+		}
 		for i, e := range res.PathEntries {
-			if e.IsDuplicate {
-				sb.WriteString(fmt.Sprintf("%2d. %s\n", i+1, e.Value))
-				orig := res.PathEntries[e.DuplicateOf]
-
-				// Show where this entry was added
-				sb.WriteString(fmt.Sprintf("    » Added by line %d of %s\n", e.LineNumber, e.SourceFile))
-
-				// Quote the actual source line
-				sourceLine := getLineFromFile(e.SourceFile, e.LineNumber)
-				if sourceLine != "" {
-					// Truncate if too long
-					if len(sourceLine) > 70 {
-						sourceLine = sourceLine[:67] + "..."
-					}
-					sb.WriteString(fmt.Sprintf("      %s\n", sourceLine))
-				}
-
-				// Check if both entries come from the same source file and line
-				if e.SourceFile == orig.SourceFile && e.LineNumber == orig.LineNumber {
-					sb.WriteString(fmt.Sprintf("    » Duplicates PATH entry #%d which was already in $PATH\n\n", e.DuplicateOf+1))
-				} else {
-					sb.WriteString(fmt.Sprintf("    » Duplicates PATH entry #%d (from line %d of %s)\n", e.DuplicateOf+1, orig.LineNumber, orig.SourceFile))
-					sb.WriteString(fmt.Sprintf("    » Advice: remove line %d from %s\n\n", e.LineNumber, e.SourceFile))
-				}
-			} else if e.SymlinkPointsTo >= 0 {
+			if !e.IsDuplicate && pol.IsDuplicate(e) && !e.IsIgnored(model.IgnoreDuplicate) {
 				sb.WriteString(fmt.Sprintf("%2d. %s\n", i+1, e.Value))
 				sb.WriteString(fmt.Sprintf("    » Symlink resolves to PATH entry %d (%s)\n", e.SymlinkPointsTo+1, e.SymlinkTarget))
 				sb.WriteString(fmt.Sprintf("    » This is normal on modern Linux systems\n\n"))
@@ -1056,8 +1276,44 @@ func GenerateReport(res model.AnalysisResult, verbose bool) string {
 		foundAny = true
 		sb.WriteString(fmt.Sprintf("%s MISSING DIRECTORIES (%d) [NOT SERIOUS]\n", model.IconMissing, missCount))
 		for i, e := range res.PathEntries {
-			if isMissing(e.Value) {
+			if reportedMissing(e) {
+				sb.WriteString(fmt.Sprintf("%2d. %s (from %s:%d)\n", i+1, e.Value, e.SourceFile, e.LineNumber))
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	// Broken symlinks
+	if brokenCount > 0 {
+		foundAny = true
+		sb.WriteString(fmt.Sprintf("%s BROKEN SYMLINKS (%d) [NOT SERIOUS]\n", model.IconSymlink, brokenCount))
+		for i, e := range res.PathEntries {
+			if reportedBroken(e) {
 				sb.WriteString(fmt.Sprintf("%2d. %s (from %s:%d)\n", i+1, e.Value, e.SourceFile, e.LineNumber))
+				sb.WriteString(fmt.Sprintf("    » %s\n", SymlinkChain(e)))
+				sb.WriteString(fmt.Sprintf("    » Broken: %s; fix the link or remove the entry\n", e.SymlinkBroken))
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	// Empty and relative segments
+	var relative []int
+	for i, e := range res.PathEntries {
+		if relativeDiagnostic(e) != "" && !e.IsIgnored(model.IgnoreRelative) {
+			relative = append(relative, i)
+		}
+	}
+	if len(relative) > 0 {
+		foundAny = true
+		sb.WriteString(fmt.Sprintf("%s EMPTY OR RELATIVE SEGMENTS (%d) [SERIOUS]\n", model.IconRelative, len(relative)))
+		for _, i := range relative {
+			e := res.PathEntries[i]
+			sb.WriteString(fmt.Sprintf("%2d. %s (from %s:%d)\n", i+1, model.DisplayPath(e.Value), e.SourceFile, e.LineNumber))
+			diag, advice := relativeDiagnostics(e)
+			sb.WriteString(fmt.Sprintf("    » %s\n", diag))
+			if advice != "" {
+				sb.WriteString(fmt.Sprintf("    » %s\n", advice))
 			}
 		}
 		sb.WriteString("\n")
@@ -1067,6 +1323,28 @@ func GenerateReport(res model.AnalysisResult, verbose bool) string {
 		sb.WriteString("No specific issues found.\n\n")
 	}
 
+	if len(res.Shadows) > 0 {
+		sb.WriteString(fmt.Sprintf("SHADOWED BINARIES (%d)\n", len(res.Shadows)))
+		sb.WriteString("---------------------\n")
+		for _, sh := range res.Shadows {
+			var hidden []string
+			for _, l := range sh.Losers {
+				hidden = append(hidden, fmt.Sprintf("#%d %s", l+1, res.PathEntries[l].Value))
+			}
+			sb.WriteString(fmt.Sprintf("%s %s: #%d %s wins over %s\n", model.IconShadow, sh.Name, sh.Winner+1, res.PathEntries[sh.Winner].Value, strings.Join(hidden, ", ")))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString(GenerateFlow(res, verbose))
+	return sb.String()
+}
+
+// GenerateFlow renders the report's CONFIGURATION FILES FLOW sections: the
+// startup files in the order the shell ran them and, if verbose, the lines
+// of each that changed the variable.
+func GenerateFlow(res model.AnalysisResult, verbose bool) string {
+	var sb strings.Builder
 	sb.WriteString("CONFIGURATION FILES FLOW - SUMMARY\n")
 	sb.WriteString("----------------------------------\n")
 	for _, n := range res.FlowNodes {
@@ -1074,7 +1352,7 @@ func GenerateReport(res model.AnalysisResult, verbose bool) string {
 		status := ""
 		if n.NotExecuted {
 			// Check if file exists
-			expandedPath := expandTilde(n.FilePath)
+			expandedPath := model.ExpandTilde(n.FilePath)
 			if _, err := os.Stat(expandedPath); os.IsNotExist(err) {
 				status = " [Not Executed - file does not exist]"
 			} else {
@@ -1082,6 +1360,8 @@ func GenerateReport(res model.AnalysisResult, verbose bool) string {
 			}
 		} else if len(n.Entries) > 0 {
 			status = fmt.Sprintf(" [%d paths]", len(n.Entries))
+		} else if isAppleTerminal(n.FilePath) {
+			status = " [never modifies PATH]"
 		} else {
 			status = " [no change]"
 		}
@@ -1112,7 +1392,7 @@ func GenerateReport(res model.AnalysisResult, verbose bool) string {
 			status := ""
 			if n.NotExecuted {
 				// Check if file exists
-				expandedPath := expandTilde(n.FilePath)
+				expandedPath := model.ExpandTilde(n.FilePath)
 				if _, err := os.Stat(expandedPath); os.IsNotExist(err) {
 					status = " [Not Executed - file does not exist]"
 				} else {
@@ -1120,6 +1400,8 @@ func GenerateReport(res model.AnalysisResult, verbose bool) string {
 				}
 			} else if len(n.Entries) > 0 {
 				status = fmt.Sprintf(" [%d paths]", len(n.Entries))
+			} else if isAppleTerminal(n.FilePath) {
+				status = " [never modifies PATH]"
 			} else {
 				status = " [no change]"
 			}
@@ -1135,6 +1417,12 @@ func GenerateReport(res model.AnalysisResult, verbose bool) string {
 			}
 
 			sb.WriteString(fmt.Sprintf("%2d. %s%s%s%s%s\n", n.Order, indent, n.FilePath, desc, status, execLabel))
+			if n.Note != "" {
+				sb.WriteString(fmt.Sprintf("      %sℹ %s\n", indent, n.Note))
+			}
+			for _, s := range n.Skipped {
+				sb.WriteString(fmt.Sprintf("      %s⤼ %s\n", indent, s))
+			}
 
 			// List the actual paths added by this node
 			if len(n.Entries) > 0 && !n.NotExecuted {
@@ -1161,7 +1449,99 @@ func GenerateReport(res model.AnalysisResult, verbose bool) string {
 	return sb.String()
 }
 
+// lineDuplicates returns the entries added by sl that duplicate an entry
+// from a different line, so removing them from sl loses nothing.
+func lineDuplicates(res model.AnalysisResult, sl model.SourceLine) []int {
+	var dups []int
+	for _, i := range sl.Entries {
+		e := res.PathEntries[i]
+		if !e.IsDuplicate {
+			continue
+		}
+		if orig := res.PathEntries[e.DuplicateOf]; orig.SourceFile != e.SourceFile || orig.LineNumber != e.LineNumber {
+			dups = append(dups, i)
+		}
+	}
+	return dups
+}
+
+// DuplicateLineAdvice gives one remediation covering every duplicate a
+// config line adds, or "" if it adds none.
+func DuplicateLineAdvice(res model.AnalysisResult, sl model.SourceLine) string {
+	dups := lineDuplicates(res, sl)
+	if len(dups) == 0 {
+		return ""
+	}
+	if len(dups) == len(sl.Entries) {
+		return fmt.Sprintf("remove line %d from %s", sl.Line, sl.File)
+	}
+	isDup := make(map[int]bool)
+	var drop, keep []string
+	for _, i := range dups {
+		isDup[i] = true
+		drop = append(drop, res.PathEntries[i].Value)
+	}
+	for _, i := range sl.Entries {
+		if !isDup[i] {
+			keep = append(keep, res.PathEntries[i].Value)
+		}
+	}
+	return fmt.Sprintf("edit line %d of %s to drop %s (it also adds %s)", sl.Line, sl.File, strings.Join(drop, ", "), strings.Join(keep, ", "))
+}
+
+// DuplicateSource says where entry e was added, e.g. "line 12 of ~/.zshrc".
+func DuplicateSource(e model.PathEntry) string {
+	if e.LineNumber == 0 {
+		return e.SourceFile
+	}
+	return fmt.Sprintf("line %d of %s", e.LineNumber, e.SourceFile)
+}
+
+// writeDuplicateGroup reports every copy of a directory together: where
+// each was added, and how to remove the ones that are never searched.
+// Copies the user's ignore rules accept are left out.
+func writeDuplicateGroup(sb *strings.Builder, res model.AnalysisResult, g model.DuplicateGroup) {
+	var copies []int
+	for _, i := range g.Copies {
+		if !res.PathEntries[i].IsIgnored(model.IgnoreDuplicate) {
+			copies = append(copies, i)
+		}
+	}
+	if len(copies) == 0 {
+		return
+	}
+	first := res.PathEntries[g.First]
+	sb.WriteString(fmt.Sprintf("%s: %d copies, only #%d is searched\n", first.Value, len(g.Copies)+1, g.First+1))
+	sb.WriteString(fmt.Sprintf("   #%-3d %s\n", g.First+1, DuplicateSource(first)))
+	for _, i := range copies {
+		e := res.PathEntries[i]
+		if e.LineNumber != 0 && e.SourceFile == first.SourceFile && e.LineNumber == first.LineNumber {
+			sb.WriteString(fmt.Sprintf("   #%-3d %s again, when it was already in $%s\n", i+1, DuplicateSource(e), res.VariableName()))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("   #%-3d %s\n", i+1, DuplicateSource(e)))
+		if e.LineNumber == 0 {
+			continue
+		}
+		if sourceLine := getLineFromFile(e.SourceFile, e.LineNumber); sourceLine != "" {
+			if len(sourceLine) > 70 {
+				sourceLine = sourceLine[:67] + "..."
+			}
+			sb.WriteString(fmt.Sprintf("          %s\n", sourceLine))
+		}
+		advice := fmt.Sprintf("remove line %d from %s", e.LineNumber, e.SourceFile)
+		if sl, ok := res.SourceLineOf(i); ok {
+			advice = DuplicateLineAdvice(res, sl) // Keeps any other entries the line adds
+		}
+		sb.WriteString(fmt.Sprintf("        » Advice: %s\n", advice))
+	}
+	sb.WriteString("\n")
+}
+
 func isMissing(path string) bool {
+	if model.IsRelativePath(path) {
+		return false // Depends on the current directory; reported separately
+	}
 	_, err := os.Stat(path)
 	return os.IsNotExist(err)
 }
@@ -1182,6 +1562,43 @@ var zshStandard = []standardConfig{
 	{"/.zlogin", 8},
 }
 
+var fishStandard = []standardConfig{
+	{"/etc/fish/config.fish", 1},
+	{"/.config/fish/config.fish", 2},
+}
+
+// pwshStandard lists the per-user profiles; the all-users ones live under
+// $PSHOME, which varies by install.
+var pwshStandard = []standardConfig{
+	{"/.config/powershell/profile.ps1", 1},
+	{"/.config/powershell/Microsoft.PowerShell_profile.ps1", 2},
+}
+
+// cshStandard is tcsh's order; csh reads ~/.cshrc, which tcsh only reads
+// when there is no ~/.tcshrc.
+var cshStandard = []standardConfig{
+	{"/etc/csh.cshrc", 1},
+	{"/etc/csh.login", 2},
+	{"/.tcshrc", 3},
+	{"/.cshrc", 4},
+	{"/.login", 5},
+}
+
+// kshStandard adds the file the Korn shells read as $ENV when it is unset;
+// OpenBSD's ~/.kshrc usually sources /etc/ksh.kshrc.
+var kshStandard = []standardConfig{
+	{"/etc/profile", 1},
+	{"/.profile", 2},
+	{"/.kshrc", 3},
+	{"/.mkshrc", 4},
+}
+
+// posixStandard is what a login sh (dash, ash) reads.
+var posixStandard = []standardConfig{
+	{"/etc/profile", 1},
+	{"/.profile", 2},
+}
+
 var bashStandard = []standardConfig{
 	{"/etc/profile", 1},
 	{"/etc/bash.bashrc", 2},
@@ -1192,10 +1609,15 @@ var bashStandard = []standardConfig{
 	{"/.bashrc", 7},
 }
 
-// detectShellFromNodes determines if the executed files are bash or zsh
+// detectShellFromNodes determines if the executed files are bash, zsh, fish, pwsh, csh, ksh or sh
 func detectShellFromNodes(nodes []model.ConfigNode) string {
 	bashCount := 0
 	zshCount := 0
+	fishCount := 0
+	pwshCount := 0
+	cshCount := 0
+	kshCount := 0
+	profileCount := 0
 
 	for _, node := range nodes {
 		if node.NotExecuted {
@@ -1208,6 +1630,43 @@ func detectShellFromNodes(nodes []model.ConfigNode) string {
 		if strings.Contains(path, "zsh") {
 			zshCount++
 		}
+		if strings.Contains(path, "fish") {
+			fishCount++
+		}
+		if strings.HasSuffix(path, ".ps1") {
+			pwshCount++
+		}
+		if strings.Contains(path, "cshrc") || strings.HasSuffix(path, "csh.login") {
+			cshCount++
+		}
+		if strings.Contains(path, "kshrc") {
+			kshCount++
+		}
+		if strings.HasSuffix(path, "/etc/profile") || strings.HasSuffix(path, "/.profile") {
+			profileCount++
+		}
+	}
+
+	if kshCount > 0 && bashCount == 0 && zshCount == 0 && fishCount == 0 && pwshCount == 0 && cshCount == 0 {
+		return "ksh"
+	}
+
+	// Only the POSIX profiles ran: a plain sh. bash reading them as a login
+	// shell goes on to a bash file almost everywhere.
+	if profileCount > 0 && bashCount == 0 && zshCount == 0 && fishCount == 0 && pwshCount == 0 && cshCount == 0 {
+		return "sh"
+	}
+
+	if cshCount > 0 && bashCount == 0 && zshCount == 0 && fishCount == 0 {
+		return "csh"
+	}
+
+	if pwshCount > 0 && bashCount == 0 && zshCount == 0 && fishCount == 0 {
+		return "pwsh"
+	}
+
+	if fishCount > 0 && bashCount == 0 && zshCount == 0 {
+		return "fish"
 	}
 
 	// If we see bash files executed, it's bash
@@ -1228,9 +1687,20 @@ func injectMissingNodes(nodes []model.ConfigNode) []model.ConfigNode {
 
 	// Only inject missing nodes for the detected shell
 	var standardConfigs []standardConfig
-	if detectedShell == "bash" {
+	switch detectedShell {
+	case "bash":
 		standardConfigs = bashStandard
-	} else {
+	case "fish":
+		standardConfigs = fishStandard
+	case "pwsh":
+		standardConfigs = pwshStandard
+	case "csh":
+		standardConfigs = cshStandard
+	case "ksh":
+		standardConfigs = kshStandard
+	case "sh":
+		standardConfigs = posixStandard
+	default:
 		standardConfigs = zshStandard
 	}
 
@@ -1323,13 +1793,23 @@ func injectMissingNodes(nodes []model.ConfigNode) []model.ConfigNode {
 
 // GuessShellMode infers shell mode from filename.
 func GuessShellMode(filename string) string {
-	if strings.Contains(filename, "zprofile") || strings.Contains(filename, "zlogin") || strings.Contains(filename, "bash_profile") || strings.Contains(filename, "profile") {
+	if strings.HasSuffix(filename, ".ps1") {
+		// pwsh runs every $PROFILE script unless started with -NoProfile
+		return "Env/All"
+	}
+	if strings.Contains(filename, "zprofile") || strings.Contains(filename, "zlogin") || strings.Contains(filename, "bash_profile") || strings.Contains(filename, "profile") ||
+		strings.HasSuffix(filename, ".login") {
 		return "Login"
 	}
-	if strings.Contains(filename, "zshrc") || strings.Contains(filename, "bashrc") {
+	if strings.Contains(filename, "cshrc") {
+		// csh reads its rc files for every shell, scripts included, unless run with -f
+		return "Env/All"
+	}
+	if strings.Contains(filename, "zshrc") || strings.Contains(filename, "bashrc") || strings.Contains(filename, "kshrc") {
 		return "Interactive"
 	}
-	if strings.Contains(filename, "zshenv") || strings.Contains(filename, "environment") {
+	if strings.Contains(filename, "zshenv") || strings.Contains(filename, "environment") || strings.HasSuffix(filename, ".fish") {
+		// fish reads config.fish and conf.d/*.fish for every shell
 		return "Env/All"
 	}
 	return "Unknown"
@@ -1352,6 +1832,10 @@ func isImportantConfig(path string) bool {
 		"bashrc", ".bashrc", "bash.bashrc",
 		"profile", ".profile",
 		"bash_login",
+		"config.fish",
+		"profile.ps1", "Microsoft.PowerShell_profile.ps1",
+		"csh.cshrc", "csh.login", ".tcshrc", ".cshrc", ".login",
+		".kshrc", ".mkshrc", "ksh.kshrc",
 	}
 
 	for _, k := range keys {
@@ -1361,9 +1845,16 @@ func isImportantConfig(path string) bool {
 	}
 	return false
 }
-func getPathCategory(path string) string {
+
+// PathCategory names the kind of directory path is, e.g. "Package
+// Managers", for the verbose report and the TUI's category order.
+func PathCategory(path string) string {
 	p := strings.ToLower(path)
 
+	if _, ok := lookupVersionManager(path); ok {
+		return "Version Managers"
+	}
+
 	// Tools & Languages
 	if strings.Contains(p, "flutter") || strings.Contains(p, "cargo") || strings.Contains(p, "go/bin") ||
 		strings.Contains(p, "dotnet") || strings.Contains(p, "dart") || strings.Contains(p, "rust") ||
@@ -1402,41 +1893,3 @@ func getPathCategory(path string) string {
 
 	return "Other Paths"
 }
-
-func getDirStats(path string) string {
-	_, err := os.Stat(path)
-	if err != nil {
-		return "unknown"
-	}
-
-	f, err := os.Open(path)
-	if err != nil {
-		return "unknown"
-	}
-	defer f.Close()
-
-	files, err := f.Readdirnames(-1)
-	if err != nil {
-		return "unknown"
-	}
-
-	fileCount := 0
-	dirCount := 0
-	for _, name := range files {
-		full := path
-		if !strings.HasSuffix(full, "/") {
-			full += "/"
-		}
-		full += name
-
-		if info, err := os.Stat(full); err == nil {
-			if info.IsDir() {
-				dirCount++
-			} else {
-				fileCount++
-			}
-		}
-	}
-
-	return fmt.Sprintf("%d files, %d dirs", fileCount, dirCount)
-}
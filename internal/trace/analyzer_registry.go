@@ -0,0 +1,132 @@
+package trace
+
+import (
+	"fmt"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// Registry sources used to attribute PATH entries on Windows.
+const (
+	SystemRegistrySource = `HKLM\SYSTEM\CurrentControlSet\Control\Session Manager\Environment`
+	UserRegistrySource   = `HKCU\Environment`
+)
+
+// AnalyzeWindows attributes the session PATH to the machine and user registry
+// Path values, which Windows concatenates (machine first) when it starts a
+// process. Entries in neither were added by the parent process or a tool.
+// LineNumber holds the entry's 1-based position within its registry value.
+func (a *Analyzer) AnalyzeWindows(sessionPath string, machine, user []string) model.AnalysisResult {
+	// Windows paths are case-insensitive
+	a.Canon.CaseInsensitive = true
+
+	machineIdx := registryIndex(machine, a.Canon)
+	userIdx := registryIndex(user, a.Canon)
+
+	machineNode := model.ConfigNode{
+		ID:          "node-0",
+		FilePath:    SystemRegistrySource,
+		Order:       1,
+		Description: "System environment variables (all users)",
+	}
+	userNode := model.ConfigNode{
+		ID:          "node-1",
+		FilePath:    UserRegistrySource,
+		Order:       2,
+		Description: "User environment variables",
+	}
+	sessionNode := model.ConfigNode{
+		ID:          "session-node",
+		FilePath:    "Session (Manual/Runtime)",
+		Order:       3,
+		Description: "Paths added in this terminal session",
+	}
+
+	var entries []model.PathEntry
+	inSession := make(map[string]bool)
+	for _, p := range strings.Split(sessionPath, ";") {
+		if p == "" {
+			continue
+		}
+		idx := len(entries)
+		key := model.CanonicalPath(p, a.Canon)
+		inSession[key] = true
+
+		entry := model.PathEntry{
+			Value:            p,
+			SymlinkPointsTo:  -1,
+			Confidence:       model.ConfidenceHigh,
+			ConfidenceReason: "Listed in the registry Path value",
+		}
+		if n, ok := machineIdx[key]; ok {
+			entry.SourceFile = SystemRegistrySource
+			entry.LineNumber = n
+			entry.Mode = "System"
+			entry.FlowID = machineNode.ID
+			machineNode.Entries = append(machineNode.Entries, idx)
+		} else if n, ok := userIdx[key]; ok {
+			entry.SourceFile = UserRegistrySource
+			entry.LineNumber = n
+			entry.Mode = "User"
+			entry.FlowID = userNode.ID
+			userNode.Entries = append(userNode.Entries, idx)
+		} else {
+			entry.SourceFile = sessionNode.FilePath
+			entry.Mode = "Session"
+			entry.IsSessionOnly = true
+			entry.SessionNote = "Not in the registry - set by the parent process or a runtime tool"
+			if note, ok := activationNote(entry.Value); ok {
+				entry.SessionNote = note
+			}
+			entry.Confidence = model.ConfidenceLow
+			entry.ConfidenceReason = "Not in the registry; assumed added by the parent process"
+			entry.FlowID = sessionNode.ID
+			sessionNode.Entries = append(sessionNode.Entries, idx)
+		}
+		entries = append(entries, entry)
+	}
+
+	a.markDuplicates(entries)
+
+	flowNodes := []model.ConfigNode{machineNode, userNode}
+	if len(sessionNode.Entries) > 0 {
+		flowNodes = append(flowNodes, sessionNode)
+	}
+
+	globalDiagnostics := []string{
+		"INFO: Windows view - entries are attributed to the system and user Path registry values.",
+	}
+	var pending []string
+	for _, list := range [][]string{machine, user} {
+		for _, p := range list {
+			if !inSession[model.CanonicalPath(p, a.Canon)] {
+				pending = append(pending, p)
+			}
+		}
+	}
+	if len(pending) > 0 {
+		globalDiagnostics = append(globalDiagnostics, fmt.Sprintf(
+			"INFO: %d registry entries are not in this session's PATH (%s). Open a new terminal to pick them up.",
+			len(pending), strings.Join(pending, "; ")))
+	}
+
+	return model.AnalysisResult{
+		PathEntries: entries,
+		FlowNodes:   flowNodes,
+		Diagnostics: globalDiagnostics,
+	}
+}
+
+// registryIndex maps each canonical registry entry to its 1-based position,
+// keeping the first occurrence.
+func registryIndex(values []string, opts model.CanonOptions) map[string]int {
+	idx := make(map[string]int)
+	for i, v := range values {
+		key := model.CanonicalPath(v, opts)
+		if _, ok := idx[key]; !ok {
+			idx[key] = i + 1
+		}
+	}
+	return idx
+}
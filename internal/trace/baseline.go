@@ -0,0 +1,84 @@
+package trace
+
+import (
+	"os"
+	"strings"
+	"sync"
+)
+
+// systemProfiles hold the base system's commands on distributions where
+// /usr/bin and /bin have little more than env and sh.
+var systemProfiles = []string{
+	"/run/current-system/sw/bin",       // NixOS
+	"/run/current-system/sw/sbin",      // NixOS
+	"/run/current-system/profile/bin",  // Guix System
+	"/run/current-system/profile/sbin", // Guix System
+}
+
+var detectedBaseline = sync.OnceValue(detectBaseline)
+
+// DetectBaseline returns this system's minimal PATH, for traced shells to
+// start from: the directories of SandboxInitialPath and of `getconf PATH`
+// that exist, then the system profile on NixOS and Guix. Where none exist
+// it is SandboxInitialPath.
+func DetectBaseline() string {
+	return detectedBaseline()
+}
+
+func detectBaseline() string {
+	var dirs []string
+	seen := make(map[string]bool)
+	add := func(dir string) {
+		if dir == "" || seen[dir] {
+			return
+		}
+		seen[dir] = true
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			dirs = append(dirs, dir)
+		}
+	}
+	for _, d := range strings.Split(SandboxInitialPath, ":") {
+		add(d)
+	}
+	for _, d := range strings.Split(toolOutput("getconf", "PATH"), ":") {
+		add(d)
+	}
+	for _, d := range systemProfiles {
+		add(d)
+	}
+	if len(dirs) == 0 {
+		return SandboxInitialPath
+	}
+	return strings.Join(dirs, ":")
+}
+
+// baseline returns the PATH traced shells start from: --baseline if given,
+// else DetectBaseline.
+func (o Options) baseline() string {
+	if o.Baseline != "" {
+		return o.Baseline
+	}
+	return DetectBaseline()
+}
+
+// initialValue is what the traced variable starts as. Variables other than
+// PATH start empty, so every entry is attributed to a file.
+func (o Options) initialValue(variable string) string {
+	if variable != DefaultVariable {
+		return ""
+	}
+	return o.baseline()
+}
+
+// baselineDiagnostic notes a baseline other than the usual one, since it
+// decides which entries count as the system's rather than a file's.
+func baselineDiagnostic(opts Options) string {
+	base := opts.baseline()
+	switch {
+	case opts.Baseline != "":
+		return "INFO: Traced from the baseline PATH given with --baseline: " + base
+	case base != SandboxInitialPath:
+		return "INFO: Traced from this system's baseline PATH " + base + " (detected; override with --baseline)"
+	}
+	return ""
+}
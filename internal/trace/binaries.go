@@ -0,0 +1,155 @@
+package trace
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"lspath/internal/model"
+)
+
+// DefaultScanBudget bounds how long deep scans of PATH directories may run
+// before returning partial results.
+const DefaultScanBudget = 10 * time.Second
+
+// ScanProgress reports how many PATH directories a deep scan has visited.
+type ScanProgress func(done, total int)
+
+// listExecutables returns the sorted names of executable files in dir.
+// Symlinks are followed so shims and linked binaries are included.
+func listExecutables(dir string) []string {
+	dir = model.ExpandTilde(dir)
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, f := range files {
+		info, err := os.Stat(filepath.Join(dir, f.Name()))
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if info.Mode().Perm()&0111 != 0 {
+			names = append(names, f.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BinaryIndex records which executables each PATH entry provides.
+type BinaryIndex struct {
+	ByEntry [][]string // Executable names per PathEntry index (nil for missing dirs)
+	Scanned int        // Number of entries scanned before the budget ran out or the scan was cancelled
+}
+
+// BuildBinaryIndex scans every PATH directory once with no time limit.
+func BuildBinaryIndex(entries []model.PathEntry) *BinaryIndex {
+	return ScanBinaryIndex(context.Background(), entries, nil)
+}
+
+// ScanBinaryIndex scans PATH directories in priority order until ctx is done.
+// Duplicate entries share the listing of their original rather than
+// re-reading the directory. On cancellation the partial index is returned.
+func ScanBinaryIndex(ctx context.Context, entries []model.PathEntry, progress ScanProgress) *BinaryIndex {
+	idx := &BinaryIndex{ByEntry: make([][]string, len(entries))}
+	for i, e := range entries {
+		if ctx.Err() != nil {
+			break
+		}
+		if e.IsDuplicate && e.DuplicateOf < i {
+			idx.ByEntry[i] = idx.ByEntry[e.DuplicateOf]
+		} else {
+			idx.ByEntry[i] = listExecutables(e.Value)
+		}
+		idx.Scanned = i + 1
+		if progress != nil {
+			progress(idx.Scanned, len(entries))
+		}
+	}
+	return idx
+}
+
+// Partial reports whether the scan stopped before visiting every entry.
+func (b *BinaryIndex) Partial() bool {
+	return b.Scanned < len(b.ByEntry)
+}
+
+// RemovalImpact returns the binaries that would stop resolving entirely if
+// entry i were removed from PATH, i.e. those no other entry provides.
+// With a partial index, unscanned entries are not considered.
+func (b *BinaryIndex) RemovalImpact(i int) []string {
+	if i < 0 || i >= len(b.ByEntry) {
+		return nil
+	}
+	elsewhere := make(map[string]bool)
+	for j, names := range b.ByEntry {
+		if j == i {
+			continue
+		}
+		for _, n := range names {
+			elsewhere[n] = true
+		}
+	}
+
+	var breaks []string
+	for _, n := range b.ByEntry[i] {
+		if !elsewhere[n] {
+			breaks = append(breaks, n)
+		}
+	}
+	return breaks
+}
+
+// EntryExecutable is one executable in a PATH entry and what running its
+// name finds.
+type EntryExecutable struct {
+	Name     string
+	Winner   int   // Entry whose copy runs; the entry itself when it wins
+	SameFile bool  // The winner's copy is the same file (e.g. a duplicate or symlinked entry)
+	Hides    []int // Later entries whose copies this one hides
+}
+
+// Shadowed reports whether an earlier entry's copy runs instead.
+func (x EntryExecutable) Shadowed(entry int) bool {
+	return x.Winner != entry && !x.SameFile
+}
+
+// EntryExecutables lists the executables of entry i, in name order, with
+// which entry wins each name. The winners and their hidden copies come from
+// the analysis's shadow index; names it does not list are looked up in the
+// earlier entries, which it skips when they hold the same file. Unscanned
+// entries give nil.
+func EntryExecutables(res model.AnalysisResult, bins *BinaryIndex, i int) []EntryExecutable {
+	if bins == nil || i < 0 || i >= bins.Scanned || i >= len(res.PathEntries) {
+		return nil
+	}
+	shadows := make(map[string]model.Shadow, len(res.Shadows))
+	for _, s := range res.Shadows {
+		shadows[s.Name] = s
+	}
+
+	var list []EntryExecutable
+	for _, name := range bins.ByEntry[i] {
+		x := EntryExecutable{Name: name, Winner: i}
+		if s, ok := shadows[name]; ok && s.Winner == i {
+			x.Hides = s.Losers
+		} else if ok && slices.Contains(s.Losers, i) {
+			x.Winner = s.Winner
+		} else {
+			for j := 0; j < i; j++ {
+				if _, found := slices.BinarySearch(bins.ByEntry[j], name); found && !model.IsRelativePath(res.PathEntries[j].Value) {
+					x.Winner = j
+					x.SameFile = sameFile(filepath.Join(model.ExpandTilde(res.PathEntries[j].Value), name), filepath.Join(model.ExpandTilde(res.PathEntries[i].Value), name))
+					break
+				}
+			}
+		}
+		list = append(list, x)
+	}
+	return list
+}
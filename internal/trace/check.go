@@ -0,0 +1,137 @@
+package trace
+
+import (
+	"fmt"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// Severities of --check findings, lowest first.
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+var severityRanks = map[string]int{SeverityInfo: 0, SeverityWarning: 1, SeverityError: 2}
+
+// ParseSeverity validates a --fail-on value.
+func ParseSeverity(s string) (string, error) {
+	if _, ok := severityRanks[s]; !ok {
+		return "", fmt.Errorf("unknown severity %q (use info, warning or error)", s)
+	}
+	return s, nil
+}
+
+// AtLeast reports whether severity is threshold or worse.
+func AtLeast(severity, threshold string) bool {
+	return severityRanks[severity] >= severityRanks[threshold]
+}
+
+// Finding is one problem --check reports.
+type Finding struct {
+	Severity string // One of the Severity* constants
+	Entry    int    // PathEntry index, or -1 for the result as a whole
+	Message  string
+	Ignored  bool // Silenced by an ignore rule: listed, but never fails the check
+}
+
+// String formats f as a line of --check output, e.g.
+// "warning  #7 /opt/old/bin: does not exist".
+func (f Finding) String(res model.AnalysisResult) string {
+	severity := f.Severity
+	if f.Ignored {
+		severity = "ignored"
+	}
+	if f.Entry < 0 {
+		return fmt.Sprintf("%-8s %s", severity, f.Message)
+	}
+	value := res.PathEntries[f.Entry].Value
+	if value == "" {
+		value = "(empty)"
+	}
+	return fmt.Sprintf("%-8s #%d %s: %s", severity, f.Entry+1, value, f.Message)
+}
+
+// Check lists the problems in res, worst first and then in PATH order:
+// entries anyone else can add commands to and broken pins are errors;
+// missing directories, broken symlinks, duplicates (errors under
+// --duplicates=error, info when harmless) and the global warnings are
+// warnings; session-only entries are info. Plugins' findings keep the severity they gave. Problems the
+// user's ignore rules silence come last, marked Ignored.
+func Check(res model.AnalysisResult) []Finding {
+	var findings, ignored []Finding
+	add := func(severity string, entry int, format string, args ...any) {
+		findings = append(findings, Finding{Severity: severity, Entry: entry, Message: fmt.Sprintf(format, args...)})
+	}
+	// addEntry files a problem of entry i under findings or ignored
+	addEntry := func(problem, severity string, i int, format string, args ...any) {
+		if !res.PathEntries[i].IsIgnored(problem) {
+			add(severity, i, format, args...)
+			return
+		}
+		ignored = append(ignored, Finding{Severity: severity, Entry: i, Message: fmt.Sprintf(format, args...), Ignored: true})
+	}
+
+	for i, e := range res.PathEntries {
+		switch {
+		case e.Value == "":
+			addEntry(model.IgnoreRelative, SeverityError, i, "empty segment; %s searches the current directory here", res.VariableName())
+		case model.IsRelativePath(e.Value):
+			addEntry(model.IgnoreRelative, SeverityError, i, "relative segment; resolved against the current directory")
+		case res.DuplicatePolicy.IsDuplicate(e):
+			severity := SeverityWarning
+			switch res.DuplicatePolicy.Severity {
+			case model.DuplicatesError:
+				severity = SeverityError
+			case model.DuplicatesHarmless:
+				severity = SeverityInfo
+			}
+			addEntry(model.IgnoreDuplicate, severity, i, "duplicate of #%d", duplicateTarget(e)+1)
+		case e.SymlinkBroken != "":
+			addEntry(model.IgnoreBroken, SeverityWarning, i, "broken symlink: %s", e.SymlinkBroken)
+		case isMissing(e.Value):
+			addEntry(model.IgnoreMissing, SeverityWarning, i, "does not exist")
+		default:
+			if why := unsafeOwnership(model.ExpandTilde(e.Value)); why != "" {
+				addEntry(model.IgnoreUnsafe, SeverityError, i, "%s, so they can add commands that run instead of yours", why)
+			}
+		}
+		if e.IsSessionOnly && e.Value != "" {
+			addEntry(model.IgnoreSession, SeverityInfo, i, "added in this session, not by a startup file")
+		}
+	}
+
+	for _, p := range res.BrokenPins() {
+		add(SeverityError, -1, "pin on line %d: %s", p.Line, p.Broken)
+	}
+	for _, d := range res.Diagnostics {
+		if msg, ok := strings.CutPrefix(d, "WARNING: "); ok && !strings.HasPrefix(msg, "Pinned ") {
+			add(SeverityWarning, -1, "%s", msg)
+		}
+	}
+	for _, f := range res.PluginFindings {
+		add(f.Severity, f.Entry, "%s: %s", f.Plugin, f.Message)
+	}
+
+	// Worst first, keeping PATH order within a severity
+	var sorted []Finding
+	for _, severity := range []string{SeverityError, SeverityWarning, SeverityInfo} {
+		for _, f := range findings {
+			if f.Severity == severity {
+				sorted = append(sorted, f)
+			}
+		}
+	}
+	return append(sorted, ignored...)
+}
+
+// duplicateTarget is the entry e duplicates, or the one its symlink
+// resolves to.
+func duplicateTarget(e model.PathEntry) int {
+	if e.IsDuplicate {
+		return e.DuplicateOf
+	}
+	return e.SymlinkPointsTo
+}
@@ -0,0 +1,37 @@
+package trace
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// CleanValue returns the analyzed variable's value without duplicates
+// (symlinks to another entry count unless the policy ignores them), missing
+// directories, broken symlinks and empty segments, keeping the first copy of everything in
+// priority order. removed explains each dropped entry.
+func CleanValue(res model.AnalysisResult) (value string, removed []string) {
+	var kept []string
+	for i, e := range res.PathEntries {
+		switch {
+		case e.Value == "":
+			removed = append(removed, fmt.Sprintf("#%d empty segment (searches the current directory)", i+1))
+		case res.DuplicatePolicy.IsDuplicate(e):
+			removed = append(removed, fmt.Sprintf("#%d %s (duplicate)", i+1, e.Value))
+		case e.SymlinkBroken != "":
+			removed = append(removed, fmt.Sprintf("#%d %s (broken symlink)", i+1, e.Value))
+		case isMissing(e.Value):
+			removed = append(removed, fmt.Sprintf("#%d %s (missing)", i+1, e.Value))
+		default:
+			kept = append(kept, e.Value)
+		}
+	}
+	return strings.Join(kept, string(os.PathListSeparator)), removed
+}
+
+// ExportLine formats an `export VAR='value'` line for POSIX shells.
+func ExportLine(variable, value string) string {
+	return fmt.Sprintf("export %s='%s'", variable, strings.ReplaceAll(value, "'", `'\''`))
+}
@@ -0,0 +1,115 @@
+package trace
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// --color modes.
+const (
+	ColorAuto   = "auto"   // Color when writing to a terminal and NO_COLOR is not set
+	ColorAlways = "always" // Color even into pipes and files
+	ColorNever  = "never"
+)
+
+// ANSI escape sequences ColorizeReport uses. Basic colors follow the
+// terminal's own palette, so they suit light and dark backgrounds.
+const (
+	ansiReset   = "\x1b[0m"
+	ansiBold    = "\x1b[1m"
+	ansiDim     = "\x1b[2m"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiYellow  = "\x1b[33m"
+	ansiMagenta = "\x1b[35m"
+	ansiCyan    = "\x1b[36m"
+)
+
+// ParseColorMode validates a --color value.
+func ParseColorMode(s string) (string, error) {
+	switch s {
+	case "", ColorAuto:
+		return ColorAuto, nil
+	case ColorAlways, ColorNever:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown --color %q (use auto, always or never)", s)
+}
+
+// ColorEnabled reports whether output to f is colored under mode. Under
+// auto that needs a terminal other than TERM=dumb, and no NO_COLOR
+// (https://no-color.org); --color=always overrides NO_COLOR.
+func ColorEnabled(mode string, f *os.File) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// iconColors are the colors of the status icons, as in the TUI.
+var iconColors = map[string]string{
+	model.IconMissing:   ansiRed,
+	model.IconDuplicate: ansiYellow,
+	model.IconSession:   ansiCyan,
+	model.IconShadow:    ansiMagenta,
+}
+
+// Report lines ColorizeReport recognizes.
+var (
+	severityPattern  = regexp.MustCompile(`\b(ERROR|WARNING|INFO):`)
+	relativePattern  = regexp.MustCompile(`^(\s*\d+\. )!`)
+	problemPattern   = regexp.MustCompile(`\((missing|broken symlink)\)|\[(NOT )?SERIOUS[^]]*\]`)
+	underlinePattern = regexp.MustCompile(`^(-+|=+)$`)
+)
+
+// ColorizeReport adds ANSI colors to a GenerateReport report: bold
+// headings, severities and problem icons colored as in the TUI (missing
+// red, duplicates yellow, session cyan, shadowing magenta), and INFO
+// diagnostics dimmed.
+func ColorizeReport(report string) string {
+	lines := strings.Split(report, "\n")
+	for i, line := range lines {
+		switch {
+		case underlinePattern.MatchString(line):
+			lines[i] = ansiDim + line + ansiReset
+			continue
+		case i+1 < len(lines) && line != "" && underlinePattern.MatchString(lines[i+1]):
+			lines[i] = ansiBold + line + ansiReset
+			continue
+		case strings.HasPrefix(line, "• INFO:"):
+			lines[i] = ansiDim + line + ansiReset
+			continue
+		}
+		line = severityPattern.ReplaceAllStringFunc(line, func(s string) string {
+			color := map[string]string{"ERROR:": ansiRed, "WARNING:": ansiYellow, "INFO:": ansiCyan}[s]
+			return ansiBold + color + s + ansiReset
+		})
+		line = problemPattern.ReplaceAllStringFunc(line, func(s string) string {
+			color := ansiRed
+			if strings.HasPrefix(s, "[NOT ") {
+				color = ansiYellow
+			}
+			return color + s + ansiReset
+		})
+		line = relativePattern.ReplaceAllString(line, "${1}"+ansiRed+"!"+ansiReset)
+		for icon, color := range iconColors {
+			line = strings.ReplaceAll(line, icon, color+icon+ansiReset)
+		}
+		if line == "No global issues detected." {
+			line = ansiGreen + line + ansiReset
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,125 @@
+package trace
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// compatRule is a shell construct that older versions of a shell reject or
+// misread, so the line it is on silently does nothing there.
+type compatRule struct {
+	Shell   string // "bash" or "zsh"
+	Major   int    // First version that supports it
+	Minor   int
+	Pattern *regexp.Regexp
+	What    string
+}
+
+// compatRules cover constructs that turn up in startup files. bash 3.2 is
+// what macOS still ships as /bin/bash.
+var compatRules = []compatRule{
+	{"bash", 4, 0, regexp.MustCompile(`\b(declare|typeset|local)\s+-[a-zA-Z]*A`), "associative arrays (declare -A)"},
+	{"bash", 4, 0, regexp.MustCompile(`\$\{[A-Za-z_][A-Za-z0-9_]*(\[[^]]*\])?(,,?|\^\^?)\}`), "case conversion (${var,,} / ${var^^})"},
+	{"bash", 4, 0, regexp.MustCompile(`(^|[\s;|&(])(mapfile|readarray)\s`), "mapfile/readarray"},
+	{"bash", 4, 0, regexp.MustCompile(`&>>`), "&>> redirection"},
+	{"bash", 4, 0, regexp.MustCompile(`\|&`), "|& pipes"},
+	{"bash", 4, 0, regexp.MustCompile(`shopt\s+-s\s+.*\b(globstar|autocd|dirspell|checkjobs)\b`), "shopt options added in bash 4"},
+	{"bash", 4, 0, regexp.MustCompile(`(^|[\s;])coproc\s`), "coproc"},
+	{"bash", 4, 2, regexp.MustCompile(`\b(declare|typeset)\s+-[a-zA-Z]*g`), "declare -g"},
+	{"bash", 4, 3, regexp.MustCompile(`\b(declare|typeset|local)\s+-[a-zA-Z]*n\s`), "namerefs (declare -n)"},
+	{"bash", 4, 3, regexp.MustCompile(`\$\{[A-Za-z_][A-Za-z0-9_]*\[-[0-9]+\]\}`), "negative array indices (${arr[-1]})"},
+	{"bash", 4, 3, regexp.MustCompile(`\bwait\s+-n\b`), "wait -n"},
+	{"bash", 4, 4, regexp.MustCompile(`\$\{[A-Za-z_][A-Za-z0-9_]*(\[[^]]*\])?@[QEPAa]\}`), "${var@...} transformations"},
+	{"bash", 5, 0, regexp.MustCompile(`\$\{?(EPOCHSECONDS|EPOCHREALTIME|BASH_ARGV0)\b`), "$EPOCHSECONDS / $EPOCHREALTIME / $BASH_ARGV0"},
+	{"zsh", 5, 1, regexp.MustCompile(`\$\{[A-Za-z_][A-Za-z0-9_]*:\|`), "${name:|array} set difference"},
+	{"zsh", 5, 10, regexp.MustCompile(`\$\{\|`), "${|...} value substitution"},
+	{"zsh", 5, 10, regexp.MustCompile(`\btypeset\s+-[a-zA-Z]*n\s`), "namerefs (typeset -n)"},
+}
+
+// shellVersionRe finds the version number in `shell --version` output:
+// "GNU bash, version 3.2.57(1)-release", "zsh 5.9 (x86_64-apple-darwin23.0)".
+var shellVersionRe = regexp.MustCompile(`^(?:GNU )?(bash|zsh)[ ,]+(?:version )?(\d+)\.(\d+)`)
+
+// parseShellVersion extracts the shell name and version from a
+// ShellVersion string.
+func parseShellVersion(v string) (shell string, major, minor int, ok bool) {
+	m := shellVersionRe.FindStringSubmatch(v)
+	if m == nil {
+		return "", 0, 0, false
+	}
+	major, _ = strconv.Atoi(m[2])
+	minor, _ = strconv.Atoi(m[3])
+	return m[1], major, minor, true
+}
+
+// CheckShellCompat flags lines in the traced startup files that use
+// constructs newer than the traced shell, which make those lines fail, often
+// silently taking a PATH export with them. It uses res.Environment for the
+// shell version and adds warnings to the diagnostics of res and of entries
+// added by a flagged line.
+func CheckShellCompat(res *model.AnalysisResult) {
+	shell, major, minor, ok := parseShellVersion(res.Environment.ShellVersion)
+	if !ok {
+		return
+	}
+	other, otherChecked := "", false
+
+	seen := make(map[string]bool)
+	for _, n := range res.FlowNodes {
+		if n.NotExecuted || seen[n.FilePath] || !filepath.IsAbs(model.ExpandTilde(n.FilePath)) {
+			continue
+		}
+		seen[n.FilePath] = true
+		f, err := os.Open(model.ExpandTilde(n.FilePath))
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+			for _, r := range compatRules {
+				if r.Shell != shell || major > r.Major || (major == r.Major && minor >= r.Minor) || !r.Pattern.MatchString(text) {
+					continue
+				}
+				if !otherChecked {
+					other, otherChecked = otherInstalledVersion(res.Environment.Shell, shell, major, minor), true
+				}
+				note := fmt.Sprintf("uses %s, which needs %s %d.%d; the traced shell is %s %d.%d, so the line fails", r.What, shell, r.Major, r.Minor, shell, major, minor)
+				res.Diagnostics = append(res.Diagnostics, fmt.Sprintf("WARNING: %s:%d %s%s.", n.FilePath, line, note, other))
+				for i := range res.PathEntries {
+					if e := &res.PathEntries[i]; e.SourceFile == n.FilePath && e.LineNumber == line {
+						e.Diagnostics = append(e.Diagnostics, "This line "+note+".")
+					}
+				}
+				break // One warning per line is enough
+			}
+		}
+		f.Close()
+	}
+}
+
+// otherInstalledVersion notes when the shell found in PATH is a different
+// version from the traced one (e.g. Homebrew's bash 5 vs /bin/bash 3.2),
+// since that is usually the version the line was written for.
+func otherInstalledVersion(tracedPath, shell string, major, minor int) string {
+	path, err := exec.LookPath(shell)
+	if err != nil || path == tracedPath {
+		return ""
+	}
+	name, maj, min, ok := parseShellVersion(ShellVersion(path))
+	if !ok || name != shell || (maj == major && min == minor) {
+		return ""
+	}
+	return fmt.Sprintf(" (%s in PATH is %d.%d; make it your login shell, or avoid the construct)", path, maj, min)
+}
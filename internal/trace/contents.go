@@ -0,0 +1,206 @@
+package trace
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// Kinds of file in a PATH directory, as classified by ClassifyFile.
+const (
+	KindBinary    = "binary"         // Compiled executable (ELF, Mach-O, PE)
+	KindScript    = "script"         // Executable starting with #!
+	KindSymlink   = "symlink"        // Symlink to an executable elsewhere (shims, linked bins)
+	KindOtherExec = "executable"     // Executable of unknown format
+	KindNonExec   = "non-executable" // Data, docs, broken links; never run from PATH
+	KindDir       = "directory"
+)
+
+// binaryMagics are the leading bytes of compiled executables.
+var binaryMagics = [][]byte{
+	{0x7f, 'E', 'L', 'F'},    // ELF (Linux, BSD)
+	{0xcf, 0xfa, 0xed, 0xfe}, // Mach-O 64-bit
+	{0xce, 0xfa, 0xed, 0xfe}, // Mach-O 32-bit
+	{0xca, 0xfe, 0xba, 0xbe}, // Mach-O universal
+	{'M', 'Z'},               // PE (Windows)
+}
+
+// windowsExecExts stand in for the executable bit on Windows.
+var windowsExecExts = map[string]bool{".exe": true, ".com": true, ".bat": true, ".cmd": true, ".ps1": true}
+
+// ClassifyFile returns the kind of d (in dir) and, for scripts, the
+// interpreter named by its #! line (e.g. "python3").
+func ClassifyFile(dir string, d fs.DirEntry) (kind, interpreter string) {
+	kind, interpreter, _ = classifyFile(dir, d)
+	return kind, interpreter
+}
+
+// classifyFile is ClassifyFile, also returning the file's info with
+// symlinks followed (nil if it cannot be read).
+func classifyFile(dir string, d fs.DirEntry) (kind, interpreter string, info fs.FileInfo) {
+	full := filepath.Join(model.ExpandTilde(dir), d.Name())
+	info, err := os.Stat(full) // Follows symlinks
+	if err != nil {
+		return KindNonExec, "", nil // Broken symlink or unreadable
+	}
+	if info.IsDir() {
+		return KindDir, "", info
+	}
+	executable := info.Mode().Perm()&0111 != 0
+	if runtime.GOOS == "windows" {
+		executable = windowsExecExts[strings.ToLower(filepath.Ext(d.Name()))]
+	}
+	if !executable {
+		return KindNonExec, "", info
+	}
+	if d.Type()&fs.ModeSymlink != 0 {
+		return KindSymlink, "", info
+	}
+
+	head := make([]byte, 128)
+	f, err := os.Open(full)
+	if err != nil {
+		return KindOtherExec, "", info
+	}
+	n, _ := f.Read(head)
+	f.Close()
+	head = head[:n]
+	for _, magic := range binaryMagics {
+		if bytes.HasPrefix(head, magic) {
+			return KindBinary, "", info
+		}
+	}
+	if bytes.HasPrefix(head, []byte("#!")) {
+		return KindScript, shebangInterpreter(string(head[2:])), info
+	}
+	if runtime.GOOS == "windows" && !strings.EqualFold(filepath.Ext(d.Name()), ".exe") && !strings.EqualFold(filepath.Ext(d.Name()), ".com") {
+		return KindScript, strings.TrimPrefix(strings.ToLower(filepath.Ext(d.Name())), "."), info
+	}
+	return KindOtherExec, "", info
+}
+
+// shebangInterpreter extracts the interpreter from the rest of a #! line,
+// looking through env: "/usr/bin/env python3 -u" gives "python3".
+func shebangInterpreter(line string) string {
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+	name := filepath.Base(fields[0])
+	if name == "env" {
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") && !strings.Contains(f, "=") {
+				return filepath.Base(f)
+			}
+		}
+	}
+	return name
+}
+
+// DirContents counts the kinds of file in a PATH directory.
+type DirContents struct {
+	Binaries     int
+	Scripts      int
+	Symlinks     int
+	OtherExecs   int
+	NonExecs     int
+	Dirs         int
+	Interpreters map[string]int // Script count per interpreter
+}
+
+// Add counts one file of the given kind.
+func (c *DirContents) Add(kind, interpreter string) {
+	switch kind {
+	case KindBinary:
+		c.Binaries++
+	case KindScript:
+		c.Scripts++
+		if interpreter != "" {
+			if c.Interpreters == nil {
+				c.Interpreters = make(map[string]int)
+			}
+			c.Interpreters[interpreter]++
+		}
+	case KindSymlink:
+		c.Symlinks++
+	case KindOtherExec:
+		c.OtherExecs++
+	case KindNonExec:
+		c.NonExecs++
+	case KindDir:
+		c.Dirs++
+	}
+}
+
+// Executables is the number of files PATH lookups can run.
+func (c DirContents) Executables() int {
+	return c.Binaries + c.Scripts + c.Symlinks + c.OtherExecs
+}
+
+// Summary lists the non-zero counts, e.g. "12 compiled binaries, 3 scripts
+// (python3 2, sh 1), 40 symlinked executables".
+func (c DirContents) Summary() string {
+	var parts []string
+	add := func(n int, one, many string) {
+		if n == 1 {
+			parts = append(parts, "1 "+one)
+		} else if n > 1 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, many))
+		}
+	}
+	add(c.Binaries, "compiled binary", "compiled binaries")
+	add(c.Scripts, "script", "scripts")
+	if len(c.Interpreters) > 0 {
+		names := make([]string, 0, len(c.Interpreters))
+		for name := range c.Interpreters {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			if c.Interpreters[names[i]] != c.Interpreters[names[j]] {
+				return c.Interpreters[names[i]] > c.Interpreters[names[j]]
+			}
+			return names[i] < names[j]
+		})
+		var counts []string
+		for _, name := range names {
+			counts = append(counts, fmt.Sprintf("%s %d", name, c.Interpreters[name]))
+		}
+		parts[len(parts)-1] += " (" + strings.Join(counts, ", ") + ")"
+	}
+	add(c.Symlinks, "symlinked executable", "symlinked executables")
+	add(c.OtherExecs, "other executable", "other executables")
+	add(c.NonExecs, "non-executable", "non-executables")
+	add(c.Dirs, "subdirectory", "subdirectories")
+	if len(parts) == 0 {
+		return "empty"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Verdict is a one-line guess at what kind of directory this is.
+func (c DirContents) Verdict() string {
+	total := c.Executables()
+	switch {
+	case total == 0 && c.NonExecs+c.Dirs == 0:
+		return "Empty directory: adds nothing to PATH"
+	case total == 0:
+		return "No executables: this entry adds nothing to PATH"
+	case c.Binaries*10 >= total*6:
+		return "Mostly compiled binaries: a system or package install directory"
+	case c.Symlinks*10 >= total*6:
+		return "Mostly symlinks: a package manager's linked bin (e.g. Homebrew, npm) or version manager shims"
+	case c.Scripts*10 >= total*6:
+		return "Mostly scripts: personal scripts or a tool's wrappers/shims"
+	}
+	return "Mixed contents"
+}
@@ -0,0 +1,287 @@
+package trace
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// LaunchContext is a way of starting a shell, such as a terminal app. It
+// decides whether the shell is a login shell, and sets variables that
+// startup files often test (e.g. TERM_PROGRAM, TMUX).
+type LaunchContext struct {
+	Name        string
+	Login       bool
+	Interactive bool
+	Env         []string
+}
+
+// Mode describes the shell mode, e.g. "login, interactive".
+func (c LaunchContext) Mode() string {
+	var parts []string
+	if c.Login {
+		parts = append(parts, "login")
+	} else {
+		parts = append(parts, "non-login")
+	}
+	if c.Interactive {
+		parts = append(parts, "interactive")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// contextEnvPrefixes are removed from the environment before a context's
+// own variables are added, so the terminal lspath runs in doesn't leak in.
+var contextEnvPrefixes = []string{"TERM_PROGRAM=", "TERM_PROGRAM_VERSION=", "LC_TERMINAL=", "LC_TERMINAL_VERSION=", "ITERM_", "TMUX=", "TMUX_PANE=", "VSCODE_", "SSH_CONNECTION=", "SSH_CLIENT=", "SSH_TTY="}
+
+// appInstalled reports whether any of the given application bundles exist.
+func appInstalled(paths ...string) bool {
+	for _, p := range paths {
+		if _, err := os.Stat(p); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// commandInstalled reports whether name is found in PATH.
+func commandInstalled(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// LaunchContexts returns the common ways of starting a shell that are
+// available on this machine, with the shell mode each uses by default.
+func LaunchContexts() []LaunchContext {
+	var contexts []LaunchContext
+	if runtime.GOOS == "darwin" {
+		if appInstalled("/System/Applications/Utilities/Terminal.app", "/Applications/Utilities/Terminal.app") {
+			contexts = append(contexts, LaunchContext{Name: "Terminal.app", Login: true, Interactive: true,
+				Env: []string{"TERM_PROGRAM=Apple_Terminal"}})
+		}
+		if appInstalled("/Applications/iTerm.app") {
+			iterm := []string{"TERM_PROGRAM=iTerm.app", "LC_TERMINAL=iTerm2"}
+			contexts = append(contexts,
+				LaunchContext{Name: "iTerm2 (login shell)", Login: true, Interactive: true, Env: iterm},
+				LaunchContext{Name: "iTerm2 (command, non-login)", Interactive: true, Env: iterm})
+		}
+		if appInstalled("/Applications/Visual Studio Code.app") || commandInstalled("code") {
+			// The default macOS terminal profiles pass -l
+			contexts = append(contexts, LaunchContext{Name: "VS Code terminal", Login: true, Interactive: true,
+				Env: []string{"TERM_PROGRAM=vscode"}})
+		}
+	} else {
+		// GNOME Terminal, Konsole and most Linux terminals start non-login shells
+		contexts = append(contexts, LaunchContext{Name: "Desktop terminal", Interactive: true})
+		if commandInstalled("code") {
+			contexts = append(contexts, LaunchContext{Name: "VS Code terminal", Interactive: true,
+				Env: []string{"TERM_PROGRAM=vscode"}})
+		}
+	}
+	if commandInstalled("tmux") {
+		// tmux starts its default-shell as a login shell
+		contexts = append(contexts, LaunchContext{Name: "tmux", Login: true, Interactive: true,
+			Env: []string{"TERM_PROGRAM=tmux", "TMUX=/tmp/tmux-lspath/default,0,0"}})
+	}
+	contexts = append(contexts, LaunchContext{Name: "SSH login", Login: true, Interactive: true,
+		Env: []string{"SSH_CONNECTION=127.0.0.1 22 127.0.0.1 22"}})
+	return contexts
+}
+
+// ContextResult is the traced startup of one launch context.
+type ContextResult struct {
+	Context LaunchContext
+	Result  model.AnalysisResult
+	Err     error
+}
+
+// RunContextMatrix traces the user's shell once for each context. Results
+// are trace-only, like Trace Mode: what a new window would start with.
+func RunContextMatrix(opts Options, contexts []LaunchContext) []ContextResult {
+	progress := opts.Progress
+	if progress == nil {
+		progress = func(string) {}
+	}
+	variable := opts.Var
+	if variable == "" {
+		variable = DefaultVariable
+	}
+
+	shell := DetectShell(os.Getenv("SHELL"))
+	environment := CollectEnvironment(os.Getenv("SHELL"))
+	var results []ContextResult
+	for _, c := range contexts {
+		progress(fmt.Sprintf("Tracing %s startup as %s…", shell.Name(), c.Name))
+		command := shell.GetTraceCommand()
+		if ms, ok := shell.(modeShell); ok {
+			command = ms.TraceCommandFor(c.Login, c.Interactive)
+		}
+		cmd := traceCommand(shell, opts.Sandbox.limitCommand(command), variable, opts.initialValue(variable), opts.baseline())
+		var env []string
+		for _, e := range cmd.Env {
+			leaked := false
+			for _, prefix := range contextEnvPrefixes {
+				if strings.HasPrefix(e, prefix) {
+					leaked = true
+					break
+				}
+			}
+			if !leaked {
+				env = append(env, e)
+			}
+		}
+		cmd.Env = append(env, c.Env...)
+
+		cr := ContextResult{Context: c}
+		ctx, cancel := opts.traceContext()
+		stderr, err := startSandboxedTrace(ctx, cmd, opts.Sandbox)
+		if err != nil {
+			cancel()
+			cr.Err = err
+			results = append(results, cr)
+			continue
+		}
+		events, stats, _ := collectEvents(shell, variable, opts.baseline(), stderr, nil)
+		timedOut := ctx.Err() != nil
+		waitErr := stderr.Wait()
+		stderr.Close()
+		cancel()
+
+		analyzer := NewAnalyzer()
+		analyzer.Variable = variable
+		analyzer.Canon = opts.canon()
+		analyzer.DisabledHeuristics = opts.DisabledHeuristics
+		analyzer.Baseline = opts.baseline()
+		cr.Result = analyzer.Analyze(events, opts.initialValue(variable))
+		cr.Result.Variable = variable
+		cr.Result.Parser = stats
+		cr.Result.DuplicatePolicy = opts.Duplicates
+		cr.Result.Environment = environment
+		cr.Result.Diagnostics = append(cr.Result.Diagnostics, exitDiagnostics(shell, opts, events, stats, timedOut, waitErr)...)
+		results = append(results, cr)
+	}
+	return results
+}
+
+// contextColumn labels context i as A, B, C...
+func contextColumn(i int) string {
+	if i < 26 {
+		return string(rune('A' + i))
+	}
+	return fmt.Sprintf("%d", i+1)
+}
+
+// FormatContextMatrix renders one row per entry seen in any context, with
+// the entry's position in each context's value ("-" if absent), followed by
+// which contexts agree.
+func FormatContextMatrix(results []ContextResult) string {
+	var sb strings.Builder
+	name := DefaultVariable
+	for _, r := range results {
+		if r.Err == nil {
+			name = r.Result.VariableName()
+			break
+		}
+	}
+	title := "LAUNCH CONTEXT MATRIX - " + name
+	sb.WriteString(title + "\n" + strings.Repeat("=", len(title)) + "\n\n")
+
+	// Legend
+	for i, r := range results {
+		status := r.Context.Mode()
+		if r.Err != nil {
+			status += fmt.Sprintf(" (trace failed: %v)", r.Err)
+		}
+		sb.WriteString(fmt.Sprintf("%-3s %-28s %s\n", contextColumn(i), r.Context.Name, status))
+	}
+	sb.WriteString("\nNumbers are each entry's position in that context; \"-\" means absent.\n\n")
+
+	// Union of entries in order of first appearance; position of first copy
+	var rows []string
+	display := make(map[string]string)
+	positions := make([]map[string]int, len(results))
+	for i, r := range results {
+		positions[i] = make(map[string]int)
+		for j, e := range r.Result.PathEntries {
+			key := model.CanonicalPath(e.Value, model.CanonOptions{})
+			if _, ok := positions[i][key]; ok {
+				continue
+			}
+			positions[i][key] = j + 1
+			if _, ok := display[key]; !ok {
+				display[key] = model.DisplayPath(e.Value)
+				rows = append(rows, key)
+			}
+		}
+	}
+
+	for i := range results {
+		sb.WriteString(fmt.Sprintf("%4s", contextColumn(i)))
+	}
+	sb.WriteString("  Entry\n")
+	for _, key := range rows {
+		for i := range results {
+			cell := "-"
+			if pos, ok := positions[i][key]; ok {
+				cell = fmt.Sprintf("%d", pos)
+			}
+			sb.WriteString(fmt.Sprintf("%4s", cell))
+		}
+		sb.WriteString("  " + display[key] + "\n")
+	}
+
+	// Group contexts with identical values
+	sb.WriteString("\nSUMMARY\n-------\n")
+	groups := make(map[string][]int)
+	var order []string
+	for i, r := range results {
+		if r.Err != nil {
+			continue
+		}
+		var keys []string
+		for _, e := range r.Result.PathEntries {
+			keys = append(keys, model.CanonicalPath(e.Value, model.CanonOptions{}))
+		}
+		sig := strings.Join(keys, "\x00")
+		if _, ok := groups[sig]; !ok {
+			order = append(order, sig)
+		}
+		groups[sig] = append(groups[sig], i)
+	}
+	if len(order) == 1 {
+		sb.WriteString(fmt.Sprintf("Every context gets the same %s.\n", name))
+		return sb.String()
+	}
+	for _, sig := range order {
+		var cols []string
+		for _, i := range groups[sig] {
+			cols = append(cols, contextColumn(i))
+		}
+		first := results[groups[sig][0]]
+		line := fmt.Sprintf("%s: %d entries", strings.Join(cols, ", "), len(first.Result.PathEntries))
+		if sig != order[0] {
+			base := results[groups[order[0]][0]]
+			d := DiffResults(base.Result, first.Result)
+			var notes []string
+			if len(d.Added) > 0 {
+				notes = append(notes, fmt.Sprintf("%d extra", len(d.Added)))
+			}
+			if len(d.Removed) > 0 {
+				notes = append(notes, fmt.Sprintf("%d missing", len(d.Removed)))
+			}
+			if len(d.Reordered) > 0 {
+				notes = append(notes, fmt.Sprintf("%d in a different order", len(d.Reordered)))
+			}
+			if len(notes) > 0 {
+				line += fmt.Sprintf(" (vs %s: %s)", contextColumn(groups[order[0]][0]), strings.Join(notes, ", "))
+			}
+		}
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString("\nIn zsh and bash, login shells read the profile files (.zprofile, .bash_profile); non-login shells read only the rc files.\n")
+	return sb.String()
+}
@@ -0,0 +1,193 @@
+package trace
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// functionPattern finds function definitions in startup files: "name() {",
+// "function name {" (bash, zsh, ksh) and "function name" (fish).
+var functionPattern = regexp.MustCompile(`^\s*(?:function\s+([A-Za-z0-9_.:+-]+)|([A-Za-z0-9_.:+-]+)\s*\(\s*\))`)
+
+// shellBuiltins are builtins (and reserved words) that share their name with
+// executables commonly found on PATH, by shell. Shells not listed have the
+// sh set.
+var shellBuiltins = map[string][]string{
+	"sh":   {"[", "cd", "command", "echo", "false", "getopts", "hash", "kill", "printf", "pwd", "read", "test", "true", "type", "ulimit", "umask", "wait"},
+	"bash": {"[", "cd", "command", "echo", "false", "getopts", "hash", "kill", "printf", "pwd", "read", "test", "time", "true", "type", "ulimit", "umask", "wait"},
+	"zsh":  {"[", "cd", "command", "echo", "false", "getopts", "hash", "kill", "printf", "pwd", "read", "test", "time", "true", "type", "ulimit", "umask", "wait", "where", "whence", "which"},
+	"ksh":  {"[", "cd", "command", "echo", "false", "getopts", "hash", "kill", "print", "printf", "pwd", "read", "test", "time", "true", "ulimit", "umask", "wait", "whence"},
+	"fish": {"[", "cd", "command", "echo", "false", "printf", "pwd", "read", "realpath", "test", "time", "true", "type", "ulimit", "wait"},
+	"csh":  {"cd", "echo", "kill", "nice", "nohup", "printenv", "time", "umask", "wait", "where", "which"},
+	"pwsh": nil,
+}
+
+// DetectDefinitions finds the aliases the traced commands define, and the
+// functions defined in the startup files they ran. It is best effort:
+// xtrace does not show function definitions, so a function inside a branch
+// that did not run is still reported. fish aliases are functions, so its
+// alias command is reported as one.
+func DetectDefinitions(shell Shell, events []model.TraceEvent) []model.Definition {
+	var defs []model.Definition
+	scanned := make(map[string]bool)
+	for _, ev := range events {
+		if ev.File != "" && !scanned[ev.File] {
+			scanned[ev.File] = true
+			defs = append(defs, fileFunctions(ev.File)...)
+		}
+		fields := fishFields(ev.RawCommand)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "alias":
+			defs = append(defs, aliasDefinitions(shell, ev, fields[1:])...)
+		case "unalias":
+			for _, name := range fields[1:] {
+				defs = removeDefinition(defs, name, model.DefinitionAlias)
+			}
+		}
+	}
+	return defs
+}
+
+// aliasDefinitions parses the arguments of a traced alias command: name=body
+// pairs, or in fish and csh a name and its body.
+func aliasDefinitions(shell Shell, ev model.TraceEvent, args []string) []model.Definition {
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		args = args[1:] // e.g. "alias -- ll=ls -l"
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	def := model.Definition{Kind: model.DefinitionAlias, File: ev.File, Line: ev.Line}
+	if !strings.Contains(args[0], "=") {
+		switch shell.(type) {
+		case *FishShell, *CshShell:
+			if len(args) < 2 {
+				return nil // Listing one alias, not defining it
+			}
+			def.Name, def.Body = args[0], strings.Join(args[1:], " ")
+			if _, ok := shell.(*FishShell); ok {
+				def.Kind = model.DefinitionFunction
+			}
+			return []model.Definition{def}
+		}
+		return nil
+	}
+	var defs []model.Definition
+	for _, arg := range args {
+		name, body, ok := strings.Cut(arg, "=")
+		if !ok || name == "" {
+			continue // e.g. "alias -g", or listing an alias
+		}
+		def.Name, def.Body = name, body
+		if _, isFish := shell.(*FishShell); isFish {
+			def.Kind = model.DefinitionFunction
+		}
+		defs = append(defs, def)
+	}
+	return defs
+}
+
+// fileFunctions returns the functions defined in file.
+func fileFunctions(file string) []model.Definition {
+	var defs []model.Definition
+	for i, line := range readLines(file) {
+		m := functionPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		name := m[1] + m[2]
+		if strings.HasPrefix(name, "_") {
+			continue // Completion helpers, not commands anyone runs
+		}
+		defs = append(defs, model.Definition{Name: name, Kind: model.DefinitionFunction, File: file, Line: i + 1})
+	}
+	return defs
+}
+
+// removeDefinition drops the definitions of name with the given kind.
+func removeDefinition(defs []model.Definition, name, kind string) []model.Definition {
+	kept := defs[:0]
+	for _, d := range defs {
+		if d.Name != name || d.Kind != kind {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
+
+// ShellOverride is what the traced shell runs for a command name before it
+// searches PATH: an alias, a function or a builtin.
+type ShellOverride struct {
+	Shell      string            // Shell that was traced, e.g. "zsh"
+	Kind       string            // One of the Resolve* constants: alias, function or builtin
+	Definition *model.Definition // The alias or function; nil for builtins
+}
+
+// FindOverride returns what the shell res traced runs for name instead of
+// searching PATH, if anything. Aliases win over functions, which win over
+// builtins; the last definition of a name is the one in effect.
+func FindOverride(res model.AnalysisResult, name string) (ShellOverride, bool) {
+	if res.VariableName() != DefaultVariable {
+		return ShellOverride{}, false
+	}
+	shell := DetectShell(res.Environment.Shell).Name()
+	for _, kind := range []string{model.DefinitionAlias, model.DefinitionFunction} {
+		for i := len(res.Definitions) - 1; i >= 0; i-- {
+			if d := res.Definitions[i]; d.Name == name && d.Kind == kind {
+				return ShellOverride{Shell: shell, Kind: kind, Definition: &d}, true
+			}
+		}
+	}
+	if res.Environment.Shell == "" {
+		return ShellOverride{}, false // Not traced (e.g. Windows), so no shell to ask
+	}
+	builtins, ok := shellBuiltins[shell]
+	if !ok {
+		builtins = shellBuiltins[builtinFamily(shell)]
+	}
+	for _, b := range builtins {
+		if b == name {
+			return ShellOverride{Shell: shell, Kind: ResolveBuiltin}, true
+		}
+	}
+	return ShellOverride{}, false
+}
+
+// builtinFamily maps a shell to the shellBuiltins set it shares.
+func builtinFamily(shell string) string {
+	switch shell {
+	case "ksh93", "mksh", "oksh":
+		return "ksh"
+	case "csh", "tcsh":
+		return "csh"
+	}
+	return "sh"
+}
+
+// FormatOverride describes o for which output, e.g. "zsh runs the alias
+// ll='ls -l' (~/.zshrc:12) before searching PATH".
+func FormatOverride(name string, o ShellOverride) string {
+	what := fmt.Sprintf("its builtin %s", name)
+	if d := o.Definition; d != nil {
+		what = fmt.Sprintf("the %s %s", d.Kind, name)
+		if d.Body != "" {
+			what = fmt.Sprintf("the %s %s='%s'", d.Kind, name, d.Body)
+		}
+		what += fmt.Sprintf(" (%s:%d)", d.File, d.Line)
+	}
+	return fmt.Sprintf("%s runs %s before searching PATH", o.Shell, what)
+}
+
+// overrideBypass says how to run the executable name despite o.
+func overrideBypass(name string, o ShellOverride) string {
+	if builtinFamily(o.Shell) == "csh" {
+		return "use a full path to run the executable" // csh has no command builtin
+	}
+	return fmt.Sprintf("use `command %s` or a full path to run the executable", name)
+}
@@ -0,0 +1,173 @@
+package trace
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"lspath/internal/model"
+)
+
+// statWorkers bounds how many files are stat'ed and classified at once per
+// directory. The work is mostly waiting on the filesystem, so it pays to
+// have more in flight than there are CPUs.
+const statWorkers = 16
+
+// dirWorkers bounds how many directories StatDirs reads at once.
+const dirWorkers = 4
+
+// FileStat is one file in a PATH directory.
+type FileStat struct {
+	Entry       fs.DirEntry
+	Info        fs.FileInfo // The entry itself; symlinks are not followed (nil if unreadable)
+	Kind        string      // One of the Kind* constants
+	Interpreter string      // For scripts, the #! interpreter
+	Size        int64       // Size of the file, following symlinks (0 for directories)
+}
+
+// DirStats describes the contents of a PATH directory.
+type DirStats struct {
+	Files       []FileStat // In name order
+	FileCount   int        // Everything that is not a directory, including broken links
+	DirCount    int        // Subdirectories, including links to directories
+	Executables int        // Files PATH lookups can run
+	Size        int64      // Total size of the files, following symlinks
+	Contents    DirContents
+}
+
+// Summary formats the counts for a report, e.g. "120 files, 2 dirs · 118
+// executables, 45.1 MB".
+func (s DirStats) Summary() string {
+	return fmt.Sprintf("%d files, %d dirs · %d executables, %s", s.FileCount, s.DirCount, s.Executables, FormatSize(s.Size))
+}
+
+// FormatSize formats a byte count, e.g. "512 B", "3.4 KB", "45.1 MB".
+func FormatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// dirStatsCache holds StatDir results by directory. An entry is reused while
+// the directory's modification time is unchanged, which is the case until a
+// file is added, removed or renamed in it.
+var dirStatsCache = struct {
+	sync.Mutex
+	m map[string]cachedDirStats
+}{m: make(map[string]cachedDirStats)}
+
+type cachedDirStats struct {
+	modTime time.Time
+	stats   DirStats
+}
+
+// StatDir lists, stats and classifies every file in dir, several at a time.
+// Results are cached until the directory changes. The returned Files must
+// not be modified.
+func StatDir(dir string) (DirStats, error) {
+	dir = model.ExpandTilde(dir)
+	info, err := os.Stat(dir)
+	if err != nil {
+		return DirStats{}, err
+	}
+	dirStatsCache.Lock()
+	cached, ok := dirStatsCache.m[dir]
+	dirStatsCache.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) {
+		return cached.stats, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return DirStats{}, err
+	}
+	files := make([]FileStat, len(entries))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(statWorkers, len(entries)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				files[i] = statFile(dir, entries[i])
+			}
+		}()
+	}
+	for i := range entries {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	stats := DirStats{Files: files}
+	for _, f := range files {
+		stats.Contents.Add(f.Kind, f.Interpreter)
+		if f.Kind == KindDir {
+			stats.DirCount++
+			continue
+		}
+		stats.FileCount++
+		stats.Size += f.Size
+	}
+	stats.Executables = stats.Contents.Executables()
+
+	dirStatsCache.Lock()
+	dirStatsCache.m[dir] = cachedDirStats{modTime: info.ModTime(), stats: stats}
+	dirStatsCache.Unlock()
+	return stats, nil
+}
+
+// statFile gathers what StatDir records about one file.
+func statFile(dir string, d fs.DirEntry) FileStat {
+	f := FileStat{Entry: d}
+	f.Info, _ = d.Info()
+	var target fs.FileInfo
+	f.Kind, f.Interpreter, target = classifyFile(dir, d)
+	if target != nil && !target.IsDir() {
+		f.Size = target.Size()
+	}
+	return f
+}
+
+// StatDirs runs StatDir for every entry, a few directories at a time.
+// Entries that are missing, unreadable or relative to the current directory
+// get nil. Duplicates share their original's stats.
+func StatDirs(entries []model.PathEntry) []*DirStats {
+	stats := make([]*DirStats, len(entries))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(dirWorkers, len(entries)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if s, err := StatDir(entries[i].Value); err == nil {
+					stats[i] = &s
+				}
+			}
+		}()
+	}
+	for i, e := range entries {
+		if model.IsRelativePath(e.Value) || (e.IsDuplicate && e.DuplicateOf < i) {
+			continue
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	for i, e := range entries {
+		if e.IsDuplicate && e.DuplicateOf < i {
+			stats[i] = stats[e.DuplicateOf]
+		}
+	}
+	return stats
+}
@@ -0,0 +1,73 @@
+package trace
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"runtime"
+	"strings"
+	"time"
+
+	"lspath/internal/model"
+)
+
+// CollectEnvironment describes this process's user and system, with
+// shellPath as the traced shell.
+func CollectEnvironment(shellPath string) model.TraceEnvironment {
+	env := model.TraceEnvironment{
+		Umask:        currentUmask(),
+		Shell:        shellPath,
+		ShellVersion: ShellVersion(shellPath),
+		OS:           runtime.GOOS + "/" + runtime.GOARCH,
+		OSRelease:    osRelease(),
+		Lspath:       model.Version,
+	}
+	if u, err := user.Current(); err == nil {
+		env.User = fmt.Sprintf("%s (uid %s)", u.Username, u.Uid)
+	}
+	return env
+}
+
+// ShellVersion returns the first line of `shell --version` (e.g. "zsh 5.9
+// (x86_64-apple-darwin23.0)"), or "" if the shell can't be run.
+func ShellVersion(shellPath string) string {
+	if shellPath == "" {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, shellPath, "--version").Output()
+	if err != nil {
+		return ""
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return strings.TrimSpace(line)
+}
+
+// osRelease names the OS release, e.g. "macOS 14.5" or "Ubuntu 24.04 LTS".
+func osRelease() string {
+	switch runtime.GOOS {
+	case "darwin":
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if out, err := exec.CommandContext(ctx, "sw_vers", "-productVersion").Output(); err == nil {
+			return "macOS " + strings.TrimSpace(string(out))
+		}
+	case "linux":
+		f, err := os.Open("/etc/os-release")
+		if err != nil {
+			return ""
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if v, ok := strings.CutPrefix(scanner.Text(), "PRETTY_NAME="); ok {
+				return strings.Trim(v, `"'`)
+			}
+		}
+	}
+	return ""
+}
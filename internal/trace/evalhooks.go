@@ -0,0 +1,148 @@
+package trace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// hookTool is a tool that sets up the shell by printing shell code for a
+// startup file to run, e.g. `eval "$(direnv hook zsh)"`.
+type hookTool struct {
+	Name    string
+	Matches *regexp.Regexp // The command whose output is run
+	Does    string         // What its code does to the shell
+}
+
+var hookTools = []hookTool{
+	{"direnv", regexp.MustCompile(`\bdirenv\s+hook\b`), "Loads each directory's .envrc as you cd into it and unloads it as you leave, so PATH can change from one directory to the next"},
+	{"mise", regexp.MustCompile(`\b(mise|rtx)\s+activate\b`), "Puts the tool versions selected for the current directory on PATH, and updates them at every prompt"},
+	{"Homebrew", regexp.MustCompile(`\bbrew\s+shellenv\b`), "Puts Homebrew's bin and sbin on PATH and sets HOMEBREW_PREFIX"},
+	{"rbenv", regexp.MustCompile(`\brbenv\s+init\b`), "Puts rbenv's shims on PATH and adds the rbenv shell function"},
+	{"pyenv", regexp.MustCompile(`\bpyenv\s+(init|virtualenv-init)\b`), "Puts pyenv's shims on PATH and adds the pyenv shell function"},
+	{"nodenv", regexp.MustCompile(`\bnodenv\s+init\b`), "Puts nodenv's shims on PATH and adds the nodenv shell function"},
+	{"goenv", regexp.MustCompile(`\bgoenv\s+init\b`), "Puts goenv's shims on PATH and adds the goenv shell function"},
+	{"jenv", regexp.MustCompile(`\bjenv\s+init\b`), "Puts jenv's shims on PATH and adds the jenv shell function"},
+	{"plenv", regexp.MustCompile(`\bplenv\s+init\b`), "Puts plenv's shims on PATH and adds the plenv shell function"},
+	{"fnm", regexp.MustCompile(`\bfnm\s+env\b`), "Puts a Node.js version directory of this shell's own on PATH"},
+	{"conda", regexp.MustCompile(`\bconda\b.*\b(hook|shell\.\w+)\b`), "Puts conda's condabin on PATH and activates the base environment, adding its bin"},
+	{"opam", regexp.MustCompile(`\bopam\s+(env|config\s+env)\b`), "Puts the current opam switch's bin on PATH"},
+	{"luarocks", regexp.MustCompile(`\bluarocks\s+path\b`), "Puts the LuaRocks bin directory on PATH and sets LUA_PATH"},
+	{"starship", regexp.MustCompile(`\bstarship\s+init\b`), "Sets up the starship prompt; it does not normally change PATH"},
+	{"zoxide", regexp.MustCompile(`\bzoxide\s+init\b`), "Adds the z and zi commands; it does not normally change PATH"},
+	{"atuin", regexp.MustCompile(`\batuin\s+init\b`), "Adds atuin's shell history hooks; it does not normally change PATH"},
+}
+
+// evalLineRes find the command whose output a startup file line runs:
+// eval "$(cmd)", eval `cmd`, source <(cmd) and fish's cmd | source.
+var evalLineRes = []*regexp.Regexp{
+	regexp.MustCompile(`\beval\s+"?\$\((.+?)\)"?\s*(?:[;&|#]|$)`),
+	regexp.MustCompile("\\beval\\s+\"?`(.+?)`"),
+	regexp.MustCompile(`(?:\bsource|^\.|[;&]\s*\.)\s+<\((.+?)\)`),
+	regexp.MustCompile(`^\s*(?:command\s+)?([\w./~-].*?)\s*\|\s*source\b`),
+}
+
+// evalCommand returns the command whose output the startup file line text
+// runs as shell code, if it does.
+func evalCommand(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "#") {
+		return "", false
+	}
+	for _, re := range evalLineRes {
+		if m := re.FindStringSubmatch(text); m != nil {
+			return strings.TrimSpace(m[1]), true
+		}
+	}
+	return "", false
+}
+
+// detectEvalHook recognizes ev as a startup file running a tool's output as
+// shell code, returning the tool. prev is the event before it: bash traces
+// the command substitution there, already expanded. path is the PATH the
+// tool was found in.
+func detectEvalHook(ev, prev model.TraceEvent, lines []string, path string) (*model.EvalHook, bool) {
+	var command string
+	if ev.Line > 0 && ev.Line <= len(lines) {
+		command, _ = evalCommand(lines[ev.Line-1])
+	}
+	fields := strings.Fields(ev.RawCommand)
+	isEval := len(fields) > 0 && (fields[0] == "eval" || fields[0] == "source" || fields[0] == ".")
+	if !isEval || command == "" {
+		// Without the file, only an unexpanded substitution gives it away
+		if !strings.Contains(ev.RawCommand, "eval ") || !(strings.Contains(ev.RawCommand, "$(") || strings.Contains(ev.RawCommand, "`")) {
+			return nil, false
+		}
+	}
+	if prev.File == ev.File && prev.Line == ev.Line && prev.Depth > ev.Depth && prev.RawCommand != "" {
+		command = prev.RawCommand
+	}
+	if command == "" {
+		return nil, true
+	}
+
+	hook := &model.EvalHook{Command: command}
+	for _, t := range hookTools {
+		if t.Matches.MatchString(command) {
+			hook.Tool = t.Name
+			break
+		}
+	}
+	name := strings.Trim(strings.Fields(command)[0], `"'`)
+	if hook.Tool == "" {
+		hook.Tool = filepath.Base(name)
+	}
+	hook.Binary = hookBinary(name, path)
+	return hook, true
+}
+
+// hookBinary finds the executable name runs as, looking in path (a PATH
+// value) if name has no directory.
+func hookBinary(name, path string) string {
+	if strings.ContainsRune(name, '/') {
+		return model.ExpandTilde(name)
+	}
+	for _, dir := range model.SplitPathList(path) {
+		candidate := filepath.Join(model.ExpandTilde(dir), name)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() && info.Mode()&0o111 != 0 {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// HookLabel is a short label for an entry an eval'd tool hook added, e.g.
+// "direnv hook", or "".
+func HookLabel(e model.PathEntry) string {
+	if e.Hook == nil {
+		return ""
+	}
+	return e.Hook.Tool + " hook"
+}
+
+// ExplainHook describes how the tool hook that added e works, one sentence
+// per line, or nil if no hook did.
+func ExplainHook(e model.PathEntry) []string {
+	h := e.Hook
+	if h == nil {
+		return nil
+	}
+	lines := []string{fmt.Sprintf("Added by the shell code `%s` prints, which line %d of %s runs.", h.Command, e.LineNumber, e.SourceFile)}
+	for _, t := range hookTools {
+		if t.Name == h.Tool {
+			lines = append(lines, t.Does+".")
+			break
+		}
+	}
+	if h.Binary != "" {
+		lines = append(lines, "Tool: "+h.Binary)
+	} else {
+		lines = append(lines, "Tool: not found on PATH")
+	}
+	lines = append(lines, "To see the code: "+h.Command)
+	return lines
+}
@@ -2,64 +2,226 @@ package trace
 
 import (
 	"bufio"
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"lspath/internal/model"
 )
 
-// Define the baseline path here.
-// Export it so main.go or the analyzer can see it if needed.
-// This is a subtle point about how your tool works:
-// The Tool Creates a Sandbox: In executor.go, your tool explicitly strips the user's existing PATH and forces PATH=/usr/bin:/bin.... It does this to create a "clean slate" so it can see exactly how the config files reconstruct the path.
-// It IS Hardcoded (by design): Currently, your executor.go does hardcode these paths (line 38 of the file you sent).
-// Is this bad?
-// Yes: If you run this on a system where /bin doesn't exist (like some distinct NixOS setups or Windows), the shell might fail to find basic commands like rm or mkdir.
-// No: For 99% of macOS and Linux systems, these paths are standard.
-// Better approach for the Executor:
-// Instead of hardcoding /usr/bin..., the executor could technically capture the system default path (confstr _CS_PATH on POSIX), but that is hard to get reliably from Go without CGO.
+// SandboxInitialPath is the usual baseline: the PATH a traced shell starts
+// from, a clean slate so the trace shows how the startup files build PATH
+// up. DetectBaseline adapts it to systems without these directories.
 const SandboxInitialPath = "/usr/bin:/bin:/usr/sbin:/sbin"
 
-// RunTrace executes the shell trace command and returns the stderr pipe.
-func RunTrace(shell Shell, initialPath string) (io.ReadCloser, error) {
-	cmd := exec.Command("sh", "-c", shell.GetTraceCommand())
+// DefaultTraceTimeout is how long a traced shell may take to start before
+// it is killed and its trace analyzed as far as it got (--trace-timeout).
+const DefaultTraceTimeout = 10 * time.Second
+
+// traceEnvShell is implemented by shells that enable tracing through
+// environment variables rather than xtrace and PS4 (e.g. fish_trace).
+type traceEnvShell interface {
+	TraceEnv() []string
+}
+
+// restrictedShell is implemented by shells that can trace with options
+// limiting what startup files do to the system (see --no-side-effects).
+type restrictedShell interface {
+	RestrictedTraceCommand() string
+}
+
+// hashShell is implemented by shells with a hash table of command locations
+// that a command can list (see withHashListing).
+type hashShell interface {
+	HashListCommand() string
+}
+
+// RunTrace starts tracing shell's startup with PATH set to initialPath.
+func RunTrace(shell Shell, initialPath string) (*ShellTrace, error) {
+	return RunTraceVar(shell, DefaultVariable, initialPath, false)
+}
+
+// RunTraceVar is RunTrace for an arbitrary PATH-like variable. The variable
+// starts as initialValue (unset if empty); when it is not PATH itself, the
+// shell still gets DetectBaseline's PATH so it can find basic commands. If
+// restricted is set and the shell supports it, the trace runs with
+// side-effect commands disabled. The shell is killed after
+// DefaultTraceTimeout.
+func RunTraceVar(shell Shell, variable, initialValue string, restricted bool) (*ShellTrace, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTraceTimeout)
+	return startTrace(ctx, traceCommand(shell, shellTraceCommand(shell, restricted), variable, initialValue, DetectBaseline()), cancel)
+}
+
+// shellTraceCommand returns the command that traces shell's startup.
+func shellTraceCommand(shell Shell, restricted bool) string {
+	if rs, ok := shell.(restrictedShell); restricted && ok {
+		return rs.RestrictedTraceCommand()
+	}
+	return shell.GetTraceCommand()
+}
+
+// traceCommand prepares command, a shell trace command line, with the
+// sandboxed environment described in RunTraceVar. basePath is the PATH the
+// shell gets when the traced variable is not PATH.
+func traceCommand(shell Shell, command, variable, initialValue, basePath string) *exec.Cmd {
+	cmd := exec.Command("sh", "-c", command)
 	// Sanitize Environment:
 	// We want to trace how the PATH is constructed. By passing in an initialPath,
-	// we can either trace from a clean slate (SandboxInitialPath) or from the
+	// we can either trace from a clean slate (the baseline) or from the
 	// user's current session PATH.
 	var env []string
 	for _, e := range os.Environ() {
-		// Filter out PATH, keeping others (TERM, USER, etc.)
-		if len(e) >= 5 && e[:5] == "PATH=" {
+		// Filter out PATH and the traced variable, keeping others (TERM, USER, etc.)
+		if strings.HasPrefix(e, "PATH=") || strings.HasPrefix(e, variable+"=") {
 			continue
 		}
 		env = append(env, e)
 	}
-	// Use the provided initialPath
-	env = append(env, "PATH="+initialPath)
+	if variable == DefaultVariable {
+		// Use the provided initialPath
+		env = append(env, "PATH="+initialValue)
+	} else {
+		env = append(env, "PATH="+basePath)
+		if initialValue != "" {
+			env = append(env, variable+"="+initialValue)
+		}
+	}
+
+	// Read by the PowerShell trace script, which has no xtrace to expand assignments
+	env = append(env, "LSPATH_TRACE_VAR="+variable)
 
 	cmd.Env = env
 	cmd.Env = append(cmd.Env, "PS4="+shell.GetPS4())
+	if es, ok := shell.(traceEnvShell); ok {
+		cmd.Env = append(cmd.Env, es.TraceEnv()...)
+	}
+	return cmd
+}
+
+// ShellTrace is a traced shell. Read the trace from it until EOF, then
+// Wait for the shell's exit status; Close cleans up after it either way.
+type ShellTrace struct {
+	io.Reader // The shell's stderr, where the trace is written
 
+	stderr  io.ReadCloser
+	cmd     *exec.Cmd
+	stop    func() bool // Stops the timeout
+	cleanup func()
+	waited  sync.Once
+	err     error
+}
+
+// startTrace starts cmd and returns its trace, read from its stderr. When
+// ctx is done the shell and everything it started are killed, which ends
+// the trace early. cleanup, if not nil, runs when the trace is closed, or
+// now if cmd fails to start.
+func startTrace(ctx context.Context, cmd *exec.Cmd, cleanup func()) (*ShellTrace, error) {
+	if cleanup == nil {
+		cleanup = func() {}
+	}
 	// We only care about stderr for the trace
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
+		cleanup()
 		return nil, err
 	}
-
+	detachTrace(cmd)
 	if err := cmd.Start(); err != nil {
+		cleanup()
 		return nil, err
 	}
+	// Closing our end too ends the trace even if something the shell
+	// started out of reach still holds the pipe open
+	stop := context.AfterFunc(ctx, func() {
+		killTrace(cmd)
+		stderr.Close()
+	})
+	return &ShellTrace{Reader: stderr, stderr: stderr, cmd: cmd, stop: stop, cleanup: cleanup}, nil
+}
 
-	// We don't wait for the command here because we need to stream the output.
-	// The caller is responsible for reading stderr until EOF.
-	// Note: This leaves the process running until it exits or stderr is closed.
-	// Since the command is `exit`, it should finish quickly after dumping init logs.
+// Wait waits for the shell to exit and returns its exit status as an
+// *exec.ExitError, or nil if it exited with status 0. Read the trace to EOF
+// first: a shell with more to write does not exit.
+func (t *ShellTrace) Wait() error {
+	t.waited.Do(func() {
+		t.err = t.cmd.Wait()
+		t.stop()
+	})
+	return t.err
+}
 
-	// However, exec.Command doesn't make it easy to wait *after* returning the pipe.
-	// We might need a wrapper logic if we want to ensure cleanup, but for now
-	// let's rely on the read loop ending.
+// Close stops reading the trace, waits for the shell and cleans up.
+func (t *ShellTrace) Close() error {
+	err := t.stderr.Close()
+	t.Wait()
+	t.cleanup()
+	if errors.Is(err, os.ErrClosed) { // Closed by Wait, or by the timeout
+		return nil
+	}
+	return err
+}
 
-	return stderr, nil
+// traceContext returns the context a trace run with o stops at.
+func (o Options) traceContext() (context.Context, context.CancelFunc) {
+	parent := o.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	switch {
+	case o.TraceTimeout < 0:
+		return context.WithCancel(parent)
+	case o.TraceTimeout == 0:
+		return context.WithTimeout(parent, DefaultTraceTimeout)
+	}
+	return context.WithTimeout(parent, o.TraceTimeout)
+}
+
+// traceLocation says where the shell had got to: the last traced command.
+func traceLocation(events []model.TraceEvent) string {
+	for i := len(events) - 1; i >= 0; i-- {
+		if ev := events[i]; ev.File != "" {
+			at := "at " + ev.File
+			if ev.Line > 0 {
+				at = fmt.Sprintf("at %s:%d", ev.File, ev.Line)
+			}
+			return at + " (last command: " + truncateCommand(ev.RawCommand, 60) + ")"
+		}
+	}
+	return "before it traced anything"
+}
+
+// exitDiagnostics explain how the traced shell finished, if not cleanly:
+// timedOut if the trace ran out of time, else waitErr, the shell's exit
+// status; and any errors the shell reported in stats.
+func exitDiagnostics(shell Shell, opts Options, events []model.TraceEvent, stats model.ParserStats, timedOut bool, waitErr error) []string {
+	var diags []string
+	var exitErr *exec.ExitError
+	switch {
+	case timedOut:
+		timeout := opts.TraceTimeout
+		if timeout == 0 {
+			timeout = DefaultTraceTimeout
+		}
+		diags = append(diags, fmt.Sprintf("WARNING: The trace timed out after %s %s, so the shell was stopped there. Entries the rest of the startup files add are attributed as Session, or missing from a trace-only view. A startup file may be waiting on the network or for input; if the shell is just slow, raise --trace-timeout.", timeout, traceLocation(events)))
+	case errors.As(waitErr, &exitErr):
+		how := fmt.Sprintf("exited with status %d", exitErr.ExitCode())
+		if exitErr.ExitCode() < 0 {
+			how = "was stopped (" + exitErr.ProcessState.String() + ")"
+		}
+		diags = append(diags, fmt.Sprintf("WARNING: %s %s %s, before it finished starting up, so later startup files did not run. A startup file may call exit or return an error under set -e.", shell.Name(), how, traceLocation(events)))
+	case waitErr != nil:
+		diags = append(diags, fmt.Sprintf("WARNING: Could not get %s's exit status: %v.", shell.Name(), waitErr))
+	}
+	for _, e := range stats.ShellErrors {
+		diags = append(diags, fmt.Sprintf("WARNING: %s reported an error while starting up: %s", shell.Name(), e))
+	}
+	return diags
 }
 
 // RunTraceSync is a helper to run and collect all output (for testing/debugging)
@@ -79,5 +241,8 @@ func RunTraceSync(shell Shell, initialPath string) ([]string, error) {
 	for scanner.Scan() {
 		lines = append(lines, scanner.Text())
 	}
-	return lines, scanner.Err()
+	if err := scanner.Err(); err != nil {
+		return lines, err
+	}
+	return lines, stderr.Wait()
 }
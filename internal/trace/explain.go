@@ -0,0 +1,96 @@
+package trace
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// FindEntry resolves a user-supplied entry reference, either a 1-based
+// PATH position or a directory value, to an index into PathEntries.
+func FindEntry(res model.AnalysisResult, ref string) (int, error) {
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n < 1 || n > len(res.PathEntries) {
+			return -1, fmt.Errorf("entry #%d out of range (PATH has %d entries)", n, len(res.PathEntries))
+		}
+		return n - 1, nil
+	}
+	for i, e := range res.PathEntries {
+		if model.SamePath(e.Value, ref, model.CanonOptions{}) {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("%s is not in PATH", ref)
+}
+
+// GenerateExplanation describes a single PATH entry: where it came from,
+// any issues, and what would break if it were removed.
+func GenerateExplanation(res model.AnalysisResult, idx int, bins *BinaryIndex) string {
+	var sb strings.Builder
+	e := res.PathEntries[idx]
+
+	sb.WriteString(fmt.Sprintf("PATH ENTRY #%d: %s\n", idx+1, e.Value))
+	sb.WriteString(strings.Repeat("-", len(fmt.Sprintf("PATH ENTRY #%d: %s", idx+1, e.Value))) + "\n")
+
+	if e.LineNumber == 0 {
+		sb.WriteString(fmt.Sprintf("Source:        %s\n", e.SourceFile))
+	} else {
+		sb.WriteString(fmt.Sprintf("Source:        %s:%d\n", e.SourceFile, e.LineNumber))
+	}
+	if e.Confidence != "" {
+		sb.WriteString(fmt.Sprintf("Confidence:    %s - %s\n", e.Confidence, e.ConfidenceReason))
+	}
+	if e.Mode != "Unknown" {
+		sb.WriteString(fmt.Sprintf("Startup Phase: %s\n", e.Mode))
+	}
+	sb.WriteString(fmt.Sprintf("Category:      %s\n", PathCategory(e.Value)))
+	if e.Package != "" {
+		sb.WriteString(fmt.Sprintf("Installed By:  %s\n", e.Package))
+	}
+	if l := VersionManagerLabel(e); l != "" {
+		sb.WriteString(fmt.Sprintf("Kind:          %s\n", l))
+		for _, line := range ExplainVersionManager(e) {
+			sb.WriteString("               " + line + "\n")
+		}
+	}
+	if l := HookLabel(e); l != "" {
+		sb.WriteString(fmt.Sprintf("Added By:      %s\n", l))
+		for _, line := range ExplainHook(e) {
+			sb.WriteString("               " + line + "\n")
+		}
+	}
+	if e.IsSessionOnly && e.SessionNote != "" {
+		sb.WriteString(fmt.Sprintf("Note:          %s\n", e.SessionNote))
+	}
+	if chain := SymlinkChain(e); chain != "" {
+		sb.WriteString(fmt.Sprintf("Symlink Chain: %s\n", chain))
+	}
+	if e.IsDuplicate {
+		sb.WriteString(fmt.Sprintf("Duplicate:     %s\n", e.DuplicateMessage))
+	} else if e.SymlinkPointsTo >= 0 {
+		sb.WriteString(fmt.Sprintf("Symlink:       %s\n", e.SymlinkMessage))
+	}
+	for _, d := range e.Diagnostics {
+		sb.WriteString(fmt.Sprintf("Issue:         %s\n", d))
+	}
+
+	sb.WriteString("\nIF REMOVED\n")
+	if bins.Partial() {
+		sb.WriteString(fmt.Sprintf("(partial scan: %d/%d directories - results may be incomplete)\n", bins.Scanned, len(bins.ByEntry)))
+	}
+	breaks := bins.RemovalImpact(idx)
+	if idx >= bins.Scanned {
+		sb.WriteString("Unknown - this entry was not scanned before the scan stopped.\n")
+	} else if len(breaks) == 0 {
+		sb.WriteString("No binaries would stop resolving - every executable here is also provided elsewhere in PATH.\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("%d binaries would stop resolving:\n", len(breaks)))
+		for _, b := range breaks {
+			sb.WriteString("  " + b + "\n")
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,171 @@
+package trace
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// GenerateYAML renders the same data as --json as YAML, with fields in
+// declaration order so it diffs cleanly between runs.
+func GenerateYAML(res model.AnalysisResult) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("SchemaVersion: %d\n", model.SchemaVersion))
+	writeYAML(&sb, reflect.ValueOf(res), 0)
+	return sb.String()
+}
+
+// writeYAML writes v as a block mapping or sequence (or a scalar line) at
+// the given indent.
+func writeYAML(sb *strings.Builder, v reflect.Value, indent int) {
+	pad := strings.Repeat("  ", indent)
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			writeYAMLField(sb, pad+t.Field(i).Name+":", v.Field(i), indent)
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return yamlKeyLess(keys[i], keys[j]) })
+		for _, k := range keys {
+			writeYAMLField(sb, pad+yamlScalar(k)+":", v.MapIndex(k), indent)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			elem := v.Index(i)
+			if isYAMLCollection(elem) && yamlLen(elem) > 0 {
+				// "- " followed by the first line of the nested block
+				var nested strings.Builder
+				writeYAML(&nested, elem, indent+1)
+				sb.WriteString(pad + "- " + strings.TrimPrefix(nested.String(), pad+"  "))
+			} else {
+				sb.WriteString(pad + "- " + yamlInline(elem) + "\n")
+			}
+		}
+	default:
+		sb.WriteString(pad + yamlScalar(v) + "\n")
+	}
+}
+
+// yamlKeyLess orders map keys the way encoding/json does for strings (byte
+// order, independent of locale) and numerically for integers.
+func yamlKeyLess(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return a.Uint() < b.Uint()
+	}
+	return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+}
+
+// writeYAMLField writes "key: value", nesting collections on the lines below.
+func writeYAMLField(sb *strings.Builder, key string, v reflect.Value, indent int) {
+	if isYAMLCollection(v) && yamlLen(v) > 0 {
+		sb.WriteString(key + "\n")
+		writeYAML(sb, v, indent+1)
+		return
+	}
+	sb.WriteString(key + " " + yamlInline(v) + "\n")
+}
+
+// isYAMLCollection reports whether v is written as a nested block.
+func isYAMLCollection(v reflect.Value) bool {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return true
+	}
+	return false
+}
+
+// yamlLen is the number of items in a collection; structs always count.
+func yamlLen(v reflect.Value) int {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Struct {
+		return v.NumField()
+	}
+	return v.Len()
+}
+
+// yamlInline formats a scalar or an empty collection for one line.
+func yamlInline(v reflect.Value) string {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "null"
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		return "[]"
+	case reflect.Map, reflect.Struct:
+		return "{}"
+	}
+	return yamlScalar(v)
+}
+
+// yamlScalar formats a string, number or bool. Strings are always quoted;
+// JSON string syntax is valid YAML and keeps values like "no" or "~" intact.
+func yamlScalar(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		b, _ := json.Marshal(v.String())
+		return string(b)
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	}
+	b, _ := json.Marshal(fmt.Sprint(v.Interface()))
+	return string(b)
+}
+
+// csvColumns are the columns of GenerateCSV.
+var csvColumns = []string{"Index", "Value", "SourceFile", "LineNumber", "Mode", "IsDuplicate", "Missing"}
+
+// GenerateCSV flattens the entries to one row each, in priority order, for
+// spreadsheets and audits. IsDuplicate follows the duplicate policy.
+func GenerateCSV(res model.AnalysisResult) string {
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+	w.Write(csvColumns)
+	for i, e := range res.PathEntries {
+		line := ""
+		if e.LineNumber > 0 {
+			line = strconv.Itoa(e.LineNumber)
+		}
+		w.Write([]string{
+			strconv.Itoa(i + 1),
+			e.Value,
+			e.SourceFile,
+			line,
+			e.Mode,
+			strconv.FormatBool(res.DuplicatePolicy.IsDuplicate(e)),
+			strconv.FormatBool(isMissing(e.Value)),
+		})
+	}
+	w.Flush()
+	return sb.String()
+}
@@ -0,0 +1,151 @@
+package trace
+
+import (
+	"fmt"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// flowEdge links two flow nodes: a file sourcing another, or the shell
+// moving on to the next top-level startup file.
+type flowEdge struct {
+	From, To int  // Indices into FlowNodes
+	Sources  bool // true if From sources To; false for startup order
+}
+
+// flowEdges derives the graph structure from node order and depth.
+func flowEdges(nodes []model.ConfigNode) []flowEdge {
+	var edges []flowEdge
+	stack := []int{} // stack[d] is the most recent node at depth d
+	lastTop := -1
+	for i, n := range nodes {
+		d := n.Depth
+		if d > len(stack) {
+			d = len(stack) // Depth jumped; attach to the deepest known parent
+		}
+		stack = append(stack[:d], i)
+		if d == 0 {
+			if lastTop >= 0 {
+				edges = append(edges, flowEdge{From: lastTop, To: i})
+			}
+			lastTop = i
+		} else {
+			edges = append(edges, flowEdge{From: stack[d-1], To: i, Sources: true})
+		}
+	}
+	return edges
+}
+
+func flowNodeLabel(n model.ConfigNode) string {
+	label := n.FilePath
+	if n.NotExecuted {
+		label += " (not executed)"
+	}
+	return label
+}
+
+// GenerateDOT renders the configuration flow and the PATH entries each file
+// contributes as a Graphviz digraph.
+func GenerateDOT(res model.AnalysisResult) string {
+	var sb strings.Builder
+	q := func(s string) string {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+	}
+
+	sb.WriteString("digraph lspath {\n")
+	sb.WriteString("  rankdir=LR;\n")
+	sb.WriteString("  node [fontname=\"Helvetica\", fontsize=10];\n\n")
+
+	for i, n := range res.FlowNodes {
+		style := "shape=note"
+		if n.NotExecuted {
+			style += ", style=dashed, fontcolor=gray50"
+		}
+		sb.WriteString(fmt.Sprintf("  f%d [label=%s, %s];\n", i, q(fmt.Sprintf("%d. %s", i+1, flowNodeLabel(n))), style))
+	}
+	sb.WriteString("\n")
+	for i, e := range res.PathEntries {
+		style := "shape=box, style=rounded"
+		switch {
+		case e.IsDuplicate:
+			style += ", color=orange"
+		case isMissing(e.Value):
+			style += ", color=red"
+		}
+		sb.WriteString(fmt.Sprintf("  p%d [label=%s, %s];\n", i, q(fmt.Sprintf("[%d] %s", i+1, e.Value)), style))
+	}
+	sb.WriteString("\n")
+
+	for _, e := range flowEdges(res.FlowNodes) {
+		if e.Sources {
+			sb.WriteString(fmt.Sprintf("  f%d -> f%d [label=\"sources\"];\n", e.From, e.To))
+		} else {
+			sb.WriteString(fmt.Sprintf("  f%d -> f%d [style=dashed];\n", e.From, e.To))
+		}
+	}
+	for i, n := range res.FlowNodes {
+		for _, idx := range n.Entries {
+			sb.WriteString(fmt.Sprintf("  f%d -> p%d [arrowhead=empty, color=gray40];\n", i, idx))
+		}
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// GenerateMermaid renders the same graph as GenerateDOT as a Mermaid
+// flowchart, for pasting into Markdown docs.
+func GenerateMermaid(res model.AnalysisResult) string {
+	var sb strings.Builder
+	q := func(s string) string {
+		return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+	}
+
+	sb.WriteString("flowchart LR\n")
+	for i, n := range res.FlowNodes {
+		sb.WriteString(fmt.Sprintf("  f%d[%s]\n", i, q(fmt.Sprintf("%d. %s", i+1, flowNodeLabel(n)))))
+	}
+	for i, e := range res.PathEntries {
+		sb.WriteString(fmt.Sprintf("  p%d([%s])\n", i, q(fmt.Sprintf("[%d] %s", i+1, e.Value))))
+	}
+
+	for _, e := range flowEdges(res.FlowNodes) {
+		if e.Sources {
+			sb.WriteString(fmt.Sprintf("  f%d -->|sources| f%d\n", e.From, e.To))
+		} else {
+			sb.WriteString(fmt.Sprintf("  f%d -.-> f%d\n", e.From, e.To))
+		}
+	}
+	for i, n := range res.FlowNodes {
+		for _, idx := range n.Entries {
+			sb.WriteString(fmt.Sprintf("  f%d --> p%d\n", i, idx))
+		}
+	}
+
+	var dups, missing, notExecuted []string
+	for i, e := range res.PathEntries {
+		if e.IsDuplicate {
+			dups = append(dups, fmt.Sprintf("p%d", i))
+		} else if isMissing(e.Value) {
+			missing = append(missing, fmt.Sprintf("p%d", i))
+		}
+	}
+	for i, n := range res.FlowNodes {
+		if n.NotExecuted {
+			notExecuted = append(notExecuted, fmt.Sprintf("f%d", i))
+		}
+	}
+	sb.WriteString("  classDef duplicate stroke:orange\n")
+	sb.WriteString("  classDef missing stroke:red\n")
+	sb.WriteString("  classDef notExecuted stroke-dasharray:4,color:gray\n")
+	if len(dups) > 0 {
+		sb.WriteString(fmt.Sprintf("  class %s duplicate\n", strings.Join(dups, ",")))
+	}
+	if len(missing) > 0 {
+		sb.WriteString(fmt.Sprintf("  class %s missing\n", strings.Join(missing, ",")))
+	}
+	if len(notExecuted) > 0 {
+		sb.WriteString(fmt.Sprintf("  class %s notExecuted\n", strings.Join(notExecuted, ",")))
+	}
+	return sb.String()
+}
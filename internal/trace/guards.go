@@ -0,0 +1,274 @@
+package trace
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// Shell modes a startup file can test for.
+const (
+	guardInteractive = "interactive"
+	guardLogin       = "login"
+)
+
+// guardTests recognize a test of the shell mode in an sh-family condition.
+var guardTests = []struct {
+	re   *regexp.Regexp
+	mode string
+}{
+	{regexp.MustCompile(`\$\{?PS1\b`), guardInteractive},
+	{regexp.MustCompile(`\$-.*\*i\*`), guardInteractive},
+	{regexp.MustCompile(`-o\s+interactive\b`), guardInteractive},
+	{regexp.MustCompile(`\blogin_shell\b`), guardLogin},
+	{regexp.MustCompile(`-o\s+login\b`), guardLogin},
+}
+
+// guardMode returns the mode cond tests and whether it is negated, e.g.
+// `[ -z "$PS1" ]` tests for a non-interactive shell.
+func guardMode(cond string) (mode string, negated, ok bool) {
+	for _, t := range guardTests {
+		if t.re.MatchString(cond) {
+			mode = t.mode
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return "", false, false
+	}
+	negated = strings.Contains(cond, "-z ") || strings.Contains(cond, "!=") || guardNotRe.MatchString(cond)
+	return mode, negated, true
+}
+
+// guardedBlock is a run of lines in a startup file that only runs in one
+// shell mode.
+type guardedBlock struct {
+	Start, End int    // 1-based, inclusive
+	Mode       string // guardInteractive or guardLogin
+	Not        bool   // Runs only when the shell is NOT in Mode
+	Line       int    // Line of the test
+	Test       string // The test, as written
+}
+
+// describe says which shells the block is for, e.g. "non-login shells".
+func (b guardedBlock) describe() string {
+	mode := b.Mode
+	if b.Not {
+		mode = "non-" + mode
+	}
+	return mode + " shells"
+}
+
+var (
+	guardIfRe     = regexp.MustCompile(`^if\s+(.*?)(?:;\s*then)?$`)
+	guardReturnRe = regexp.MustCompile(`^(.*?)\s*(&&|\|\|)\s*(?:return|exit)\b`)
+	guardCaseRe   = regexp.MustCompile(`^case\s+"?\$-"?\s+in\b`)
+	guardNotRe    = regexp.MustCompile(`(^|[\s;(\[])!\s`)
+	caseReturnsRe = regexp.MustCompile(`\*i\*\)\s*return`)
+	ifWordRe      = regexp.MustCompile(`^(if|fi)\b`)
+	fiRe          = regexp.MustCompile(`\bfi\b`)
+)
+
+// findGuardedBlocks finds the parts of an sh-family startup file that test
+// whether the shell is interactive or a login shell: if blocks (and their
+// else branches), and early returns such as `[ -z "$PS1" ] && return` or
+// `case $- in *i*) ;; *) return;; esac`, which guard the rest of the file.
+func findGuardedBlocks(lines []string) []guardedBlock {
+	var blocks []guardedBlock
+	for i := 0; i < len(lines); i++ {
+		text := strings.TrimSpace(lines[i])
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		lineNo := i + 1
+
+		if m := guardIfRe.FindStringSubmatch(text); m != nil && !fiRe.MatchString(text) {
+			mode, neg, ok := guardMode(m[1])
+			if !ok {
+				continue
+			}
+			elseLine, fiLine := matchIf(lines, i)
+			if fiLine == 0 {
+				continue
+			}
+			end := fiLine - 1
+			if elseLine > 0 {
+				end = elseLine - 1
+			}
+			blocks = append(blocks, guardedBlock{Start: lineNo + 1, End: end, Mode: mode, Not: neg, Line: lineNo, Test: text})
+			if elseLine > 0 && strings.TrimSpace(lines[elseLine-1]) == "else" {
+				blocks = append(blocks, guardedBlock{Start: elseLine + 1, End: fiLine - 1, Mode: mode, Not: !neg, Line: lineNo, Test: text})
+			}
+			continue
+		}
+
+		if m := guardReturnRe.FindStringSubmatch(text); m != nil {
+			if mode, neg, ok := guardMode(m[1]); ok {
+				// `test && return` carries on when the test fails
+				not := neg != (m[2] == "&&")
+				blocks = append(blocks, guardedBlock{Start: lineNo + 1, End: len(lines), Mode: mode, Not: not, Line: lineNo, Test: text})
+				return blocks
+			}
+		}
+
+		if guardCaseRe.MatchString(text) {
+			// case $- in *i*) ;; *) return;; esac, on one line or a few
+			stmt := text
+			j := i
+			for ; j < len(lines) && j < i+6 && !strings.Contains(lines[j], "esac"); j++ {
+				if j > i {
+					stmt += " " + strings.TrimSpace(lines[j])
+				}
+			}
+			if j < len(lines) && j > i {
+				stmt += " " + strings.TrimSpace(lines[j])
+			}
+			if strings.Contains(stmt, "*i*)") && strings.Contains(stmt, "return") && strings.Contains(stmt, "esac") {
+				// Whichever arm returns, the rest runs in the other mode
+				returnsInteractive := caseReturnsRe.MatchString(stmt)
+				blocks = append(blocks, guardedBlock{Start: j + 2, End: len(lines), Mode: guardInteractive, Not: returnsInteractive, Line: lineNo, Test: text})
+				return blocks
+			}
+		}
+	}
+	return blocks
+}
+
+// matchIf finds the else (or elif) and fi lines, 1-based, of the if
+// statement that starts at index start. A missing fi gives 0.
+func matchIf(lines []string, start int) (elseLine, fiLine int) {
+	depth := 0
+	for i := start; i < len(lines); i++ {
+		text := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(text, "#") {
+			continue
+		}
+		word := ifWordRe.FindString(text)
+		switch {
+		case word == "if" && !fiRe.MatchString(text):
+			depth++
+		case word == "fi":
+			depth--
+			if depth == 0 {
+				return elseLine, i + 1
+			}
+		case depth == 1 && elseLine == 0 && (text == "else" || strings.HasPrefix(text, "elif ")):
+			elseLine = i + 1
+		}
+	}
+	return elseLine, 0
+}
+
+// hasCommands reports whether lines[start-1:end] contain anything the
+// shell would trace, rather than only comments and keywords.
+func hasCommands(lines []string, start, end int) bool {
+	for n := start; n <= end && n <= len(lines); n++ {
+		text := strings.TrimSpace(lines[n-1])
+		switch text {
+		case "", "fi", "else", "then", "done", "esac", "{", "}", ";;":
+			continue
+		}
+		if !strings.HasPrefix(text, "#") {
+			return true
+		}
+	}
+	return false
+}
+
+// explainSkipped annotates flow nodes with what did not run and why: for
+// files that ran, guarded blocks the trace never entered; for standard
+// files that did not run at all, the rule that made the shell skip them.
+func explainSkipped(nodes []model.ConfigNode, events []model.TraceEvent) {
+	ran := make(map[string]map[int]bool) // file -> lines the trace ran
+	numbered := make(map[string]bool)    // files whose events carry line numbers
+	for _, ev := range events {
+		if ran[ev.File] == nil {
+			ran[ev.File] = make(map[int]bool)
+		}
+		if ev.Line > 0 {
+			ran[ev.File][ev.Line] = true
+			if ev.PathChange == "" {
+				numbered[ev.File] = true
+			}
+		}
+	}
+	login := IsLoginShell(nodes)
+	for _, n := range nodes {
+		if !n.NotExecuted && GuessShellMode(n.FilePath) == "Login" {
+			login = true
+		}
+	}
+
+	explained := make(map[string][]string)
+	for i := range nodes {
+		n := &nodes[i]
+		if n.NotExecuted {
+			n.Skipped = skippedFileReason(n.FilePath, nodes, login)
+			continue
+		}
+		// fish and csh traces only place PATH changes, so an untraced line
+		// proves nothing there
+		if !numbered[n.FilePath] || strings.HasSuffix(n.FilePath, ".fish") || strings.Contains(n.FilePath, "csh") || strings.HasSuffix(n.FilePath, ".login") {
+			continue
+		}
+		if reasons, ok := explained[n.FilePath]; ok {
+			n.Skipped = reasons
+			continue
+		}
+		lines := readLines(n.FilePath)
+		var reasons []string
+		for _, b := range findGuardedBlocks(lines) {
+			if b.End < b.Start || !hasCommands(lines, b.Start, b.End) {
+				continue
+			}
+			entered := false
+			for line := b.Start; line <= b.End && !entered; line++ {
+				entered = ran[n.FilePath][line]
+			}
+			if entered {
+				continue
+			}
+			span := fmt.Sprintf("Lines %d-%d", b.Start, b.End)
+			if b.Start == b.End {
+				span = fmt.Sprintf("Line %d", b.Start)
+			}
+			reasons = append(reasons, fmt.Sprintf("%s skipped: only for %s (line %d: %s)", span, b.describe(), b.Line, truncateCommand(b.Test, 60)))
+		}
+		explained[n.FilePath] = reasons
+		n.Skipped = reasons
+	}
+}
+
+// skippedFileReason explains why the shell did not read a standard startup
+// file that exists.
+func skippedFileReason(file string, nodes []model.ConfigNode, login bool) []string {
+	if _, err := os.Stat(model.ExpandTilde(file)); err != nil {
+		return nil
+	}
+	base := file[strings.LastIndex(file, "/")+1:]
+	switch base {
+	case ".bash_login", ".profile":
+		for _, n := range nodes {
+			if !n.NotExecuted && (strings.HasSuffix(n.FilePath, "/.bash_profile") || (base == ".profile" && strings.HasSuffix(n.FilePath, "/.bash_login"))) {
+				return []string{"Skipped: a bash login shell reads only the first of ~/.bash_profile, ~/.bash_login and ~/.profile that exists"}
+			}
+		}
+	case ".bashrc":
+		if login {
+			return []string{"Skipped: a bash login shell reads ~/.bashrc only if the profile sources it"}
+		}
+	}
+	switch GuessShellMode(file) {
+	case "Login":
+		if !login {
+			return []string{"Skipped: only read by login shells; this was a non-login shell"}
+		}
+	case "Interactive":
+		return []string{"Skipped: only read by interactive shells"}
+	}
+	return nil
+}
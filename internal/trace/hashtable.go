@@ -0,0 +1,90 @@
+package trace
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// Lines the traced shell prints to stderr around its hash table listing, so
+// the parser can tell the listing from the trace.
+const (
+	hashBeginMarker = "lspath-hash-begin"
+	hashEndMarker   = "lspath-hash-end"
+)
+
+// maxStaleHashes bounds the commands named in the stale hash warning.
+const maxStaleHashes = 5
+
+// withHashListing makes command, a shell trace command line, list the
+// shell's hash table on stderr once startup has finished, between
+// hashBeginMarker and hashEndMarker. Shells without one are left alone.
+func withHashListing(shell Shell, command string) string {
+	hs, ok := shell.(hashShell)
+	if !ok {
+		return command
+	}
+	script := fmt.Sprintf("echo %s >&2; %s >&2; echo %s >&2; exit 0", hashBeginMarker, hs.HashListCommand(), hashEndMarker)
+	return strings.Replace(command, "-c 'exit 0'", "-c '"+script+"'", 1)
+}
+
+// parseHashLine reads one line of a hash table listing: "hash ls=/bin/ls"
+// (zsh), "builtin hash -p /bin/ls ls" (bash), "ls=/bin/ls" (ksh) or
+// "/bin/ls" (dash).
+func parseHashLine(line string) (model.HashedCommand, bool) {
+	line = strings.TrimSpace(line)
+	if rest, ok := strings.CutPrefix(line, "builtin hash -p "); ok {
+		path, name, ok := strings.Cut(rest, " ")
+		if !ok || !filepath.IsAbs(unquote(path)) {
+			return model.HashedCommand{}, false
+		}
+		return model.HashedCommand{Name: unquote(name), Path: unquote(path)}, true
+	}
+	line = strings.TrimPrefix(line, "hash ")
+	if name, path, ok := strings.Cut(line, "="); ok {
+		if path = unquote(path); name != "" && filepath.IsAbs(path) {
+			return model.HashedCommand{Name: unquote(name), Path: path}, true
+		}
+		return model.HashedCommand{}, false
+	}
+	if filepath.IsAbs(line) && !strings.ContainsAny(line, " \t") {
+		return model.HashedCommand{Name: filepath.Base(line), Path: line}, true
+	}
+	return model.HashedCommand{}, false
+}
+
+// CheckHashTable warns about commands in res.HashTable the shell would run
+// from somewhere other than where PATH now finds them, or that are gone:
+// the startup files ran them before the directory that now wins was added,
+// or the executable was since installed or removed. Entries added in this
+// session are left out, since the traced shell never saw them.
+func CheckHashTable(res *model.AnalysisResult, shell Shell) {
+	var stale []string
+	for _, h := range res.HashTable {
+		idx, path := -1, ""
+		for _, hit := range FindCommand(res.PathEntries, h.Name) {
+			if !hit.Broken && !res.PathEntries[hit.Index].IsSessionOnly {
+				idx, path = hit.Index, hit.Path
+				break
+			}
+		}
+		switch {
+		case idx < 0:
+			stale = append(stale, fmt.Sprintf("%s (%s, no longer in %s)", h.Name, h.Path, res.VariableName()))
+		case path != h.Path && !sameFile(path, h.Path):
+			stale = append(stale, fmt.Sprintf("%s (%s, but entry #%d has %s)", h.Name, h.Path, idx+1, path))
+		}
+	}
+	if len(stale) == 0 {
+		return
+	}
+	more := ""
+	if len(stale) > maxStaleHashes {
+		more = fmt.Sprintf(" and %d more", len(stale)-maxStaleHashes)
+		stale = stale[:maxStaleHashes]
+	}
+	res.Diagnostics = append(res.Diagnostics, fmt.Sprintf("WARNING: %s's hash table is stale after startup: it remembers %s%s. Shells keep running the remembered copy; run `hash -r` in them, or after the startup file line that changes what PATH finds.",
+		shell.Name(), strings.Join(stale, ", "), more))
+}
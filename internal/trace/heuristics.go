@@ -0,0 +1,155 @@
+package trace
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// Heuristics the analyzer applies to the raw trace. Each can be turned off
+// with --no-heuristic when it guesses wrong.
+const (
+	// HeuristicEval attributes a PATH change to the `eval "$(tool init)"`
+	// line before it, rather than the line inside the tool's output.
+	HeuristicEval = "eval"
+	// HeuristicCoalesce folds zsh's own function files (/usr/share/zsh) into
+	// the startup file that called them, and merges consecutive flow nodes
+	// of the same file.
+	HeuristicCoalesce = "coalesce"
+	// HeuristicGhostNodes adds the standard startup files the shell did not
+	// run to the flow, marked Not Executed.
+	HeuristicGhostNodes = "ghost-nodes"
+	// HeuristicNoisyFiles drops flow nodes for files that added nothing and
+	// are not standard startup files.
+	HeuristicNoisyFiles = "noisy-files"
+)
+
+// heuristics lists every heuristic, in the order the report shows them.
+var heuristics = []struct{ Name, Description string }{
+	{HeuristicEval, "PATH changes attributed to the eval line that produced them"},
+	{HeuristicCoalesce, "flow nodes folded into the file that called them"},
+	{HeuristicGhostNodes, "startup files the shell did not run, added to the flow"},
+	{HeuristicNoisyFiles, "flow nodes dropped because they added nothing"},
+}
+
+// ParseHeuristics turns --no-heuristic values into the set of disabled
+// heuristics. "all" disables every one.
+func ParseHeuristics(names []string) (map[string]bool, error) {
+	disabled := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "all" {
+			for _, h := range heuristics {
+				disabled[h.Name] = true
+			}
+			continue
+		}
+		if heuristicDescription(name) == "" {
+			var known []string
+			for _, h := range heuristics {
+				known = append(known, h.Name)
+			}
+			return nil, fmt.Errorf("unknown heuristic %q (use %s or all)", name, strings.Join(known, ", "))
+		}
+		disabled[name] = true
+	}
+	return disabled, nil
+}
+
+func heuristicDescription(name string) string {
+	for _, h := range heuristics {
+		if h.Name == name {
+			return h.Description
+		}
+	}
+	return ""
+}
+
+// HeuristicsFile returns the user's list of heuristics to leave off, one per
+// line with # comments, e.g. ~/.config/lspath/no-heuristics.
+func HeuristicsFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "lspath", "no-heuristics")
+}
+
+// ReadHeuristicsFile reads the heuristic names in file, ignoring blank lines
+// and # comments. A missing file lists none.
+func ReadHeuristicsFile(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			names = append(names, line)
+		}
+	}
+	return names, scanner.Err()
+}
+
+// enabled reports whether the analyzer may apply heuristic name.
+func (a *Analyzer) enabled(name string) bool {
+	return !a.DisabledHeuristics[name]
+}
+
+// heuristicUses summarizes fired, the times each heuristic changed the
+// result, for AnalysisResult.Heuristics.
+func (a *Analyzer) heuristicUses(fired map[string]int) []model.HeuristicUse {
+	uses := make([]model.HeuristicUse, len(heuristics))
+	for i, h := range heuristics {
+		uses[i] = model.HeuristicUse{Name: h.Name, Fired: fired[h.Name], Disabled: !a.enabled(h.Name)}
+	}
+	return uses
+}
+
+// disabledHeuristicsDiagnostic notes which heuristics were left off, so a
+// report never passes off the raw view as the usual one.
+func disabledHeuristicsDiagnostic(res model.AnalysisResult) string {
+	var off []string
+	for _, h := range res.Heuristics {
+		if h.Disabled {
+			off = append(off, h.Name)
+		}
+	}
+	if len(off) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("INFO: Analyzer heuristics turned off: %s. Attribution and the flow show the raw trace there.", strings.Join(off, ", "))
+}
+
+// GenerateHeuristics lists which heuristics shaped res, for the verbose
+// report.
+func GenerateHeuristics(res model.AnalysisResult) string {
+	if len(res.Heuristics) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("ANALYZER HEURISTICS\n")
+	sb.WriteString("-------------------\n")
+	for _, h := range res.Heuristics {
+		status := fmt.Sprintf("fired %d×", h.Fired)
+		switch {
+		case h.Disabled:
+			status = "disabled"
+		case h.Fired == 0:
+			status = "did not fire"
+		}
+		sb.WriteString(fmt.Sprintf("• %-12s %-13s %s\n", h.Name, status, heuristicDescription(h.Name)))
+	}
+	sb.WriteString("Turn one off with --no-heuristic=NAME to see the raw trace behavior.\n\n")
+	return sb.String()
+}
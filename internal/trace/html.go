@@ -0,0 +1,196 @@
+package trace
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// entryStatus returns the report icon and a short label for e, using the
+// same precedence and duplicate policy as the text report.
+func entryStatus(pol model.DuplicatePolicy, e model.PathEntry) (icon, label string) {
+	switch {
+	case e.IsSessionOnly:
+		return model.IconSession, "session"
+	case pol.Flagged(e) && e.IsDuplicate:
+		return model.IconDuplicate, "duplicate"
+	case pol.Flagged(e):
+		return model.IconDuplicate, "symlink duplicate"
+	case isMissing(e.Value):
+		return model.IconMissing, "missing"
+	case model.IsRelativePath(e.Value):
+		return model.IconRelative, "relative"
+	}
+	return model.IconOK, "ok"
+}
+
+// entrySource formats where e was added, e.g. "~/.zshrc:12".
+func entrySource(e model.PathEntry) string {
+	if e.LineNumber == 0 {
+		return e.SourceFile
+	}
+	return fmt.Sprintf("%s:%d", e.SourceFile, e.LineNumber)
+}
+
+// htmlStyle keeps the report self-contained: no scripts, fonts or images.
+const htmlStyle = `
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em auto; max-width: 1100px; color: #1f2328; padding: 0 1em; }
+h1 { font-size: 1.6em; border-bottom: 1px solid #d0d7de; padding-bottom: .3em; }
+h2 { font-size: 1.25em; margin-top: 1.8em; border-bottom: 1px solid #d0d7de; padding-bottom: .2em; }
+table { border-collapse: collapse; width: 100%; font-size: .9em; }
+th, td { border: 1px solid #d0d7de; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f6f8fa; }
+code, pre, .path { font-family: ui-monospace, Menlo, Consolas, monospace; }
+pre { background: #f6f8fa; padding: 1em; overflow-x: auto; font-size: .85em; }
+.duplicate { background: #fff8c5; }
+.missing, .relative { background: #ffebe9; }
+.session { background: #ddf4ff; }
+.muted { color: #656d76; }
+.flow div { padding: 2px 0; }
+ul { padding-left: 1.4em; }
+`
+
+// GenerateHTMLReport renders the report as a standalone HTML page, suitable
+// for attaching to a support ticket.
+func GenerateHTMLReport(res model.AnalysisResult) string {
+	var sb strings.Builder
+	h := html.EscapeString
+	name := res.VariableName()
+
+	sb.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n")
+	sb.WriteString(fmt.Sprintf("<title>lspath report: %s</title>\n", h(name)))
+	sb.WriteString("<style>" + htmlStyle + "</style>\n</head>\n<body>\n")
+	sb.WriteString(fmt.Sprintf("<h1>LS-PATH Analysis Report: %s</h1>\n", h(name)))
+	sb.WriteString(fmt.Sprintf("<p class=\"muted\">Generated by lspath %s</p>\n", h(model.Version)))
+	if env := res.Environment.Summary(); env != "" {
+		sb.WriteString(fmt.Sprintf("<p class=\"muted\">Traced as: %s</p>\n", h(env)))
+	}
+
+	sb.WriteString("<h2>Global Diagnostics</h2>\n<ul>\n")
+	for _, d := range res.Diagnostics {
+		sb.WriteString(fmt.Sprintf("<li>%s</li>\n", h(d)))
+	}
+	sb.WriteString("</ul>\n")
+
+	sb.WriteString(fmt.Sprintf("<h2>%s Entries (%d) in Priority Order</h2>\n", h(name), len(res.PathEntries)))
+	sb.WriteString("<table>\n<tr><th>#</th><th>Status</th><th>Directory</th><th>Added By</th><th>Confidence</th><th>Notes</th></tr>\n")
+	for i, e := range res.PathEntries {
+		icon, label := entryStatus(res.DuplicatePolicy, e)
+		var notes []string
+		if e.IsDuplicate {
+			notes = append(notes, e.DuplicateMessage)
+		} else if e.SymlinkPointsTo >= 0 {
+			notes = append(notes, e.SymlinkMessage)
+		}
+		if e.Package != "" {
+			notes = append(notes, "Installed by "+e.Package)
+		}
+		if l := VersionManagerLabel(e); l != "" {
+			notes = append(notes, l)
+		}
+		if e.Hook != nil {
+			notes = append(notes, "Added by `"+e.Hook.Command+"`")
+		}
+		notes = append(notes, e.Diagnostics...)
+		confidence := e.Confidence
+		if e.Confidence != "" && e.Confidence != model.ConfidenceHigh {
+			confidence += " - " + e.ConfidenceReason
+		}
+		sb.WriteString(fmt.Sprintf("<tr class=\"%s\"><td>%d</td><td>%s %s</td><td class=\"path\">%s</td><td class=\"path\">%s</td><td>%s</td><td>%s</td></tr>\n",
+			strings.Fields(label)[0], i+1, h(icon), h(label), h(model.DisplayPath(e.Value)), h(entrySource(e)), h(confidence), h(strings.Join(notes, "; "))))
+	}
+	sb.WriteString("</table>\n")
+
+	sb.WriteString("<h2>Issues</h2>\n")
+	issues := 0
+	lineDone := make(map[string]bool)
+	for i, e := range res.PathEntries {
+		switch {
+		case e.IsDuplicate && res.DuplicatePolicy.Flagged(e):
+			issues++
+			advice := fmt.Sprintf("remove line %d from %s", e.LineNumber, e.SourceFile)
+			if sl, ok := res.SourceLineOf(i); ok {
+				key := fmt.Sprintf("%s:%d", sl.File, sl.Line)
+				if lineDone[key] {
+					continue
+				}
+				lineDone[key] = true
+				advice = DuplicateLineAdvice(res, sl)
+			}
+			sb.WriteString(fmt.Sprintf("<p>%s <b>Duplicate</b> #%d <code>%s</code> (%s)", model.IconDuplicate, i+1, h(e.Value), h(e.DuplicateMessage)))
+			if e.LineNumber > 0 {
+				if line := getLineFromFile(e.SourceFile, e.LineNumber); line != "" {
+					sb.WriteString(fmt.Sprintf("<pre>%s:%d: %s</pre>", h(e.SourceFile), e.LineNumber, h(line)))
+				}
+				sb.WriteString(fmt.Sprintf("Advice: %s", h(advice)))
+			}
+			sb.WriteString("</p>\n")
+		case isMissing(e.Value):
+			issues++
+			sb.WriteString(fmt.Sprintf("<p>%s <b>Missing</b> #%d <code>%s</code> (from %s)</p>\n", model.IconMissing, i+1, h(e.Value), h(entrySource(e))))
+		case relativeDiagnostic(e) != "":
+			issues++
+			sb.WriteString(fmt.Sprintf("<p>%s <b>Empty or relative</b> #%d <code>%s</code> (from %s)<br>%s</p>\n",
+				model.IconRelative, i+1, h(model.DisplayPath(e.Value)), h(entrySource(e)), h(strings.Join(e.Diagnostics, " "))))
+		}
+	}
+	if issues == 0 {
+		sb.WriteString("<p>No specific issues found.</p>\n")
+	}
+
+	if len(res.Shadows) > 0 {
+		sb.WriteString(fmt.Sprintf("<h2>Shadowed Binaries (%d)</h2>\n<table>\n<tr><th>Command</th><th>Runs From</th><th>Hidden Copies</th></tr>\n", len(res.Shadows)))
+		for _, s := range res.Shadows {
+			var hidden []string
+			for _, l := range s.Losers {
+				hidden = append(hidden, fmt.Sprintf("#%d %s", l+1, res.PathEntries[l].Value))
+			}
+			sb.WriteString(fmt.Sprintf("<tr><td><code>%s</code></td><td class=\"path\">#%d %s</td><td class=\"path\">%s</td></tr>\n",
+				h(s.Name), s.Winner+1, h(res.PathEntries[s.Winner].Value), h(strings.Join(hidden, ", "))))
+		}
+		sb.WriteString("</table>\n")
+	}
+
+	if len(res.SideEffects) > 0 {
+		sb.WriteString(fmt.Sprintf("<h2>Side Effects During Trace (%d)</h2>\n<ul>\n", len(res.SideEffects)))
+		for _, e := range res.SideEffects {
+			sb.WriteString(fmt.Sprintf("<li><code>%s:%d</code> %s: <code>%s</code></li>\n", h(e.File), e.Line, h(e.Kind), h(truncateCommand(e.Command, 100))))
+		}
+		sb.WriteString("</ul>\n")
+	}
+
+	sb.WriteString("<h2>Configuration Files Flow</h2>\n<div class=\"flow\">\n")
+	for _, n := range res.FlowNodes {
+		status := fmt.Sprintf("%d entries", len(n.Entries))
+		if n.NotExecuted {
+			status = "not executed"
+		}
+		sb.WriteString(fmt.Sprintf("<div style=\"padding-left: %.1fem\">%d. <span class=\"path\">%s</span> <span class=\"muted\">%s [%s]</span>",
+			float64(n.Depth)*1.5, n.Order, h(n.FilePath), h(n.Description), status))
+		for _, s := range n.Skipped {
+			sb.WriteString(fmt.Sprintf("<div class=\"muted\">⤼ %s</div>", h(s)))
+		}
+		if len(n.Entries) > 0 && !n.NotExecuted {
+			sb.WriteString("<ul>")
+			for _, idx := range n.Entries {
+				if idx < len(res.PathEntries) {
+					icon, _ := entryStatus(res.DuplicatePolicy, res.PathEntries[idx])
+					sb.WriteString(fmt.Sprintf("<li class=\"path\">#%d %s %s</li>", idx+1, h(model.DisplayPath(res.PathEntries[idx].Value)), h(icon)))
+				}
+			}
+			sb.WriteString("</ul>")
+		}
+		sb.WriteString("</div>\n")
+	}
+	sb.WriteString("</div>\n")
+
+	if excerpts := GenerateSourceExcerpts(res); excerpts != "" {
+		sb.WriteString("<h2>Config File Excerpts</h2>\n<details><summary>Show the lines that added each entry</summary>\n")
+		sb.WriteString(fmt.Sprintf("<pre>%s</pre>\n</details>\n", h(excerpts)))
+	}
+
+	sb.WriteString("</body>\n</html>\n")
+	return sb.String()
+}
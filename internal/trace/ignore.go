@@ -0,0 +1,188 @@
+package trace
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// ignoreProblems are the problem names an ignore rule can use.
+var ignoreProblems = []string{model.IgnoreMissing, model.IgnoreBroken, model.IgnoreDuplicate, model.IgnoreRelative, model.IgnoreSession, model.IgnoreUnsafe}
+
+// IgnoreFile returns the user's ignore rules file, e.g.
+// ~/.config/lspath/ignore.
+func IgnoreFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "lspath", "ignore")
+}
+
+// ParseIgnoreRule parses one rule: a problem ("missing"), a directory
+// ("~/go/bin", all of its problems) or both ("missing=~/go/bin").
+func ParseIgnoreRule(s, source string) (model.IgnoreRule, error) {
+	rule := model.IgnoreRule{Source: source}
+	problem, dir, scoped := strings.Cut(strings.TrimSpace(s), "=")
+	if !scoped && !isIgnoreProblem(problem) {
+		problem, dir = "", problem
+	}
+	if problem != "" && !isIgnoreProblem(problem) {
+		return rule, fmt.Errorf("%s: unknown problem %q (use %s)", source, problem, strings.Join(ignoreProblems, ", "))
+	}
+	if dir != "" && !filepath.IsAbs(model.ExpandTilde(dir)) && !strings.HasPrefix(dir, "/") {
+		return rule, fmt.Errorf("%s: %q is neither a problem (%s) nor an absolute directory", source, dir, strings.Join(ignoreProblems, ", "))
+	}
+	if problem == "" && dir == "" {
+		return rule, fmt.Errorf("%s: empty ignore rule", source)
+	}
+	rule.Problem, rule.Dir = problem, dir
+	return rule, nil
+}
+
+func isIgnoreProblem(name string) bool {
+	for _, p := range ignoreProblems {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadIgnoreFile reads one rule per line, ignoring blank lines and #
+// comments. A missing file has no rules.
+func ReadIgnoreFile(file string) ([]model.IgnoreRule, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	var rules []model.IgnoreRule
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := ParseIgnoreRule(line, fmt.Sprintf("line %d of %s", n, file))
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// entryProblems lists the problems e has that an ignore rule could silence.
+func entryProblems(e model.PathEntry, pol model.DuplicatePolicy) []string {
+	var problems []string
+	switch {
+	case relativeDiagnostic(e) != "":
+		problems = append(problems, model.IgnoreRelative)
+	case e.SymlinkBroken != "":
+		problems = append(problems, model.IgnoreBroken)
+	case isMissing(e.Value):
+		problems = append(problems, model.IgnoreMissing)
+	default:
+		if unsafeOwnership(model.ExpandTilde(e.Value)) != "" {
+			problems = append(problems, model.IgnoreUnsafe)
+		}
+	}
+	if pol.IsDuplicate(e) {
+		problems = append(problems, model.IgnoreDuplicate)
+	}
+	if e.IsSessionOnly {
+		problems = append(problems, model.IgnoreSession)
+	}
+	return problems
+}
+
+// ApplyIgnores marks the problems rules silence in each entry's Ignored,
+// noting the rule in the entry's diagnostics so the details still say what
+// was ignored and why. Rules for a directory that no longer has the problem
+// are reported, so stale ones can be cleaned up.
+func ApplyIgnores(res *model.AnalysisResult, rules []model.IgnoreRule) {
+	used := make([]bool, len(rules))
+	ignored := 0
+	for i := range res.PathEntries {
+		e := &res.PathEntries[i]
+		for _, problem := range entryProblems(*e, res.DuplicatePolicy) {
+			for r, rule := range rules {
+				if rule.Problem != "" && rule.Problem != problem {
+					continue
+				}
+				if rule.Dir != "" && !model.SamePath(e.Value, rule.Dir, model.CanonOptions{}) {
+					continue
+				}
+				used[r] = true
+				e.Ignored = append(e.Ignored, problem)
+				e.Diagnostics = append(e.Diagnostics, fmt.Sprintf("Ignored problem: %s (rule from %s).", problem, rule.Source))
+				ignored++
+				break
+			}
+		}
+	}
+	if ignored > 0 {
+		res.Diagnostics = append(res.Diagnostics, fmt.Sprintf("INFO: %d problems are ignored by your ignore rules and not reported below; --check -v lists them.", ignored))
+	}
+	for r, rule := range rules {
+		if !used[r] && rule.Dir != "" {
+			res.Diagnostics = append(res.Diagnostics, fmt.Sprintf("INFO: Ignore rule %s (%s) matched nothing; it can be removed.", formatIgnoreRule(rule), rule.Source))
+		}
+	}
+}
+
+// reportedMissing reports whether e is a missing directory the user has not
+// chosen to ignore.
+func reportedMissing(e model.PathEntry) bool {
+	return isMissing(e.Value) && e.SymlinkBroken == "" && !e.IsIgnored(model.IgnoreMissing)
+}
+
+// reportedBroken reports whether e is a broken symlink the user has not
+// chosen to ignore.
+func reportedBroken(e model.PathEntry) bool {
+	return e.SymlinkBroken != "" && !e.IsIgnored(model.IgnoreBroken)
+}
+
+// missingProblem is the problem of an entry not on disk: a broken symlink
+// (whose loop os.Stat does not call missing), or a missing directory.
+func missingProblem(e model.PathEntry) string {
+	if e.SymlinkBroken != "" {
+		return model.IgnoreBroken
+	}
+	return model.IgnoreMissing
+}
+
+// missingLabel is the report's suffix for an entry not on disk, e.g.
+// " (broken symlink, ignored)", or "" for one that is.
+func missingLabel(e model.PathEntry) string {
+	label := "missing"
+	switch {
+	case e.SymlinkBroken != "":
+		label = "broken symlink"
+	case !isMissing(e.Value):
+		return ""
+	}
+	if e.IsIgnored(missingProblem(e)) {
+		label += ", ignored"
+	}
+	return " (" + label + ")"
+}
+
+// formatIgnoreRule writes rule the way ParseIgnoreRule reads it.
+func formatIgnoreRule(rule model.IgnoreRule) string {
+	switch {
+	case rule.Problem == "":
+		return rule.Dir
+	case rule.Dir == "":
+		return rule.Problem
+	}
+	return rule.Problem + "=" + rule.Dir
+}
@@ -0,0 +1,139 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"lspath/internal/model"
+)
+
+// installRoot is a user-level directory that a language toolchain installs
+// executables into (go install, npm -g, pipx, cargo install).
+type installRoot struct {
+	Name  string   // Toolchain label, e.g. "cargo install"
+	Dir   string   // Directory that must be in PATH for the tools to run
+	Tools []string // Tools found installed there
+}
+
+// toolCommandTimeout bounds helper commands such as `npm config get prefix`.
+const toolCommandTimeout = 3 * time.Second
+
+// detectInstallRoots finds the install roots on this machine that have at
+// least one tool installed.
+func detectInstallRoots() []installRoot {
+	home, _ := os.UserHomeDir()
+	var roots []installRoot
+	add := func(name, dir string, tools []string) {
+		if dir != "" && len(tools) > 0 {
+			roots = append(roots, installRoot{Name: name, Dir: dir, Tools: tools})
+		}
+	}
+
+	// Go: GOBIN, else the first GOPATH entry's bin, else ~/go/bin
+	goBin := os.Getenv("GOBIN")
+	if goBin == "" {
+		if gopath := os.Getenv("GOPATH"); gopath != "" {
+			goBin = filepath.Join(filepath.SplitList(gopath)[0], "bin")
+		} else if home != "" {
+			goBin = filepath.Join(home, "go", "bin")
+		}
+	}
+	add("go install (GOBIN)", goBin, listExecutables(goBin))
+
+	// npm: global installs link into <prefix>/bin
+	if prefix := toolOutput("npm", "config", "get", "prefix"); prefix != "" {
+		npmBin := filepath.Join(prefix, "bin")
+		add("npm global (npm prefix)", npmBin, listExecutables(npmBin))
+	}
+
+	// pipx: one venv per app, with entry points linked into PIPX_BIN_DIR
+	if home != "" {
+		pipxBin := os.Getenv("PIPX_BIN_DIR")
+		if pipxBin == "" {
+			pipxBin = filepath.Join(home, ".local", "bin")
+		}
+		var apps []string
+		for _, venvs := range []string{
+			filepath.Join(home, ".local", "pipx", "venvs"),
+			filepath.Join(home, ".local", "share", "pipx", "venvs"),
+		} {
+			dirs, _ := os.ReadDir(venvs)
+			for _, d := range dirs {
+				if d.IsDir() {
+					apps = append(apps, d.Name())
+				}
+			}
+		}
+		add("pipx", pipxBin, apps)
+	}
+
+	// cargo: CARGO_HOME/bin, default ~/.cargo/bin
+	cargoHome := os.Getenv("CARGO_HOME")
+	if cargoHome == "" && home != "" {
+		cargoHome = filepath.Join(home, ".cargo")
+	}
+	if cargoHome != "" {
+		cargoBin := filepath.Join(cargoHome, "bin")
+		add("cargo install (CARGO_HOME)", cargoBin, listExecutables(cargoBin))
+	}
+
+	return roots
+}
+
+// toolOutput runs a helper command and returns its trimmed stdout, or ""
+// if the tool is not installed, fails, or takes too long.
+func toolOutput(name string, args ...string) string {
+	if _, err := exec.LookPath(name); err != nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), toolCommandTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// checkInstallRoots labels PATH entries that are toolchain install roots and
+// advises on roots that have tools installed but are missing from PATH, with
+// the export line for the user's shell. It returns global advice lines.
+func (a *Analyzer) checkInstallRoots(entries []model.PathEntry, nodes []model.ConfigNode) []string {
+	var advice []string
+	res := model.AnalysisResult{PathEntries: entries, FlowNodes: nodes}
+	shellName := detectShellFromNodes(nodes)
+
+	for _, root := range detectInstallRoots() {
+		inPath := false
+		for i := range entries {
+			if model.SamePath(entries[i].Value, root.Dir, a.Canon) {
+				inPath = true
+				if entries[i].Package == "" {
+					entries[i].Package = root.Name
+				}
+			}
+		}
+		if inPath {
+			continue
+		}
+
+		loc := AdviseLocation(shellName, res, root.Dir)
+		advice = append(advice, fmt.Sprintf(
+			"ADVICE: %d %s tool(s) are installed in %s (%s) but it is not in PATH. Add to %s: %s",
+			len(root.Tools), root.Name, root.Dir, summarizeNames(root.Tools, 5), loc.File, loc.Snippet))
+	}
+	return advice
+}
+
+// summarizeNames joins up to limit names, noting how many were left out.
+func summarizeNames(names []string, limit int) string {
+	if len(names) <= limit {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s, +%d more", strings.Join(names[:limit], ", "), len(names)-limit)
+}
@@ -0,0 +1,174 @@
+package trace
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// ServiceLaunchd is the --context for apps started from the Finder, Dock or
+// Spotlight on macOS, which launchd starts without a shell.
+const ServiceLaunchd = "launchd"
+
+// launchdDefaultPath is the PATH launchd gives apps when nothing sets one.
+const launchdDefaultPath = "/usr/bin:/bin:/usr/sbin:/sbin"
+
+// launchdUserConfig is where `launchctl config user path` saves the PATH
+// for every app the user starts (macOS 10.10 and later).
+var launchdUserConfig = "/private/var/db/com.apple.xpc.launchd/config/user.plist"
+
+// launchAgentDirs hold the agents launchd runs at login, which are where a
+// `launchctl setenv PATH` usually lives.
+var launchAgentDirs = []string{"~/Library/LaunchAgents", "/Library/LaunchAgents"}
+
+// launchdStartupFiles are the shell startup files that may run
+// `launchctl setenv PATH` so apps started later get the terminal's PATH.
+var launchdStartupFiles = []string{"/etc/zshenv", "~/.zshenv", "/etc/zprofile", "~/.zprofile", "/etc/zshrc", "~/.zshrc", "~/.zlogin", "/etc/profile", "~/.bash_profile", "~/.bashrc", "~/.profile", "~/.config/fish/config.fish"}
+
+var (
+	setenvRe      = regexp.MustCompile(`launchctl\s+setenv\s+PATH\s+("[^"]*"|'[^']*'|\S+)`)
+	plistStringRe = regexp.MustCompile(`<string>(.*?)</string>`)
+)
+
+// plistString is a <string> in a property list and the line it is on.
+type plistString struct {
+	Line int
+	Text string
+}
+
+// plistStrings returns the strings in an XML property list, in order.
+// Binary lists are converted with plutil first, losing line numbers.
+func plistStrings(file string) []plistString {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	text, numbered := string(data), true
+	if strings.HasPrefix(text, "bplist") {
+		text, numbered = toolOutput("plutil", "-convert", "xml1", "-o", "-", file), false
+	}
+	var found []plistString
+	for i, line := range strings.Split(text, "\n") {
+		for _, m := range plistStringRe.FindAllStringSubmatch(line, -1) {
+			s := plistString{Text: html.UnescapeString(m[1])}
+			if numbered {
+				s.Line = i + 1
+			}
+			found = append(found, s)
+		}
+	}
+	return found
+}
+
+// plistPathSetenvs finds the `launchctl setenv PATH` calls in an agent's
+// ProgramArguments, run directly or through sh -c.
+func plistPathSetenvs(file string) []pathSetting {
+	strs := plistStrings(file)
+	var found []pathSetting
+	for i, s := range strs {
+		if m := setenvRe.FindStringSubmatch(s.Text); m != nil {
+			found = append(found, pathSetting{File: file, Line: s.Line, Value: unquote(m[1]), Raw: s.Text})
+			continue
+		}
+		if s.Text == "setenv" && i+2 < len(strs) && strs[i+1].Text == DefaultVariable {
+			v := strs[i+2]
+			found = append(found, pathSetting{File: file, Line: v.Line, Value: v.Text, Raw: "launchctl setenv PATH " + v.Text})
+		}
+	}
+	return found
+}
+
+// launchdSetenvs finds every `launchctl setenv PATH` in the user's launch
+// agents and shell startup files.
+func launchdSetenvs() []pathSetting {
+	var found []pathSetting
+	for _, dir := range launchAgentDirs {
+		files, _ := filepath.Glob(filepath.Join(model.ExpandTilde(dir), "*.plist"))
+		for _, file := range files {
+			found = append(found, plistPathSetenvs(file)...)
+		}
+	}
+	for _, file := range launchdStartupFiles {
+		file = model.ExpandTilde(file)
+		for i, line := range readLines(file) {
+			text := strings.TrimSpace(line)
+			if strings.HasPrefix(text, "#") {
+				continue
+			}
+			if m := setenvRe.FindStringSubmatch(text); m != nil {
+				found = append(found, pathSetting{File: file, Line: i + 1, Value: unquote(m[1]), Raw: text})
+			}
+		}
+	}
+	return found
+}
+
+// launchdPathSettings returns where the PATH of apps launched from the
+// Finder or Dock comes from: launchd's default, `launchctl config user
+// path`, then the PATH launchd holds now (`launchctl setenv`), attributed to
+// the agent or startup file that set it when one can be found.
+func launchdPathSettings() ([]pathSetting, []string) {
+	settings := []pathSetting{{File: "launchd (built-in default)", Value: launchdDefaultPath, Raw: "PATH=" + launchdDefaultPath}}
+	var notes []string
+
+	// <key>PathEnvironmentVariable</key> <string>/usr/local/bin:...</string>
+	for i, line := range readLines(launchdUserConfig) {
+		if !strings.Contains(line, "<key>PathEnvironmentVariable</key>") {
+			continue
+		}
+		for _, s := range plistStrings(launchdUserConfig) {
+			if s.Line >= i+1 {
+				settings = append(settings, pathSetting{File: launchdUserConfig, Line: s.Line, Value: s.Text, Raw: "launchctl config user path " + s.Text})
+				break
+			}
+		}
+		break
+	}
+
+	for i, line := range readLines("/etc/launchd.conf") {
+		if strings.Contains(line, "setenv PATH") {
+			notes = append(notes, fmt.Sprintf("WARN: /etc/launchd.conf line %d sets PATH, but macOS has ignored that file since 10.10. Use `sudo launchctl config user path` instead.", i+1))
+		}
+	}
+
+	setenvs := launchdSetenvs()
+	current := toolOutput("launchctl", "getenv", DefaultVariable)
+	switch {
+	case current != "":
+		set := pathSetting{File: "launchctl setenv", Value: current, Raw: "launchctl setenv PATH " + current}
+		var exact, guessed *pathSetting
+		for i, s := range setenvs {
+			// A shell expands $PATH and the like before launchd sees the
+			// value, so a call in a startup file can only be guessed at
+			if s.Value == current {
+				exact = &setenvs[i]
+			} else if strings.Contains(s.Value, "$") && !strings.HasSuffix(s.File, ".plist") {
+				guessed = &setenvs[i]
+			}
+		}
+		switch {
+		case exact != nil:
+			set.File, set.Line, set.Raw = exact.File, exact.Line, exact.Raw
+		case guessed != nil:
+			set.File, set.Line, set.Raw = guessed.File, guessed.Line, guessed.Raw
+			notes = append(notes, fmt.Sprintf("INFO: launchd's PATH was most likely set by %s line %d; the shell expanded its value when it ran, so it cannot be matched exactly.", guessed.File, guessed.Line))
+		default:
+			notes = append(notes, "INFO: launchd's PATH was set with `launchctl setenv`, but no launch agent or startup file does it; it was run by hand or by an app, and lasts until you log out.")
+		}
+		settings = append(settings, set)
+	case len(setenvs) > 0:
+		for _, s := range setenvs {
+			notes = append(notes, fmt.Sprintf("INFO: %s line %d runs `launchctl setenv PATH`, but launchd has no PATH set now; it takes effect once that runs, for apps started afterwards.", s.File, s.Line))
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(pathHelperDir, "paths")); err == nil {
+		notes = append(notes, "INFO: /etc/paths and /etc/paths.d are read by path_helper, which only login shells run, so apps launched from the Finder or Dock do not get those entries.")
+	}
+	return settings, notes
+}
@@ -0,0 +1,156 @@
+package trace
+
+import (
+	"fmt"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// mdCell escapes text for a Markdown table cell.
+func mdCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+// mdCode wraps s in backticks, using a longer fence if s contains one.
+func mdCode(s string) string {
+	if strings.Contains(s, "`") {
+		return "`` " + s + " ``"
+	}
+	return "`" + s + "`"
+}
+
+// GenerateMarkdownReport renders the report as GitHub-flavored Markdown for
+// issues and wikis: tables for entries, fenced blocks for source lines, and
+// collapsible sections for the longer detail.
+func GenerateMarkdownReport(res model.AnalysisResult) string {
+	var sb strings.Builder
+	name := res.VariableName()
+
+	sb.WriteString(fmt.Sprintf("# LS-PATH Analysis Report: %s\n\n", name))
+	sb.WriteString(fmt.Sprintf("_Generated by lspath %s_\n\n", model.Version))
+	if env := res.Environment.Summary(); env != "" {
+		sb.WriteString(fmt.Sprintf("Traced as: %s\n\n", mdCell(env)))
+	}
+
+	sb.WriteString("## Global Diagnostics\n\n")
+	for _, d := range res.Diagnostics {
+		sb.WriteString(fmt.Sprintf("- %s\n", d))
+	}
+	sb.WriteString("\n")
+
+	sb.WriteString(fmt.Sprintf("## %s Entries (%d) in Priority Order\n\n", name, len(res.PathEntries)))
+	sb.WriteString("| # | Status | Directory | Added By | Confidence |\n")
+	sb.WriteString("|--:|:--|:--|:--|:--|\n")
+	for i, e := range res.PathEntries {
+		icon, label := entryStatus(res.DuplicatePolicy, e)
+		confidence := e.Confidence
+		if e.Confidence != "" && e.Confidence != model.ConfidenceHigh {
+			confidence += ": " + e.ConfidenceReason
+		}
+		sb.WriteString(fmt.Sprintf("| %d | %s %s | %s | %s | %s |\n",
+			i+1, icon, label, mdCell(mdCode(model.DisplayPath(e.Value))), mdCell(entrySource(e)), mdCell(confidence)))
+	}
+	sb.WriteString("\n")
+
+	sb.WriteString("## Issues\n\n")
+	issues := 0
+	lineDone := make(map[string]bool)
+	for i, e := range res.PathEntries {
+		switch {
+		case e.IsDuplicate && res.DuplicatePolicy.Flagged(e):
+			advice := ""
+			if sl, ok := res.SourceLineOf(i); ok {
+				key := fmt.Sprintf("%s:%d", sl.File, sl.Line)
+				if lineDone[key] {
+					continue
+				}
+				lineDone[key] = true
+				advice = DuplicateLineAdvice(res, sl)
+			}
+			issues++
+			sb.WriteString(fmt.Sprintf("### %s Duplicate #%d %s\n\n", model.IconDuplicate, i+1, mdCode(e.Value)))
+			sb.WriteString(e.DuplicateMessage + ".\n\n")
+			if e.LineNumber > 0 {
+				if line := getLineFromFile(e.SourceFile, e.LineNumber); line != "" {
+					sb.WriteString(fmt.Sprintf("Line %d of %s:\n\n```sh\n%s\n```\n\n", e.LineNumber, mdCode(e.SourceFile), line))
+				}
+			}
+			if advice != "" {
+				sb.WriteString(fmt.Sprintf("**Advice:** %s\n\n", advice))
+			}
+		case isMissing(e.Value):
+			issues++
+			sb.WriteString(fmt.Sprintf("### %s Missing #%d %s\n\nAdded by %s; the directory does not exist.\n\n", model.IconMissing, i+1, mdCode(e.Value), mdCode(entrySource(e))))
+		case relativeDiagnostic(e) != "":
+			issues++
+			sb.WriteString(fmt.Sprintf("### %s Empty or relative #%d %s\n\n", model.IconRelative, i+1, mdCode(model.DisplayPath(e.Value))))
+			for _, d := range e.Diagnostics {
+				sb.WriteString(fmt.Sprintf("- %s\n", d))
+			}
+			sb.WriteString("\n")
+		}
+	}
+	if issues == 0 {
+		sb.WriteString("No specific issues found.\n\n")
+	}
+
+	if len(res.Shadows) > 0 {
+		sb.WriteString(fmt.Sprintf("<details>\n<summary>Shadowed binaries (%d)</summary>\n\n", len(res.Shadows)))
+		sb.WriteString("| Command | Runs From | Hidden Copies |\n|:--|:--|:--|\n")
+		for _, s := range res.Shadows {
+			var hidden []string
+			for _, l := range s.Losers {
+				hidden = append(hidden, fmt.Sprintf("#%d %s", l+1, res.PathEntries[l].Value))
+			}
+			sb.WriteString(fmt.Sprintf("| %s | #%d %s | %s |\n", mdCell(mdCode(s.Name)), s.Winner+1, mdCell(res.PathEntries[s.Winner].Value), mdCell(strings.Join(hidden, ", "))))
+		}
+		sb.WriteString("\n</details>\n\n")
+	}
+
+	if len(res.SideEffects) > 0 {
+		sb.WriteString(fmt.Sprintf("## Side Effects During Trace (%d)\n\n", len(res.SideEffects)))
+		for _, e := range res.SideEffects {
+			sb.WriteString(fmt.Sprintf("- %s %s: %s\n", mdCode(fmt.Sprintf("%s:%d", e.File, e.Line)), e.Kind, mdCode(truncateCommand(e.Command, 100))))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("## Configuration Files Flow\n\n")
+	for _, n := range res.FlowNodes {
+		status := fmt.Sprintf("%d paths", len(n.Entries))
+		if n.NotExecuted {
+			status = "not executed"
+		}
+		desc := ""
+		if n.Description != "" {
+			desc = " " + n.Description
+		}
+		sb.WriteString(fmt.Sprintf("%s%d. %s%s [%s]\n", strings.Repeat("   ", n.Depth), n.Order, mdCode(n.FilePath), desc, status))
+	}
+	sb.WriteString("\n")
+
+	sb.WriteString("<details>\n<summary>Entries added by each file</summary>\n\n")
+	for _, n := range res.FlowNodes {
+		if len(n.Entries) == 0 || n.NotExecuted {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("**%s**\n\n", n.FilePath))
+		for _, idx := range n.Entries {
+			if idx < len(res.PathEntries) {
+				e := res.PathEntries[idx]
+				icon, _ := entryStatus(res.DuplicatePolicy, e)
+				sb.WriteString(fmt.Sprintf("- #%d %s %s\n", idx+1, mdCode(model.DisplayPath(e.Value)), icon))
+			}
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("</details>\n")
+
+	if excerpts := GenerateSourceExcerpts(res); excerpts != "" {
+		sb.WriteString("\n<details>\n<summary>Config file excerpts</summary>\n\n```\n")
+		sb.WriteString(strings.TrimRight(excerpts, "\n"))
+		sb.WriteString("\n```\n\n</details>\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,150 @@
+package trace
+
+import (
+	"fmt"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// ShellModes are the four modes a shell can start in, named after what
+// usually starts it in each.
+func ShellModes() []LaunchContext {
+	return []LaunchContext{
+		{Name: "Terminal.app, SSH logins, tmux", Login: true, Interactive: true},
+		{Name: "Desktop session, bash -lc", Login: true},
+		{Name: "Most Linux terminals, subshells", Interactive: true},
+		{Name: "cron, scripts, IDE tasks, ssh host cmd"},
+	}
+}
+
+// modeGroups name the sets of modes an entry can be limited to.
+var modeGroups = []struct {
+	label string
+	in    func(c LaunchContext) bool
+}{
+	{"login interactive shells only", func(c LaunchContext) bool { return c.Login && c.Interactive }},
+	{"login shells only", func(c LaunchContext) bool { return c.Login }},
+	{"interactive shells only", func(c LaunchContext) bool { return c.Interactive }},
+	{"every mode but plain non-interactive shells", func(c LaunchContext) bool { return c.Login || c.Interactive }},
+	{"non-login shells only", func(c LaunchContext) bool { return !c.Login }},
+	{"non-interactive shells only", func(c LaunchContext) bool { return !c.Interactive }},
+}
+
+// modesLabel describes the modes in has, e.g. "interactive shells only",
+// or lists their columns.
+func modesLabel(results []ContextResult, has []bool) string {
+	for _, g := range modeGroups {
+		matches := true
+		for i, r := range results {
+			if r.Err == nil && g.in(r.Context) != has[i] {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return g.label
+		}
+	}
+	var cols []string
+	for i := range results {
+		if has[i] {
+			cols = append(cols, contextColumn(i))
+		}
+	}
+	return "only in " + strings.Join(cols, ", ")
+}
+
+// modeAdvice says how shell's startup files can give every mode the same
+// entries.
+func modeAdvice(shell string) string {
+	switch shell {
+	case "zsh":
+		return "zsh reads ~/.zshenv in every mode, and ~/.zprofile only in login shells and ~/.zshrc only in interactive ones. Export what scripts, cron and IDEs need from ~/.zshenv (keep it quick: every zsh script runs it)."
+	case "bash":
+		return "Login bash reads ~/.bash_profile (or ~/.profile), interactive bash ~/.bashrc, and non-interactive bash only the file $BASH_ENV names. Have ~/.bash_profile source ~/.bashrc, and set what scripts and cron need in the job itself or through BASH_ENV."
+	case "fish":
+		return "fish reads config.fish in every mode; entries limited to some modes are set inside `status is-login` or `status is-interactive` blocks."
+	}
+	return "A login shell reads ~/.profile and an interactive one the file $ENV names; a non-interactive shell reads neither, so scripts and cron need their PATH set in the job itself."
+}
+
+// FormatModeComparison reports how the variable differs between the shell
+// modes traced in results (see ShellModes): the entries that only some
+// modes get, where each comes from, and what shell's startup files can do
+// about it.
+func FormatModeComparison(results []ContextResult, shell string) string {
+	var sb strings.Builder
+	name := DefaultVariable
+	for _, r := range results {
+		if r.Err == nil {
+			name = r.Result.VariableName()
+			break
+		}
+	}
+	title := "SHELL MODES - " + name
+	sb.WriteString(title + "\n" + strings.Repeat("=", len(title)) + "\n\n")
+	for i, r := range results {
+		status := fmt.Sprintf("%d entries", len(r.Result.PathEntries))
+		if r.Err != nil {
+			status = fmt.Sprintf("trace failed: %v", r.Err)
+		}
+		sb.WriteString(fmt.Sprintf("%-3s %-23s %-40s %s\n", contextColumn(i), r.Context.Mode(), r.Context.Name, status))
+	}
+	sb.WriteString("\n")
+
+	// Union of entries in order of first appearance, and where each came from
+	var rows []string
+	display := make(map[string]string)
+	source := make(map[string]model.PathEntry)
+	present := make(map[string][]bool)
+	for i, r := range results {
+		for _, e := range r.Result.PathEntries {
+			key := model.CanonicalPath(e.Value, model.CanonOptions{})
+			if _, ok := present[key]; !ok {
+				present[key] = make([]bool, len(results))
+				display[key] = model.DisplayPath(e.Value)
+				source[key] = e
+				rows = append(rows, key)
+			}
+			present[key][i] = true
+		}
+	}
+
+	var partial []string
+	for _, key := range rows {
+		for i, r := range results {
+			if r.Err == nil && !present[key][i] {
+				partial = append(partial, key)
+				break
+			}
+		}
+	}
+	if len(partial) == 0 {
+		sb.WriteString(fmt.Sprintf("Every mode gets the same %s entries, so scripts, cron jobs and IDEs see what your terminal sees.\n", name))
+		return sb.String()
+	}
+
+	sb.WriteString("ENTRIES NOT IN EVERY MODE\n-------------------------\n")
+	missingNonInteractive := 0
+	for _, key := range partial {
+		e := source[key]
+		from := e.SourceFile
+		if e.LineNumber > 0 {
+			from = fmt.Sprintf("%s:%d", e.SourceFile, e.LineNumber)
+		}
+		sb.WriteString(fmt.Sprintf("%s\n    %s; added by %s\n", display[key], modesLabel(results, present[key]), from))
+		for i, r := range results {
+			if r.Err == nil && !r.Context.Interactive && !r.Context.Login && !present[key][i] {
+				missingNonInteractive++
+				break
+			}
+		}
+	}
+	sb.WriteString("\n")
+	if missingNonInteractive > 0 {
+		sb.WriteString(fmt.Sprintf("%d of these are missing when nothing interactive starts the shell: a cron job, a script or an IDE's build task won't find the commands in them, though your terminal does.\n", missingNonInteractive))
+	}
+	sb.WriteString(modeAdvice(shell) + "\n")
+	return sb.String()
+}
@@ -0,0 +1,129 @@
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"lspath/internal/model"
+)
+
+// Oneline is what --oneline needs from an analysis. It is cached between
+// runs so prompts and status bars don't pay for a trace every time.
+type Oneline struct {
+	Variable    string
+	Entries     []string         // Entry values in priority order
+	Flagged     []bool           // Whether each entry is a duplicate under the policy
+	StartupTime time.Duration    // How long the traced shell took to start
+	Key         string           // Inputs the analysis depended on; see onelineKey
+	Files       map[string]int64 // Traced config files and their modification times
+}
+
+// NewOneline extracts the summary of res. startup is how long the trace took.
+func NewOneline(res model.AnalysisResult, opts Options, startup time.Duration) Oneline {
+	o := Oneline{Variable: res.VariableName(), StartupTime: startup, Key: onelineKey(opts), Files: make(map[string]int64)}
+	for _, e := range res.PathEntries {
+		o.Entries = append(o.Entries, e.Value)
+		o.Flagged = append(o.Flagged, res.DuplicatePolicy.Flagged(e))
+	}
+	for _, n := range res.FlowNodes {
+		if n.FilePath != "" {
+			o.Files[n.FilePath] = modTime(n.FilePath)
+		}
+	}
+	return o
+}
+
+// String formats the summary, e.g. "PATH: 23 entries · 2 dup · 1 missing ·
+// 310ms startup". Missing directories are checked now, not when cached, and
+// count as in the report: a flagged duplicate is not also missing.
+func (o Oneline) String() string {
+	dups, missing := 0, 0
+	for i, v := range o.Entries {
+		if o.Flagged[i] {
+			dups++
+		} else if isMissing(v) {
+			missing++
+		}
+	}
+	return fmt.Sprintf("%s: %d entries · %d dup · %d missing · %dms startup",
+		o.Variable, len(o.Entries), dups, missing, o.StartupTime.Milliseconds())
+}
+
+// onelineKey identifies the settings and session an analysis was made for.
+// A cached summary is only reused for the same key.
+func onelineKey(opts Options) string {
+	variable := opts.Var
+	if variable == "" {
+		variable = DefaultVariable
+	}
+	session := opts.SessionPath
+	if session == "" {
+		session = os.Getenv(variable)
+	}
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%s\x00%+v", model.Version, os.Getenv("SHELL"), variable, session, opts.Duplicates)
+}
+
+// modTime returns file's modification time, or 0 if it doesn't exist, so
+// creating a file that was looked for also invalidates the cache.
+func modTime(file string) int64 {
+	info, err := os.Stat(model.ExpandTilde(file))
+	if err != nil {
+		return 0
+	}
+	return info.ModTime().UnixNano()
+}
+
+// onelineCachePath is where the summary for variable is cached.
+func onelineCachePath(variable string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "lspath", "oneline-"+variable+".json"), nil
+}
+
+// CachedOneline returns the cached summary for opts if no traced config file
+// has changed since it was made.
+func CachedOneline(opts Options) (Oneline, bool) {
+	var o Oneline
+	variable := opts.Var
+	if variable == "" {
+		variable = DefaultVariable
+	}
+	path, err := onelineCachePath(variable)
+	if err != nil {
+		return o, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || json.Unmarshal(data, &o) != nil {
+		return o, false
+	}
+	if o.Key != onelineKey(opts) || len(o.Flagged) != len(o.Entries) {
+		return o, false
+	}
+	for file, mtime := range o.Files {
+		if modTime(file) != mtime {
+			return o, false
+		}
+	}
+	return o, true
+}
+
+// SaveOneline caches o for CachedOneline.
+func SaveOneline(o Oneline) error {
+	path, err := onelineCachePath(o.Variable)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
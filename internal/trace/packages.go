@@ -0,0 +1,80 @@
+package trace
+
+import (
+	"fmt"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// systemPackage describes a macOS installer that adds PATH entries,
+// usually through a file in /etc/paths.d read by path_helper.
+type systemPackage struct {
+	Name    string
+	Matches func(p string) bool
+	IsXcode bool // Part of the Xcode / Command Line Tools family (goes stale on removal)
+	Fix     string
+}
+
+var systemPackages = []systemPackage{
+	{
+		Name:    "Munki managed software (/etc/paths.d/munki)",
+		Matches: func(p string) bool { return strings.HasPrefix(p, "/usr/local/munki") },
+	},
+	{
+		Name:    "XQuartz (/etc/paths.d/40-XQuartz)",
+		Matches: func(p string) bool { return strings.HasPrefix(p, "/opt/X11") },
+	},
+	{
+		Name: "Xcode",
+		Matches: func(p string) bool {
+			return strings.HasPrefix(p, "/Applications/Xcode") && strings.Contains(p, ".app/Contents/Developer")
+		},
+		IsXcode: true,
+		Fix:     "reinstall Xcode, or run 'sudo xcode-select --reset' and remove this entry",
+	},
+	{
+		Name:    "Xcode Command Line Tools",
+		Matches: func(p string) bool { return strings.HasPrefix(p, "/Library/Developer/CommandLineTools") },
+		IsXcode: true,
+		Fix:     "run 'xcode-select --install', or remove this entry",
+	},
+	{
+		Name:    "Apple rvictl (/etc/paths.d/100-rvictl)",
+		Matches: func(p string) bool { return p == "/Library/Apple/usr/bin" },
+	},
+	{
+		Name:    "MacTeX (/etc/paths.d/TeX)",
+		Matches: func(p string) bool { return strings.HasPrefix(p, "/Library/TeX") },
+	},
+}
+
+// lookupSystemPackage returns the installer responsible for path, if known.
+func lookupSystemPackage(path string) (systemPackage, bool) {
+	for _, pkg := range systemPackages {
+		if pkg.Matches(path) {
+			return pkg, true
+		}
+	}
+	return systemPackage{}, false
+}
+
+// attributePackages labels entries installed by known system packages and
+// flags Xcode-related entries left behind after Xcode was removed.
+// It returns global advice lines for the report.
+func attributePackages(entries []model.PathEntry) []string {
+	var advice []string
+	for i := range entries {
+		e := &entries[i]
+		pkg, ok := lookupSystemPackage(e.Value)
+		if !ok {
+			continue
+		}
+		e.Package = pkg.Name
+		if pkg.IsXcode && isMissing(model.ExpandTilde(e.Value)) {
+			e.Diagnostics = append(e.Diagnostics, fmt.Sprintf("Stale %s path: the toolchain is no longer installed.", pkg.Name))
+			advice = append(advice, fmt.Sprintf("ADVICE: PATH entry #%d (%s) points to a removed %s - %s.", i+1, e.Value, pkg.Name, pkg.Fix))
+		}
+	}
+	return advice
+}
@@ -2,6 +2,7 @@ package trace
 
 import (
 	"bufio"
+	"fmt"
 	"io"
 	"regexp"
 	"strconv"
@@ -12,17 +13,64 @@ import (
 
 // Parser handles the parsing of shell trace output.
 type Parser struct {
-	re *regexp.Regexp
+	re   *regexp.Regexp
+	fish bool // fish_trace output has its own format (see parser_fish.go)
+	zsh  bool // zsh can also change PATH through its path array (see parser_zsh.go)
+	csh  bool // csh echoes commands without file or line (see parser_csh.go)
+	sh   bool // POSIX sh and most kshs trace no file name (see parser_posix.go)
+
+	profiles []string // Startup files an sh trace is matched against
+	name     string   // The shell's name, which prefixes its error messages
+	last     model.TraceEvent
+
+	// Variable is the PATH-like variable whose assignments are reported
+	// as PathChange events. Defaults to PATH.
+	Variable string
+
+	// Baseline is the PATH the traced shell started from. Defaults to
+	// DetectBaseline's.
+	Baseline string
+
+	// HashTable is the shell's hash table as listed after startup (see
+	// withHashListing). It is complete once the event channel is closed.
+	HashTable []model.HashedCommand
+
+	// Stats counts the lines Parse read. It is complete once the event
+	// channel is closed.
+	Stats model.ParserStats
 }
 
+// maxSkippedSamples and maxSampleLen bound the skipped lines kept in
+// ParserStats, which end up in reports users share.
+const (
+	maxSkippedSamples = 5
+	maxSampleLen      = 120
+	maxShellErrors    = 10
+)
+
 // NewParser creates a new Parser with the appropriate regex for the shell.
 func NewParser(shell Shell) *Parser {
 	// Pattern: .*?(\++) ?(.*?):(\d+)>(.*)
 	// Matches:
 	// + file:10>command
 	// ...garbage...+ file:10>command
+	_, isFish := shell.(*FishShell)
+	_, isZsh := shell.(*ZshShell)
+	_, isCsh := shell.(*CshShell)
+	isSh := unnamedFiles(shell)
+	var profiles []string
+	if isSh {
+		profiles = profileFiles(shell)
+	}
 	return &Parser{
-		re: regexp.MustCompile(`.*?(\++)(?: )?([^:]+):(\d+)>(.*)`),
+		re:       regexp.MustCompile(`.*?(\++)(?: )?([^:]+):(\d+)>(.*)`),
+		fish:     isFish,
+		zsh:      isZsh,
+		csh:      isCsh,
+		sh:       isSh,
+		profiles: profiles,
+		name:     shell.Name(),
+		Variable: DefaultVariable,
 	}
 }
 
@@ -41,10 +89,60 @@ func (p *Parser) Parse(r io.Reader) (chan model.TraceEvent, chan error) {
 		buf := make([]byte, 0, 1024*1024)
 		scanner.Buffer(buf, 10*1024*1024) // 10MB max line, should be enough
 
+		initial := ""
+		if p.Variable == DefaultVariable {
+			initial = p.Baseline
+			if initial == "" {
+				initial = DetectBaseline()
+			}
+		}
+		// Shells whose traces lack PS4's file and line have their own parsers
+		var parseLine func(string) (model.TraceEvent, bool)
+		switch {
+		case p.fish:
+			parseLine = newFishState(initial, p.Variable).parseLine
+		case p.csh:
+			parseLine = newCshState(p.Variable).parseLine
+		case p.sh:
+			parseLine = newPosixState(p.Variable, p.profiles).parseLine
+		}
+		var zsh *zshPathState
+		if p.zsh {
+			zsh = newZshPathState(initial, p.Variable)
+		}
+
+		inHashListing := false
 		for scanner.Scan() {
 			line := scanner.Text()
+			switch {
+			case line == hashBeginMarker:
+				inHashListing = true
+				continue
+			case line == hashEndMarker:
+				inHashListing = false
+				continue
+			case inHashListing:
+				// Its trace lines are lspath's own commands, not startup's
+				if h, ok := parseHashLine(line); ok {
+					p.HashTable = append(p.HashTable, h)
+				}
+				continue
+			}
+			p.Stats.LinesRead++
+			if parseLine != nil {
+				if ev, ok := parseLine(line); ok {
+					p.count(ev.PathChange != "")
+					p.last = ev
+					events <- ev
+				} else {
+					p.skip(line)
+				}
+				continue
+			}
 			matches := p.re.FindStringSubmatch(line)
-			if len(matches) == 5 {
+			if len(matches) != 5 {
+				p.skip(line)
+			} else {
 				depthStr := matches[1]
 				file := matches[2]
 				lineNumStr := matches[3]
@@ -53,58 +151,11 @@ func (p *Parser) Parse(r io.Reader) (chan model.TraceEvent, chan error) {
 				depth := len(depthStr)
 				lineNum, _ := strconv.Atoi(lineNumStr)
 
-				// We are looking for PATH changes.
-				// Heuristic: command starts with "PATH=" or "export PATH="
-				// Or "typeset -x PATH=" etc.
-				// Simple heuristic: contains "PATH="
+				// We are looking for changes to the analyzed variable.
 				// The trace expands variables, so we see "PATH=/foo:/bar"
-
-				pathChange := ""
-				// Identify if this is a PATH assignment
-				var isPathChange bool
-				var value string
-
-				// 1. Direct Assignment: PATH='...' or export PATH='...'
-				// Regex to capture value inside optional quotes.
-				// Handles: PATH=val, PATH='val', export PATH="val"
-				// Note: cmd is the rest of the trace line.
-				// We look for "PATH=" pattern.
-
-				// Find start of "PATH="
-				idx := strings.Index(cmd, "PATH=")
-				if idx != -1 {
-					// Safety check: Needs to be start of string or preceded by space/export
-					valid := false
-					if idx == 0 {
-						valid = true
-					} else if idx > 0 && (cmd[idx-1] == ' ' || strings.HasSuffix(cmd[:idx], "export ")) {
-						// Ensure it's not SOMEOTHERPATH=
-						if idx > 0 && cmd[idx-1] != ' ' {
-							// potential suffix match like MYPATH=
-							// Check character before
-							// If it was "export PATH=", preceding char is space.
-							// parsing "match whole word PATH" is tricky without regex.
-							// simpler: check if character before P is space or delimiter.
-							r := cmd[idx-1]
-							if r == ' ' || r == ';' {
-								valid = true
-							}
-						} else {
-							valid = true
-						}
-					}
-
-					if valid {
-						// Extract everything after PATH=
-						// Value might be quoted.
-						rhs := cmd[idx+5:]
-						value = cleanPathValue(rhs)
-						isPathChange = true
-					}
-				}
-
-				if isPathChange {
-					pathChange = value
+				pathChange, assigned := assignedValue(cmd, p.Variable)
+				if zsh != nil {
+					pathChange = zsh.update(cmd, pathChange, assigned)
 				}
 
 				event := model.TraceEvent{
@@ -114,6 +165,8 @@ func (p *Parser) Parse(r io.Reader) (chan model.TraceEvent, chan error) {
 					RawCommand: cmd,
 					PathChange: pathChange,
 				}
+				p.count(assigned || pathChange != "")
+				p.last = event
 				events <- event
 			}
 		}
@@ -125,6 +178,52 @@ func (p *Parser) Parse(r io.Reader) (chan model.TraceEvent, chan error) {
 	return events, errs
 }
 
+// count records a line that matched the trace format.
+func (p *Parser) count(assigned bool) {
+	p.Stats.LinesMatched++
+	if assigned {
+		p.Stats.PathEvents++
+	}
+}
+
+// skip records a line that did not match the trace format, keeping the
+// first few as samples, and the first few errors from the shell.
+func (p *Parser) skip(line string) {
+	p.Stats.LinesSkipped++
+	if msg, ok := p.shellError(line); ok && len(p.Stats.ShellErrors) < maxShellErrors {
+		p.Stats.ShellErrors = append(p.Stats.ShellErrors, msg)
+	}
+	if len(p.Stats.SkippedSamples) < maxSkippedSamples && strings.TrimSpace(line) != "" {
+		if r := []rune(line); len(r) > maxSampleLen {
+			line = string(r[:maxSampleLen]) + "…"
+		}
+		p.Stats.SkippedSamples = append(p.Stats.SkippedSamples, line)
+	}
+}
+
+// assignedValue returns the value assigned to variable by a traced command,
+// e.g. PATH=val, PATH='val', export PATH="val" or typeset -x PATH=val.
+// Longer names that merely end in the variable (MANPATH= when looking for
+// PATH) are skipped.
+func assignedValue(cmd, variable string) (string, bool) {
+	// eval's arguments are code not yet expanded, traced again as it runs
+	if strings.HasPrefix(cmd, "eval ") {
+		return "", false
+	}
+	prefix := variable + "="
+	for off := 0; ; {
+		idx := strings.Index(cmd[off:], prefix)
+		if idx == -1 {
+			return "", false
+		}
+		idx += off
+		if idx == 0 || cmd[idx-1] == ' ' || cmd[idx-1] == ';' {
+			return cleanPathValue(cmd[idx+len(prefix):]), true
+		}
+		off = idx + len(prefix)
+	}
+}
+
 func cleanPathValue(v string) string {
 	// Remove quotes if present
 	v = strings.TrimPrefix(v, "'")
@@ -133,3 +232,75 @@ func cleanPathValue(v string) string {
 	v = strings.TrimSuffix(v, "\"")
 	return v
 }
+
+// parserDiagnostic warns when the trace had output but none of it was in
+// the shell's trace format, which leaves every entry unattributed.
+func parserDiagnostic(stats model.ParserStats, shell Shell) string {
+	if stats.LinesRead == 0 || stats.LinesMatched > 0 {
+		return ""
+	}
+	return fmt.Sprintf("WARNING: None of the %d lines the %s trace printed were in the expected trace format, so nothing could be attributed. A startup file may set PS4 or turn tracing off, or the shell ignored the PS4 lspath passed it (bash does as root); lspath -v shows samples of the lines.", stats.LinesRead, shell.Name())
+}
+
+// GenerateParserStats summarizes what the parser made of the raw trace, for
+// the verbose report.
+func GenerateParserStats(res model.AnalysisResult) string {
+	s := res.Parser
+	if s.LinesRead == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("TRACE PARSER\n")
+	sb.WriteString("------------\n")
+	sb.WriteString(fmt.Sprintf("• %d lines read, %d in the trace format, %d skipped\n", s.LinesRead, s.LinesMatched, s.LinesSkipped))
+	sb.WriteString(fmt.Sprintf("• %d assignments of %s recognized\n", s.PathEvents, res.VariableName()))
+	if len(s.SkippedSamples) > 0 {
+		sb.WriteString("Skipped lines (first few):\n")
+		for _, line := range s.SkippedSamples {
+			sb.WriteString(fmt.Sprintf("  %q\n", line))
+		}
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+var (
+	// shellErrorFileRe matches an error naming its file and line:
+	// "/home/u/.bashrc: line 3: ..." (bash, ksh) or "/home/u/.zshrc:3: ..." (zsh).
+	shellErrorFileRe = regexp.MustCompile(`^([/~]\S*?)(?:: line |:)(\d+): (.+)$`)
+	// shellErrorLineRe matches dash's "3: ..." once its name is stripped.
+	shellErrorLineRe = regexp.MustCompile(`^(\d+): (.+)$`)
+	// shellNoiseRe matches complaints about running without a terminal,
+	// which every trace has.
+	shellNoiseRe = regexp.MustCompile(`(?i)job control|terminal process group|access tty|no tty`)
+)
+
+// shellError recognizes an error message from the traced shell among the
+// lines that are not trace output, as "file:line: message". Errors that do
+// not say where they are (bash's "bash: foo: command not found") are placed
+// at the command traced just before them.
+func (p *Parser) shellError(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if shellNoiseRe.MatchString(line) {
+		return "", false
+	}
+	rest, named := strings.CutPrefix(line, p.name+": ")
+	if m := shellErrorFileRe.FindStringSubmatch(rest); m != nil {
+		if strings.HasPrefix(m[3], "`") { // bash quoting the line it just reported
+			return "", false
+		}
+		return truncateCommand(fmt.Sprintf("%s:%s: %s", m[1], m[2], m[3]), maxSampleLen), true
+	}
+	if !named || rest == "" {
+		return "", false
+	}
+	file, lineNo := p.last.File, p.last.Line
+	if m := shellErrorLineRe.FindStringSubmatch(rest); m != nil {
+		lineNo, _ = strconv.Atoi(m[1])
+		rest = m[2]
+	}
+	if file == "" {
+		return truncateCommand(rest, maxSampleLen), true
+	}
+	return truncateCommand(fmt.Sprintf("%s:%d: %s", file, lineNo, rest), maxSampleLen), true
+}
@@ -0,0 +1,109 @@
+package trace
+
+import (
+	"regexp"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// cshSourceRe matches the lines the csh trace driver prints before it
+// sources each startup file: "+/etc/csh.cshrc:0>source /etc/csh.cshrc".
+var cshSourceRe = regexp.MustCompile(`^\+(.+):0>source `)
+
+// cshSetPathRe matches csh's path array being set, as echoed after variable
+// substitution: "set path = ( /opt/bin /usr/bin /bin )".
+var cshSetPathRe = regexp.MustCompile(`^set\s+(?:-[a-z]\s+)*path\s*=\s*\((.*)\)\s*$`)
+
+// cshState tracks what csh's echo output leaves out: which file is
+// executing and how far into each file we have matched lines.
+type cshState struct {
+	stack    []string // Files being sourced, innermost last
+	variable string
+	cursors  map[string]int // file -> last matched line number
+	lines    map[string][]string
+}
+
+func newCshState(variable string) *cshState {
+	return &cshState{
+		variable: variable,
+		cursors:  make(map[string]int),
+		lines:    make(map[string][]string),
+	}
+}
+
+// parseLine turns a line of the csh trace into a TraceEvent. Lines before
+// the driver's first marker are the shell's own output and are skipped.
+func (st *cshState) parseLine(line string) (model.TraceEvent, bool) {
+	if m := cshSourceRe.FindStringSubmatch(line); m != nil {
+		st.stack = []string{m[1]}
+		return model.TraceEvent{File: m[1], Depth: 1, RawCommand: strings.TrimPrefix(line, "+"+m[1]+":0>")}, true
+	}
+	cmd := strings.TrimSpace(line)
+	if len(st.stack) == 0 || cmd == "" || cmd == "unset echo" {
+		return model.TraceEvent{}, false
+	}
+
+	fields := strings.Fields(cmd)
+	if len(fields) >= 2 && fields[0] == "source" {
+		file := model.ExpandTilde(strings.Trim(fields[len(fields)-1], `"'`))
+		ev := model.TraceEvent{File: st.stack[len(st.stack)-1], Depth: len(st.stack), RawCommand: cmd}
+		st.stack = append(st.stack, file)
+		return ev, true
+	}
+
+	ev := model.TraceEvent{File: st.stack[len(st.stack)-1], Depth: len(st.stack), RawCommand: cmd}
+	if value, ok := cshAssignedValue(cmd, st.variable); ok {
+		ev.PathChange = value
+		// A nested file with no assignment left in it has returned
+		for {
+			ev.File, ev.Depth = st.stack[len(st.stack)-1], len(st.stack)
+			if ev.Line = st.findLine(ev.File); ev.Line > 0 || len(st.stack) == 1 {
+				break
+			}
+			st.stack = st.stack[:len(st.stack)-1]
+		}
+	}
+	return ev, true
+}
+
+// cshAssignedValue returns the new value of variable set by an echoed csh
+// command: `setenv PATH value`, or for PATH, `set path = ( dirs )`.
+func cshAssignedValue(cmd, variable string) (string, bool) {
+	if variable == DefaultVariable {
+		if m := cshSetPathRe.FindStringSubmatch(cmd); m != nil {
+			var dirs []string
+			for _, d := range strings.Fields(m[1]) {
+				dirs = append(dirs, model.ExpandTilde(strings.Trim(d, `"'`)))
+			}
+			return strings.Join(dirs, ":"), true
+		}
+	}
+	fields := strings.Fields(cmd)
+	if len(fields) >= 2 && fields[0] == "setenv" && fields[1] == variable {
+		return cleanPathValue(strings.Join(fields[2:], " ")), true
+	}
+	return "", false
+}
+
+// findLine locates the line of file that set the variable, since csh's
+// echo output has no line numbers. It scans forward from the previous match
+// for the next line that mentions the variable.
+func (st *cshState) findLine(file string) int {
+	lines, ok := st.lines[file]
+	if !ok {
+		lines = readLines(file)
+		st.lines[file] = lines
+	}
+	for n := st.cursors[file]; n < len(lines); n++ {
+		text := strings.TrimSpace(lines[n])
+		if strings.HasPrefix(text, "#") {
+			continue
+		}
+		if strings.Contains(text, st.variable) || (st.variable == DefaultVariable && strings.Contains(text, "path")) {
+			st.cursors[file] = n + 1
+			return n + 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,211 @@
+package trace
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// fishStartupFile labels commands traced before fish sources any file.
+const fishStartupFile = "fish (built-in startup)"
+
+// fishTraceRe matches fish_trace output lines: "----> set -gx PATH /a /b".
+// The number of dashes is the nesting depth.
+var fishTraceRe = regexp.MustCompile(`^(-+)> (.*)$`)
+
+type fishFrame struct {
+	file  string
+	depth int // Depth of the source command; lines deeper than this belong to file
+}
+
+// fishState tracks what fish_trace leaves out: which file is executing,
+// the current PATH list, and how far into each file we have matched lines.
+type fishState struct {
+	stack    []fishFrame
+	variable string // Variable being tracked (PATH unless --var was given)
+	path     []string
+	cursors  map[string]int // file -> last matched line number
+	lines    map[string][]string
+}
+
+func newFishState(initialPath, variable string) *fishState {
+	st := &fishState{
+		variable: variable,
+		cursors:  make(map[string]int),
+		lines:    make(map[string][]string),
+	}
+	for _, p := range strings.Split(initialPath, ":") {
+		if p != "" {
+			st.path = append(st.path, p)
+		}
+	}
+	return st
+}
+
+// parseLine turns a fish_trace line into a TraceEvent.
+func (st *fishState) parseLine(line string) (model.TraceEvent, bool) {
+	m := fishTraceRe.FindStringSubmatch(line)
+	if m == nil {
+		return model.TraceEvent{}, false
+	}
+	depth := len(m[1])
+	cmd := m[2]
+
+	// Leaving a sourced file: pop frames we are no longer nested inside
+	for len(st.stack) > 0 && depth <= st.stack[len(st.stack)-1].depth {
+		st.stack = st.stack[:len(st.stack)-1]
+	}
+
+	file := fishStartupFile
+	if len(st.stack) > 0 {
+		file = st.stack[len(st.stack)-1].file
+	}
+
+	fields := fishFields(cmd)
+	ev := model.TraceEvent{
+		File:       file,
+		Depth:      depth,
+		RawCommand: cmd,
+	}
+
+	if src := fishSourcedFile(fields); src != "" {
+		st.stack = append(st.stack, fishFrame{file: src, depth: depth})
+		return ev, true
+	}
+
+	if newPath, ok := st.applySet(fields); ok {
+		ev.PathChange = strings.Join(newPath, ":")
+		ev.Line = st.findLine(file)
+	}
+	return ev, true
+}
+
+// fishSourcedFile returns the file argument of a source command, if any.
+func fishSourcedFile(fields []string) string {
+	if len(fields) > 0 && fields[0] == "builtin" {
+		fields = fields[1:]
+	}
+	if len(fields) >= 2 && (fields[0] == "source" || fields[0] == ".") && fields[1] != "-" {
+		return fields[1]
+	}
+	return ""
+}
+
+// applySet interprets "set [flags] PATH values..." (or the tracked variable)
+// and returns the new value.
+func (st *fishState) applySet(fields []string) ([]string, bool) {
+	if len(fields) < 2 || fields[0] != "set" {
+		return nil, false
+	}
+
+	prepend, appendMode, erase := false, false, false
+	i := 1
+	for ; i < len(fields) && strings.HasPrefix(fields[i], "-"); i++ {
+		switch f := fields[i]; {
+		case f == "--prepend" || (!strings.HasPrefix(f, "--") && strings.Contains(f, "p")):
+			prepend = true
+		case f == "--append" || (!strings.HasPrefix(f, "--") && strings.Contains(f, "a")):
+			appendMode = true
+		case f == "--erase" || (!strings.HasPrefix(f, "--") && strings.Contains(f, "e")):
+			erase = true
+		}
+	}
+	if i >= len(fields) || fields[i] != st.variable || erase {
+		return nil, false
+	}
+
+	var values []string
+	for _, v := range fields[i+1:] {
+		// fish accepts colon-joined values for PATH variables
+		for _, p := range strings.Split(v, ":") {
+			if p != "" {
+				values = append(values, p)
+			}
+		}
+	}
+
+	switch {
+	case prepend:
+		st.path = append(values, st.path...)
+	case appendMode:
+		st.path = append(st.path, values...)
+	default:
+		st.path = values
+	}
+	return append([]string(nil), st.path...), true
+}
+
+// findLine locates the source line responsible for a PATH change in file,
+// since fish_trace does not report line numbers. It scans forward from the
+// previous match for the next line that mentions the tracked variable.
+func (st *fishState) findLine(file string) int {
+	lines, ok := st.lines[file]
+	if !ok {
+		lines = readLines(file)
+		st.lines[file] = lines
+	}
+	for n := st.cursors[file]; n < len(lines); n++ {
+		text := strings.TrimSpace(lines[n])
+		if strings.HasPrefix(text, "#") {
+			continue
+		}
+		if strings.Contains(text, "PATH") || strings.Contains(text, "fish_add_path") {
+			st.cursors[file] = n + 1
+			return n + 1
+		}
+	}
+	return 0
+}
+
+func readLines(file string) []string {
+	f, err := os.Open(model.ExpandTilde(file))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+// fishFields splits a traced fish command into arguments, removing the
+// quoting fish_trace adds around values with special characters.
+func fishFields(cmd string) []string {
+	var fields []string
+	var cur strings.Builder
+	var quote rune
+	inField := false
+	for _, r := range cmd {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inField = true
+		case r == ' ' || r == '\t':
+			if inField {
+				fields = append(fields, cur.String())
+				cur.Reset()
+				inField = false
+			}
+		default:
+			cur.WriteRune(r)
+			inField = true
+		}
+	}
+	if inField {
+		fields = append(fields, cur.String())
+	}
+	return fields
+}
@@ -0,0 +1,206 @@
+package trace
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// posixTraceRe matches the xtrace lines of PosixShell's PS4, "+sh:12>cmd".
+// dash leaves LINENO empty there ("+sh:>cmd") and never names the file.
+var posixTraceRe = regexp.MustCompile(`^(\++)sh:(\d*)>(.*)$`)
+
+type posixFrame struct {
+	file   string
+	cursor int    // Line last matched
+	key    string // commandKey of the command matched there
+}
+
+// posixState works out which startup file each traced command came from,
+// since sh's trace does not say. A login sh reads /etc/profile and then
+// ~/.profile, and an interactive one the file $ENV names; files they source
+// with . are followed. A command belongs to the first file, innermost first,
+// with a line that runs it.
+type posixState struct {
+	stack    []posixFrame
+	queue    []string // Top-level startup files not reached yet
+	variable string
+	lines    map[string][]string
+}
+
+func newPosixState(variable string, files []string) *posixState {
+	return &posixState{
+		queue:    files,
+		variable: variable,
+		lines:    make(map[string][]string),
+	}
+}
+
+// unnamedFiles reports whether shell's trace leaves out the file each
+// command is in, so its parser must match commands to files by content.
+func unnamedFiles(shell Shell) bool {
+	switch s := shell.(type) {
+	case *PosixShell:
+		return true
+	case *KshShell:
+		return !s.FileVar
+	}
+	return false
+}
+
+// profileFiles lists the top-level startup files of shell, in the order it
+// reads them. The Korn shells read ~/.kshrc (mksh ~/.mkshrc) when $ENV is
+// unset.
+func profileFiles(shell Shell) []string {
+	files := []string{"/etc/profile", model.ExpandTilde("~/.profile")}
+	env := os.Getenv("ENV")
+	if _, ok := shell.(*KshShell); ok && env == "" {
+		env = "~/.kshrc"
+		if shell.Name() == "mksh" {
+			env = "~/.mkshrc"
+		}
+	}
+	if env != "" {
+		files = append(files, model.ExpandTilde(env))
+	}
+	return files
+}
+
+// parseLine turns a line of sh's trace into a TraceEvent.
+func (st *posixState) parseLine(line string) (model.TraceEvent, bool) {
+	m := posixTraceRe.FindStringSubmatch(line)
+	if m == nil {
+		return model.TraceEvent{}, false
+	}
+	lineNo, _ := strconv.Atoi(m[2])
+	cmd := m[3]
+
+	ev := model.TraceEvent{Depth: len(m[1]), RawCommand: cmd}
+	ev.File, ev.Line = st.locate(cmd, lineNo)
+	ev.PathChange, _ = assignedValue(cmd, st.variable)
+
+	fields := strings.Fields(cmd)
+	if len(fields) >= 2 && (fields[0] == "." || fields[0] == "source") {
+		st.stack = append(st.stack, posixFrame{file: model.ExpandTilde(fields[1])})
+	}
+	return ev, true
+}
+
+// locate finds the file and line of cmd. lineNo, if known, is where it is
+// in its file. Frames are searched forward, innermost first, and a match in
+// an outer frame means the inner files have returned. Failing that the
+// current file is searched backwards (a loop going round again), then the
+// top-level files still to come.
+func (st *posixState) locate(cmd string, lineNo int) (string, int) {
+	key := commandKey(cmd)
+	for i := len(st.stack) - 1; i >= 0; i-- {
+		if n := st.find(st.stack[i], key, lineNo, true); n > 0 {
+			st.stack = st.stack[:i+1]
+			st.stack[i].cursor, st.stack[i].key = n, key
+			return st.stack[i].file, n
+		}
+	}
+	if len(st.stack) > 0 {
+		top := &st.stack[len(st.stack)-1]
+		if n := st.find(*top, key, lineNo, false); n > 0 {
+			top.cursor, top.key = n, key
+			return top.file, n
+		}
+	}
+	for i, file := range st.queue {
+		if n := st.find(posixFrame{file: file}, key, lineNo, true); n > 0 {
+			st.stack = []posixFrame{{file: file, cursor: n, key: key}}
+			st.queue = st.queue[i+1:]
+			return file, n
+		}
+	}
+	// Nothing matched: stay in the current file, line unknown
+	if len(st.stack) == 0 && len(st.queue) > 0 {
+		st.stack = []posixFrame{{file: st.queue[0]}}
+		st.queue = st.queue[1:]
+	}
+	if len(st.stack) == 0 {
+		return "sh", 0
+	}
+	return st.stack[len(st.stack)-1].file, 0
+}
+
+// find returns the 1-based line of f's file, searching forward or backward
+// from f's cursor, that could have run a command starting with key, or 0.
+// The cursor line itself counts for a different command, as in
+// `if [ "$(id -u)" -eq 0 ]`. If lineNo is known that line is tried first;
+// shells differ on whether LINENO counts from the top of a sourced file.
+func (st *posixState) find(f posixFrame, key string, lineNo int, forward bool) int {
+	lines, ok := st.lines[f.file]
+	if !ok {
+		lines = readLines(f.file)
+		st.lines[f.file] = lines
+	}
+	matches := func(n int) bool {
+		text := strings.TrimSpace(lines[n-1])
+		return !strings.HasPrefix(text, "#") && containsWord(text, key) && (n != f.cursor || key != f.key)
+	}
+	if lineNo > 0 {
+		if lineNo <= len(lines) && (lineNo >= f.cursor) == forward && matches(lineNo) {
+			return lineNo
+		}
+	}
+	if forward {
+		for n := max(f.cursor, 1); n <= len(lines); n++ {
+			if matches(n) {
+				return n
+			}
+		}
+	} else {
+		for n := min(f.cursor, len(lines)); n >= 1; n-- {
+			if matches(n) {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+// commandKey is the part of a traced command that appears unexpanded in
+// the file: the command name, or "NAME=" for an assignment.
+func commandKey(cmd string) string {
+	fields := strings.Fields(cmd)
+	if len(fields) == 0 {
+		return ""
+	}
+	first := fields[0]
+	if (first == "export" || first == "readonly" || first == "local") && len(fields) > 1 && strings.Contains(fields[1], "=") {
+		first = fields[1]
+	}
+	if name, _, ok := strings.Cut(first, "="); ok {
+		return name + "="
+	}
+	return first
+}
+
+// containsWord reports whether text contains word not run into other
+// letters, digits or underscores. An assignment key ("NAME=") may be
+// followed by anything.
+func containsWord(text, word string) bool {
+	if word == "" {
+		return false
+	}
+	isWord := func(b byte) bool {
+		return b == '_' || (b >= '0' && b <= '9') || (b|0x20 >= 'a' && b|0x20 <= 'z')
+	}
+	for off := 0; ; {
+		i := strings.Index(text[off:], word)
+		if i < 0 {
+			return false
+		}
+		i += off
+		end := i + len(word)
+		if (i == 0 || !isWord(text[i-1])) && (end == len(text) || strings.HasSuffix(word, "=") || !isWord(text[end])) {
+			return true
+		}
+		off = i + 1
+	}
+}
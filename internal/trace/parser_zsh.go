@@ -0,0 +1,169 @@
+package trace
+
+import (
+	"regexp"
+	"strings"
+)
+
+// zshTiedArrays maps variables to the array zsh ties them to: assigning
+// path=(...) sets PATH, and typeset -U path dedupes both.
+var zshTiedArrays = map[string]string{
+	"PATH":        "path",
+	"MANPATH":     "manpath",
+	"FPATH":       "fpath",
+	"CDPATH":      "cdpath",
+	"MODULE_PATH": "module_path",
+}
+
+// zshPathState tracks the traced variable through zsh array assignments,
+// which xtrace shows as "path=( /a /b )" or "path+=( /c )" rather than as a
+// PATH= assignment, and through typeset -U, which silently drops repeats.
+type zshPathState struct {
+	array   string
+	arrayRe *regexp.Regexp
+	value   []string
+	unique  bool
+}
+
+// newZshPathState returns nil if variable has no tied array.
+func newZshPathState(initial, variable string) *zshPathState {
+	array, ok := zshTiedArrays[variable]
+	if !ok {
+		return nil
+	}
+	return &zshPathState{
+		array: array,
+		// path=( ... ), path+=( ... ) and the prepend idiom path[1,0]=( ... )
+		arrayRe: regexp.MustCompile(`(?:^|[ ;])` + array + `(\+|\[1,0\])?=\((.*?)\)`),
+		value:   splitNonEmpty(initial),
+	}
+}
+
+// splitNonEmpty splits a colon-separated list, dropping empty segments.
+func splitNonEmpty(list string) []string {
+	var out []string
+	for _, p := range strings.Split(list, ":") {
+		if p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// update follows cmd, whose scalar assignment to the variable (if any) is
+// assigned, and returns the variable's new value if cmd changed it.
+func (z *zshPathState) update(cmd, assigned string, isAssign bool) string {
+	switch {
+	case isAssign:
+		z.value = splitNonEmpty(assigned)
+		if z.unique && z.dedupe() {
+			return strings.Join(z.value, ":")
+		}
+		return assigned
+
+	case z.arrayRe.MatchString(cmd):
+		m := z.arrayRe.FindStringSubmatch(cmd)
+		words := zshWords(m[2])
+		switch m[1] {
+		case "+":
+			z.value = append(z.value, words...)
+		case "[1,0]":
+			z.value = append(words, z.value...)
+		default:
+			z.value = words
+		}
+		if z.unique {
+			z.dedupe()
+		}
+		return strings.Join(z.value, ":")
+
+	case z.setsUnique(cmd):
+		if z.dedupe() {
+			return strings.Join(z.value, ":")
+		}
+	}
+	return ""
+}
+
+// setsUnique reports whether cmd is a typeset that turns the array's
+// unique flag on (-U) or off (+U), and records it.
+func (z *zshPathState) setsUnique(cmd string) bool {
+	fields := strings.Fields(cmd)
+	if len(fields) < 2 {
+		return false
+	}
+	switch fields[0] {
+	case "typeset", "declare", "local", "export":
+	default:
+		return false
+	}
+	flag, named := "", false
+	for _, f := range fields[1:] {
+		name, _, _ := strings.Cut(f, "=")
+		switch {
+		case (strings.HasPrefix(f, "-") || strings.HasPrefix(f, "+")) && strings.Contains(f, "U"):
+			flag = f[:1]
+		case name == z.array || name == strings.ToUpper(z.array):
+			named = true
+		}
+	}
+	if flag == "" || !named {
+		return false
+	}
+	z.unique = flag == "-"
+	return true
+}
+
+// dedupe drops repeated entries, keeping the first, and reports whether any
+// were dropped.
+func (z *zshPathState) dedupe() bool {
+	seen := make(map[string]bool)
+	var out []string
+	for _, p := range z.value {
+		if !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	changed := len(out) != len(z.value)
+	z.value = out
+	return changed
+}
+
+// zshWords splits the words of an array as xtrace prints them: separated by
+// spaces, with single quotes or backslashes around special characters.
+func zshWords(s string) []string {
+	var words []string
+	var cur strings.Builder
+	inWord, quoted := false, false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quoted:
+			if c == '\'' {
+				quoted = false
+			} else {
+				cur.WriteByte(c)
+			}
+		case c == '\'':
+			quoted, inWord = true, true
+		case c == '\\' && i+1 < len(s):
+			i++
+			cur.WriteByte(s[i])
+			inWord = true
+		case c == ' ' || c == '\t':
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words
+}
@@ -0,0 +1,149 @@
+package trace
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// pathHelperDir holds the files macOS path_helper builds PATH from.
+var pathHelperDir = "/etc"
+
+// pathHelperFiles returns the files path_helper reads, in its order:
+// /etc/paths, then /etc/paths.d/* sorted by name.
+func pathHelperFiles() []string {
+	files := []string{filepath.Join(pathHelperDir, "paths")}
+	dir := filepath.Join(pathHelperDir, "paths.d")
+	list, err := os.ReadDir(dir) // Sorted by name, as path_helper reads them
+	if err != nil {
+		return files
+	}
+	for _, f := range list {
+		if !f.IsDir() {
+			files = append(files, filepath.Join(dir, f.Name()))
+		}
+	}
+	return files
+}
+
+// pathHelperListing is where a directory is listed for path_helper.
+type pathHelperListing struct {
+	File string
+	Line int
+}
+
+// readPathHelperListings maps each directory in path_helper's files to the
+// first place it is listed.
+func (a *Analyzer) readPathHelperListings() map[string]pathHelperListing {
+	listings := make(map[string]pathHelperListing)
+	for _, file := range pathHelperFiles() {
+		f, err := os.Open(file)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		for n := 1; scanner.Scan(); n++ {
+			dir := strings.TrimSpace(scanner.Text())
+			if dir == "" || strings.HasPrefix(dir, "#") {
+				continue
+			}
+			key := model.CanonicalPath(dir, a.Canon)
+			if _, ok := listings[key]; !ok {
+				listings[key] = pathHelperListing{File: file, Line: n}
+			}
+		}
+		f.Close()
+	}
+	return listings
+}
+
+// expandPathHelper attributes entries added by a path_helper call (as in
+// macOS's /etc/zprofile and /etc/profile) to the /etc/paths or /etc/paths.d
+// file that lists them, with a flow node per file below the node that ran
+// path_helper. Entries already in PATH before the call keep their source.
+func (a *Analyzer) expandPathHelper(entries []model.PathEntry, nodes []model.ConfigNode) []model.ConfigNode {
+	isCall := make(map[string]bool) // "file:line" -> runs path_helper
+	callsPathHelper := func(e model.PathEntry) bool {
+		key := fmt.Sprintf("%s:%d", e.SourceFile, e.LineNumber)
+		called, ok := isCall[key]
+		if !ok {
+			called = e.LineNumber > 0 && strings.Contains(getLineFromFile(e.SourceFile, e.LineNumber), "path_helper")
+			isCall[key] = called
+		}
+		return called
+	}
+
+	var listings map[string]pathHelperListing
+	children := make(map[string][]model.ConfigNode) // Parent node ID -> nodes for path_helper's files
+	for i := range entries {
+		e := &entries[i]
+		if !callsPathHelper(*e) {
+			continue
+		}
+		if listings == nil {
+			listings = a.readPathHelperListings()
+		}
+		l, ok := listings[model.CanonicalPath(e.Value, a.Canon)]
+		if !ok {
+			continue // Came from the PATH path_helper started with
+		}
+
+		var parent *model.ConfigNode
+		for j := range nodes {
+			if nodes[j].ID == e.FlowID {
+				parent = &nodes[j]
+				break
+			}
+		}
+		if parent == nil {
+			continue
+		}
+		var child *model.ConfigNode
+		for j := range children[parent.ID] {
+			if children[parent.ID][j].FilePath == l.File {
+				child = &children[parent.ID][j]
+				break
+			}
+		}
+		if child == nil {
+			children[parent.ID] = append(children[parent.ID], model.ConfigNode{
+				ID:          fmt.Sprintf("%s.%d", parent.ID, len(children[parent.ID])+1),
+				FilePath:    l.File,
+				Depth:       parent.Depth + 1,
+				Entries:     []int{},
+				Description: "(read by path_helper)",
+				Note:        fmt.Sprintf("path_helper, run from line %d of %s, puts the directories listed in /etc/paths and /etc/paths.d/* ahead of the rest of PATH.", e.LineNumber, e.SourceFile),
+			})
+			child = &children[parent.ID][len(children[parent.ID])-1]
+		}
+
+		e.Confidence = model.ConfidenceHigh
+		e.ConfidenceReason = fmt.Sprintf("Listed on line %d of %s, which path_helper read when line %d of %s ran it", l.Line, l.File, e.LineNumber, e.SourceFile)
+		e.SourceFile = l.File
+		e.LineNumber = l.Line
+		e.FlowID = child.ID
+	}
+	if len(children) == 0 {
+		return nodes
+	}
+
+	// path_helper's files go right after the file that ran it, in the order
+	// path_helper read them
+	order := make(map[string]int)
+	for i, f := range pathHelperFiles() {
+		order[f] = i
+	}
+	var expanded []model.ConfigNode
+	for _, n := range nodes {
+		expanded = append(expanded, n)
+		kids := children[n.ID]
+		sort.SliceStable(kids, func(i, j int) bool { return order[kids[i].FilePath] < order[kids[j].FilePath] })
+		expanded = append(expanded, kids...)
+	}
+	return expanded
+}
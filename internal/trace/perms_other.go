@@ -0,0 +1,32 @@
+//go:build !windows
+
+package trace
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// unsafeOwnership explains why someone other than the user or root can put
+// commands in dir, or returns "" if only they can.
+func unsafeOwnership(dir string) string {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return ""
+	}
+	if info.Mode().Perm()&0002 != 0 {
+		return "writable by every user"
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || st.Uid == 0 || int(st.Uid) == os.Getuid() {
+		return ""
+	}
+	owner := strconv.Itoa(int(st.Uid))
+	if u, err := user.LookupId(owner); err == nil {
+		owner = u.Username
+	}
+	return fmt.Sprintf("owned by %s, not you or root", owner)
+}
@@ -0,0 +1,196 @@
+package trace
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// PinsFile returns the default pins file for variable:
+// ~/.config/lspath/pins for PATH, ~/.config/lspath/pins-MANPATH and so on
+// for other variables.
+func PinsFile(variable string) string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	name := "pins"
+	if variable != "" && variable != DefaultVariable {
+		name += "-" + variable
+	}
+	return filepath.Join(dir, "lspath", name)
+}
+
+// readPins reads one directory per line, in the order they must appear,
+// ignoring blank lines and # comments.
+func readPins(file string) ([]model.Pin, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var pins []model.Pin
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pins = append(pins, model.Pin{Dir: line, Line: n, Entry: -1})
+	}
+	return pins, scanner.Err()
+}
+
+// CheckPins checks res against the pins in file (the default PinsFile if
+// empty, where a missing file just means nothing is pinned), recording them
+// in res.Pins and warning about each one that does not hold: a pinned
+// directory missing from PATH, or appearing after one pinned below it.
+func CheckPins(res *model.AnalysisResult, file string) error {
+	explicit := file != ""
+	if !explicit {
+		file = PinsFile(res.VariableName())
+	}
+	pins, err := readPins(file)
+	if err != nil {
+		if !explicit && os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("reading pins: %w", err)
+	}
+
+	for i := range pins {
+		for j, e := range res.PathEntries {
+			if model.SamePath(e.Value, pins[i].Dir, model.CanonOptions{}) {
+				pins[i].Entry = j
+				break
+			}
+		}
+	}
+
+	for i := range pins {
+		p := &pins[i]
+		if p.Entry < 0 {
+			p.Broken = fmt.Sprintf("%s is not in %s", p.Dir, res.VariableName())
+			res.Diagnostics = append(res.Diagnostics, fmt.Sprintf("WARNING: Pinned directory %s (line %d of %s) is not in %s.", p.Dir, p.Line, file, res.VariableName()))
+			continue
+		}
+		// Report the earliest entry this pin should be ahead of but isn't
+		var ahead *model.Pin
+		for j := i + 1; j < len(pins); j++ {
+			if q := &pins[j]; q.Entry >= 0 && q.Entry < p.Entry && (ahead == nil || q.Entry < ahead.Entry) {
+				ahead = q
+			}
+		}
+		if ahead == nil {
+			continue
+		}
+		p.Broken = fmt.Sprintf("%s must come before %s, but is entry #%d and %s is #%d", p.Dir, ahead.Dir, p.Entry+1, ahead.Dir, ahead.Entry+1)
+		if res.PathEntries[ahead.Entry].IsSessionOnly {
+			p.Broken += " (added in this session, not by a startup file)"
+		}
+		res.Diagnostics = append(res.Diagnostics, fmt.Sprintf("WARNING: Pinned order broken (line %d of %s): %s. Run lspath --fix to restore it.", p.Line, file, p.Broken))
+		e := &res.PathEntries[p.Entry]
+		e.Diagnostics = append(e.Diagnostics, fmt.Sprintf("Pinned ahead of %s in %s, but comes after it.", ahead.Dir, file))
+	}
+	res.Pins = pins
+	return nil
+}
+
+// PinAdvice returns the startup file and the snippet that restore the pinned
+// order. The snippet moves every pinned directory to the front, in order, so
+// it belongs at the end of the last startup file the shell runs, after
+// anything that could reorder PATH again.
+func PinAdvice(shellName string, res model.AnalysisResult) (file, snippet string) {
+	var dirs []string
+	for _, p := range res.Pins {
+		dirs = append(dirs, portablePath(p.Dir))
+	}
+	file = AdviseLocation(shellName, res, "").File
+	if shellName != "fish" && shellName != "pwsh" {
+		if last := lastUserStartupFile(res.FlowNodes); last != "" {
+			file = last
+		}
+	}
+	return file, pinSnippet(shellName, res.VariableName(), dirs)
+}
+
+// lastUserStartupFile returns the last of the user's own startup files
+// (~/.zshrc, ~/.bashrc, ...) that the trace ran.
+func lastUserStartupFile(nodes []model.ConfigNode) string {
+	for i := len(nodes) - 1; i >= 0; i-- {
+		n := nodes[i]
+		if n.NotExecuted || strings.HasPrefix(n.FilePath, "/etc/") {
+			continue
+		}
+		for _, std := range append(append([]standardConfig{}, zshStandard...), bashStandard...) {
+			if strings.HasSuffix(n.FilePath, std.PathSuffix) {
+				return n.FilePath
+			}
+		}
+	}
+	return ""
+}
+
+// portablePath writes paths under the home directory relative to $HOME.
+func portablePath(dir string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return dir
+	}
+	expanded := model.ExpandTilde(dir)
+	if expanded == home || strings.HasPrefix(expanded, home+"/") {
+		return "$HOME" + strings.TrimPrefix(expanded, home)
+	}
+	return dir
+}
+
+// pinSnippet builds the lines that move dirs to the front of variable, in
+// order, removing their later copies.
+func pinSnippet(shellName, variable string, dirs []string) string {
+	quoted := make([]string, len(dirs))
+	switch shellName {
+	case "fish":
+		for i, d := range dirs {
+			quoted[i] = `"` + d + `"`
+		}
+		if variable == DefaultVariable {
+			return "fish_add_path --move --path " + strings.Join(quoted, " ")
+		}
+		reversed := make([]string, len(quoted))
+		for i, q := range quoted {
+			reversed[len(quoted)-1-i] = q
+		}
+		return fmt.Sprintf("for d in %s; set -gx %s $d (string match -v -- $d $%s); end", strings.Join(reversed, " "), variable, variable)
+	case "pwsh":
+		for i, d := range dirs {
+			quoted[i] = `"` + d + `"`
+		}
+		list := "@(" + strings.Join(quoted, ", ") + ")"
+		return fmt.Sprintf("$env:%s = (%s + ($env:%s -split [IO.Path]::PathSeparator | Where-Object { $_ -notin %s })) -join [IO.Path]::PathSeparator",
+			variable, list, variable, list)
+	case "tcsh", "csh":
+		// csh has no string substitution; filter the old value with grep
+		for i, d := range dirs {
+			quoted[len(dirs)-1-i] = `"` + d + `"`
+		}
+		return fmt.Sprintf("foreach _d ( %s )\n  setenv %s \"${_d}:`printenv %s | tr : '\\n' | grep -vxF -- $_d | paste -sd: -`\"\nend\nunset _d",
+			strings.Join(quoted, " "), variable, variable)
+	case "sh", "dash", "ash", "ksh", "ksh93", "mksh", "oksh":
+		// POSIX sh and OpenBSD's ksh have no ${var//pattern/}; filter the old value with grep
+		for i, d := range dirs {
+			quoted[len(dirs)-1-i] = `"` + d + `"`
+		}
+		return fmt.Sprintf(`for _d in %s; do %s="$_d:$(printf '%%s\n' "$%s" | tr : '\n' | grep -vxF -- "$_d" | paste -sd: -)"; done; export %s; unset _d`,
+			strings.Join(quoted, " "), variable, variable, variable)
+	}
+	// Prepend in reverse so the first pin ends up first
+	for i, d := range dirs {
+		quoted[len(dirs)-1-i] = `"` + d + `"`
+	}
+	return fmt.Sprintf(`_p=":$%s:"; for _d in %s; do _p=":$_d${_p//:$_d:/:}"; done; _p="${_p#:}"; export %s="${_p%%:}"; unset _p _d`,
+		variable, strings.Join(quoted, " "), variable)
+}
@@ -0,0 +1,141 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"lspath/internal/model"
+)
+
+// pluginTimeout is how long a plugin may take before it is killed.
+const pluginTimeout = 10 * time.Second
+
+// PluginDir returns the directory whose executables run after every
+// analysis, e.g. ~/.config/lspath/plugins.
+func PluginDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "lspath", "plugins")
+}
+
+// FindPlugins lists the executables in dir, in name order. A missing
+// directory has none.
+func FindPlugins(dir string) []string {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var plugins []string
+	for _, f := range files {
+		info, err := f.Info()
+		if err != nil || info.IsDir() || strings.HasPrefix(f.Name(), ".") {
+			continue
+		}
+		if runtime.GOOS == "windows" {
+			switch strings.ToLower(filepath.Ext(f.Name())) {
+			case ".exe", ".bat", ".cmd":
+				plugins = append(plugins, filepath.Join(dir, f.Name()))
+			}
+		} else if info.Mode().Perm()&0111 != 0 {
+			plugins = append(plugins, filepath.Join(dir, f.Name()))
+		}
+	}
+	return plugins
+}
+
+// pluginOutput is what a plugin writes to stdout:
+//
+//	{"diagnostics": [{"severity": "error", "message": "...", "entry": 3}]}
+//
+// entry is the 1-based entry number the report shows, or 0 (or absent) for
+// the result as a whole; severity is error, warning or info.
+type pluginOutput struct {
+	Diagnostics []struct {
+		Severity string `json:"severity"`
+		Message  string `json:"message"`
+		Entry    int    `json:"entry"`
+	} `json:"diagnostics"`
+}
+
+// severityPrefixes start the Diagnostics line of a plugin finding.
+var severityPrefixes = map[string]string{SeverityError: "ERROR: ", SeverityWarning: "WARN: ", SeverityInfo: "INFO: "}
+
+// RunPlugins runs each plugin with res on stdin, as --json writes it, and
+// adds what it reports to res: to PluginFindings, which --check counts, and
+// to the global or entry diagnostics reports show. A plugin that fails or
+// writes something else is a warning finding of its own, and the rest
+// still run.
+func RunPlugins(res *model.AnalysisResult, plugins []string) {
+	if len(plugins) == 0 {
+		return
+	}
+	input, err := MarshalDocument(*res, 0)
+	if err != nil {
+		res.Diagnostics = append(res.Diagnostics, fmt.Sprintf("WARN: Plugins not run: %v", err))
+		return
+	}
+	for _, plugin := range plugins {
+		name := filepath.Base(plugin)
+		out, err := runPlugin(plugin, input)
+		if err != nil {
+			// A broken rule should not pass a --check silently
+			res.PluginFindings = append(res.PluginFindings, model.PluginFinding{Plugin: name, Severity: SeverityWarning, Entry: -1, Message: fmt.Sprintf("plugin failed: %v", err)})
+			res.Diagnostics = append(res.Diagnostics, fmt.Sprintf("WARN: Plugin %s failed: %v", name, err))
+			continue
+		}
+		for _, d := range out.Diagnostics {
+			f := model.PluginFinding{Plugin: name, Severity: strings.ToLower(d.Severity), Entry: d.Entry - 1, Message: d.Message}
+			if _, ok := severityPrefixes[f.Severity]; !ok {
+				f.Severity = SeverityWarning
+			}
+			if f.Entry < 0 || f.Entry >= len(res.PathEntries) {
+				f.Entry = -1
+			}
+			res.PluginFindings = append(res.PluginFindings, f)
+			if f.Entry < 0 {
+				res.Diagnostics = append(res.Diagnostics, fmt.Sprintf("%s%s: %s", severityPrefixes[f.Severity], name, f.Message))
+			} else {
+				e := &res.PathEntries[f.Entry]
+				e.Diagnostics = append(e.Diagnostics, fmt.Sprintf("%s: %s", name, f.Message))
+			}
+		}
+	}
+}
+
+// runPlugin runs one plugin on input and parses what it wrote.
+func runPlugin(plugin string, input []byte) (pluginOutput, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), pluginTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, plugin)
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	data, err := cmd.Output()
+	if ctx.Err() != nil {
+		return pluginOutput{}, fmt.Errorf("no answer within %s", pluginTimeout)
+	}
+	if err != nil {
+		if msg, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n"); msg != "" {
+			return pluginOutput{}, fmt.Errorf("%v: %s", err, msg)
+		}
+		return pluginOutput{}, err
+	}
+	var out pluginOutput
+	if len(bytes.TrimSpace(data)) == 0 {
+		return out, nil // Nothing to report
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return pluginOutput{}, fmt.Errorf("output is not plugin JSON: %v", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+
+package trace
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detachTrace starts cmd in a session of its own: with no controlling
+// terminal, startup files cannot stop on it or change its settings, and
+// killTrace can reach everything they start.
+func detachTrace(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+// killTrace kills the traced shell and its process group.
+func killTrace(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,13 @@
+//go:build windows
+
+package trace
+
+import "os/exec"
+
+// detachTrace does nothing: Windows has no sessions to start cmd in.
+func detachTrace(cmd *exec.Cmd) {}
+
+// killTrace kills the traced shell; what it started is left running.
+func killTrace(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package trace
+
+import "errors"
+
+// readRegistryPaths is only meaningful on Windows.
+func readRegistryPaths() (machine, user []string, err error) {
+	return nil, nil, errors.New("registry PATH attribution is only available on Windows")
+}
@@ -0,0 +1,57 @@
+//go:build windows
+
+package trace
+
+import (
+	"os"
+	"regexp"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+const machineEnvKey = `SYSTEM\CurrentControlSet\Control\Session Manager\Environment`
+
+// readRegistryPaths returns the machine-wide (HKLM) and per-user (HKCU) Path
+// values that Windows concatenates to build a new process's PATH.
+func readRegistryPaths() (machine, user []string, err error) {
+	machine, err = readRegistryPath(registry.LOCAL_MACHINE, machineEnvKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	// A user without a Path value is normal
+	user, _ = readRegistryPath(registry.CURRENT_USER, "Environment")
+	return machine, user, nil
+}
+
+func readRegistryPath(root registry.Key, path string) ([]string, error) {
+	k, err := registry.OpenKey(root, path, registry.QUERY_VALUE)
+	if err != nil {
+		return nil, err
+	}
+	defer k.Close()
+
+	value, _, err := k.GetStringValue("Path")
+	if err != nil {
+		return nil, err
+	}
+	var parts []string
+	for _, p := range strings.Split(value, ";") {
+		if p = expandWindowsEnv(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return parts, nil
+}
+
+var windowsEnvRe = regexp.MustCompile(`%([^%]+)%`)
+
+// expandWindowsEnv expands %VAR% references in REG_EXPAND_SZ values.
+func expandWindowsEnv(s string) string {
+	return windowsEnvRe.ReplaceAllStringFunc(s, func(m string) string {
+		if v, ok := os.LookupEnv(m[1 : len(m)-1]); ok {
+			return v
+		}
+		return m
+	})
+}
@@ -0,0 +1,215 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"lspath/internal/model"
+)
+
+// How an interactive shell resolves a command name
+const (
+	ResolveFile     = "file"      // An executable found by searching PATH
+	ResolveHashed   = "hashed"    // An executable remembered in the shell's hash table
+	ResolveAlias    = "alias"     // An alias
+	ResolveFunction = "function"  // A shell function
+	ResolveBuiltin  = "builtin"   // A builtin or reserved word
+	ResolveNotFound = "not found" // The shell cannot run it
+)
+
+// shellQueryTimeout bounds the interactive shell started by ResolveInShell,
+// whose startup files may be slow.
+const shellQueryTimeout = 5 * time.Second
+
+// commandNamePattern restricts names passed to the shell, which are
+// interpolated into its command line.
+var commandNamePattern = regexp.MustCompile(`^[A-Za-z0-9._+-]+$`)
+
+// ErrUnsupportedShell is returned by ResolveInShell for a shell it does not
+// know how to ask.
+var ErrUnsupportedShell = errors.New("cannot ask this shell what a command runs")
+
+// ShellResolution is what the user's interactive shell runs for a command
+// name, as reported by `type` (bash, fish), `whence -v` (zsh) or
+// Get-Command (pwsh).
+type ShellResolution struct {
+	Shell  string // Shell that was asked, e.g. "zsh"
+	Name   string // Command name
+	Kind   string // One of the Resolve* constants
+	Path   string // Executable path for ResolveFile and ResolveHashed
+	Output string // The shell's own description
+}
+
+// ResolveInShell starts the shell at shellPath (the user's $SHELL) as an
+// interactive login shell, so aliases and functions from startup files are
+// in effect, and asks it what name runs with its own lookup builtin. csh
+// cannot be both a login shell and run a command, so it reads only its rc
+// file there. Shells DetectShell does not recognize give ErrUnsupportedShell.
+func ResolveInShell(shellPath, name string) (ShellResolution, error) {
+	shell := DetectShell(shellPath)
+	res := ShellResolution{Shell: shell.Name(), Name: name, Kind: ResolveNotFound}
+	if !commandNamePattern.MatchString(name) {
+		return res, fmt.Errorf("invalid command name %q", name)
+	}
+
+	var args []string
+	switch shell.(type) {
+	case *ZshShell:
+		if shellPath == "" {
+			return res, errors.New("SHELL is not set")
+		}
+		if !strings.Contains(filepath.Base(shellPath), "zsh") {
+			// DetectShell's fallback for a shell it does not know
+			res.Shell = filepath.Base(shellPath)
+			return res, fmt.Errorf("%w: %q", ErrUnsupportedShell, shellPath)
+		}
+		args = []string{"-li", "-c", "whence -v -- " + name}
+	case *BashShell:
+		args = []string{"-li", "-c", "type -- " + name}
+	case *FishShell:
+		args = []string{"--login", "--interactive", "--command", "type -- " + name}
+	case *CshShell:
+		args = []string{"-c", "which " + name}
+	case *PosixShell:
+		args = []string{"-l", "-c", "command -V " + name}
+	case *KshShell:
+		args = []string{"-li", "-c", "whence -v -- " + name}
+	case *PowerShellShell:
+		// Get-Command has no "<name> is" form; print one in the bash style
+		args = []string{"-NoLogo", "-NonInteractive", "-Command", fmt.Sprintf(
+			`$c = Get-Command -Name '%[1]s' -ErrorAction SilentlyContinue | Select-Object -First 1; `+
+				`if ($c.CommandType -eq 'Application') { '%[1]s is ' + $c.Source } `+
+				`elseif ($c.CommandType -eq 'Alias') { '%[1]s is an alias for ' + $c.Definition } `+
+				`elseif ($c) { '%[1]s is a ' + "$($c.CommandType)".ToLower() }`, name)}
+	default:
+		return res, fmt.Errorf("%w: %q", ErrUnsupportedShell, shellPath)
+	}
+
+	// The user's own shell, not whichever one of that name PATH finds first
+	bin, err := exec.LookPath(shellPath)
+	if err != nil {
+		return res, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shellQueryTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, bin, args...)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	err = cmd.Run()
+	if ctx.Err() != nil {
+		return res, fmt.Errorf("%s did not answer within %s", shell.Name(), shellQueryTimeout)
+	}
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		return res, err
+	}
+
+	// Startup files may print banners; the answer starts with "<name> is ".
+	prefix := name + " is "
+	lines := strings.Split(stdout.String(), "\n")
+	if _, ok := shell.(*CshShell); ok {
+		lines = cshWhichAnswers(name, lines)
+	}
+	for _, line := range lines {
+		if strings.HasPrefix(line, prefix) {
+			parseShellResolution(&res, strings.TrimSpace(line), strings.TrimPrefix(line, prefix))
+			break
+		}
+	}
+	return res, nil
+}
+
+// cshWhichAnswers rewrites tcsh's which output ("/usr/bin/ls", "ll:
+// aliased to ls -l", "cd: shell built-in command.") in the "<name> is" form
+// of the other shells.
+func cshWhichAnswers(name string, lines []string) []string {
+	var answers []string
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		desc := strings.TrimSpace(strings.TrimPrefix(line, name+":"))
+		switch {
+		case strings.HasPrefix(line, "/"):
+			answers = append(answers, name+" is "+line)
+		case strings.HasPrefix(desc, "aliased to"):
+			answers = append(answers, name+" is "+desc)
+		case strings.HasPrefix(desc, "shell built-in"):
+			answers = append(answers, name+" is a shell builtin")
+		}
+	}
+	return answers
+}
+
+// parseShellResolution classifies the text after "<name> is ".
+func parseShellResolution(res *ShellResolution, line, desc string) {
+	res.Output = line
+	switch {
+	case strings.HasPrefix(desc, "hashed ("):
+		// bash: "python is hashed (/usr/bin/python)"
+		res.Kind = ResolveHashed
+		res.Path = strings.TrimSuffix(strings.TrimPrefix(desc, "hashed ("), ")")
+	case strings.HasPrefix(desc, "a tracked alias for "):
+		// ksh: "python is a tracked alias for /usr/bin/python"
+		res.Kind = ResolveHashed
+		res.Path = strings.TrimPrefix(desc, "a tracked alias for ")
+	case strings.HasPrefix(desc, "/"):
+		res.Kind = ResolveFile
+		res.Path = strings.TrimSpace(desc)
+	case strings.HasPrefix(desc, "aliased to") || strings.HasPrefix(desc, "an alias"):
+		res.Kind = ResolveAlias
+	case strings.Contains(desc, "function"):
+		res.Kind = ResolveFunction
+	case strings.Contains(desc, "builtin") || strings.Contains(desc, "keyword") || strings.Contains(desc, "reserved word") ||
+		strings.Contains(desc, "cmdlet"):
+		res.Kind = ResolveBuiltin
+	}
+}
+
+// ResolveInPath returns the PATH entry index and full path of the first
+// executable called name, or -1 if no entry provides it.
+func ResolveInPath(entries []model.PathEntry, name string) (int, string) {
+	for _, h := range FindCommand(entries, name) {
+		if !h.Broken {
+			return h.Index, h.Path
+		}
+	}
+	return -1, ""
+}
+
+// CompareResolution explains how the shell's answer differs from the PATH
+// lspath analyzed. It returns "" when they agree.
+func CompareResolution(res model.AnalysisResult, sr ShellResolution) string {
+	idx, path := ResolveInPath(res.PathEntries, sr.Name)
+
+	switch sr.Kind {
+	case ResolveFile, ResolveHashed:
+		if idx >= 0 && (sr.Path == path || sameFile(sr.Path, path)) {
+			return ""
+		}
+		msg := fmt.Sprintf("%s runs %s", sr.Shell, sr.Path)
+		if idx >= 0 {
+			msg += fmt.Sprintf(", but PATH resolves %s to %s (entry #%d)", sr.Name, path, idx+1)
+		} else {
+			msg += ", which is not in any PATH entry"
+		}
+		return msg
+	case ResolveBuiltin:
+		return "" // Builtins such as echo and test are expected to win over PATH
+	case ResolveAlias, ResolveFunction:
+		if idx < 0 {
+			return ""
+		}
+		return fmt.Sprintf("%s runs the %s %s, hiding %s from PATH entry #%d", sr.Shell, sr.Kind, sr.Name, path, idx+1)
+	default:
+		if idx < 0 {
+			return ""
+		}
+		return fmt.Sprintf("%s cannot find %s, but PATH entry #%d provides %s; the interactive shell's PATH differs", sr.Shell, sr.Name, idx+1, path)
+	}
+}
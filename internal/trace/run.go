@@ -0,0 +1,247 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"time"
+
+	"lspath/internal/model"
+)
+
+// DefaultVariable is the variable analyzed unless Options.Var says otherwise.
+const DefaultVariable = "PATH"
+
+// Options controls a full analysis run.
+type Options struct {
+	SessionPath string // Value to analyze; defaults to the variable's current value
+	Var         string // PATH-like variable to analyze (e.g. MANPATH); defaults to PATH
+	Shell       string // Shell whose startup is traced (e.g. /bin/zsh); defaults to $SHELL
+
+	// Context, if set, stops the trace when it is done, as the TraceTimeout
+	// does.
+	Context context.Context
+
+	// Duplicates controls how duplicate entries are reported (--duplicates).
+	Duplicates model.DuplicatePolicy
+
+	// PathCase is how the case of entries is compared when matching them
+	// (--path-case): one of the model.Case* constants; empty means
+	// model.CaseAuto.
+	PathCase string
+
+	// TraceTimeout is how long the traced shell may take before it is
+	// killed and the trace analyzed as far as it got (--trace-timeout). 0
+	// means DefaultTraceTimeout; negative means no limit.
+	TraceTimeout time.Duration
+
+	// Baseline is the PATH the traced shell starts from (--baseline). If
+	// empty, DetectBaseline's is used.
+	Baseline string
+
+	// RawTrace, if set, receives a copy of the shell's trace output.
+	RawTrace io.Writer
+
+	// StartupTime, if set, receives how long the traced shell took to start.
+	StartupTime *time.Duration
+
+	// NoSideEffects traces with commands that start daemons or write files
+	// disabled where the shell allows it (best effort).
+	NoSideEffects bool
+
+	// Sandbox limits the traced shell's resources (--sandbox). Traces of
+	// other users always use at least DefaultSandbox.
+	Sandbox Sandbox
+
+	// PinsFile is the pins file to check the result against (--pins). If
+	// empty, PinsFile(variable) is used when it exists.
+	PinsFile string
+
+	// Ignores are the problems the user accepts (--ignore, or the
+	// IgnoreFile); they are marked on the entries rather than reported.
+	Ignores []model.IgnoreRule
+
+	// DisabledHeuristics holds the analyzer heuristics to leave off
+	// (--no-heuristic, or the HeuristicsFile).
+	DisabledHeuristics map[string]bool
+
+	// Plugins are the executables RunPlugins runs on the result (--plugin,
+	// and those in the PluginDir).
+	Plugins []string
+
+	// Progress, if set, is called as the analysis moves between stages.
+	Progress func(stage string)
+}
+
+// RunAnalysis traces the user's shell startup and merges it with the session
+// value of the variable. On Windows there is no startup trace; PATH entries
+// are attributed to the registry instead.
+func RunAnalysis(opts Options) (model.AnalysisResult, error) {
+	progress := opts.Progress
+	if progress == nil {
+		progress = func(string) {}
+	}
+
+	variable := opts.Var
+	if variable == "" {
+		variable = DefaultVariable
+	}
+	sessionPath := opts.SessionPath
+	if sessionPath == "" {
+		sessionPath = os.Getenv(variable)
+	}
+
+	if runtime.GOOS == "windows" {
+		if variable != DefaultVariable {
+			return model.AnalysisResult{}, fmt.Errorf("%s cannot be analyzed on Windows; only PATH is supported", variable)
+		}
+		progress("Reading PATH from the registry…")
+		machine, user, err := readRegistryPaths()
+		if err != nil {
+			return model.AnalysisResult{}, err
+		}
+		analyzer := NewAnalyzer()
+		analyzer.Canon = opts.canon()
+		res := analyzer.AnalyzeWindows(sessionPath, machine, user)
+		res.Variable = variable
+		res.DuplicatePolicy = opts.Duplicates
+		res.Environment = CollectEnvironment(opts.shellPath())
+		if err := CheckPins(&res, opts.PinsFile); err != nil {
+			return model.AnalysisResult{}, err
+		}
+		ApplyIgnores(&res, opts.Ignores)
+		RunPlugins(&res, opts.Plugins)
+		return res, nil
+	}
+
+	// Run shell trace to find config file sources
+	shell := DetectShell(opts.shellPath())
+	progress(fmt.Sprintf("Running %s startup files…", shell.Name()))
+	start := time.Now()
+	ctx, cancel := opts.traceContext()
+	defer cancel()
+	traceOut, err := openTrace(ctx, shell, opts, variable)
+	if err != nil {
+		return model.AnalysisResult{}, err
+	}
+	defer traceOut.Close()
+	allEvents, stats, hashed := collectEvents(shell, variable, opts.baseline(), traceOut, progress)
+	timedOut := ctx.Err() != nil
+	waitErr := traceOut.Wait()
+	if opts.StartupTime != nil {
+		*opts.StartupTime = time.Since(start)
+	}
+
+	// Unified analysis: merge trace results with session value
+	progress(fmt.Sprintf("Analyzing %d trace events…", len(allEvents)))
+	analyzer := NewAnalyzer()
+	analyzer.Variable = variable
+	analyzer.Canon = opts.canon()
+	analyzer.DisabledHeuristics = opts.DisabledHeuristics
+	analyzer.Baseline = opts.baseline()
+	res := analyzer.AnalyzeUnified(sessionPath, allEvents)
+	res.Variable = variable
+	res.Parser = stats
+	res.HashTable = hashed
+	res.DuplicatePolicy = opts.Duplicates
+	res.Environment = CollectEnvironment(opts.shellPath())
+	if opts.SessionPath == "" && variable == DefaultVariable {
+		// The prompt hook logs this shell's PATH, not a value passed in
+		ApplySessionLog(&res, os.Getenv(SessionLogEnv), sessionPath)
+	}
+	CheckShellCompat(&res)
+	if variable == DefaultVariable {
+		CheckHashTable(&res, shell)
+	}
+	if err := CheckPins(&res, opts.PinsFile); err != nil {
+		return model.AnalysisResult{}, err
+	}
+	ApplyIgnores(&res, opts.Ignores)
+	// Under --no-side-effects bash still traces the commands, but as no-ops
+	if _, stubbed := shell.(*BashShell); !opts.NoSideEffects || !stubbed {
+		res.SideEffects = DetectSideEffects(allEvents)
+	}
+	if variable == DefaultVariable {
+		res.Definitions = DetectDefinitions(shell, allEvents)
+	}
+	if opts.NoSideEffects {
+		res.Diagnostics = append(res.Diagnostics, fmt.Sprintf("INFO: Traced with --no-side-effects (%s). Startup files that rely on agents or files they create may behave differently.", restrictionSummary(shell)))
+	} else if len(res.SideEffects) > 0 {
+		res.Diagnostics = append(res.Diagnostics, sideEffectDiagnostic(res.SideEffects))
+	}
+	if opts.Sandbox.Enabled() {
+		res.Diagnostics = append(res.Diagnostics, "INFO: Traced in a sandbox: "+opts.Sandbox.Describe()+".")
+	}
+	res.Diagnostics = append(res.Diagnostics, exitDiagnostics(shell, opts, allEvents, stats, timedOut, waitErr)...)
+	if d := baselineDiagnostic(opts); d != "" && variable == DefaultVariable {
+		res.Diagnostics = append(res.Diagnostics, d)
+	}
+	if d := disabledHeuristicsDiagnostic(res); d != "" {
+		res.Diagnostics = append(res.Diagnostics, d)
+	}
+	if d := parserDiagnostic(res.Parser, shell); d != "" {
+		res.Diagnostics = append(res.Diagnostics, d)
+	}
+	if unnamedFiles(shell) {
+		res.Diagnostics = append(res.Diagnostics, fmt.Sprintf("INFO: %s's trace does not name the file each command comes from. Commands were matched by content to its startup files and the files they source, so a line number may point at a similar line.", shell.Name()))
+	}
+	RunPlugins(&res, opts.Plugins)
+	return res, nil
+}
+
+// canon is how o's analyses compare entries.
+func (o Options) canon() model.CanonOptions {
+	return model.CanonOptionsFor(o.PathCase)
+}
+
+// shellPath is the shell o traces.
+func (o Options) shellPath() string {
+	if o.Shell != "" {
+		return o.Shell
+	}
+	return os.Getenv("SHELL")
+}
+
+// openTrace starts tracing shell's startup with the settings in opts and
+// returns its trace output, copied to opts.RawTrace if set, and followed by
+// the shell's hash table. The shell is killed when ctx is done. Closing it
+// cleans up after the trace.
+func openTrace(ctx context.Context, shell Shell, opts Options, variable string) (*ShellTrace, error) {
+	command := withHashListing(shell, shellTraceCommand(shell, opts.NoSideEffects))
+	cmd := traceCommand(shell, opts.Sandbox.limitCommand(command), variable, opts.initialValue(variable), opts.baseline())
+	t, err := startSandboxedTrace(ctx, cmd, opts.Sandbox)
+	if err == nil && opts.RawTrace != nil {
+		t.Reader = io.TeeReader(t.Reader, opts.RawTrace)
+	}
+	return t, err
+}
+
+// progressEvery is how many trace events collectEvents parses between
+// progress reports.
+const progressEvery = 500
+
+// collectEvents parses a whole trace of variable, started from baseline,
+// returning its events, what the parser made of it and the hash table the
+// shell listed, if any. If progress is not nil it is told how many events
+// have been parsed as the trace streams in, so a slow startup doesn't look
+// like a hang.
+func collectEvents(shell Shell, variable, baseline string, stderr io.Reader, progress func(string)) ([]model.TraceEvent, model.ParserStats, []model.HashedCommand) {
+	parser := NewParser(shell)
+	parser.Variable = variable
+	parser.Baseline = baseline
+	events, errs := parser.Parse(stderr)
+	var allEvents []model.TraceEvent
+	for ev := range events {
+		allEvents = append(allEvents, ev)
+		if progress != nil && len(allEvents)%progressEvery == 0 {
+			progress(fmt.Sprintf("Parsing %s trace: %d events…", shell.Name(), len(allEvents)))
+		}
+	}
+	go func() {
+		for range errs {
+		}
+	}()
+	return allEvents, parser.Stats, parser.HashTable
+}
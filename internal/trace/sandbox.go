@@ -0,0 +1,167 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Sandbox limits what a traced shell can do to the machine, so broken or
+// hostile startup files cannot wedge it or leave state behind (--sandbox).
+// The zero value applies no limits.
+type Sandbox struct {
+	CPUSeconds   int   // CPU time limit (ulimit -t); 0 means none
+	MaxFileBytes int64 // Largest file the shell may write (ulimit -f); 0 means none
+	NoNetwork    bool  // Block network access where supported (Linux, macOS)
+	PrivateTmp   bool  // Point TMPDIR at a fresh directory, removed after the trace
+}
+
+// DefaultSandbox is used by --sandbox and when tracing other users.
+var DefaultSandbox = Sandbox{CPUSeconds: 30, MaxFileBytes: 16 << 20, NoNetwork: true, PrivateTmp: true}
+
+// Enabled reports whether s limits anything.
+func (s Sandbox) Enabled() bool {
+	return s != Sandbox{}
+}
+
+// limitCommand prefixes a shell command line with the resource limits. The
+// limits are inherited by everything the startup files run.
+func (s Sandbox) limitCommand(command string) string {
+	var limits []string
+	if s.CPUSeconds > 0 {
+		limits = append(limits, fmt.Sprintf("ulimit -t %d", s.CPUSeconds))
+	}
+	if s.MaxFileBytes > 0 {
+		limits = append(limits, fmt.Sprintf("ulimit -f %d", (s.MaxFileBytes+511)/512)) // 512-byte blocks
+	}
+	if len(limits) == 0 {
+		return command
+	}
+	// Errors would end up in the trace, which is read from stderr
+	return strings.Join(limits, " 2>/dev/null; ") + " 2>/dev/null; " + command
+}
+
+// noNetworkWrappers are tried in order; the first that runs on this machine
+// is used to start the trace without network access.
+var noNetworkWrappers = map[string][][]string{
+	"linux": {
+		{"unshare", "--net"},                       // Root
+		{"unshare", "--net", "--map-current-user"}, // Unprivileged user namespaces
+	},
+	"darwin": {
+		{"sandbox-exec", "-p", "(version 1)(allow default)(deny network*)"},
+	},
+}
+
+var (
+	noNetworkWrapper     []string
+	noNetworkWrapperOnce sync.Once
+)
+
+// networkWrapper returns the command prefix that blocks network access, or
+// nil if none works here (e.g. user namespaces are disabled).
+func networkWrapper() []string {
+	noNetworkWrapperOnce.Do(func() {
+		for _, w := range noNetworkWrappers[runtime.GOOS] {
+			if _, err := exec.LookPath(w[0]); err != nil {
+				continue
+			}
+			if exec.Command(w[0], append(w[1:], "true")...).Run() == nil {
+				noNetworkWrapper = w
+				return
+			}
+		}
+	})
+	return noNetworkWrapper
+}
+
+// privateTmp points cmd's TMPDIR (and TMP, TEMP) at a new directory. owner,
+// if not -1, must be able to write to it (another user's shell).
+func (s Sandbox) privateTmp(cmd *exec.Cmd, owner int) (cleanup func(), err error) {
+	if !s.PrivateTmp {
+		return func() {}, nil
+	}
+	dir, err := os.MkdirTemp("", "lspath-tmp-")
+	if err != nil {
+		return func() {}, err
+	}
+	cleanup = func() { os.RemoveAll(dir) }
+	if owner >= 0 && owner != os.Geteuid() {
+		if os.Chown(dir, owner, -1) != nil {
+			// Not root: a fresh sticky directory anyone can write is the
+			// next best thing
+			if err := os.Chmod(dir, 01777); err != nil {
+				cleanup()
+				return func() {}, err
+			}
+		}
+	}
+	var env []string
+	for _, e := range cmd.Env {
+		if !strings.HasPrefix(e, "TMPDIR=") && !strings.HasPrefix(e, "TMP=") && !strings.HasPrefix(e, "TEMP=") {
+			env = append(env, e)
+		}
+	}
+	cmd.Env = append(env, "TMPDIR="+dir, "TMP="+dir, "TEMP="+dir)
+	return cleanup, nil
+}
+
+// blockNetwork runs cmd through the network wrapper, if there is one.
+func (s Sandbox) blockNetwork(cmd *exec.Cmd) error {
+	if !s.NoNetwork {
+		return nil
+	}
+	w := networkWrapper()
+	if w == nil {
+		return nil
+	}
+	path, err := exec.LookPath(w[0])
+	if err != nil {
+		return err
+	}
+	cmd.Args = append(append([]string{}, w...), cmd.Args...)
+	cmd.Path = path
+	return nil
+}
+
+// Describe summarizes s for a diagnostic, noting limits this machine could
+// not apply.
+func (s Sandbox) Describe() string {
+	var parts []string
+	if s.CPUSeconds > 0 {
+		parts = append(parts, fmt.Sprintf("CPU time limited to %ds", s.CPUSeconds))
+	}
+	if s.MaxFileBytes > 0 {
+		parts = append(parts, fmt.Sprintf("files limited to %d MB", s.MaxFileBytes>>20))
+	}
+	if s.PrivateTmp {
+		parts = append(parts, "private TMPDIR")
+	}
+	if s.NoNetwork {
+		if networkWrapper() != nil {
+			parts = append(parts, "no network")
+		} else {
+			parts = append(parts, "network NOT blocked (not supported here)")
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// startSandboxedTrace starts cmd, a shell trace not yet started, inside the
+// environment and network parts of s, until ctx is done. Its command line
+// should already carry s.limitCommand.
+func startSandboxedTrace(ctx context.Context, cmd *exec.Cmd, s Sandbox) (*ShellTrace, error) {
+	cleanup, err := s.privateTmp(cmd, -1)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.blockNetwork(cmd); err != nil {
+		cleanup()
+		return nil, err
+	}
+	return startTrace(ctx, cmd, cleanup)
+}
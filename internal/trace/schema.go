@@ -0,0 +1,124 @@
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// jsonDowngrades turn a document of one schema version into the version
+// before it, keyed by the newer version, so scripts written against an old
+// version can ask for it with --json-version. A change that bumps
+// model.SchemaVersion adds its entry here.
+var jsonDowngrades = map[int]func(doc map[string]any){}
+
+// MarshalDocument renders res as indented JSON in the given schema version,
+// 0 meaning the current one.
+func MarshalDocument(res model.AnalysisResult, version int) ([]byte, error) {
+	if version == 0 {
+		version = model.SchemaVersion
+	}
+	if version < 1 || version > model.SchemaVersion {
+		return nil, fmt.Errorf("unknown JSON schema version %d (this lspath writes 1 to %d)", version, model.SchemaVersion)
+	}
+	doc := model.NewDocument(res)
+	if version == model.SchemaVersion {
+		return json.MarshalIndent(doc, "", "  ")
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var generic map[string]any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	for v := model.SchemaVersion; v > version; v-- {
+		jsonDowngrades[v](generic)
+	}
+	generic["SchemaVersion"] = version
+	return json.MarshalIndent(generic, "", "  ")
+}
+
+// JSONSchema describes the current schema version as a JSON Schema (draft
+// 2020-12). It is generated from the model, so it always matches what
+// MarshalDocument writes; doco/lspath.schema.json is its published copy.
+func JSONSchema() ([]byte, error) {
+	g := schemaGenerator{defs: make(map[string]any)}
+	root := g.object(reflect.TypeOf(model.Document{}))
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["title"] = "lspath analysis"
+	root["description"] = fmt.Sprintf("The output of lspath --json (schema version %d). Fields may be added without a new version.", model.SchemaVersion)
+	root["properties"].(map[string]any)["SchemaVersion"] = map[string]any{"type": "integer", "const": model.SchemaVersion}
+	root["$defs"] = g.defs
+	return json.MarshalIndent(root, "", "  ")
+}
+
+// schemaGenerator builds a JSON Schema from Go types the way encoding/json
+// marshals them. Named structs go in defs and are referenced.
+type schemaGenerator struct {
+	defs map[string]any
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schema(t.Elem())
+	case reflect.Struct:
+		if _, ok := g.defs[t.Name()]; !ok {
+			g.defs[t.Name()] = nil // Placeholder for recursive types
+			g.defs[t.Name()] = g.object(t)
+		}
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}
+	case reflect.Slice, reflect.Array:
+		// nil slices are written as null
+		return map[string]any{"type": []string{"array", "null"}, "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": []string{"object", "null"}, "additionalProperties": g.schema(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	}
+	return map[string]any{}
+}
+
+// object describes struct t. Embedded structs' fields are inlined, as
+// encoding/json does; fields without omitempty are always present.
+func (g *schemaGenerator) object(t reflect.Type) map[string]any {
+	props := make(map[string]any)
+	var required []string
+	var add func(t reflect.Type)
+	add = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				add(f.Type)
+				continue
+			}
+			tag := f.Tag.Get("json")
+			if !f.IsExported() || tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			if name == "" {
+				name = f.Name
+			}
+			props[name] = g.schema(f.Type)
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+	}
+	add(t)
+	return map[string]any{"type": "object", "properties": props, "required": required}
+}
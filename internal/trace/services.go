@@ -0,0 +1,471 @@
+package trace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// Service contexts --context can reconstruct. A systemd unit is named as
+// "systemd:UNIT" or "systemd-user:UNIT".
+const (
+	ServiceCron        = "cron"
+	ServiceSystemd     = "systemd"
+	ServiceSystemdUser = "systemd-user"
+)
+
+// cronDefaultPath is the PATH cron (Vixie cron, cronie, macOS) gives jobs
+// whose crontab does not set one.
+const cronDefaultPath = "/usr/bin:/bin"
+
+// systemdDefaultPath is the PATH systemd gives services when nothing
+// overrides it.
+const systemdDefaultPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin"
+
+// pathSetting is one place a service context's PATH is set.
+type pathSetting struct {
+	File  string
+	Line  int
+	Value string
+	Raw   string // The line as written
+}
+
+// event turns s into the TraceEvent the analyzer attributes entries from.
+func (s pathSetting) event() model.TraceEvent {
+	return model.TraceEvent{File: s.File, Line: s.Line, Depth: 1, RawCommand: s.Raw, PathChange: s.Value}
+}
+
+// RunServiceAnalysis reconstructs the PATH the named context gives the
+// commands it runs: cron jobs, macOS apps launched from the Finder or Dock,
+// systemd services (or the user manager's), or one systemd unit. No shell startup file runs there, so the PATH comes from
+// the context's own defaults and configuration, each attributed to the line
+// that sets it.
+func RunServiceAnalysis(name string, opts Options) (model.AnalysisResult, error) {
+	if runtime.GOOS == "windows" {
+		return model.AnalysisResult{}, fmt.Errorf("--context is not supported on Windows")
+	}
+	if opts.Var != "" && opts.Var != DefaultVariable {
+		return model.AnalysisResult{}, fmt.Errorf("--context only reconstructs PATH, not %s", opts.Var)
+	}
+
+	var settings []pathSetting
+	var notes []string
+	var err error
+	context, unit, _ := strings.Cut(name, ":")
+	switch {
+	case context == ServiceCron && unit == "":
+		settings, notes = cronPathSettings()
+	case context == ServiceLaunchd && unit == "":
+		if runtime.GOOS != "darwin" {
+			return model.AnalysisResult{}, fmt.Errorf("--context %s is for macOS, where launchd starts apps from the Finder and Dock", ServiceLaunchd)
+		}
+		settings, notes = launchdPathSettings()
+	case context == ServiceSystemd || context == ServiceSystemdUser:
+		user := context == ServiceSystemdUser
+		settings, notes = systemdPathSettings(user)
+		if unit != "" {
+			var unitSettings []pathSetting
+			unitSettings, err = unitPathSettings(unit, user)
+			settings = append(settings, unitSettings...)
+		}
+	default:
+		err = fmt.Errorf("unknown context %q (want %s, %s, %s, %s, or %s:UNIT)", name, ServiceCron, ServiceLaunchd, ServiceSystemd, ServiceSystemdUser, ServiceSystemd)
+	}
+	if err != nil {
+		return model.AnalysisResult{}, err
+	}
+
+	var events []model.TraceEvent
+	for _, s := range settings {
+		if strings.Contains(s.Value, "$") {
+			notes = append(notes, fmt.Sprintf("WARN: %s line %d: $ is taken literally there, so %q is not expanded.", s.File, s.Line, s.Value))
+		}
+		events = append(events, s.event())
+	}
+
+	analyzer := NewAnalyzer()
+	analyzer.Canon = opts.canon()
+	res := analyzer.Analyze(events, "")
+	// The analyzer's heuristics, advice on shell modes and startup files
+	// that did not run are about shells, and no shell runs here
+	res.Heuristics = nil
+	var nodes []model.ConfigNode
+	for _, n := range res.FlowNodes {
+		if !n.NotExecuted {
+			nodes = append(nodes, n)
+		}
+	}
+	res.FlowNodes = nodes
+	var diags []string
+	for _, d := range res.Diagnostics {
+		if !strings.HasPrefix(d, "INFO: Detected as") && !strings.HasPrefix(d, "INFO: Trace Mode") {
+			diags = append(diags, d)
+		}
+	}
+	res.Diagnostics = diags
+	res.Variable = DefaultVariable
+	res.DuplicatePolicy = opts.Duplicates
+	res.Environment = CollectEnvironment(os.Getenv("SHELL"))
+	res.Diagnostics = append(res.Diagnostics, fmt.Sprintf("INFO: Reconstructed the PATH for %s from configuration; no shell startup file runs there.", contextLabel(name)))
+	res.Diagnostics = append(res.Diagnostics, notes...)
+	if err := CheckPins(&res, opts.PinsFile); err != nil {
+		return model.AnalysisResult{}, err
+	}
+	ApplyIgnores(&res, opts.Ignores)
+	RunPlugins(&res, opts.Plugins)
+	return res, nil
+}
+
+// contextLabel names a --context for reports, e.g. "cron jobs".
+func contextLabel(name string) string {
+	context, unit, _ := strings.Cut(name, ":")
+	switch {
+	case context == ServiceCron:
+		return "cron jobs"
+	case context == ServiceLaunchd:
+		return "apps launched from the Finder or Dock"
+	case unit != "":
+		return "unit " + unit
+	case context == ServiceSystemdUser:
+		return "systemd user services"
+	}
+	return "systemd services"
+}
+
+// assignmentRe matches a PATH assignment in a crontab or environment file:
+// "PATH=/usr/bin:/bin", "PATH = ...", `export PATH="..."`.
+var assignmentRe = regexp.MustCompile(`^(?:export\s+)?PATH\s*=\s*(.*)$`)
+
+// pathAssignments finds the PATH assignments in a crontab or environment
+// file's text, attributed to file.
+func pathAssignments(file, text string) []pathSetting {
+	var found []pathSetting
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if m := assignmentRe.FindStringSubmatch(line); m != nil {
+			found = append(found, pathSetting{File: file, Line: i + 1, Value: unquote(m[1]), Raw: line})
+		}
+	}
+	return found
+}
+
+// unquote removes one layer of matching quotes around s.
+func unquote(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// cronPathSettings returns where the PATH of the user's cron jobs comes
+// from: cron's default, then the crontab's last PATH= line.
+func cronPathSettings() ([]pathSetting, []string) {
+	settings := []pathSetting{{File: "cron (built-in default)", Value: cronDefaultPath, Raw: "PATH=" + cronDefaultPath}}
+	var notes []string
+	crontab := toolOutput("crontab", "-l")
+	if found := pathAssignments("crontab -l", crontab); len(found) > 0 {
+		// Each assignment applies to the jobs below it; the last one covers
+		// jobs at the end, which is where most crontabs put theirs
+		settings = append(settings, found...)
+		if len(found) > 1 {
+			notes = append(notes, fmt.Sprintf("INFO: Your crontab sets PATH %d times; each applies to the jobs below it, and this report shows the last.", len(found)))
+		}
+	} else if crontab == "" {
+		notes = append(notes, "INFO: No crontab found for this user (crontab -l), so jobs would get cron's default PATH.")
+	}
+	for _, file := range []string{"/etc/crontab", "/etc/anacrontab"} {
+		if data, err := os.ReadFile(file); err == nil {
+			if found := pathAssignments(file, string(data)); len(found) > 0 {
+				last := found[len(found)-1]
+				notes = append(notes, fmt.Sprintf("INFO: System jobs in %s get PATH=%s (line %d), not your crontab's.", file, last.Value, last.Line))
+			}
+		}
+	}
+	if s := etcEnvironmentPath(); s != nil {
+		notes = append(notes, fmt.Sprintf("INFO: /etc/environment sets PATH=%s (line %d), but cron replaces it with its own unless the crontab sets PATH.", s.Value, s.Line))
+	}
+	return settings, notes
+}
+
+// etcEnvironmentPath returns the PATH /etc/environment sets for PAM login
+// sessions, or nil.
+func etcEnvironmentPath() *pathSetting {
+	data, err := os.ReadFile("/etc/environment")
+	if err != nil {
+		return nil
+	}
+	found := pathAssignments("/etc/environment", string(data))
+	if len(found) == 0 {
+		return nil
+	}
+	return &found[len(found)-1]
+}
+
+// systemdSearchDirs lists the directories systemd reads a kind of
+// configuration from, lowest precedence first.
+func systemdSearchDirs(user bool, kind string) []string {
+	switch kind {
+	case "environment.d":
+		return []string{"/usr/lib/environment.d", "/usr/local/lib/environment.d", "/run/environment.d", "/etc/environment.d", model.ExpandTilde("~/.config/environment.d")}
+	case "units":
+		if user {
+			return []string{"/usr/lib/systemd/user", "/usr/local/lib/systemd/user", "/run/systemd/user", "/etc/systemd/user", model.ExpandTilde("~/.config/systemd/user")}
+		}
+		return []string{"/lib/systemd/system", "/usr/lib/systemd/system", "/usr/local/lib/systemd/system", "/run/systemd/system", "/etc/systemd/system"}
+	}
+	// Manager configuration drop-ins, e.g. system.conf.d
+	return []string{"/usr/lib/systemd/" + kind, "/usr/local/lib/systemd/" + kind, "/run/systemd/" + kind, "/etc/systemd/" + kind}
+}
+
+// dropIns returns the *.conf files in dirs in the order systemd applies
+// them: by file name, a file in a later directory replacing one of the same
+// name in an earlier one.
+func dropIns(dirs []string) []string {
+	byName := make(map[string]string)
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if !e.IsDir() && strings.HasSuffix(e.Name(), ".conf") {
+				byName[e.Name()] = filepath.Join(dir, e.Name())
+			}
+		}
+	}
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	files := make([]string, len(names))
+	for i, name := range names {
+		files[i] = byName[name]
+	}
+	return files
+}
+
+// systemdAssignments splits the value of an Environment= style setting into
+// its assignments, which are separated by spaces and may be quoted:
+// `"PATH=/opt/bin:/usr/bin" LANG=C`.
+func systemdAssignments(value string) []string {
+	var out []string
+	var cur strings.Builder
+	var quote byte
+	inWord := false
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			cur.WriteByte(c)
+		case c == '"' || c == '\'':
+			quote, inWord = c, true
+		case c == ' ' || c == '\t':
+			if inWord {
+				out = append(out, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		out = append(out, cur.String())
+	}
+	return out
+}
+
+// settingPaths finds the PATH assignments made by key= lines (e.g.
+// DefaultEnvironment=) in an ini-style systemd file.
+func settingPaths(file, key string) []pathSetting {
+	var found []pathSetting
+	for i, line := range readLines(file) {
+		value, ok := strings.CutPrefix(strings.TrimSpace(line), key+"=")
+		if !ok {
+			continue
+		}
+		for _, a := range systemdAssignments(value) {
+			if v, ok := strings.CutPrefix(a, "PATH="); ok {
+				found = append(found, pathSetting{File: file, Line: i + 1, Value: v, Raw: strings.TrimSpace(line)})
+			}
+		}
+	}
+	return found
+}
+
+// systemdPathSettings returns where the PATH of systemd's services (or the
+// user manager's) comes from: systemd's default, DefaultEnvironment= in the
+// manager configuration, environment.d for user services, then whatever
+// the running manager reports if it differs.
+func systemdPathSettings(user bool) ([]pathSetting, []string) {
+	settings := []pathSetting{{File: "systemd (built-in default)", Value: systemdDefaultPath, Raw: "PATH=" + systemdDefaultPath}}
+	var notes []string
+
+	conf := "system.conf"
+	if user {
+		conf = "user.conf"
+	}
+	files := []string{"/etc/systemd/" + conf}
+	files = append(files, dropIns(systemdSearchDirs(user, conf+".d"))...)
+	for _, file := range files {
+		settings = append(settings, settingPaths(file, "DefaultEnvironment")...)
+	}
+
+	if user {
+		for _, file := range dropIns(systemdSearchDirs(user, "environment.d")) {
+			for _, s := range pathAssignments(file, strings.Join(readLines(file), "\n")) {
+				// environment.d expands variables, PATH from the lines before
+				s.Value = expandEnvironmentD(s.Value, settings[len(settings)-1].Value)
+				settings = append(settings, s)
+			}
+		}
+	}
+	if s := etcEnvironmentPath(); s != nil {
+		notes = append(notes, fmt.Sprintf("INFO: /etc/environment sets PATH=%s (line %d) for login sessions; systemd does not read it unless a unit names it in EnvironmentFile=.", s.Value, s.Line))
+	}
+
+	args := []string{"show-environment"}
+	command := "systemctl show-environment"
+	if user {
+		args = []string{"--user", "show-environment"}
+		command = "systemctl --user show-environment"
+	}
+	running := ""
+	for _, line := range strings.Split(toolOutput("systemctl", args...), "\n") {
+		if v, ok := strings.CutPrefix(line, "PATH="); ok {
+			running = v
+		}
+	}
+	switch {
+	case running == "":
+		notes = append(notes, fmt.Sprintf("INFO: Could not ask the running manager (%s); the PATH is reconstructed from configuration alone.", command))
+	case running != settings[len(settings)-1].Value:
+		settings = append(settings, pathSetting{File: command, Value: running, Raw: "PATH=" + running})
+		notes = append(notes, fmt.Sprintf("INFO: The running manager's PATH differs from its configuration: it was changed at runtime (systemctl set-environment or import-environment) or the configuration changed since it started. %s is what services get now.", command))
+	}
+	return settings, notes
+}
+
+// expandEnvironmentD expands $VAR, ${VAR}, ${VAR:-default} and
+// ${VAR:+alternate} in an environment.d value; PATH is path, the value the
+// lines before set.
+func expandEnvironmentD(value, path string) string {
+	lookup := func(name string) string {
+		if name == DefaultVariable {
+			return path
+		}
+		return os.Getenv(name)
+	}
+	return os.Expand(value, func(expr string) string {
+		if name, def, ok := strings.Cut(expr, ":-"); ok {
+			if v := lookup(name); v != "" {
+				return v
+			}
+			return def
+		}
+		if name, alt, ok := strings.Cut(expr, ":+"); ok {
+			if lookup(name) != "" {
+				return alt
+			}
+			return ""
+		}
+		return lookup(expr)
+	})
+}
+
+// unitPathSettings returns the PATH assignments in a systemd unit's file and
+// drop-ins: Environment= lines, then the EnvironmentFile= files they name,
+// which systemd applies after them.
+func unitPathSettings(unit string, user bool) ([]pathSetting, error) {
+	if !strings.Contains(unit, ".") {
+		unit += ".service"
+	}
+	dirs := systemdSearchDirs(user, "units")
+	names := []string{unit}
+	if prefix, rest, ok := strings.Cut(unit, "@"); ok {
+		// foo@bar.service is configured by foo@.service
+		names = append(names, prefix+"@"+rest[strings.LastIndex(rest, "."):])
+	}
+
+	fragment := ""
+	var dropInDirs []string
+	for _, name := range names {
+		for i := len(dirs) - 1; i >= 0 && fragment == ""; i-- {
+			if _, err := os.Stat(filepath.Join(dirs[i], name)); err == nil {
+				fragment = filepath.Join(dirs[i], name)
+			}
+		}
+		for _, dir := range dirs {
+			dropInDirs = append(dropInDirs, filepath.Join(dir, name+".d"))
+		}
+	}
+	if fragment == "" {
+		return nil, fmt.Errorf("unit %s not found in %s", unit, strings.Join(dirs, ", "))
+	}
+
+	files := append([]string{fragment}, dropIns(dropInDirs)...)
+	var settings, fromFiles []pathSetting
+	for _, file := range files {
+		settings = append(settings, settingPaths(file, "Environment")...)
+		for _, line := range readLines(file) {
+			envFile, ok := strings.CutPrefix(strings.TrimSpace(line), "EnvironmentFile=")
+			if !ok {
+				continue
+			}
+			// A leading - means the file may be missing
+			envFile = strings.TrimPrefix(envFile, "-")
+			data, err := os.ReadFile(envFile)
+			if err != nil {
+				continue
+			}
+			for _, s := range pathAssignments(envFile, string(data)) {
+				s.Raw = s.Raw + " (via EnvironmentFile= in " + filepath.Base(file) + ")"
+				fromFiles = append(fromFiles, s)
+			}
+		}
+	}
+	return append(settings, fromFiles...), nil
+}
+
+// serviceAdvice says how to change the PATH a context gives what it runs.
+func serviceAdvice(name string) string {
+	context, unit, _ := strings.Cut(name, ":")
+	switch {
+	case context == ServiceLaunchd:
+		return "Run `sudo launchctl config user path \"<dirs>\"` and restart to give every app a PATH, or `launchctl setenv PATH \"<dirs>\"` for this login only. An app keeps the PATH it started with, so quit and reopen it."
+	case context == ServiceCron:
+		return "Set PATH= at the top of your crontab (crontab -e). cron takes it literally, so write out every directory instead of using $PATH."
+	case unit != "":
+		cmd := "systemctl edit " + unit
+		if context == ServiceSystemdUser {
+			cmd = "systemctl --user edit " + unit
+		}
+		return fmt.Sprintf("Add Environment=\"PATH=...\" under [Service] with `%s`, writing out every directory; systemd does not expand $PATH there.", cmd)
+	case context == ServiceSystemdUser:
+		return "Add PATH=$HOME/bin:$PATH (for example) to a file in ~/.config/environment.d/ ending in .conf, where $PATH is expanded, then log in again."
+	}
+	return "Set DefaultEnvironment=\"PATH=...\" in a drop-in under /etc/systemd/system.conf.d/ for every service, or Environment= in one unit with `systemctl edit UNIT`, then run `systemctl daemon-reexec`."
+}
+
+// FormatServiceComparison explains how the PATH a context gives what it
+// runs differs from the user's shell's.
+func FormatServiceComparison(mine, service model.AnalysisResult, name string) string {
+	var sb strings.Builder
+	title := "COMPARED WITH YOUR SHELL"
+	sb.WriteString(title + "\n" + strings.Repeat("-", len(title)) + "\n")
+	sb.WriteString(fmt.Sprintf("ADDED: only in the PATH of %s; REMOVED: only in your shell's, so commands there are not found by name.\n\n", contextLabel(name)))
+	sb.WriteString(FormatDiff(mine, service, DiffResults(mine, service)))
+	sb.WriteString("\nTo change it: " + serviceAdvice(name) + "\n")
+	return sb.String()
+}
@@ -0,0 +1,193 @@
+package trace
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"lspath/internal/model"
+)
+
+// SessionLogEnv names the variable the prompt hook exports: the file it
+// appends the shell's PATH to whenever a command changes it.
+const SessionLogEnv = "LSPATH_SESSION_LOG"
+
+// sessionLogMaxAge is how long the log of a shell that stopped changing
+// PATH (most likely one that exited) is kept.
+const sessionLogMaxAge = 30 * 24 * time.Hour
+
+// SessionLogDir returns the directory of the prompt hooks' logs, one per
+// shell, e.g. ~/.cache/lspath/sessions.
+func SessionLogDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "lspath", "sessions")
+}
+
+// The prompt hooks log one line per change: the Unix time, the command
+// that ran before the prompt (empty if none did, e.g. direnv changed PATH
+// from a prompt hook) and the new PATH, separated by tabs. The first line,
+// written at the first prompt, is the PATH the startup files left.
+var promptHooks = map[string]string{
+	"zsh": `# lspath: log PATH changes made at the prompt (put this last in ~/.zshrc)
+export ` + SessionLogEnv + `="{{DIR}}/$$"
+mkdir -p "{{DIR}}" && : >| "$` + SessionLogEnv + `"
+typeset -g _lspath_path= _lspath_cmd=
+_lspath_preexec() { _lspath_cmd=$1 }
+_lspath_precmd() {
+  if [[ $PATH != "$_lspath_path" ]]; then
+    local cmd=${${_lspath_cmd//$'\n'/ }//$'\t'/ }
+    print -r -- "${EPOCHSECONDS:-$(date +%s)}"$'\t'"$cmd"$'\t'"$PATH" >> "$` + SessionLogEnv + `"
+    _lspath_path=$PATH
+  fi
+  _lspath_cmd=
+}
+zmodload zsh/datetime 2>/dev/null
+autoload -Uz add-zsh-hook
+add-zsh-hook preexec _lspath_preexec
+add-zsh-hook precmd _lspath_precmd
+`,
+	"bash": `# lspath: log PATH changes made at the prompt (put this last in ~/.bashrc)
+export ` + SessionLogEnv + `="{{DIR}}/$$"
+mkdir -p "{{DIR}}" && : >| "$` + SessionLogEnv + `"
+_lspath_path= _lspath_histno=
+_lspath_precmd() {
+  [[ $PATH == "$_lspath_path" ]] && return
+  local cmd= entry
+  entry=$(HISTTIMEFORMAT= builtin history 1)
+  if [[ $entry =~ ^\ *([0-9]+)\*?\ +(.*)$ ]]; then
+    # The same entry as at the last change means no command ran since
+    [[ -n $_lspath_path && ${BASH_REMATCH[1]} != "$_lspath_histno" ]] && cmd=${BASH_REMATCH[2]}
+    _lspath_histno=${BASH_REMATCH[1]}
+  fi
+  cmd=${cmd//$'\n'/ }
+  printf '%s\t%s\t%s\n' "${EPOCHSECONDS:-$(date +%s)}" "${cmd//$'\t'/ }" "$PATH" >> "$` + SessionLogEnv + `"
+  _lspath_path=$PATH
+}
+if [[ "$(declare -p PROMPT_COMMAND 2>/dev/null)" == "declare -a"* ]]; then
+  PROMPT_COMMAND+=(_lspath_precmd)
+else
+  PROMPT_COMMAND="${PROMPT_COMMAND:+$PROMPT_COMMAND;}_lspath_precmd"
+fi
+`,
+	"fish": `# lspath: log PATH changes made at the prompt (put this last in ~/.config/fish/config.fish)
+set -gx ` + SessionLogEnv + ` "{{DIR}}/$fish_pid"
+mkdir -p "{{DIR}}"; and echo -n > $` + SessionLogEnv + `
+set -g _lspath_path
+set -g _lspath_cmd
+function _lspath_preexec --on-event fish_preexec
+    set -g _lspath_cmd (string join ' ' -- $argv)
+end
+function _lspath_prompt --on-event fish_prompt
+    set -l p (string join : -- $PATH)
+    if test "$p" != "$_lspath_path"
+        set -l cmd (string replace -a \t ' ' -- "$_lspath_cmd" | string join ' ')
+        printf '%s\t%s\t%s\n' (date +%s) "$cmd" "$p" >> $` + SessionLogEnv + `
+        set -g _lspath_path $p
+    end
+    set -g _lspath_cmd
+end
+`,
+}
+
+// PromptInit returns the code that installs the prompt hook in shell, to
+// be evaluated by its startup file, e.g. eval "$(lspath prompt-init zsh)".
+// It also removes logs no shell has written to in a month.
+func PromptInit(shell string) (string, error) {
+	hook, ok := promptHooks[filepath.Base(shell)]
+	if !ok {
+		return "", fmt.Errorf("prompt-init supports zsh, bash and fish, not %q", shell)
+	}
+	dir := SessionLogDir()
+	if files, err := os.ReadDir(dir); err == nil {
+		for _, f := range files {
+			if info, err := f.Info(); err == nil && time.Since(info.ModTime()) > sessionLogMaxAge {
+				os.Remove(filepath.Join(dir, f.Name()))
+			}
+		}
+	}
+	return strings.ReplaceAll(hook, "{{DIR}}", dir), nil
+}
+
+// sessionChange is a line of a prompt hook's log.
+type sessionChange struct {
+	At      time.Time
+	Command string
+	Path    string
+}
+
+// readSessionLog reads the changes logged in file, oldest first.
+func readSessionLog(file string) []sessionChange {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var changes []sessionChange
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		secs, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		changes = append(changes, sessionChange{At: time.Unix(secs, 0), Command: strings.TrimSpace(fields[1]), Path: fields[2]})
+	}
+	return changes
+}
+
+// ApplySessionLog explains the session-only entries of res with the
+// prompt hook's log: the command after which each appeared, and when. The
+// log must end with sessionPath, or it belongs to another shell or PATH
+// has changed since the last prompt, and is not used.
+func ApplySessionLog(res *model.AnalysisResult, file, sessionPath string) {
+	if file == "" {
+		return
+	}
+	changes := readSessionLog(file)
+	if len(changes) == 0 || changes[len(changes)-1].Path != sessionPath {
+		return
+	}
+	sets := make([]map[string]bool, len(changes))
+	for i, c := range changes {
+		sets[i] = make(map[string]bool)
+		for _, p := range model.SplitPathList(c.Path) {
+			sets[i][model.CanonicalPath(p, model.CanonOptions{})] = true
+		}
+	}
+	for i := range res.PathEntries {
+		e := &res.PathEntries[i]
+		if !e.IsSessionOnly {
+			continue
+		}
+		key := model.CanonicalPath(e.Value, model.CanonOptions{})
+		// The latest change that added it; earlier ones were undone since
+		for c := len(changes) - 1; c > 0; c-- {
+			if !sets[c][key] || sets[c-1][key] {
+				continue
+			}
+			when := changes[c].At.Format("Jan 2 15:04")
+			note := fmt.Sprintf("Added on %s by `%s`", when, changes[c].Command)
+			if changes[c].Command == "" {
+				note = fmt.Sprintf("Added at the prompt on %s, not by a command (a prompt hook such as direnv's)", when)
+			}
+			if e.SessionNote != manualSessionNote && e.SessionNote != "" {
+				note = e.SessionNote + ". " + note // What the activation was, then when
+			}
+			e.SessionNote = note
+			e.Confidence = model.ConfidenceMedium
+			e.ConfidenceReason = "Recorded by the lspath prompt-init hook"
+			break
+		}
+	}
+}
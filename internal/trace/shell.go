@@ -1,7 +1,15 @@
 package trace
 
 import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
+	"unicode/utf16"
 )
 
 // Shell defines the interface for shell-specific tracing commands.
@@ -11,11 +19,34 @@ type Shell interface {
 	Name() string
 }
 
+// modeShell is implemented by shells whose startup files depend on whether
+// the shell is a login and/or interactive shell. GetTraceCommand traces a
+// login interactive shell.
+type modeShell interface {
+	TraceCommandFor(login, interactive bool) string
+}
+
+// xtraceFlags returns the flags for an xtrace run in the given mode.
+func xtraceFlags(login, interactive bool) string {
+	flags := "-x"
+	if login {
+		flags += "l"
+	}
+	if interactive {
+		flags += "i"
+	}
+	return flags
+}
+
 // ZshShell implements Shell for Zsh.
 type ZshShell struct{}
 
 func (s *ZshShell) GetTraceCommand() string {
-	return "zsh -xli -c exit"
+	return "zsh -xli -c 'exit 0'"
+}
+
+func (s *ZshShell) TraceCommandFor(login, interactive bool) string {
+	return "zsh " + xtraceFlags(login, interactive) + " -c 'exit 0'"
 }
 
 func (s *ZshShell) GetPS4() string {
@@ -27,11 +58,26 @@ func (s *ZshShell) Name() string {
 	return "zsh"
 }
 
+func (s *ZshShell) HashListCommand() string {
+	return "hash -L"
+}
+
+// RestrictedTraceCommand adds NO_CLOBBER so redirections cannot overwrite
+// files. zsh cannot import functions from the environment, so unlike bash
+// the side-effect commands themselves still run.
+func (s *ZshShell) RestrictedTraceCommand() string {
+	return "zsh -C -xli -c 'exit 0'"
+}
+
 // BashShell implements Shell for Bash.
 type BashShell struct{}
 
 func (s *BashShell) GetTraceCommand() string {
-	return "bash -xli -c exit"
+	return "bash -xli -c 'exit 0'"
+}
+
+func (s *BashShell) TraceCommandFor(login, interactive bool) string {
+	return "bash " + xtraceFlags(login, interactive) + " -c 'exit 0'"
 }
 
 func (s *BashShell) GetPS4() string {
@@ -43,13 +89,275 @@ func (s *BashShell) Name() string {
 	return "bash"
 }
 
-// DetectShell attempts to identify the user's shell or defaults to Zsh.
+func (s *BashShell) HashListCommand() string {
+	return "hash -l"
+}
+
+// RestrictedTraceCommand adds noclobber so redirections cannot overwrite
+// files, and imports a do-nothing function for each command that starts a
+// daemon or writes files, shadowing the real command. The functions are
+// passed with env(1) because sh drops variables named like BASH_FUNC_x%%.
+func (s *BashShell) RestrictedTraceCommand() string {
+	var names []string
+	for _, cmds := range []map[string][]string{daemonCommands, writeCommands} {
+		for name := range cmds {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	sb.WriteString("env")
+	for _, name := range names {
+		sb.WriteString(" 'BASH_FUNC_" + name + "%%=() { :; }'")
+	}
+	sb.WriteString(" bash -C -xli -c 'exit 0'")
+	return sb.String()
+}
+
+// FishShell implements Shell for fish. fish has no xtrace/PS4; tracing is
+// enabled with the fish_trace variable instead (see TraceEnv).
+type FishShell struct{}
+
+func (s *FishShell) GetTraceCommand() string {
+	return "fish --login --interactive --command 'exit 0'"
+}
+
+func (s *FishShell) TraceCommandFor(login, interactive bool) string {
+	cmd := "fish"
+	if login {
+		cmd += " --login"
+	}
+	if interactive {
+		cmd += " --interactive"
+	}
+	return cmd + " --command 'exit 0'"
+}
+
+func (s *FishShell) GetPS4() string {
+	return ""
+}
+
+func (s *FishShell) Name() string {
+	return "fish"
+}
+
+// RestrictedTraceCommand uses private mode, so the trace leaves no history.
+func (s *FishShell) RestrictedTraceCommand() string {
+	return "fish --private --login --interactive --command 'exit 0'"
+}
+
+// TraceEnv enables fish's built-in command tracing.
+func (s *FishShell) TraceEnv() []string {
+	return []string{"fish_trace=1"}
+}
+
+// PowerShellShell implements Shell for PowerShell 7 (pwsh) on macOS and
+// Linux. pwsh has no xtrace, so the trace command dot-sources each $PROFILE
+// script itself with a breakpoint on every line, and prints xtrace-style
+// "+file:line>PATH=value" lines whenever the variable changes.
+type PowerShellShell struct{}
+
+// pwshTraceScript loads the profiles in pwsh's own order. A breakpoint fires
+// before its line runs, so a change seen there was made by the previous line.
+// The traced variable is named by LSPATH_TRACE_VAR (see RunTraceVar).
+const pwshTraceScript = `
+$global:lspathVar = if ($env:LSPATH_TRACE_VAR) { $env:LSPATH_TRACE_VAR } else { 'PATH' }
+$global:lspathLast = [Environment]::GetEnvironmentVariable($global:lspathVar)
+$global:lspathLine = 0
+function global:LspathEmit([string]$file) {
+  $value = [Environment]::GetEnvironmentVariable($global:lspathVar)
+  if ($value -ne $global:lspathLast) {
+    $global:lspathLast = $value
+    [Console]::Error.WriteLine("+$($file):$($global:lspathLine)>$($global:lspathVar)=$value")
+  }
+}
+foreach ($p in @($PROFILE.AllUsersAllHosts, $PROFILE.AllUsersCurrentHost, $PROFILE.CurrentUserAllHosts, $PROFILE.CurrentUserCurrentHost)) {
+  if (-not (Test-Path -LiteralPath $p)) { continue }
+  [Console]::Error.WriteLine("+$($p):0>. $p")
+  $global:lspathLine = 0
+  $n = @(Get-Content -LiteralPath $p).Count
+  $bps = @()
+  if ($n -gt 0) {
+    $bps = Set-PSBreakpoint -Script $p -Line (1..$n) -Action {
+      LspathEmit $_.Script
+      $global:lspathLine = $_.Line
+    }
+  }
+  try { . $p } catch { [Console]::Error.WriteLine("lspath: $p failed: $_") }
+  LspathEmit $p
+  $bps | Remove-PSBreakpoint
+}
+exit 0
+`
+
+func (s *PowerShellShell) GetTraceCommand() string {
+	// -EncodedCommand takes base64 UTF-16LE, which avoids quoting the script for sh
+	units := utf16.Encode([]rune(pwshTraceScript))
+	buf := make([]byte, 0, len(units)*2)
+	for _, u := range units {
+		buf = append(buf, byte(u), byte(u>>8))
+	}
+	return "pwsh -NoLogo -NoProfile -NonInteractive -EncodedCommand " + base64.StdEncoding.EncodeToString(buf)
+}
+
+func (s *PowerShellShell) GetPS4() string {
+	return ""
+}
+
+func (s *PowerShellShell) Name() string {
+	return "pwsh"
+}
+
+// CshShell implements Shell for tcsh and csh. Their -x output has no file
+// or line and a login shell cannot be traced (-l must be the only flag), so
+// the trace command sources the startup files itself, in the shell's own
+// order, with echo set, and marks each file in the xtrace style.
+type CshShell struct {
+	Binary string // "tcsh" or "csh"
+}
+
+func (s *CshShell) GetTraceCommand() string {
+	return s.TraceCommandFor(true, true)
+}
+
+// cshTraceScript sources the startup files in csh's order, marking each
+// file. The shell runs it with -f, so it has not read them already. The
+// placeholders are filled in by TraceCommandFor.
+const cshTraceScript = `<<'LSPATH_CSH'
+%sset lspath_rc = $HOME/.cshrc
+%sforeach lspath_f ( %s )
+  if ( -r $lspath_f ) then
+    echo "+${lspath_f}:0>source $lspath_f" >& /dev/stderr
+    set echo
+    source $lspath_f
+    unset echo
+  endif
+end
+exit 0
+LSPATH_CSH`
+
+// TraceCommandFor feeds the driver script to the shell on stdin. tcsh
+// prefers ~/.tcshrc; csh only reads ~/.cshrc.
+func (s *CshShell) TraceCommandFor(login, interactive bool) string {
+	prompt := ""
+	if interactive {
+		// Startup files test $?prompt to tell an interactive shell
+		prompt = "set prompt = '> '\n"
+	}
+	rc := ""
+	if s.Binary == "tcsh" {
+		rc = "if ( -e $HOME/.tcshrc ) set lspath_rc = $HOME/.tcshrc\n"
+	}
+	files := "/etc/csh.cshrc $lspath_rc"
+	if login {
+		files = "/etc/csh.cshrc /etc/csh.login $lspath_rc $HOME/.login"
+	}
+	return s.Binary + " -f -s " + fmt.Sprintf(cshTraceScript, prompt, rc, files)
+}
+
+func (s *CshShell) GetPS4() string {
+	return ""
+}
+
+func (s *CshShell) Name() string {
+	return s.Binary
+}
+
+// PosixShell implements Shell for a plain POSIX sh such as dash or busybox
+// ash, often the only shell in a container. Its PS4 cannot name the file
+// being read, and dash leaves LINENO empty there, so the parser attributes
+// commands by matching them against the startup files (see parser_posix.go).
+type PosixShell struct {
+	Binary string // "sh", "dash" or "ash"
+}
+
+func (s *PosixShell) GetTraceCommand() string {
+	return s.TraceCommandFor(true, true)
+}
+
+func (s *PosixShell) TraceCommandFor(login, interactive bool) string {
+	return s.Binary + " " + xtraceFlags(login, interactive) + " -c 'exit 0'"
+}
+
+func (s *PosixShell) GetPS4() string {
+	// Format: +sh:line>command
+	return "+sh:${LINENO}>"
+}
+
+func (s *PosixShell) Name() string {
+	return s.Binary
+}
+
+func (s *PosixShell) HashListCommand() string {
+	return "hash"
+}
+
+// KshShell implements Shell for the Korn shells. ksh93 can name the file
+// being read in PS4 with ${.sh.file}; mksh and OpenBSD's ksh cannot, and
+// are parsed like a plain sh (see parser_posix.go).
+type KshShell struct {
+	Binary  string // "ksh", "ksh93", "mksh" or "oksh"
+	FileVar bool   // The shell has ksh93's ${.sh.file}
+}
+
+func (s *KshShell) GetTraceCommand() string {
+	return s.TraceCommandFor(true, true)
+}
+
+func (s *KshShell) TraceCommandFor(login, interactive bool) string {
+	return s.Binary + " " + xtraceFlags(login, interactive) + " -c 'exit 0'"
+}
+
+func (s *KshShell) GetPS4() string {
+	if s.FileVar {
+		// Format: +file:line>command
+		return "+${.sh.file}:${LINENO}>"
+	}
+	return "+sh:${LINENO}>"
+}
+
+func (s *KshShell) Name() string {
+	return s.Binary
+}
+
+func (s *KshShell) HashListCommand() string {
+	return "hash"
+}
+
+// kshHasFileVar reports whether the ksh at shellPath is a ksh93, by asking
+// it to expand ${.sh.version}, which is a syntax error in other Korn shells.
+func kshHasFileVar(shellPath string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return exec.CommandContext(ctx, shellPath, "-c", `[ -n "${.sh.version}" ]`).Run() == nil
+}
+
 // DetectShell attempts to identify the user's shell or defaults to Zsh.
 func DetectShell(shellPath string) Shell {
 	// Check for "bash" in the path or name
 	if strings.Contains(shellPath, "bash") {
 		return &BashShell{}
 	}
+	if strings.Contains(shellPath, "fish") {
+		return &FishShell{}
+	}
+	if strings.Contains(shellPath, "pwsh") || strings.Contains(shellPath, "powershell") {
+		return &PowerShellShell{}
+	}
+	if strings.HasSuffix(shellPath, "tcsh") {
+		return &CshShell{Binary: "tcsh"}
+	}
+	if strings.HasSuffix(shellPath, "csh") {
+		return &CshShell{Binary: "csh"}
+	}
+	switch base := filepath.Base(shellPath); base {
+	case "sh", "dash", "ash":
+		return &PosixShell{Binary: base}
+	case "ksh", "ksh93":
+		return &KshShell{Binary: base, FileVar: kshHasFileVar(shellPath)}
+	case "mksh", "oksh":
+		return &KshShell{Binary: base}
+	}
 	// Default to Zsh as it's the specific request target, and macOS default.
 	return &ZshShell{}
 }
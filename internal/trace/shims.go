@@ -0,0 +1,168 @@
+package trace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// versionManager describes a tool that switches between installed versions
+// of a language by what it puts on PATH.
+type versionManager struct {
+	Name    string
+	Kind    string // model.KindShims or model.KindManagedVersion
+	Matches func(p string) bool
+	Selects string // How it picks the version, e.g. ".python-version"
+	Which   string // Command that shows what a name really runs, "%s" for the name
+}
+
+// shimDir matches dir, relative to the home directory or to the directory
+// named by rootEnv if set, followed by /shims (e.g. ~/.pyenv/shims).
+func shimDir(dir, rootEnv string) func(p string) bool {
+	return func(p string) bool {
+		p = filepath.ToSlash(p)
+		if root := os.Getenv(rootEnv); root != "" && p == filepath.ToSlash(filepath.Join(root, "shims")) {
+			return true
+		}
+		return strings.HasSuffix(p, "/"+dir+"/shims")
+	}
+}
+
+var pathVersionManagers = []versionManager{
+	{
+		Name: "asdf", Kind: model.KindShims, Matches: shimDir(".asdf", "ASDF_DATA_DIR"),
+		Selects: ".tool-versions in this directory or a parent, else ~/.tool-versions",
+		Which:   "asdf which %s",
+	},
+	{
+		Name: "mise", Kind: model.KindShims,
+		Matches: func(p string) bool {
+			return shimDir("mise", "MISE_DATA_DIR")(p) || shimDir("rtx", "RTX_DATA_DIR")(p)
+		},
+		Selects: "mise.toml or .tool-versions in this directory or a parent, else the global config",
+		Which:   "mise which %s",
+	},
+	{
+		Name: "pyenv", Kind: model.KindShims,
+		Matches: func(p string) bool {
+			return shimDir(".pyenv", "PYENV_ROOT")(p) || strings.HasSuffix(filepath.ToSlash(p), "/pyenv-win/shims")
+		},
+		Selects: "$PYENV_VERSION, else .python-version in this directory or a parent, else `pyenv global`",
+		Which:   "pyenv which %s",
+	},
+	{
+		Name: "rbenv", Kind: model.KindShims, Matches: shimDir(".rbenv", "RBENV_ROOT"),
+		Selects: "$RBENV_VERSION, else .ruby-version in this directory or a parent, else `rbenv global`",
+		Which:   "rbenv which %s",
+	},
+	{
+		Name: "nodenv", Kind: model.KindShims, Matches: shimDir(".nodenv", "NODENV_ROOT"),
+		Selects: "$NODENV_VERSION, else .node-version in this directory or a parent, else `nodenv global`",
+		Which:   "nodenv which %s",
+	},
+	{
+		Name: "goenv", Kind: model.KindShims, Matches: shimDir(".goenv", "GOENV_ROOT"),
+		Selects: "$GOENV_VERSION, else .go-version in this directory or a parent, else `goenv global`",
+		Which:   "goenv which %s",
+	},
+	{
+		Name: "jenv", Kind: model.KindShims, Matches: shimDir(".jenv", "JENV_ROOT"),
+		Selects: "$JENV_VERSION, else .java-version in this directory or a parent, else `jenv global`",
+		Which:   "jenv which %s",
+	},
+	{
+		Name: "plenv", Kind: model.KindShims, Matches: shimDir(".plenv", "PLENV_ROOT"),
+		Selects: "$PLENV_VERSION, else .perl-version in this directory or a parent, else `plenv global`",
+		Which:   "plenv which %s",
+	},
+	{
+		Name: "volta", Kind: model.KindShims,
+		Matches: func(p string) bool {
+			p = filepath.ToSlash(p)
+			if home := os.Getenv("VOLTA_HOME"); home != "" && p == filepath.ToSlash(filepath.Join(home, "bin")) {
+				return true
+			}
+			return strings.HasSuffix(p, "/.volta/bin") || strings.HasSuffix(p, "/Volta/bin")
+		},
+		Selects: "the volta section of the nearest package.json, else your default (`volta install`)",
+		Which:   "volta which %s",
+	},
+	{
+		Name: "nvm", Kind: model.KindManagedVersion,
+		Matches: func(p string) bool {
+			p = filepath.ToSlash(p)
+			return strings.Contains(p, "/.nvm/versions/node/") && strings.HasSuffix(p, "/bin")
+		},
+		Selects: "`nvm use` in this shell, else `nvm alias default`; .nvmrc only when you run nvm use",
+		Which:   "lspath which %s",
+	},
+	{
+		Name: "fnm", Kind: model.KindManagedVersion,
+		Matches: func(p string) bool {
+			return strings.Contains(filepath.ToSlash(p), "/fnm_multishells/")
+		},
+		Selects: "`fnm use` in this shell (automatic with --use-on-cd and .node-version or .nvmrc), else `fnm default`",
+		Which:   "lspath which %s",
+	},
+}
+
+// lookupVersionManager returns the version manager that owns dir, if any.
+func lookupVersionManager(dir string) (versionManager, bool) {
+	if dir == "" {
+		return versionManager{}, false
+	}
+	p := model.ExpandTilde(dir)
+	for _, vm := range pathVersionManagers {
+		if vm.Matches(p) {
+			return vm, true
+		}
+	}
+	return versionManager{}, false
+}
+
+// labelVersionManagers marks entries that are a version manager's shims or
+// managed version directory.
+func labelVersionManagers(entries []model.PathEntry) {
+	for i := range entries {
+		if vm, ok := lookupVersionManager(entries[i].Value); ok {
+			entries[i].Kind = vm.Kind
+			entries[i].VersionManager = vm.Name
+		}
+	}
+}
+
+// VersionManagerLabel is a short label for an entry a version manager
+// owns, e.g. "pyenv shims", or "".
+func VersionManagerLabel(e model.PathEntry) string {
+	if e.VersionManager == "" {
+		return ""
+	}
+	return e.VersionManager + " " + e.Kind
+}
+
+// ExplainVersionManager describes how the version manager that owns e
+// decides what runs, one sentence per line, or nil if none does.
+func ExplainVersionManager(e model.PathEntry) []string {
+	vm, ok := lookupVersionManager(e.Value)
+	if !ok {
+		return nil
+	}
+	var lines []string
+	if vm.Kind == model.KindShims {
+		lines = append(lines,
+			fmt.Sprintf("Every command here is a small script that hands the command to %s.", vm.Name),
+			"It runs whichever installed version is selected, so the same name can run different binaries in different directories.",
+			"A shim wins over later entries with the same name; with the \"system\" version selected it passes the command on to the next one on PATH.")
+	} else {
+		lines = append(lines,
+			fmt.Sprintf("This is one installed version's bin directory, put on PATH by %s.", vm.Name),
+			"Switching versions replaces this entry, so it can differ between shells.")
+	}
+	lines = append(lines,
+		"Selected by: "+vm.Selects+".",
+		"To see what a command really runs: "+fmt.Sprintf(vm.Which, "<command>"))
+	return lines
+}
@@ -0,0 +1,145 @@
+package trace
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// daemonCommands start agents or other processes that outlive the shell.
+// A nil entry means any use counts; otherwise one of the listed arguments
+// must appear (e.g. "emacs --daemon" but not plain "emacs").
+var daemonCommands = map[string][]string{
+	"gpg-agent":   nil,
+	"ssh-agent":   nil,
+	"keychain":    nil,
+	"dbus-launch": nil,
+	"nohup":       nil,
+	"setsid":      nil,
+	"disown":      nil,
+	"gpgconf":     {"--launch"},
+	"emacs":       {"--daemon", "--bg-daemon"},
+	"tmux":        {"start-server", "-d"},
+	"screen":      {"-dm", "-d"},
+}
+
+// writeCommands create, change or delete files.
+var writeCommands = map[string][]string{
+	"touch":    nil,
+	"mkdir":    nil,
+	"rm":       nil,
+	"rmdir":    nil,
+	"mv":       nil,
+	"cp":       nil,
+	"ln":       nil,
+	"tee":      nil,
+	"chmod":    nil,
+	"chown":    nil,
+	"truncate": nil,
+	"install":  nil,
+	"sed":      {"-i"},
+}
+
+// DetectSideEffects finds traced commands that start background processes
+// or modify files. It is best effort: xtrace shows commands but not
+// redirections, so "echo x > file" goes unnoticed.
+func DetectSideEffects(events []model.TraceEvent) []model.SideEffect {
+	var effects []model.SideEffect
+	seen := make(map[string]bool)
+	for _, ev := range events {
+		fields := strings.Fields(ev.RawCommand)
+		if len(fields) == 0 {
+			continue
+		}
+		name := filepath.Base(strings.Trim(fields[0], `'"`))
+		kind := ""
+		if args, ok := daemonCommands[name]; ok && hasAnyArg(fields[1:], args) {
+			kind = model.SideEffectDaemon
+		} else if args, ok := writeCommands[name]; ok && hasAnyArg(fields[1:], args) {
+			kind = model.SideEffectWrite
+		}
+		if kind == "" {
+			continue
+		}
+		key := fmt.Sprintf("%s:%d:%s", ev.File, ev.Line, name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		effects = append(effects, model.SideEffect{
+			File:    ev.File,
+			Line:    ev.Line,
+			Command: ev.RawCommand,
+			Kind:    kind,
+		})
+	}
+	return effects
+}
+
+// hasAnyArg reports whether args contains one of want; an empty want
+// matches anything.
+func hasAnyArg(args, want []string) bool {
+	if len(want) == 0 {
+		return true
+	}
+	for _, a := range args {
+		for _, w := range want {
+			if strings.Trim(a, `'"`) == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sideEffectDiagnostic summarizes effects for the global diagnostics.
+func sideEffectDiagnostic(effects []model.SideEffect) string {
+	daemons := 0
+	for _, e := range effects {
+		if e.Kind == model.SideEffectDaemon {
+			daemons++
+		}
+	}
+	return fmt.Sprintf("WARNING: Tracing your startup files ran %d command(s) with side effects (%d starting background processes, %d modifying files). Every new shell does the same; use --no-side-effects to trace with these commands disabled.",
+		len(effects), daemons, len(effects)-daemons)
+}
+
+// GenerateSideEffects renders the side effects section of the report.
+func GenerateSideEffects(res model.AnalysisResult) string {
+	if len(res.SideEffects) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("SIDE EFFECTS DURING TRACE\n")
+	sb.WriteString("-------------------------\n")
+	for _, e := range res.SideEffects {
+		sb.WriteString(fmt.Sprintf("⚠️  %s:%d %s\n", e.File, e.Line, e.Kind))
+		sb.WriteString(fmt.Sprintf("    %s\n", truncateCommand(e.Command, 100)))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// truncateCommand shortens long traced commands for display.
+func truncateCommand(cmd string, limit int) string {
+	if len(cmd) <= limit {
+		return cmd
+	}
+	return cmd[:limit-1] + "…"
+}
+
+// restrictionSummary describes what --no-side-effects does for shell.
+func restrictionSummary(shell Shell) string {
+	switch shell.(type) {
+	case *BashShell:
+		return "noclobber, side-effect commands replaced by no-ops"
+	case *ZshShell:
+		return "NO_CLOBBER only; zsh cannot disable individual commands"
+	case *FishShell:
+		return "private mode only; fish cannot disable individual commands"
+	default:
+		return "not supported by " + shell.Name() + "; traced normally"
+	}
+}
@@ -0,0 +1,192 @@
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// SaveSnapshot writes res as indented JSON, the same format as --json.
+func SaveSnapshot(path string, res model.AnalysisResult) error {
+	data, err := MarshalDocument(res, 0)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// LoadSnapshot reads a result saved by SaveSnapshot or --json, of any
+// schema version up to the current one.
+func LoadSnapshot(path string) (model.AnalysisResult, error) {
+	var doc model.Document
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return doc.AnalysisResult, err
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return doc.AnalysisResult, fmt.Errorf("%s is not an lspath snapshot: %w", path, err)
+	}
+	if err := doc.Supported(); err != nil {
+		return doc.AnalysisResult, fmt.Errorf("%s was %w", path, err)
+	}
+	return doc.AnalysisResult, nil
+}
+
+// EntryMove is an entry found in both analyses.
+type EntryMove struct {
+	Value    string
+	From, To int // Indices in the earlier and later PathEntries
+}
+
+// ResultDiff is what changed between two analyses. Entries are matched by
+// canonical value; duplicates are ignored since they never take effect.
+type ResultDiff struct {
+	Added        []int       // Indices into the later PathEntries
+	Removed      []int       // Indices into the earlier PathEntries
+	Reordered    []EntryMove // Entries whose priority relative to the others changed
+	Reattributed []EntryMove // Entries now added by a different file or line
+}
+
+// Empty reports whether the analyses are equivalent.
+func (d ResultDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Reordered) == 0 && len(d.Reattributed) == 0
+}
+
+// DiffResults compares an earlier analysis with a later one.
+func DiffResults(before, after model.AnalysisResult) ResultDiff {
+	var d ResultDiff
+	beforeIdx := firstIndices(before.PathEntries)
+	afterIdx := firstIndices(after.PathEntries)
+
+	// Common entries in earlier order, with their later positions
+	var common []EntryMove
+	for i, e := range before.PathEntries {
+		key := model.CanonicalPath(e.Value, model.CanonOptions{})
+		if beforeIdx[key] != i {
+			continue
+		}
+		j, ok := afterIdx[key]
+		if !ok {
+			d.Removed = append(d.Removed, i)
+			continue
+		}
+		common = append(common, EntryMove{Value: e.Value, From: i, To: j})
+
+		b, a := before.PathEntries[i], after.PathEntries[j]
+		if b.SourceFile != a.SourceFile || b.LineNumber != a.LineNumber {
+			d.Reattributed = append(d.Reattributed, EntryMove{Value: e.Value, From: i, To: j})
+		}
+	}
+	for j, e := range after.PathEntries {
+		key := model.CanonicalPath(e.Value, model.CanonOptions{})
+		if afterIdx[key] == j {
+			if _, ok := beforeIdx[key]; !ok {
+				d.Added = append(d.Added, j)
+			}
+		}
+	}
+
+	// Entries outside the longest run that kept its relative order moved
+	kept := longestIncreasing(common)
+	for k, m := range common {
+		if !kept[k] {
+			d.Reordered = append(d.Reordered, m)
+		}
+	}
+	return d
+}
+
+// firstIndices maps each canonical value to its first index.
+func firstIndices(entries []model.PathEntry) map[string]int {
+	idx := make(map[string]int)
+	for i, e := range entries {
+		key := model.CanonicalPath(e.Value, model.CanonOptions{})
+		if _, ok := idx[key]; !ok {
+			idx[key] = i
+		}
+	}
+	return idx
+}
+
+// longestIncreasing marks a longest subsequence of moves whose To indices
+// increase, i.e. the largest set of entries that kept their relative order.
+func longestIncreasing(moves []EntryMove) map[int]bool {
+	var tails []int // tails[l] = index into moves ending the best run of length l+1
+	prev := make([]int, len(moves))
+	for k, m := range moves {
+		l := sort.Search(len(tails), func(i int) bool { return moves[tails[i]].To >= m.To })
+		if l > 0 {
+			prev[k] = tails[l-1]
+		} else {
+			prev[k] = -1
+		}
+		if l == len(tails) {
+			tails = append(tails, k)
+		} else {
+			tails[l] = k
+		}
+	}
+	kept := make(map[int]bool)
+	if len(tails) > 0 {
+		for k := tails[len(tails)-1]; k >= 0; k = prev[k] {
+			kept[k] = true
+		}
+	}
+	return kept
+}
+
+// FormatDiff renders d as text, in the style of the report.
+func FormatDiff(before, after model.AnalysisResult, d ResultDiff) string {
+	var sb strings.Builder
+	name := after.VariableName()
+	if before.VariableName() != name {
+		sb.WriteString(fmt.Sprintf("Note: comparing %s with %s\n\n", before.VariableName(), name))
+	}
+	if d.Empty() {
+		sb.WriteString(fmt.Sprintf("No differences: %s has the same entries, order and sources.\n", name))
+		return sb.String()
+	}
+
+	source := func(e model.PathEntry) string {
+		if e.LineNumber == 0 {
+			return e.SourceFile
+		}
+		return fmt.Sprintf("%s:%d", e.SourceFile, e.LineNumber)
+	}
+
+	if len(d.Added) > 0 {
+		sb.WriteString(fmt.Sprintf("ADDED (%d)\n", len(d.Added)))
+		for _, j := range d.Added {
+			e := after.PathEntries[j]
+			sb.WriteString(fmt.Sprintf("+ #%d %s (from %s)\n", j+1, e.Value, source(e)))
+		}
+		sb.WriteString("\n")
+	}
+	if len(d.Removed) > 0 {
+		sb.WriteString(fmt.Sprintf("REMOVED (%d)\n", len(d.Removed)))
+		for _, i := range d.Removed {
+			e := before.PathEntries[i]
+			sb.WriteString(fmt.Sprintf("- #%d %s (was from %s)\n", i+1, e.Value, source(e)))
+		}
+		sb.WriteString("\n")
+	}
+	if len(d.Reordered) > 0 {
+		sb.WriteString(fmt.Sprintf("REORDERED (%d)\n", len(d.Reordered)))
+		for _, m := range d.Reordered {
+			sb.WriteString(fmt.Sprintf("~ %s: #%d → #%d\n", m.Value, m.From+1, m.To+1))
+		}
+		sb.WriteString("\n")
+	}
+	if len(d.Reattributed) > 0 {
+		sb.WriteString(fmt.Sprintf("RE-ATTRIBUTED (%d)\n", len(d.Reattributed)))
+		for _, m := range d.Reattributed {
+			sb.WriteString(fmt.Sprintf("* %s: %s → %s\n", m.Value, source(before.PathEntries[m.From]), source(after.PathEntries[m.To])))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
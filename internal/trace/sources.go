@@ -0,0 +1,100 @@
+package trace
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// sourceContext is the number of lines shown around each contributing line.
+const sourceContext = 2
+
+// GenerateSourceExcerpts renders the lines of each config file that
+// contributed entries, with surrounding context and a note on what each line
+// added. Appended to a report it makes a self-contained support artifact.
+func GenerateSourceExcerpts(res model.AnalysisResult) string {
+	// Contributing lines per file, in startup order
+	var files []string
+	byFile := make(map[string]map[int][]int)
+	for _, n := range res.FlowNodes {
+		for _, idx := range n.Entries {
+			e := res.PathEntries[idx]
+			if e.LineNumber == 0 || e.IsSessionOnly {
+				continue
+			}
+			lines, ok := byFile[e.SourceFile]
+			if !ok {
+				lines = make(map[int][]int)
+				byFile[e.SourceFile] = lines
+				files = append(files, e.SourceFile)
+			}
+			lines[e.LineNumber] = append(lines[e.LineNumber], idx)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("CONFIG FILE EXCERPTS\n")
+	sb.WriteString("--------------------\n")
+	if len(files) == 0 {
+		sb.WriteString("No entries were traced to a config file line.\n")
+		return sb.String()
+	}
+
+	for _, file := range files {
+		sb.WriteString("\n" + file + "\n")
+		content, err := os.ReadFile(model.ExpandTilde(file))
+		if err != nil {
+			sb.WriteString(fmt.Sprintf("  (cannot read file: %v)\n", err))
+			continue
+		}
+		text := strings.TrimSuffix(string(content), "\n")
+		fileLines := strings.Split(text, "\n")
+
+		contributing := byFile[file]
+		var nums []int
+		for n := range contributing {
+			nums = append(nums, n)
+		}
+		sort.Ints(nums)
+
+		width := len(fmt.Sprint(min(nums[len(nums)-1]+sourceContext, len(fileLines))))
+		shownTo := 0 // Last line written, so overlapping context isn't repeated
+		for _, n := range nums {
+			from := max(n-sourceContext, shownTo+1, 1)
+			to := min(n+sourceContext, len(fileLines))
+			if shownTo > 0 && from > shownTo+1 {
+				sb.WriteString(fmt.Sprintf("  %*s\n", width+4, "..."))
+			}
+			for ln := from; ln <= to; ln++ {
+				marker := "  "
+				if _, ok := contributing[ln]; ok {
+					marker = "> "
+				}
+				sb.WriteString(fmt.Sprintf("  %s%*d | %s\n", marker, width, ln, fileLines[ln-1]))
+				for _, idx := range contributing[ln] {
+					sb.WriteString(fmt.Sprintf("  %*s   ↳ adds #%d %s%s\n", width+2, "", idx+1, res.PathEntries[idx].Value, excerptNote(res, idx)))
+				}
+			}
+			shownTo = max(shownTo, to)
+			if n > len(fileLines) {
+				sb.WriteString(fmt.Sprintf("  (line %d is past the end of the file; it changed since the trace)\n", n))
+			}
+		}
+	}
+	return sb.String()
+}
+
+// excerptNote flags entries worth attention in an excerpt.
+func excerptNote(res model.AnalysisResult, idx int) string {
+	e := res.PathEntries[idx]
+	switch {
+	case e.IsDuplicate:
+		return fmt.Sprintf(" (duplicate of #%d)", e.DuplicateOf+1)
+	case isMissing(e.Value):
+		return " (missing)"
+	}
+	return ""
+}
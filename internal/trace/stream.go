@@ -0,0 +1,128 @@
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"lspath/internal/model"
+)
+
+// Types of StreamRecord, in the order they appear.
+const (
+	StreamStart  = "start"  // Once, before the trace
+	StreamEvent  = "event"  // Each parsed trace line
+	StreamChange = "change" // After an event that changed the variable
+	StreamEnd    = "end"    // Once, when the trace has been read
+)
+
+// StreamRecord is one line of --json --stream output. Which fields are set
+// depends on Type.
+type StreamRecord struct {
+	Type          string
+	SchemaVersion int    `json:",omitempty"` // start
+	Variable      string `json:",omitempty"` // start
+	Shell         string `json:",omitempty"` // start
+
+	*model.TraceEvent // event
+
+	Source  string   `json:",omitempty"` // change: file:line of the command that made it
+	Value   string   `json:",omitempty"` // change: the new value; end: the final one
+	Added   []string `json:",omitempty"` // change: segments it added
+	Removed []string `json:",omitempty"` // change: segments it removed
+
+	Events    int                `json:",omitempty"` // end
+	StartupMs int64              `json:",omitempty"` // end: how long the traced shell took
+	Parser    *model.ParserStats `json:",omitempty"` // end
+	Warnings  []string           `json:",omitempty"` // end: why the shell stopped early and errors it reported
+}
+
+// StreamTrace traces the user's shell startup and writes each event, and
+// each change to the variable, to w as a line of JSON as soon as it is
+// parsed. Nothing is kept, so a huge trace needs no more memory than a
+// small one; the catch is that there is no analysis (attribution of the
+// final entries, duplicates and so on), for which see RunAnalysis.
+func StreamTrace(opts Options, w io.Writer) error {
+	if runtime.GOOS == "windows" {
+		return fmt.Errorf("streaming needs a shell startup trace, which Windows does not have")
+	}
+	variable := opts.Var
+	if variable == "" {
+		variable = DefaultVariable
+	}
+	enc := json.NewEncoder(w)
+
+	shell := DetectShell(os.Getenv("SHELL"))
+	start := time.Now()
+	if err := enc.Encode(StreamRecord{Type: StreamStart, SchemaVersion: model.SchemaVersion, Variable: variable, Shell: shell.Name()}); err != nil {
+		return err
+	}
+	ctx, cancel := opts.traceContext()
+	defer cancel()
+	traceOut, err := openTrace(ctx, shell, opts, variable)
+	if err != nil {
+		return err
+	}
+	defer traceOut.Close()
+
+	parser := NewParser(shell)
+	parser.Variable = variable
+	parser.Baseline = opts.baseline()
+	events, errs := parser.Parse(traceOut)
+	go func() {
+		for range errs {
+		}
+	}()
+
+	value := opts.initialValue(variable)
+	count := 0
+	var last model.TraceEvent
+	for ev := range events {
+		count++
+		last = ev
+		if err := enc.Encode(StreamRecord{Type: StreamEvent, TraceEvent: &ev}); err != nil {
+			return err
+		}
+		if ev.PathChange == "" || ev.PathChange == value {
+			continue
+		}
+		added, removed := segmentChanges(value, ev.PathChange)
+		value = ev.PathChange
+		source := fmt.Sprintf("%s:%d", ev.File, ev.Line)
+		if err := enc.Encode(StreamRecord{Type: StreamChange, Source: source, Value: value, Added: added, Removed: removed}); err != nil {
+			return err
+		}
+	}
+	end := StreamRecord{Type: StreamEnd, Value: value, Events: count, StartupMs: time.Since(start).Milliseconds(), Parser: &parser.Stats}
+	timedOut := ctx.Err() != nil
+	for _, d := range exitDiagnostics(shell, opts, []model.TraceEvent{last}, parser.Stats, timedOut, traceOut.Wait()) {
+		end.Warnings = append(end.Warnings, strings.TrimPrefix(d, "WARNING: "))
+	}
+	return enc.Encode(end)
+}
+
+// segmentChanges returns the segments of after that are not in before, and
+// those of before that are not in after, each in its own order.
+func segmentChanges(before, after string) (added, removed []string) {
+	old := make(map[string]bool)
+	for _, s := range model.SplitPathList(before) {
+		old[s] = true
+	}
+	now := make(map[string]bool)
+	for _, s := range model.SplitPathList(after) {
+		now[s] = true
+		if !old[s] {
+			added = append(added, s)
+		}
+	}
+	for _, s := range model.SplitPathList(before) {
+		if !now[s] {
+			removed = append(removed, s)
+		}
+	}
+	return added, removed
+}
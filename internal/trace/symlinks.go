@@ -0,0 +1,95 @@
+package trace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"lspath/internal/model"
+)
+
+// maxSymlinkDepth is how many links resolveSymlink follows before giving
+// up, as Linux does (ELOOP).
+const maxSymlinkDepth = 40
+
+// resolveSymlink follows path while it is a symlink and returns each link's
+// target in turn, the last being where the chain ends, and why it leads
+// nowhere if it does: a target that does not exist, a loop, or more than
+// maxSymlinkDepth links. A path that is not a symlink has no chain.
+func resolveSymlink(path string) (chain []string, broken string) {
+	seen := map[string]bool{filepath.Clean(path): true}
+	cur := path
+	for {
+		info, err := os.Lstat(cur)
+		if err != nil {
+			if len(chain) > 0 {
+				broken = fmt.Sprintf("%s does not exist", cur)
+			}
+			return chain, broken
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			return chain, ""
+		}
+		target, err := os.Readlink(cur)
+		if err != nil {
+			return chain, ""
+		}
+		if !filepath.IsAbs(target) {
+			// Relative to the directory holding the link
+			target = filepath.Join(filepath.Dir(cur), target)
+		}
+		target = filepath.Clean(target)
+		chain = append(chain, target)
+		if seen[target] {
+			return chain, fmt.Sprintf("loops back to %s", target)
+		}
+		if len(chain) > maxSymlinkDepth {
+			return chain, fmt.Sprintf("more than %d links", maxSymlinkDepth)
+		}
+		seen[target] = true
+		cur = target
+	}
+}
+
+// linkEntry records the symlink chain e's directory (path, expanded) starts,
+// if it is a symlink, and returns where the chain ends, else path. Only the
+// directory itself is followed, not its parents.
+func linkEntry(e *model.PathEntry, path string) string {
+	chain, broken := resolveSymlink(path)
+	if len(chain) == 0 {
+		return path
+	}
+	e.IsSymlink = true
+	e.SymlinkChain = chain
+	e.SymlinkTarget = chain[len(chain)-1]
+	e.SymlinkBroken = broken
+	return e.SymlinkTarget
+}
+
+// existenceDiagnostic is the diagnostic for an entry (path, expanded) that
+// is not on disk, or "" if it is or is relative.
+func existenceDiagnostic(e model.PathEntry, path string) string {
+	if e.SymlinkBroken != "" {
+		return fmt.Sprintf("Broken symlink: %s.", e.SymlinkBroken)
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) && !model.IsRelativePath(e.Value) {
+		return "Directory does not exist on disk."
+	}
+	return ""
+}
+
+// SymlinkChain renders e's chain of symlinks, e.g.
+// "/bin → /usr/bin", or "" if it is not a symlink.
+func SymlinkChain(e model.PathEntry) string {
+	if !e.IsSymlink {
+		return ""
+	}
+	s := model.DisplayPath(e.Value)
+	for _, link := range e.SymlinkChain {
+		s += " " + model.IconSymlink + " " + model.DisplayPath(link)
+	}
+	if len(e.SymlinkChain) == 0 {
+		s += " " + model.IconSymlink + " " + model.DisplayPath(e.SymlinkTarget) // Saved before chains were recorded
+	}
+	return s
+}
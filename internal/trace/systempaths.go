@@ -0,0 +1,118 @@
+package trace
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// defaultSystemPaths are directories that belong to the system default PATH
+// on common distros. Paths added by /etc/bash.bashrc or /etc/environment can be
+// missed in the trace due to the minimal baseline it starts from.
+var defaultSystemPaths = []string{
+	"/usr/local/sbin",
+	"/usr/local/bin",
+	"/usr/sbin",
+	"/usr/bin",
+	"/sbin",
+	"/bin",
+	"/usr/games",
+	"/usr/local/games",
+	"/snap/bin",
+	"/opt/local/bin",
+	"/opt/local/sbin",
+}
+
+// SystemPathsFile returns the user's list of extra system directories, one
+// per line with # comments, e.g. ~/.config/lspath/system-paths.
+func SystemPathsFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "lspath", "system-paths")
+}
+
+var (
+	systemPathsOnce sync.Once
+	systemPathSet   map[string]bool
+)
+
+// SystemPaths returns the directories treated as part of the system default
+// PATH: the built-in list, the user's SystemPathsFile, /etc/environment,
+// literal PATH assignments in /etc/profile, and `getconf PATH`.
+func SystemPaths() []string {
+	var paths []string
+	paths = append(paths, defaultSystemPaths...)
+	paths = append(paths, readSystemPathsFile(SystemPathsFile())...)
+	paths = append(paths, profilePaths("/etc/environment")...)
+	paths = append(paths, profilePaths("/etc/profile")...)
+	if out := toolOutput("getconf", "PATH"); out != "" {
+		paths = append(paths, filepath.SplitList(out)...)
+	}
+	return paths
+}
+
+// isLikelySystemPath returns true if the path looks like it should be part
+// of the system default PATH rather than a session-specific addition.
+func isLikelySystemPath(path string) bool {
+	systemPathsOnce.Do(func() {
+		systemPathSet = make(map[string]bool)
+		for _, p := range SystemPaths() {
+			if filepath.IsAbs(p) {
+				systemPathSet[filepath.Clean(p)] = true
+			}
+		}
+	})
+	return systemPathSet[filepath.Clean(path)]
+}
+
+// readSystemPathsFile reads one directory per line, ignoring blank lines
+// and # comments.
+func readSystemPathsFile(file string) []string {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var paths []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line != "" {
+			paths = append(paths, line)
+		}
+	}
+	return paths
+}
+
+// literalPathAssignment matches PATH="/a:/b" style assignments whose value
+// has no variable references.
+var literalPathAssignment = regexp.MustCompile(`(?:^|[\s;])(?:export\s+)?PATH=["']?([^"'$\s;]+)["']?`)
+
+// profilePaths extracts the directories from literal PATH assignments in a
+// system file such as /etc/environment or /etc/profile.
+func profilePaths(file string) []string {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+
+	var paths []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		for _, m := range literalPathAssignment.FindAllStringSubmatch(line, -1) {
+			paths = append(paths, filepath.SplitList(m[1])...)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package trace
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// currentUmask returns the process umask, which the traced shell inherits.
+// It has to be set to read it, so it is put straight back.
+func currentUmask() string {
+	mask := syscall.Umask(0)
+	syscall.Umask(mask)
+	return fmt.Sprintf("%04o", mask)
+}
@@ -0,0 +1,8 @@
+//go:build windows
+
+package trace
+
+// currentUmask returns "": Windows has no umask.
+func currentUmask() string {
+	return ""
+}
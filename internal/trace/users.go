@@ -0,0 +1,241 @@
+package trace
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// UserInfo describes an account whose startup files can be traced.
+type UserInfo struct {
+	Name  string
+	Uid   string
+	Home  string
+	Shell string // Login shell, e.g. /bin/bash
+}
+
+// LookupUser finds name in the user database, including its login shell.
+func LookupUser(name string) (UserInfo, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		return UserInfo{}, err
+	}
+	return UserInfo{Name: u.Username, Uid: u.Uid, Home: u.HomeDir, Shell: loginShell(u.Username)}, nil
+}
+
+// InvokingUser returns the user who ran lspath, looking through sudo.
+func InvokingUser() (UserInfo, error) {
+	if name := os.Getenv("SUDO_USER"); name != "" && os.Geteuid() == 0 {
+		return LookupUser(name)
+	}
+	u, err := user.Current()
+	if err != nil {
+		return UserInfo{}, err
+	}
+	return LookupUser(u.Username)
+}
+
+// loginShell returns the login shell of name, or "" if unknown. os/user
+// doesn't expose it, so ask getent or Directory Services, falling back to
+// /etc/passwd.
+func loginShell(name string) string {
+	if runtime.GOOS == "darwin" {
+		// "UserShell: /bin/zsh"
+		out := toolOutput("dscl", ".", "-read", "/Users/"+name, "UserShell")
+		if _, shell, ok := strings.Cut(out, ":"); ok {
+			return strings.TrimSpace(shell)
+		}
+	}
+	if fields := strings.Split(toolOutput("getent", "passwd", name), ":"); len(fields) == 7 {
+		return fields[6]
+	}
+	f, err := os.Open("/etc/passwd")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if fields := strings.Split(scanner.Text(), ":"); len(fields) == 7 && fields[0] == name {
+			return fields[6]
+		}
+	}
+	return ""
+}
+
+// userTraceEnv lists the variables kept when tracing as another user; the
+// invoking user's environment (tokens, agent sockets) is not passed on.
+var userTraceEnv = []string{"PATH=", "PS4=", "LSPATH_TRACE_VAR=", "fish_trace=", "TERM=", "LANG=", "LC_"}
+
+// RunTraceAs traces the startup files of u inside sb. It always runs with
+// side-effect commands disabled (see --no-side-effects), since the files may
+// run as root. Another user's files are run through sudo, which must not need
+// a password: run lspath itself with sudo, or `sudo -v` first. basePath is
+// the PATH the shell gets when variable is not PATH. The trace stops when ctx
+// is done.
+func RunTraceAs(ctx context.Context, u UserInfo, shell Shell, variable, initialValue, basePath string, sb Sandbox) (*ShellTrace, error) {
+	cmd := traceCommand(shell, sb.limitCommand(shellTraceCommand(shell, true)), variable, initialValue, basePath)
+
+	env := []string{"HOME=" + u.Home, "USER=" + u.Name, "LOGNAME=" + u.Name, "SHELL=" + u.Shell}
+	for _, e := range cmd.Env {
+		if strings.HasPrefix(e, variable+"=") {
+			env = append(env, e)
+			continue
+		}
+		for _, keep := range userTraceEnv {
+			if strings.HasPrefix(e, keep) {
+				env = append(env, e)
+				break
+			}
+		}
+	}
+	cmd.Env = env
+	owner, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		owner = -1
+	}
+	cleanup, err := sb.privateTmp(cmd, owner)
+	if err != nil {
+		return nil, err
+	}
+	env = cmd.Env
+	if info, err := os.Stat(u.Home); err == nil && info.IsDir() {
+		cmd.Dir = u.Home // The caller's directory may not be readable by u
+	}
+
+	if u.Uid != strconv.Itoa(os.Geteuid()) {
+		if _, err := exec.LookPath("sudo"); err != nil {
+			cleanup()
+			return nil, fmt.Errorf("tracing %s's startup files needs sudo, which was not found", u.Name)
+		}
+		if err := exec.Command("sudo", "-n", "-u", u.Name, "true").Run(); err != nil {
+			cleanup()
+			return nil, fmt.Errorf("cannot run commands as %s without a password; run `sudo lspath --user %s` instead", u.Name, u.Name)
+		}
+		// sudo resets the environment, so pass it through env(1)
+		args := append([]string{"-n", "-u", u.Name, "--", "env", "-i"}, env...)
+		args = append(args, cmd.Args...)
+		wrapped := exec.Command("sudo", args...)
+		wrapped.Dir = cmd.Dir
+		cmd = wrapped
+	}
+	// Outside sudo, where this process's privileges can create namespaces
+	if err := sb.blockNetwork(cmd); err != nil {
+		cleanup()
+		return nil, err
+	}
+	return startTrace(ctx, cmd, cleanup)
+}
+
+// RunUserAnalysis traces the startup files of the named user. There is no
+// session to merge with, so the result is the trace-only view of what a
+// fresh login shell for that user would have.
+func RunUserAnalysis(name string, opts Options) (model.AnalysisResult, error) {
+	if runtime.GOOS == "windows" {
+		return model.AnalysisResult{}, fmt.Errorf("--user is not supported on Windows")
+	}
+	u, err := LookupUser(name)
+	if err != nil {
+		return model.AnalysisResult{}, err
+	}
+
+	variable := opts.Var
+	if variable == "" {
+		variable = DefaultVariable
+	}
+
+	switch filepath.Base(u.Shell) {
+	case "nologin", "false":
+		return model.AnalysisResult{}, fmt.Errorf("%s cannot log in (shell %s), so has no startup files to trace", u.Name, u.Shell)
+	}
+
+	sb := opts.Sandbox
+	if !sb.Enabled() {
+		sb = DefaultSandbox
+	}
+	shell := DetectShell(u.Shell)
+	initialValue := opts.initialValue(variable)
+	ctx, cancel := opts.traceContext()
+	defer cancel()
+	stderr, err := RunTraceAs(ctx, u, shell, variable, initialValue, opts.baseline(), sb)
+	if err != nil {
+		return model.AnalysisResult{}, err
+	}
+	defer stderr.Close()
+	events, stats, _ := collectEvents(shell, variable, opts.baseline(), stderr, nil)
+	timedOut := ctx.Err() != nil
+	waitErr := stderr.Wait()
+
+	analyzer := NewAnalyzer()
+	analyzer.Variable = variable
+	analyzer.Canon = opts.canon()
+	analyzer.DisabledHeuristics = opts.DisabledHeuristics
+	analyzer.Baseline = opts.baseline()
+	res := analyzer.Analyze(events, initialValue)
+	res.Variable = variable
+	res.Parser = stats
+	res.DuplicatePolicy = opts.Duplicates
+	res.Environment = CollectEnvironment(u.Shell)
+	res.Environment.User = fmt.Sprintf("%s (uid %s)", u.Name, u.Uid)
+	CheckShellCompat(&res)
+	res.Diagnostics = append(res.Diagnostics, exitDiagnostics(shell, opts, events, stats, timedOut, waitErr)...)
+	if d := parserDiagnostic(res.Parser, shell); d != "" {
+		res.Diagnostics = append(res.Diagnostics, d)
+	}
+	res.Diagnostics = append(res.Diagnostics, fmt.Sprintf("INFO: Traced the %s startup files of %s (%s) with side-effect commands disabled, in a sandbox: %s.", shell.Name(), u.Name, u.Home, sb.Describe()))
+	return res, nil
+}
+
+// SudoSecurePath returns the secure_path sudo uses for commands, if set in
+// the sudoers files readable by this process.
+func SudoSecurePath() string {
+	files := []string{"/etc/sudoers"}
+	if entries, err := os.ReadDir("/etc/sudoers.d"); err == nil {
+		for _, e := range entries {
+			files = append(files, "/etc/sudoers.d/"+e.Name())
+		}
+	}
+	securePath := ""
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			// Defaults secure_path="/usr/local/sbin:/usr/local/bin:..."
+			fields := strings.Fields(line)
+			if len(fields) < 2 || fields[0] != "Defaults" {
+				continue
+			}
+			setting := strings.Join(fields[1:], " ")
+			if v, ok := strings.CutPrefix(setting, "secure_path="); ok {
+				securePath = strings.Trim(v, `"`) // Later settings win
+			}
+		}
+	}
+	return securePath
+}
+
+// FormatUserComparison explains how other's PATH differs from mine's.
+func FormatUserComparison(mine, other model.AnalysisResult, me, them string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("COMPARED WITH %s\n", strings.ToUpper(me)))
+	sb.WriteString(strings.Repeat("-", len("COMPARED WITH ")+len(me)) + "\n")
+	sb.WriteString(fmt.Sprintf("ADDED: only in %s's %s; REMOVED: only in %s's.\n\n", them, other.VariableName(), me))
+	sb.WriteString(FormatDiff(mine, other, DiffResults(mine, other)))
+	if them == "root" && other.VariableName() == DefaultVariable {
+		if sp := SudoSecurePath(); sp != "" {
+			sb.WriteString(fmt.Sprintf("Note: `sudo <command>` does not read root's startup files; it searches secure_path from sudoers:\n  %s\n", sp))
+		}
+	}
+	return sb.String()
+}
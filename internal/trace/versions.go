@@ -0,0 +1,119 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VersionTimeout bounds each executable ProbeVersions runs, as one that
+// ignores --version may wait for input or run forever.
+const VersionTimeout = 3 * time.Second
+
+// maxVersionOutput is how much of an executable's output ProbeVersions
+// keeps; the rest is discarded.
+const maxVersionOutput = 4096
+
+// versionPattern finds a version number in --version output, e.g. "3.12.1"
+// in "Python 3.12.1" or "1.22.3" in "go version go1.22.3 darwin/arm64".
+var versionPattern = regexp.MustCompile(`\d+(?:\.\d+)+(?:[-+]?[a-z]+\d*)?`)
+
+// cappedBuffer keeps the first maxVersionOutput bytes written to it.
+type cappedBuffer struct {
+	mu  sync.Mutex // Stdout and stderr are copied in separate goroutines
+	buf bytes.Buffer
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if room := maxVersionOutput - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+// ProbeVersions runs each hit with --version, all at once and each for at
+// most VersionTimeout, and records the version it prints in Version. Broken
+// links are skipped, and a hit that is the same file as an earlier one
+// shares its answer rather than running again.
+func ProbeVersions(hits []CommandHit) {
+	var wg sync.WaitGroup
+	for i := range hits {
+		if hits[i].Broken || sameAsEarlierHit(hits, i) >= 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(h *CommandHit) {
+			defer wg.Done()
+			h.Version = probeVersion(h.Path)
+		}(&hits[i])
+	}
+	wg.Wait()
+	for i := range hits {
+		if j := sameAsEarlierHit(hits, i); j >= 0 && !hits[i].Broken {
+			hits[i].Version = hits[j].Version
+		}
+	}
+}
+
+// sameAsEarlierHit returns the first hit before i that is the same file as
+// hit i, or -1.
+func sameAsEarlierHit(hits []CommandHit, i int) int {
+	for j := 0; j < i; j++ {
+		if !hits[j].Broken && sameFile(hits[j].Path, hits[i].Path) {
+			return j
+		}
+	}
+	return -1
+}
+
+// probeVersion returns the version path --version prints (some print it on
+// stderr), "" if it prints none, or why it has none, e.g. "timed out".
+func probeVersion(path string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), VersionTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, path, "--version")
+	detachTrace(cmd) // No terminal to stop on, and its children die with it
+	cmd.Cancel = func() error {
+		killTrace(cmd)
+		return nil
+	}
+	cmd.WaitDelay = time.Second // Children may hold the output open
+	var out cappedBuffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	cmd.Run()
+	if ctx.Err() != nil {
+		return "timed out"
+	}
+	for _, line := range strings.Split(out.buf.String(), "\n") {
+		if v := versionPattern.FindString(line); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// FormatVersions compares the versions ProbeVersions found, one per
+// directory in priority order, e.g. "python3: 3.12.1 (/opt/homebrew/bin) vs
+// 3.9.6 (/usr/bin)". Hits with no version show "?", or why they have none.
+func FormatVersions(name string, hits []CommandHit) string {
+	var parts []string
+	for _, h := range hits {
+		if h.Broken {
+			continue
+		}
+		v := h.Version
+		if v == "" {
+			v = "?"
+		}
+		parts = append(parts, fmt.Sprintf("%s (%s)", v, filepath.Dir(h.Path)))
+	}
+	return fmt.Sprintf("%s: %s", name, strings.Join(parts, " vs "))
+}
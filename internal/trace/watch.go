@@ -0,0 +1,75 @@
+package trace
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"lspath/internal/model"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WatchDebounce groups the burst of events a single editor save produces.
+const WatchDebounce = 300 * time.Millisecond
+
+// ConfigFiles returns the config files behind the flow nodes of res,
+// including standard files that were not executed, since creating one
+// changes the result too.
+func ConfigFiles(res model.AnalysisResult) []string {
+	var files []string
+	seen := make(map[string]bool)
+	for _, n := range res.FlowNodes {
+		path := model.ExpandTilde(n.FilePath)
+		if !filepath.IsAbs(path) || seen[path] {
+			continue // Built-in nodes such as "System (Default)"
+		}
+		seen[path] = true
+		files = append(files, path)
+	}
+	return files
+}
+
+// WaitForChange blocks until one of files is written, created, removed or
+// renamed, and returns its path. Parent directories are watched so editors
+// that save by replacing the file are noticed. It returns ctx.Err() if ctx
+// is done first.
+func WaitForChange(ctx context.Context, files []string) (string, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return "", err
+	}
+	defer w.Close()
+
+	wanted := make(map[string]bool)
+	for _, f := range files {
+		wanted[filepath.Clean(f)] = true
+		// Directories that don't exist can't be watched; skip them
+		_ = w.Add(filepath.Dir(f))
+	}
+
+	var changed string
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case ev, ok := <-w.Events:
+			if !ok {
+				return "", nil
+			}
+			if !wanted[filepath.Clean(ev.Name)] || ev.Op == fsnotify.Chmod {
+				continue
+			}
+			changed = ev.Name
+			debounce = time.After(WatchDebounce)
+		case err, ok := <-w.Errors:
+			if !ok {
+				return "", nil
+			}
+			return "", err
+		case <-debounce:
+			return changed, nil
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package trace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"lspath/internal/model"
+)
+
+// CommandHit is one PATH entry that provides an executable.
+type CommandHit struct {
+	Index      int    // PathEntries index
+	Path       string // Full path of the executable
+	LinkTarget string // Symlink target, if the executable is a symlink
+	Broken     bool   // True if the symlink target does not exist
+	Version    string // What --version printed, if ProbeVersions ran it
+}
+
+// FindCommand returns every PATH entry with an executable called name, in
+// priority order. The first hit is the one the shell runs. Duplicate entries
+// are skipped since they can never win.
+func FindCommand(entries []model.PathEntry, name string) []CommandHit {
+	var hits []CommandHit
+	for i, e := range entries {
+		if e.IsDuplicate {
+			continue
+		}
+		p := filepath.Join(model.ExpandTilde(e.Value), name)
+		linfo, err := os.Lstat(p)
+		if err != nil {
+			continue
+		}
+		hit := CommandHit{Index: i, Path: p}
+		if linfo.Mode()&os.ModeSymlink != 0 {
+			hit.LinkTarget, _ = os.Readlink(p)
+		}
+		info, err := os.Stat(p)
+		if err != nil {
+			if hit.LinkTarget == "" {
+				continue
+			}
+			hit.Broken = true // Report dangling links; they explain "command not found"
+		} else if info.IsDir() || info.Mode().Perm()&0111 == 0 {
+			continue
+		}
+		hits = append(hits, hit)
+	}
+	return hits
+}
+
+// FormatWhich lists the hits for name, marking the winner and attributing
+// each directory to the config line that added it. If ProbeVersions ran,
+// the versions found come first. An alias, function or builtin the shell
+// runs instead is noted before either.
+func FormatWhich(res model.AnalysisResult, name string, hits []CommandHit) string {
+	var sb strings.Builder
+	override, overridden := FindOverride(res, name)
+	if overridden {
+		note := FormatOverride(name, override)
+		if len(hits) > 0 {
+			note += "; " + overrideBypass(name, override)
+		}
+		sb.WriteString("note: " + note + "\n")
+	}
+	if len(hits) == 0 {
+		sb.WriteString(fmt.Sprintf("%s: not found in %s\n", name, res.VariableName()))
+		return sb.String()
+	}
+	for _, h := range hits {
+		if h.Version != "" {
+			sb.WriteString(FormatVersions(name, hits) + "\n")
+			break
+		}
+	}
+
+	winner := -1
+	for i, h := range hits {
+		if !h.Broken {
+			winner = i
+			break
+		}
+	}
+
+	for i, h := range hits {
+		e := res.PathEntries[h.Index]
+		marker := "  "
+		if i == winner {
+			marker = "* "
+		}
+		sb.WriteString(fmt.Sprintf("%s%s", marker, h.Path))
+		if h.LinkTarget != "" {
+			sb.WriteString(fmt.Sprintf(" %s %s", model.IconSymlink, h.LinkTarget))
+			if h.Broken {
+				sb.WriteString(" (broken link)")
+			}
+		}
+		sb.WriteString("\n")
+
+		source := e.SourceFile
+		if e.LineNumber > 0 {
+			source = fmt.Sprintf("%s:%d", e.SourceFile, e.LineNumber)
+		}
+		status := ""
+		switch {
+		case i == winner && overridden:
+			status = "first in PATH"
+		case i == winner:
+			status = "runs"
+		case h.Broken:
+			status = "skipped"
+		case winner >= 0 && sameFile(h.Path, hits[winner].Path):
+			status = "same file as the winner"
+		case winner >= 0:
+			status = fmt.Sprintf("shadowed by #%d", hits[winner].Index+1)
+		}
+		sb.WriteString(fmt.Sprintf("    PATH #%d, from %s; %s\n", h.Index+1, source, status))
+		if i == winner {
+			if w, ok := FollowWrapper(res.PathEntries, h.Index, h.Path, name); ok {
+				sb.WriteString("    " + FormatWrapper(w) + "\n")
+			}
+		}
+	}
+	return sb.String()
+}
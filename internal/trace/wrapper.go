@@ -0,0 +1,211 @@
+package trace
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"lspath/internal/model"
+)
+
+// wrapperQueryTimeout bounds the `<tool> which` command FollowWrapper runs
+// to ask a version manager which executable its shim selects.
+const wrapperQueryTimeout = 3 * time.Second
+
+// maxWrapperScript is how much of an executable is read looking for the
+// line that re-executes; shims and wrappers are a few lines long.
+const maxWrapperScript = 8 * 1024
+
+// versionManagers are tools whose shims run `<tool> exec <command>`, and
+// which answer `<tool> which <command>` with the executable selected.
+var versionManagers = []string{"asdf", "pyenv", "rbenv", "nodenv", "goenv", "jenv", "plenv"}
+
+// Wrapper describes an executable found on PATH that is itself a script
+// re-executing a command looked up again, such as an asdf or pyenv shim, or
+// a script ending in `exec env FOO=1 cmd "$@"` or `direnv exec . cmd`.
+type Wrapper struct {
+	Via     string // What re-executes, e.g. "asdf exec", "env", "direnv exec"
+	Command string // Command it runs
+	Line    string // The script line that re-executes
+	Target  string // Executable that ultimately runs; "" if it could not be found
+	Index   int    // PathEntries index providing Target, or -1
+	PathSet bool   // The wrapper sets its own PATH for the lookup
+}
+
+// FollowWrapper checks whether path, the executable for name found in
+// PathEntries[idx], is a wrapper script, and if so follows it one level to
+// the executable it runs.
+func FollowWrapper(entries []model.PathEntry, idx int, path, name string) (Wrapper, bool) {
+	w := Wrapper{Index: -1}
+	f, err := os.Open(path)
+	if err != nil {
+		return w, false
+	}
+	defer f.Close()
+	buf := make([]byte, maxWrapperScript)
+	n, _ := f.Read(buf)
+	if !bytes.HasPrefix(buf[:n], []byte("#!")) {
+		return w, false
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(buf[:n]))
+	scanner.Scan() // The #! line names an interpreter, not a wrapped command
+	var tool, pathValue string
+	found := false
+	for scanner.Scan() && !found {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		w.Line = line
+		tool, pathValue, found = parseWrapperLine(line, &w)
+	}
+	if !found {
+		return w, false
+	}
+	if strings.HasPrefix(w.Command, "$") {
+		w.Command = name // "$program", "${0##*/}": the shim's own name
+	}
+
+	switch {
+	case strings.Contains(w.Command, "/"):
+		w.Target = w.Command
+	case tool != "":
+		w.Target = askVersionManager(entries, tool, w.Command)
+	default:
+		search := entries
+		if w.PathSet {
+			search = nil
+			for _, dir := range model.SplitPathList(pathValue) {
+				search = append(search, model.PathEntry{Value: dir})
+			}
+		}
+		for _, h := range FindCommand(search, w.Command) {
+			// Re-executing its own name only makes sense past the wrapper
+			if h.Broken || (!w.PathSet && w.Command == name && h.Index <= idx) || sameFile(h.Path, path) {
+				continue
+			}
+			w.Target = h.Path
+			break
+		}
+	}
+	if w.Target != "" {
+		dir := filepath.Dir(w.Target)
+		for i, e := range entries {
+			if !e.IsDuplicate && model.SamePath(e.Value, dir, model.CanonOptions{}) {
+				w.Index = i
+				break
+			}
+		}
+	}
+	return w, true
+}
+
+// FormatWrapper describes what a wrapper runs, e.g. "wrapper: asdf exec
+// python → /home/alice/.asdf/installs/python/3.12.1/bin/python".
+func FormatWrapper(w Wrapper) string {
+	s := fmt.Sprintf("wrapper: %s %s → ", w.Via, w.Command)
+	switch {
+	case w.Target == "":
+		return s + "target not found"
+	case w.Index >= 0:
+		return s + fmt.Sprintf("%s (PATH #%d)", w.Target, w.Index+1)
+	case w.PathSet:
+		return s + w.Target + " (from the PATH the wrapper sets)"
+	}
+	return s + w.Target
+}
+
+// parseWrapperLine recognizes a line that re-executes a command through a
+// version manager, direnv or env, filling in w. It returns the version
+// manager to ask for the target, if any, and the PATH env sets, if it does.
+func parseWrapperLine(line string, w *Wrapper) (tool, pathValue string, ok bool) {
+	w.PathSet = false
+	var words []string
+	for _, f := range strings.Fields(line) {
+		words = append(words, strings.Trim(f, `"'`))
+	}
+	if len(words) > 0 && words[0] == "exec" {
+		words = words[1:]
+	}
+	if len(words) < 2 {
+		return "", "", false
+	}
+	base := filepath.Base(words[0])
+	args := words[1:]
+
+	for _, vm := range versionManagers {
+		if base == vm && args[0] == "exec" {
+			args = skipDashDash(args[1:])
+			if len(args) == 0 {
+				return "", "", false
+			}
+			w.Via, w.Command = vm+" exec", args[0]
+			return words[0], "", true
+		}
+	}
+
+	switch base {
+	case "direnv":
+		// direnv exec DIR command...
+		if args[0] != "exec" || len(args) < 3 {
+			return "", "", false
+		}
+		w.Via, w.Command = "direnv exec", args[2]
+		return "", "", true
+	case "env":
+		for i := 0; i < len(args); i++ {
+			a := args[i]
+			switch {
+			case a == "-u" || a == "-C" || a == "--unset" || a == "--chdir":
+				i++ // Takes a value
+			case strings.HasPrefix(a, "-"):
+			case strings.Contains(a, "="):
+				if v, isPath := strings.CutPrefix(a, "PATH="); isPath && !strings.Contains(v, "$") {
+					pathValue, w.PathSet = v, true
+				}
+			default:
+				w.Via, w.Command = "env", a
+				return "", pathValue, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+func skipDashDash(args []string) []string {
+	if len(args) > 0 && args[0] == "--" {
+		return args[1:]
+	}
+	return args
+}
+
+// askVersionManager runs `<tool> which <command>`, with tool as the shim
+// names it or looked up in entries, and returns the executable it reports.
+func askVersionManager(entries []model.PathEntry, tool, command string) string {
+	if !filepath.IsAbs(tool) {
+		if _, p := ResolveInPath(entries, tool); p != "" {
+			tool = p
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), wrapperQueryTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, tool, "which", command).Output()
+	if err != nil {
+		return ""
+	}
+	target, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	if !filepath.IsAbs(target) {
+		return ""
+	}
+	if _, err := os.Stat(target); err != nil {
+		return ""
+	}
+	return target
+}
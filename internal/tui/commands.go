@@ -0,0 +1,500 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"lspath/internal/fix"
+	"lspath/internal/model"
+	"lspath/internal/trace"
+
+	"github.com/atotto/clipboard"
+	"github.com/aymanbagabas/go-osc52/v2"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// All filesystem access for the TUI happens in the commands below. Each runs
+// off the UI goroutine and reports back with a typed message, so Update and
+// View only ever work with data already in the model.
+
+// binaryInfo describes the file matched by a binary search in one entry.
+type binaryInfo struct {
+	Name       string
+	Path       string
+	Size       int64
+	Mode       os.FileMode
+	ModTime    time.Time
+	LinkTarget string // Non-empty if the file is a symlink
+	TargetMode os.FileMode
+	Broken     bool // Symlink target does not exist
+}
+
+// loadDirListingCmd lists dir for PATH entry idx, along with the source line
+// context and details of the searched-for binary (if any).
+func loadDirListingCmd(idx int, entry model.PathEntry, match string) tea.Cmd {
+	return func() tea.Msg {
+		msg := MsgDirListing{Index: idx}
+		if !entry.IsSessionOnly {
+			msg.LineContext = model.GetLineContext(entry.SourceFile, entry.LineNumber)
+		}
+		if match != "" {
+			msg.Binary = statBinary(entry.Value, match)
+		}
+
+		dir := model.ExpandTilde(entry.Value)
+		stats, err := trace.StatDir(dir)
+		if err != nil {
+			// Provide user-friendly error messages
+			if os.IsNotExist(err) {
+				msg.Listing = fmt.Sprintf("Directory does not exist:\n\n%s", dir)
+			} else if os.IsPermission(err) {
+				msg.Listing = fmt.Sprintf("⚠️ Permission denied: Cannot read directory\n\n%s", dir)
+			} else {
+				msg.Listing = fmt.Sprintf("⚠️ Cannot access directory:\n\n%s\n\nError: %v", dir, err)
+			}
+			return msg
+		}
+
+		msg.Stats = stats
+
+		// Handle empty directory
+		if len(stats.Files) == 0 {
+			msg.Listing = "Directory is empty"
+			return msg
+		}
+
+		var sb strings.Builder
+		w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+
+		for _, f := range stats.Files {
+			info := f.Info
+			if info == nil {
+				continue
+			}
+
+			// Permissions
+			mode := info.Mode().String()
+
+			// Size
+			size := info.Size()
+			sizeStr := fmt.Sprintf("%d", size)
+			if size > 1024*1024 {
+				sizeStr = fmt.Sprintf("%.1fM", float64(size)/(1024*1024))
+			} else if size > 1024 {
+				sizeStr = fmt.Sprintf("%.1fK", float64(size)/1024)
+			}
+
+			// Time
+			modTime := info.ModTime().Format("Jan 02 15:04")
+
+			// Icon and Name
+			icon := "📄"
+			switch f.Kind {
+			case trace.KindDir:
+				icon = "📁"
+			case trace.KindScript:
+				icon = "📜"
+			case trace.KindSymlink:
+				icon = "🔗"
+			case trace.KindBinary, trace.KindOtherExec:
+				icon = "🚀"
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s %s\n", mode, sizeStr, modTime, icon, f.Entry.Name())
+		}
+		w.Flush()
+		msg.Listing = sb.String()
+		return msg
+	}
+}
+
+// statBinary gathers file details for a search match in dir.
+func statBinary(dir, name string) *binaryInfo {
+	fullPath := filepath.Join(dir, name)
+	info, err := os.Lstat(model.ExpandTilde(fullPath))
+	if err != nil {
+		return nil
+	}
+	b := &binaryInfo{
+		Name:    name,
+		Path:    fullPath,
+		Size:    info.Size(),
+		Mode:    info.Mode(),
+		ModTime: info.ModTime(),
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		if target, err := os.Readlink(model.ExpandTilde(fullPath)); err == nil {
+			b.LinkTarget = target
+			if tInfo, err := os.Stat(model.ExpandTilde(fullPath)); err == nil {
+				b.TargetMode = tInfo.Mode()
+			} else {
+				b.Broken = true
+			}
+		}
+	}
+	return b
+}
+
+// searchCmd finds the best matching file in each unique PATH directory
+// under mode: the shortest name starting with term (an exact match first),
+// the best fuzzy match, or term itself. Fuzzy results are ranked best first,
+// keeping PATH order among equals.
+func searchCmd(term string, mode int, entries []model.PathEntry) tea.Cmd {
+	return func() tea.Msg {
+		msg := MsgSearchResult{Term: term, Mode: mode, Matches: make(map[int]string), Positions: make(map[int][]int)}
+		seenDirs := make(map[string]bool)
+		scores := make(map[int]int)
+
+		for i, entry := range entries {
+			key := model.CanonicalPath(entry.Value, model.CanonOptions{})
+
+			// Deduplication: Only show unique directories in search results
+			if seenDirs[key] {
+				continue
+			}
+
+			files, err := os.ReadDir(model.ExpandTilde(entry.Value))
+			if err != nil {
+				continue
+			}
+
+			found := false
+			for _, f := range files {
+				if f.IsDir() {
+					continue
+				}
+				name := strings.ToLower(f.Name())
+				score, pos, ok := matchName(term, name, mode)
+				if !ok {
+					continue
+				}
+				if mode == SearchPrefix {
+					score = -len(name)
+				}
+				if !found || score > scores[i] {
+					found = true
+					scores[i] = score
+					msg.Matches[i] = f.Name() // Store original case
+					msg.Positions[i] = pos
+				}
+			}
+
+			if found {
+				seenDirs[key] = true
+				msg.Indices = append(msg.Indices, i)
+			}
+		}
+		if mode == SearchFuzzy {
+			sort.SliceStable(msg.Indices, func(a, b int) bool {
+				return scores[msg.Indices[a]] > scores[msg.Indices[b]]
+			})
+		}
+		return msg
+	}
+}
+
+// searchHit is one executable a global search ('/') found.
+type searchHit struct {
+	Entry     int // PathEntries index of its directory
+	Name      string
+	Path      string
+	Size      int64
+	ModTime   time.Time
+	Positions []int // Rune positions of the term in Name
+	Score     int
+	Winner    int  // Entry whose copy of Name runs; Entry itself when this one does
+	SameFile  bool // The winner's copy is this same file
+	info      os.FileInfo
+}
+
+// globalSearchCmd lists every executable in the PATH directories matching
+// term under mode, with which copy of each name runs. Duplicate entries are
+// skipped, since their directory is searched as the original. Hits are in
+// name order, or best first for fuzzy matching, then in PATH order.
+func globalSearchCmd(term string, mode int, entries []model.PathEntry) tea.Cmd {
+	return func() tea.Msg {
+		msg := MsgGlobalSearch{Term: term, Mode: mode}
+		first := make(map[string]int) // Name -> index in msg.Hits of its first copy
+		for i, entry := range entries {
+			if entry.IsDuplicate || model.IsRelativePath(entry.Value) {
+				continue
+			}
+			dir := model.ExpandTilde(entry.Value)
+			files, err := os.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			for _, f := range files {
+				score, pos, ok := matchName(term, strings.ToLower(f.Name()), mode)
+				if !ok {
+					continue
+				}
+				path := filepath.Join(dir, f.Name())
+				info, err := os.Stat(path) // Follows symlinks, as running it does
+				if err != nil || info.IsDir() || info.Mode().Perm()&0111 == 0 {
+					continue
+				}
+				hit := searchHit{Entry: i, Name: f.Name(), Path: path, Size: info.Size(), ModTime: info.ModTime(),
+					Positions: pos, Score: score, Winner: i, info: info}
+				if w, seen := first[f.Name()]; seen {
+					hit.Winner = msg.Hits[w].Entry
+					hit.SameFile = os.SameFile(msg.Hits[w].info, info)
+				} else {
+					first[f.Name()] = len(msg.Hits)
+				}
+				msg.Hits = append(msg.Hits, hit)
+			}
+		}
+		sort.SliceStable(msg.Hits, func(a, b int) bool {
+			ha, hb := msg.Hits[a], msg.Hits[b]
+			if mode == SearchFuzzy && ha.Score != hb.Score {
+				return ha.Score > hb.Score
+			}
+			return ha.Name < hb.Name
+		})
+		return msg
+	}
+}
+
+// saveLayoutCmd saves the pane layout for the next session.
+func saveLayoutCmd(l Layout) tea.Cmd {
+	return func() tea.Msg {
+		return MsgLayoutSaved{Err: SaveLayout(LayoutFile(), l)}
+	}
+}
+
+// shellResolveCmd asks the user's interactive shell what term runs, catching
+// aliases and functions that a PATH search cannot see.
+func shellResolveCmd(term string, res model.AnalysisResult) tea.Cmd {
+	return func() tea.Msg {
+		sr, err := trace.ResolveInShell(os.Getenv("SHELL"), term)
+		msg := MsgShellResolution{Term: term, Resolution: sr, Err: err}
+		if err == nil {
+			msg.Mismatch = trace.CompareResolution(res, sr)
+		}
+		if idx, path := trace.ResolveInPath(res.PathEntries, term); idx >= 0 {
+			if w, ok := trace.FollowWrapper(res.PathEntries, idx, path, term); ok {
+				msg.Wrapper = &w
+			}
+		}
+		return msg
+	}
+}
+
+// loadFileCmd reads a config file for the flow preview.
+func loadFileCmd(path string, notExecuted bool) tea.Cmd {
+	return func() tea.Msg {
+		msg := MsgFileLoaded{Path: path}
+
+		// Check if file was not executed (placeholder node)
+		if notExecuted {
+			if _, err := os.Stat(path); err != nil {
+				if os.IsNotExist(err) {
+					msg.Content = fmt.Sprintf("File not executed during this shell session.\n\nFile does not exist:\n%s", path)
+				} else {
+					msg.Content = fmt.Sprintf("File not executed during this shell session.\n\nCannot access file:\n%v", err)
+				}
+			} else {
+				msg.Content = fmt.Sprintf("File not executed during this shell session.\n\nFile exists at:\n%s", path)
+			}
+			return msg
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				msg.Content = fmt.Sprintf("File not found: %s\n(This file does not exist on your system)", path)
+			} else {
+				msg.Content = fmt.Sprintf("Error reading file: %v", err)
+			}
+		} else {
+			msg.Content = string(content)
+		}
+		return msg
+	}
+}
+
+// generateReportCmd builds the diagnostics report, which stats every PATH
+// directory and reads source lines.
+func generateReportCmd(res model.AnalysisResult, verbose bool) tea.Cmd {
+	return func() tea.Msg {
+		return MsgReport{Verbose: verbose, Text: trace.GenerateReport(res, verbose)}
+	}
+}
+
+// planFixCmd proposes what --fix would: removing config lines that add
+// duplicate PATH entries, and restoring the pinned order.
+func planFixCmd(res model.AnalysisResult) tea.Cmd {
+	return func() tea.Msg {
+		edits, notes := fix.Proposals(res, trace.DetectShell(os.Getenv("SHELL")).Name())
+		if len(edits) == 0 {
+			return MsgFixPlan{Notes: notes}
+		}
+		changes, err := fix.Plan(edits)
+		return MsgFixPlan{Changes: changes, Notes: notes, Err: err}
+	}
+}
+
+// applyFixCmd backs up and rewrites each file in the plan.
+func applyFixCmd(changes []fix.Change) tea.Cmd {
+	return func() tea.Msg {
+		var backups []string
+		for _, c := range changes {
+			backup, err := fix.ApplyChange(c)
+			if err != nil {
+				return MsgFixApplied{Backups: backups, Err: err}
+			}
+			if backup != "" {
+				backups = append(backups, backup)
+			}
+		}
+		return MsgFixApplied{Backups: backups}
+	}
+}
+
+// saveReportCmd writes the diagnostics report to a timestamped file.
+func saveReportCmd(report string) tea.Cmd {
+	return func() tea.Msg {
+		filename := fmt.Sprintf("lspath-report-%s.txt", time.Now().Format("2006-01-02-15-04-05"))
+		return MsgReportSaved{File: filename, Err: os.WriteFile(filename, []byte(report), 0644)}
+	}
+}
+
+// copyEntryCmd copies an entry's directory to the clipboard.
+func copyEntryCmd(entry model.PathEntry) tea.Cmd {
+	return func() tea.Msg {
+		return copyToClipboard(model.DisplayPath(entry.Value), entry.Value)
+	}
+}
+
+// copySourceLineCmd copies the config line that added entry.
+func copySourceLineCmd(entry model.PathEntry) tea.Cmd {
+	return func() tea.Msg {
+		if entry.IsSessionOnly || entry.LineNumber == 0 {
+			return MsgCopied{Err: fmt.Errorf("%s was not added by a config file line", model.DisplayPath(entry.Value))}
+		}
+		lc := model.GetLineContext(entry.SourceFile, entry.LineNumber)
+		if lc.ErrorMsg != "" {
+			return MsgCopied{Err: errors.New(lc.ErrorMsg)}
+		}
+		return copyToClipboard(fmt.Sprintf("line %d of %s", entry.LineNumber, entry.SourceFile), strings.TrimSpace(lc.Target))
+	}
+}
+
+// errNoClipboard is the copy error when neither the platform clipboard nor
+// OSC 52 is available.
+var errNoClipboard = errors.New("no clipboard (install xclip, xsel or wl-clipboard, or use a terminal with OSC 52 support)")
+
+// copyToClipboard copies text, described as what, with the platform
+// clipboard (pbcopy, xclip/xsel/wl-copy, the Windows API). Over SSH, or when
+// there is none, the message it returns carries text on to copyOSC52Cmd,
+// since only the program may write to the terminal.
+func copyToClipboard(what, text string) MsgCopied {
+	remote := os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != ""
+	if !remote && clipboard.WriteAll(text) == nil {
+		return MsgCopied{What: what}
+	}
+	switch os.Getenv("TERM") {
+	case "", "dumb", "linux": // Terminals that don't understand OSC 52
+		return MsgCopied{Err: errNoClipboard}
+	}
+	return MsgCopied{What: what, osc52: text}
+}
+
+// copyOSC52Cmd sends text to the terminal's clipboard as an OSC 52 escape
+// sequence, which most terminals pass on to the local clipboard. The
+// sequence is written through the program's own output while it is paused,
+// so it can't land in the middle of a frame.
+func copyOSC52Cmd(what, text string) tea.Cmd {
+	return tea.Exec(&osc52Writer{text: text}, func(err error) tea.Msg {
+		if err != nil {
+			return MsgCopied{Err: err}
+		}
+		return MsgCopied{What: what}
+	})
+}
+
+// osc52Writer is a tea.ExecCommand that writes text as an OSC 52 sequence
+// to the output the program gives it.
+type osc52Writer struct {
+	text string
+	out  io.Writer
+}
+
+func (w *osc52Writer) Run() error {
+	if w.out == nil {
+		return errNoClipboard
+	}
+	seq := osc52.New(w.text)
+	if strings.HasPrefix(os.Getenv("TERM"), "screen") {
+		seq = seq.Screen()
+	}
+	_, err := seq.WriteTo(w.out)
+	return err
+}
+
+func (w *osc52Writer) SetStdin(io.Reader)      {}
+func (w *osc52Writer) SetStdout(out io.Writer) { w.out = out }
+func (w *osc52Writer) SetStderr(io.Writer)     {}
+
+// watchConfigCmd blocks until one of files changes. A cancelled watch sends
+// nothing, since a newer one has replaced it.
+func watchConfigCmd(ctx context.Context, files []string) tea.Cmd {
+	return func() tea.Msg {
+		file, err := trace.WaitForChange(ctx, files)
+		if ctx.Err() != nil {
+			return nil
+		}
+		return MsgConfigChanged{File: file, Err: err}
+	}
+}
+
+// InitTraceCmd runs unified analysis (session + trace) with opts, sending
+// MsgTraceProgress for each stage before the final MsgTraceReady or MsgError.
+// Stages stream in as the trace runs; if the UI falls behind, it gets the
+// latest one rather than a backlog.
+func InitTraceCmd(opts trace.Options) tea.Cmd {
+	stages := make(chan MsgTraceProgress, 1)
+	run := func() tea.Msg {
+		defer close(stages)
+		opts.Progress = func(stage string) {
+			msg := MsgTraceProgress{Stage: stage, next: stages}
+			for {
+				select {
+				case stages <- msg:
+					return
+				default: // Don't block the trace; replace the stage not yet shown
+					select {
+					case <-stages:
+					default:
+					}
+				}
+			}
+		}
+		res, err := trace.RunAnalysis(opts)
+		if err != nil {
+			return MsgError(err)
+		}
+		return MsgTraceReady(res)
+	}
+	return tea.Batch(run, waitForTrace(stages))
+}
+
+// waitForTrace returns the next trace stage, or nothing once the trace ends.
+func waitForTrace(stages chan MsgTraceProgress) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-stages
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
@@ -0,0 +1,125 @@
+package tui
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// How a search term matches executable names, cycled with Tab while typing.
+const (
+	SearchPrefix = iota // Names starting with the term
+	SearchFuzzy         // Names containing the term's characters in order, best first
+	SearchExact         // The name itself
+	searchModes
+)
+
+// searchModeNames name the search modes in the search prompt.
+var searchModeNames = []string{"prefix", "fuzzy", "exact"}
+
+// Fuzzy match scoring, after fzf: each matched character scores, more so at
+// the start of a word or right after the previous match, and each skipped
+// character between matches costs a little.
+const (
+	scoreMatch       = 16
+	bonusBoundary    = 8
+	bonusConsecutive = 12
+	penaltyGap       = 1
+)
+
+// matchName matches term against name, both lowercase, under mode. It
+// returns a score (higher is better) and the rune positions of name that
+// matched, for highlighting.
+func matchName(term, name string, mode int) (int, []int, bool) {
+	switch mode {
+	case SearchExact:
+		if name != term {
+			return 0, nil, false
+		}
+		return 0, runeRange(utf8.RuneCountInString(term)), true
+	case SearchFuzzy:
+		return fuzzyMatch(term, name)
+	}
+	if !strings.HasPrefix(name, term) {
+		return 0, nil, false
+	}
+	return 0, runeRange(utf8.RuneCountInString(term)), true
+}
+
+// fuzzyMatch finds term's characters in name in order, trying each place the
+// first one occurs and keeping the best scoring, so "gst" prefers
+// "git-status" to "gist".
+func fuzzyMatch(term, name string) (int, []int, bool) {
+	t, n := []rune(term), []rune(name)
+	if len(t) == 0 {
+		return 0, nil, true
+	}
+	best, bestPos, found := 0, []int(nil), false
+	for start := range n {
+		if n[start] != t[0] {
+			continue
+		}
+		pos := []int{start}
+		for i, j := 1, start+1; i < len(t) && j < len(n); j++ {
+			if n[j] == t[i] {
+				pos = append(pos, j)
+				i++
+			}
+		}
+		if len(pos) < len(t) {
+			break // Later starts cannot match either
+		}
+		if score := fuzzyScore(n, pos); !found || score > best {
+			best, bestPos, found = score, pos, true
+		}
+	}
+	return best, bestPos, found
+}
+
+// fuzzyScore scores the matched positions pos of name.
+func fuzzyScore(name []rune, pos []int) int {
+	score := 0
+	for k, p := range pos {
+		score += scoreMatch
+		if p == 0 || strings.ContainsRune("-_. ", name[p-1]) {
+			score += bonusBoundary
+		}
+		if k > 0 {
+			if p == pos[k-1]+1 {
+				score += bonusConsecutive
+			} else {
+				score -= penaltyGap * (p - pos[k-1] - 1)
+			}
+		}
+	}
+	return score - penaltyGap*(len(name)-pos[len(pos)-1]-1)/4 // Prefer shorter names
+}
+
+// highlightMatch renders name in style, with the characters at the rune
+// positions pos in bold, underlined and in the highlight color.
+func highlightMatch(name string, pos []int, style lipgloss.Style) string {
+	hit := make(map[int]bool)
+	for _, p := range pos {
+		hit[p] = true
+	}
+	matched := style.Foreground(theme.Highlight).Bold(true).Underline(true)
+	var sb strings.Builder
+	for i, r := range []rune(name) {
+		if hit[i] {
+			sb.WriteString(matched.Render(string(r)))
+		} else {
+			sb.WriteString(style.Render(string(r)))
+		}
+	}
+	return sb.String()
+}
+
+// runeRange returns 0, 1, ..., n-1.
+func runeRange(n int) []int {
+	r := make([]int, n)
+	for i := range r {
+		r[i] = i
+	}
+	return r
+}
@@ -0,0 +1,106 @@
+package tui
+
+import (
+	"strings"
+
+	"lspath/internal/model"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// Headless drives an AppModel without a terminal so View output can be
+// compared against golden files. Commands returned by Update are discarded,
+// so nothing touches the filesystem or starts goroutines; callers inject the
+// results they want (MsgDirListing, MsgFileLoaded, MsgScanDone, ...) with Send.
+type Headless struct {
+	Model AppModel
+}
+
+// NewHeadless returns a model sized width x height with res already loaded.
+// Colors are disabled while it updates and renders, so the output is plain
+// text on every terminal; lipgloss's color profile is restored afterwards.
+func NewHeadless(res model.AnalysisResult, width, height int) *Headless {
+	h := &Headless{Model: InitialModel()}
+	// Keep golden files independent of the version and the user's saved layout
+	h.Model.HelpContent = strings.ReplaceAll(h.Model.HelpContent, model.Version, "VERSION")
+	h.Model.Layout = Layout{Split: DefaultSplit}
+	h.Send(tea.WindowSizeMsg{Width: width, Height: height}, MsgTraceReady(res))
+	return h
+}
+
+// Send delivers messages to Update in order.
+func (h *Headless) Send(msgs ...tea.Msg) *Headless {
+	defer plain()()
+	for _, msg := range msgs {
+		h.Model.Update(msg)
+	}
+	return h
+}
+
+// Keys presses keys in order. Names follow tea.Key.String ("down", "tab",
+// "enter", "esc", "pgdown", "ctrl+c", ...); anything else is typed as text.
+func (h *Headless) Keys(keys ...string) *Headless {
+	for _, k := range keys {
+		h.Send(keyMsg(k))
+	}
+	return h
+}
+
+// Resize changes the window size, as when the terminal is resized.
+func (h *Headless) Resize(width, height int) *Headless {
+	return h.Send(tea.WindowSizeMsg{Width: width, Height: height})
+}
+
+// Render returns the current View output.
+func (h *Headless) Render() string {
+	defer plain()()
+	return h.Model.View()
+}
+
+// plain switches lipgloss to plain text, returning a func that switches it
+// back.
+func plain() func() {
+	prev := lipgloss.ColorProfile()
+	lipgloss.SetColorProfile(termenv.Ascii)
+	return func() { lipgloss.SetColorProfile(prev) }
+}
+
+var namedKeys = map[string]tea.KeyType{
+	"enter":  tea.KeyEnter,
+	"esc":    tea.KeyEsc,
+	"tab":    tea.KeyTab,
+	"up":     tea.KeyUp,
+	"down":   tea.KeyDown,
+	"left":   tea.KeyLeft,
+	"right":  tea.KeyRight,
+	"pgup":   tea.KeyPgUp,
+	"pgdown": tea.KeyPgDown,
+	"home":   tea.KeyHome,
+	"end":    tea.KeyEnd,
+	"ctrl+c": tea.KeyCtrlC,
+	"ctrl+d": tea.KeyCtrlD,
+	"ctrl+u": tea.KeyCtrlU,
+	"ctrl+b": tea.KeyCtrlB,
+	"ctrl+f": tea.KeyCtrlF,
+	"ctrl+g": tea.KeyCtrlG,
+	"ctrl+n": tea.KeyCtrlN,
+	"ctrl+p": tea.KeyCtrlP,
+	"ctrl+s": tea.KeyCtrlS,
+	"ctrl+v": tea.KeyCtrlV,
+	" ":      tea.KeySpace,
+}
+
+// keyMsg builds the key named k; "alt+" presses the rest with Alt.
+func keyMsg(k string) tea.KeyMsg {
+	if rest, ok := strings.CutPrefix(k, "alt+"); ok && rest != "" {
+		msg := keyMsg(rest)
+		msg.Alt = true
+		return msg
+	}
+	if t, ok := namedKeys[k]; ok {
+		return tea.KeyMsg{Type: t}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+}
@@ -0,0 +1,197 @@
+package tui
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"lspath/internal/model"
+	"lspath/internal/trace"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// goldenResult is a small trace with a duplicate, a missing directory, a
+// session-only entry and a path too long for narrow terminals.
+func goldenResult() model.AnalysisResult {
+	entry := func(value, source string, line int, mode string) model.PathEntry {
+		return model.PathEntry{Value: value, SourceFile: source, LineNumber: line, Mode: mode, SymlinkPointsTo: -1, FlowID: "node-1"}
+	}
+	res := model.AnalysisResult{
+		PathEntries: []model.PathEntry{
+			entry("/home/user/.local/bin", "/home/user/.zshrc", 3, "Interactive"),
+			entry("/opt/homebrew/bin", "/home/user/.zprofile", 1, "Login"),
+			entry("/usr/local/bin", "/etc/paths", 1, "Login"),
+			entry("/usr/bin", "/etc/paths", 2, "Login"),
+			entry("/bin", "/etc/paths", 3, "Login"),
+			entry("/opt/homebrew/bin", "/home/user/.zshrc", 7, "Interactive"),
+			entry("/home/user/projects/some-rather-long-project-name/node_modules/.bin", "/home/user/.zshrc", 12, "Interactive"),
+			entry("/opt/missing/bin", "/home/user/.zshrc", 15, "Interactive"),
+			entry("/home/user/venv/bin", "", 0, "Unknown"),
+		},
+		FlowNodes: []model.ConfigNode{
+			{ID: "node-1", FilePath: "/etc/paths", Order: 1, Entries: []int{2, 3, 4}, Description: "(system-wide)"},
+			{ID: "node-2", FilePath: "/home/user/.zprofile", Order: 2, Entries: []int{1}, Description: "(user-specific)"},
+			{ID: "node-3", FilePath: "/home/user/.zshrc", Order: 3, Entries: []int{0, 5, 6, 7}, Description: "(user-specific)"},
+		},
+	}
+	dup := &res.PathEntries[5]
+	dup.IsDuplicate, dup.DuplicateOf = true, 1
+	dup.DuplicateMessage = "Duplicate of entry 2"
+	dup.Diagnostics = []string{"Duplicate of entry 2"}
+	res.PathEntries[7].Diagnostics = []string{"Directory does not exist"}
+	session := &res.PathEntries[8]
+	session.IsSessionOnly, session.SessionNote = true, "Virtual environment"
+	return res
+}
+
+// goldenFiles are the startup files goldenResult was traced from.
+var goldenFiles = map[string]string{
+	"/etc/paths":           "/usr/local/bin\n/usr/bin\n/bin\n",
+	"/home/user/.zprofile": "eval \"$(/opt/homebrew/bin/brew shellenv)\"\n",
+	"/home/user/.zshrc": `# Interactive shells
+export EDITOR=vim
+export PATH="$HOME/.local/bin:$PATH"
+
+alias ll='ls -l'
+
+export PATH="/opt/homebrew/bin:$PATH"
+
+# Project tools
+cd_project() { cd ~/projects/some-rather-long-project-name; }
+
+export PATH="$HOME/projects/some-rather-long-project-name/node_modules/.bin:$PATH"
+
+# Left over from an old install
+export PATH="/opt/missing/bin:$PATH"
+`,
+}
+
+// goldenBinaries are the executables in each of goldenResult's directories.
+var goldenBinaries = map[string][]string{
+	"/home/user/.local/bin": {"pipx", "poetry"},
+	"/opt/homebrew/bin":     {"brew", "git", "python3"},
+	"/usr/local/bin":        {"git", "node"},
+	"/usr/bin":              {"git", "python3", "ssh"},
+	"/bin":                  {"ls", "sh"},
+	"/home/user/projects/some-rather-long-project-name/node_modules/.bin": {"eslint", "tsc"},
+	"/home/user/venv/bin": {"pip", "python3"},
+}
+
+// settle delivers what the commands Headless discards would have: the
+// finished executable scan, the selected entry's directory listing and the
+// flow preview's file.
+func settle(h *Headless) *Headless {
+	entries := h.Model.TraceResult.PathEntries
+	index := &trace.BinaryIndex{ByEntry: make([][]string, len(entries)), Scanned: len(entries)}
+	for i, e := range entries {
+		index.ByEntry[i] = goldenBinaries[e.Value]
+	}
+	h.Send(MsgScanDone{Gen: h.Model.scanGen, Index: index})
+
+	if i, ok := h.Model.selectedEntry(); ok {
+		h.Send(goldenListing(i, entries[i]))
+	}
+	if content, ok := goldenFiles[h.Model.PreviewPath]; ok {
+		h.Send(MsgFileLoaded{Path: h.Model.PreviewPath, Content: content})
+	}
+	return h
+}
+
+// goldenListing is the listing loadDirListingCmd would make of entry i.
+func goldenListing(i int, entry model.PathEntry) MsgDirListing {
+	msg := MsgDirListing{Index: i}
+	if lines := strings.Split(goldenFiles[entry.SourceFile], "\n"); entry.LineNumber > 0 && entry.LineNumber <= len(lines) {
+		msg.LineContext = model.LineContext{Target: lines[entry.LineNumber-1], LineNumber: entry.LineNumber}
+	}
+	names, ok := goldenBinaries[entry.Value]
+	if !ok {
+		msg.Listing = "Directory does not exist:\n\n" + entry.Value
+		return msg
+	}
+	var sb strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&sb, "-rwxr-xr-x  48.2K  Jan 02 15:04  %s\n", name)
+	}
+	msg.Listing = sb.String()
+	msg.Stats = trace.DirStats{FileCount: len(names), Executables: len(names), Size: int64(len(names)) * 49357,
+		Contents: trace.DirContents{Binaries: len(names)}}
+	return msg
+}
+
+// TestHeadlessGolden renders the TUI at several terminal sizes and compares
+// each screen with testdata/<name>.golden. Run with -update to rewrite them.
+func TestHeadlessGolden(t *testing.T) {
+	t.Setenv("HOME", "/home/user") // The flow list shows startup files under HOME as ~
+	tests := []struct {
+		name          string
+		width, height int
+		keys          []string
+	}{
+		{"list-80x24", 80, 24, nil},
+		{"list-120x40", 120, 40, nil},
+		{"list-40x12", 40, 12, nil},
+		{"scrolled-80x24", 80, 24, []string{"down", "down", "down", "down", "down", "down"}},
+		{"scrolled-40x12", 40, 12, []string{"down", "down", "down", "down", "down", "down", "down", "down"}},
+		{"flow-120x40", 120, 40, []string{"f"}},
+		{"flow-40x12", 40, 12, []string{"f", "down", "down"}},
+		{"help-80x24", 80, 24, []string{"?"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := settle(NewHeadless(goldenResult(), tt.width, tt.height).Keys(tt.keys...))
+			got := h.Render()
+			lines := strings.Split(got, "\n")
+			if len(lines) != tt.height {
+				t.Errorf("%d lines, want the terminal's %d", len(lines), tt.height)
+			}
+			for i, line := range lines {
+				if w := ansi.StringWidth(line); w > tt.width {
+					t.Errorf("line %d is %d columns, wider than the %d-column terminal: %q", i+1, w, tt.width, line)
+				}
+			}
+
+			file := filepath.Join("testdata", tt.name+".golden")
+			if *update {
+				if err := os.MkdirAll("testdata", 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(file, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to create it)", err)
+			}
+			if got != string(want) {
+				t.Errorf("View differs from %s (run go test -update if the change is intended):\n%s", file, diffLines(string(want), got))
+			}
+		})
+	}
+}
+
+// diffLines lists the lines of want and got that differ.
+func diffLines(want, got string) string {
+	w, g := strings.Split(want, "\n"), strings.Split(got, "\n")
+	var sb strings.Builder
+	for i := 0; i < max(len(w), len(g)); i++ {
+		var wl, gl string
+		if i < len(w) {
+			wl = w[i]
+		}
+		if i < len(g) {
+			gl = g[i]
+		}
+		if wl != gl {
+			fmt.Fprintf(&sb, "line %d:\n  want %q\n  got  %q\n", i+1, wl, gl)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,413 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// KeyMap is the TUI's key bindings. Keys are named as tea.Key.String names
+// them: "up", "ctrl+p", "alt+v", "enter", "G", " " for space, and so on.
+type KeyMap struct {
+	Name string
+
+	Up       key.Binding
+	Down     key.Binding
+	PageUp   key.Binding
+	PageDown key.Binding
+	Top      key.Binding
+	Bottom   key.Binding
+	Focus    key.Binding // Switch panels
+	Back     key.Binding // Clear the search, leave Flow Mode or stop a scan
+	Close    key.Binding // Close a dialog
+	Help     key.Binding
+	Quit     key.Binding
+
+	Which       key.Binding
+	Search      key.Binding // Search every executable
+	Copy        key.Binding
+	CopyLine    key.Binding
+	Flow        key.Binding
+	Cumulative  key.Binding
+	Fix         key.Binding
+	Executables key.Binding
+	Diagnostics key.Binding
+	Retrace     key.Binding
+
+	Sort             key.Binding
+	Group            key.Binding
+	ToggleGroup      key.Binding
+	FilterDuplicates key.Binding
+	FilterMissing    key.Binding
+	FilterSession    key.Binding
+	FilterSource     key.Binding
+	FilterNone       key.Binding
+	LongPaths        key.Binding
+	ScrollLeft       key.Binding
+	ScrollRight      key.Binding
+	Narrow           key.Binding
+	Widen            key.Binding
+	HideDetails      key.Binding
+
+	// Used only in a dialog or the tour
+	Apply    key.Binding // Apply the planned fix
+	Cancel   key.Binding // Leave the fix dialog without applying it
+	Verbose  key.Binding // Toggle the verbose diagnostics report
+	Save     key.Binding // Save the diagnostics report
+	TourNext key.Binding
+	TourBack key.Binding
+	TourEnd  key.Binding
+}
+
+// keyActions maps the keys file's action names to their KeyMap fields.
+// Actions marked dialog are only used while a dialog or the tour is open, so
+// may share keys with the rest.
+var keyActions = map[string]struct {
+	binding func(*KeyMap) *key.Binding
+	dialog  bool
+}{
+	"up":                {binding: func(k *KeyMap) *key.Binding { return &k.Up }},
+	"down":              {binding: func(k *KeyMap) *key.Binding { return &k.Down }},
+	"page-up":           {binding: func(k *KeyMap) *key.Binding { return &k.PageUp }},
+	"page-down":         {binding: func(k *KeyMap) *key.Binding { return &k.PageDown }},
+	"top":               {binding: func(k *KeyMap) *key.Binding { return &k.Top }},
+	"bottom":            {binding: func(k *KeyMap) *key.Binding { return &k.Bottom }},
+	"focus":             {binding: func(k *KeyMap) *key.Binding { return &k.Focus }},
+	"back":              {binding: func(k *KeyMap) *key.Binding { return &k.Back }},
+	"close":             {binding: func(k *KeyMap) *key.Binding { return &k.Close }, dialog: true},
+	"help":              {binding: func(k *KeyMap) *key.Binding { return &k.Help }},
+	"quit":              {binding: func(k *KeyMap) *key.Binding { return &k.Quit }},
+	"which":             {binding: func(k *KeyMap) *key.Binding { return &k.Which }},
+	"search":            {binding: func(k *KeyMap) *key.Binding { return &k.Search }},
+	"copy":              {binding: func(k *KeyMap) *key.Binding { return &k.Copy }},
+	"copy-line":         {binding: func(k *KeyMap) *key.Binding { return &k.CopyLine }},
+	"flow":              {binding: func(k *KeyMap) *key.Binding { return &k.Flow }},
+	"cumulative":        {binding: func(k *KeyMap) *key.Binding { return &k.Cumulative }},
+	"fix":               {binding: func(k *KeyMap) *key.Binding { return &k.Fix }},
+	"executables":       {binding: func(k *KeyMap) *key.Binding { return &k.Executables }},
+	"diagnostics":       {binding: func(k *KeyMap) *key.Binding { return &k.Diagnostics }},
+	"retrace":           {binding: func(k *KeyMap) *key.Binding { return &k.Retrace }},
+	"sort":              {binding: func(k *KeyMap) *key.Binding { return &k.Sort }},
+	"group":             {binding: func(k *KeyMap) *key.Binding { return &k.Group }},
+	"toggle-group":      {binding: func(k *KeyMap) *key.Binding { return &k.ToggleGroup }},
+	"filter-duplicates": {binding: func(k *KeyMap) *key.Binding { return &k.FilterDuplicates }},
+	"filter-missing":    {binding: func(k *KeyMap) *key.Binding { return &k.FilterMissing }},
+	"filter-session":    {binding: func(k *KeyMap) *key.Binding { return &k.FilterSession }},
+	"filter-source":     {binding: func(k *KeyMap) *key.Binding { return &k.FilterSource }},
+	"filter-none":       {binding: func(k *KeyMap) *key.Binding { return &k.FilterNone }},
+	"long-paths":        {binding: func(k *KeyMap) *key.Binding { return &k.LongPaths }},
+	"scroll-left":       {binding: func(k *KeyMap) *key.Binding { return &k.ScrollLeft }},
+	"scroll-right":      {binding: func(k *KeyMap) *key.Binding { return &k.ScrollRight }},
+	"narrow":            {binding: func(k *KeyMap) *key.Binding { return &k.Narrow }},
+	"widen":             {binding: func(k *KeyMap) *key.Binding { return &k.Widen }},
+	"hide-details":      {binding: func(k *KeyMap) *key.Binding { return &k.HideDetails }},
+	"apply":             {binding: func(k *KeyMap) *key.Binding { return &k.Apply }, dialog: true},
+	"cancel":            {binding: func(k *KeyMap) *key.Binding { return &k.Cancel }, dialog: true},
+	"verbose":           {binding: func(k *KeyMap) *key.Binding { return &k.Verbose }, dialog: true},
+	"save":              {binding: func(k *KeyMap) *key.Binding { return &k.Save }, dialog: true},
+	"tour-next":         {binding: func(k *KeyMap) *key.Binding { return &k.TourNext }, dialog: true},
+	"tour-back":         {binding: func(k *KeyMap) *key.Binding { return &k.TourBack }, dialog: true},
+	"tour-end":          {binding: func(k *KeyMap) *key.Binding { return &k.TourEnd }, dialog: true},
+}
+
+// keys builds a binding for ks.
+func keys(ks ...string) key.Binding {
+	return key.NewBinding(key.WithKeys(ks...))
+}
+
+// defaultKeyMap is lspath's own bindings, after less and vi.
+func defaultKeyMap() KeyMap {
+	return KeyMap{
+		Up:       keys("up", "k"),
+		Down:     keys("down", "j"),
+		PageUp:   keys("pgup", "ctrl+u", "ctrl+b", "b"),
+		PageDown: keys("pgdown", "ctrl+d", "ctrl+f", " "),
+		Top:      keys("home", "g"),
+		Bottom:   keys("end", "G"),
+		Focus:    keys("tab"),
+		Back:     keys("esc"),
+		Close:    keys("esc", "q"),
+		Help:     keys("?", "h"),
+		Quit:     keys("q", "ctrl+c"),
+
+		Which:       keys("w"),
+		Search:      keys("/"),
+		Copy:        keys("y"),
+		CopyLine:    keys("Y"),
+		Flow:        keys("f"),
+		Cumulative:  keys("c"),
+		Fix:         keys("x"),
+		Executables: keys("e"),
+		Diagnostics: keys("d"),
+		Retrace:     keys("r"),
+
+		Sort:             keys("s"),
+		Group:            keys("z"),
+		ToggleGroup:      keys("enter"),
+		FilterDuplicates: keys("1"),
+		FilterMissing:    keys("2"),
+		FilterSession:    keys("3"),
+		FilterSource:     keys("4"),
+		FilterNone:       keys("5"),
+		LongPaths:        keys("L"),
+		ScrollLeft:       keys("left"),
+		ScrollRight:      keys("right"),
+		Narrow:           keys("["),
+		Widen:            keys("]"),
+		HideDetails:      keys("\\"),
+
+		Apply:    keys("y"),
+		Cancel:   keys("n"),
+		Verbose:  keys("v"),
+		Save:     keys("s"),
+		TourNext: keys("right", "enter", "l", "n", " "),
+		TourBack: keys("left", "h", "p", "backspace"),
+		TourEnd:  keys("esc", "q"),
+	}
+}
+
+// emacsKeyMap moves and cancels as Emacs does, keeping the default letters
+// for everything else. Ctrl+F and Ctrl+B scroll sideways rather than page.
+func emacsKeyMap() KeyMap {
+	k := defaultKeyMap()
+	k.Up = keys("ctrl+p", "up")
+	k.Down = keys("ctrl+n", "down")
+	k.PageUp = keys("alt+v", "pgup", "b")
+	k.PageDown = keys("ctrl+v", "pgdown", " ")
+	k.Top = keys("alt+<", "home")
+	k.Bottom = keys("alt+>", "end")
+	k.Back = keys("ctrl+g", "esc")
+	k.Close = keys("ctrl+g", "esc", "q")
+	k.Which = keys("ctrl+s", "w")
+	k.ScrollLeft = keys("ctrl+b", "left")
+	k.ScrollRight = keys("ctrl+f", "right")
+	k.TourNext = keys("ctrl+f", "right", "enter", "ctrl+n", " ")
+	k.TourBack = keys("ctrl+b", "left", "ctrl+p", "backspace")
+	k.TourEnd = keys("ctrl+g", "esc", "q")
+	return k
+}
+
+// KeyMaps are the built-in key maps, chosen with the keys file's "keymap"
+// line.
+var KeyMaps = map[string]func() KeyMap{
+	"default": defaultKeyMap,
+	"emacs":   emacsKeyMap,
+}
+
+// DefaultKeyMap is used when the keys file picks none.
+const DefaultKeyMap = "default"
+
+// KeyMapNames lists the built-in key maps, for help and errors.
+func KeyMapNames() []string {
+	var names []string
+	for name := range KeyMaps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// KeysFile returns the user's key bindings, e.g. ~/.config/lspath/keys.
+func KeysFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "lspath", "keys")
+}
+
+// LoadKeyMap returns the key map file's "keymap" line picks, or
+// DefaultKeyMap, with the bindings file sets on top. Each line of file is a
+// name and its value, e.g. "keymap emacs" or "fix ctrl+x F" to bind an action
+// to one or more keys ("none" unbinds it); blank lines and # comments are
+// ignored. A missing file sets nothing. Two actions bound to the same key is
+// an error, except that dialog keys may share with the rest.
+func LoadKeyMap(file string) (KeyMap, error) {
+	base := ""
+	overrides := make(map[string][]string)
+	var order []string
+	if f, err := os.Open(file); err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for n := 1; scanner.Scan(); n++ {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			fields := strings.Fields(line)
+			action, values := fields[0], fields[1:]
+			switch _, isAction := keyActions[action]; {
+			case action == "keymap" && len(values) == 1:
+				base = values[0]
+			case action == "keymap":
+				return KeyMap{}, fmt.Errorf("line %d of %s: keymap takes one name", n, file)
+			case !isAction:
+				return KeyMap{}, fmt.Errorf("line %d of %s: unknown action %q", n, file, action)
+			case len(values) == 0:
+				return KeyMap{}, fmt.Errorf("line %d of %s: no keys for %s (use none to unbind it)", n, file, action)
+			default:
+				if _, seen := overrides[action]; !seen {
+					order = append(order, action)
+				}
+				overrides[action] = values
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return KeyMap{}, err
+		}
+	} else if !os.IsNotExist(err) {
+		return KeyMap{}, err
+	}
+
+	if base == "" {
+		base = DefaultKeyMap
+	}
+	build, ok := KeyMaps[base]
+	if !ok {
+		return KeyMap{}, fmt.Errorf("%s: unknown keymap %q (use %s)", file, base, strings.Join(KeyMapNames(), ", "))
+	}
+	k := build()
+	k.Name = base
+	for _, action := range order {
+		ks := overrides[action]
+		if len(ks) == 1 && ks[0] == "none" {
+			ks = nil
+		}
+		for i, name := range ks {
+			if name == "space" {
+				ks[i] = " " // A space can't be written as itself
+			}
+		}
+		*keyActions[action].binding(&k) = keys(ks...)
+	}
+	if err := k.conflicts(); err != nil {
+		return KeyMap{}, fmt.Errorf("%s: %w", file, err)
+	}
+	return k, nil
+}
+
+// conflicts reports a key bound to two actions that are used together.
+func (k *KeyMap) conflicts() error {
+	var actions []string
+	for action := range keyActions {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	owner := make(map[string]string)
+	for _, action := range actions {
+		if keyActions[action].dialog {
+			continue
+		}
+		for _, name := range keyActions[action].binding(k).Keys() {
+			if other, taken := owner[name]; taken {
+				return fmt.Errorf("%q is bound to both %s and %s", keyLabel(name), other, action)
+			}
+			owner[name] = action
+		}
+	}
+	return nil
+}
+
+// keymap is the bindings Update acts on and View describes; see
+// ApplyKeyMap.
+var keymap = defaultKeyMap()
+
+// ApplyKeyMap makes the TUI use k from now on.
+func ApplyKeyMap(k KeyMap) {
+	keymap = k
+}
+
+// keyLabels spell out key names for the footer.
+var keyLabels = map[string]string{
+	"up": "↑", "down": "↓", "left": "←", "right": "→",
+	"pgup": "PgUp", "pgdown": "PgDn", "home": "Home", "end": "End",
+	"tab": "Tab", "enter": "Enter", "esc": "Esc", "backspace": "Backspace", " ": "Space",
+}
+
+// keyLabel spells out a key name, e.g. "ctrl+p" as "Ctrl+P".
+func keyLabel(name string) string {
+	if label, ok := keyLabels[name]; ok {
+		return label
+	}
+	mods := ""
+	for _, mod := range []string{"ctrl+", "alt+", "shift+"} {
+		if rest, ok := strings.CutPrefix(name, mod); ok && rest != "" {
+			mods += strings.ToUpper(mod[:1]) + mod[1:]
+			name = rest
+		}
+	}
+	if mods != "" {
+		if label, ok := keyLabels[name]; ok {
+			return mods + label
+		}
+		return mods + strings.ToUpper(name)
+	}
+	return name
+}
+
+// bindingLabel names b's first key, which the footer shows, or "" if it is
+// unbound.
+func bindingLabel(b key.Binding) string {
+	if !b.Enabled() {
+		return ""
+	}
+	return keyLabel(b.Keys()[0])
+}
+
+// helpItem is a footer entry: what its bindings do, and what goes between
+// their keys.
+type helpItem struct {
+	desc     string
+	sep      string
+	bindings []key.Binding
+}
+
+// item describes bindings shown as "a/b: desc".
+func item(desc string, bindings ...key.Binding) helpItem {
+	return helpItem{desc: desc, sep: "/", bindings: bindings}
+}
+
+// helpLine renders items as "k: desc • ...", leaving out items whose keys
+// are all unbound.
+func helpLine(items ...helpItem) string {
+	var parts []string
+	for _, it := range items {
+		var labels []string
+		for _, b := range it.bindings {
+			if label := bindingLabel(b); label != "" {
+				labels = append(labels, label)
+			}
+		}
+		if len(labels) > 0 {
+			parts = append(parts, strings.Join(labels, it.sep)+": "+it.desc)
+		}
+	}
+	return strings.Join(parts, " • ")
+}
+
+// quotedLabel is bindingLabel with a single character quoted, as in
+// "Press 'd'", so it reads as a key.
+func quotedLabel(b key.Binding) string {
+	label := bindingLabel(b)
+	if len([]rune(label)) == 1 {
+		return "'" + label + "'"
+	}
+	return label
+}
+
+// closeLabel names the keys that close a dialog opened with open, e.g.
+// "'d'/Esc", followed by any of the dialog's own keys that also close it.
+func closeLabel(open key.Binding, also ...key.Binding) string {
+	var labels []string
+	for _, b := range append([]key.Binding{open, keymap.Close}, also...) {
+		if label := quotedLabel(b); label != "" {
+			labels = append(labels, label)
+		}
+	}
+	return strings.Join(labels, "/")
+}
@@ -0,0 +1,111 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Layout is how the TUI divides the screen between the PATH list and the
+// pane beside it. Changes are saved to LayoutFile for next time.
+type Layout struct {
+	Split     int  // Percentage of the width the PATH list takes
+	HideRight bool // Collapse the details pane, e.g. on a narrow terminal ('\')
+}
+
+// Split limits and the step '[' and ']' change it by.
+const (
+	DefaultSplit = 50
+	minSplit     = 20
+	maxSplit     = 80
+	splitStep    = 5
+)
+
+// LayoutFile returns where the TUI keeps its layout, e.g.
+// ~/.config/lspath/layout.
+func LayoutFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "lspath", "layout")
+}
+
+// LoadLayout reads file's "split <percent>" and "details hidden|shown"
+// lines. Blank lines and # comments are ignored, and anything missing or
+// unreadable keeps the default layout.
+func LoadLayout(file string) Layout {
+	l := Layout{Split: DefaultSplit}
+	f, err := os.Open(file)
+	if err != nil {
+		return l
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, _ := strings.Cut(line, " ")
+		value = strings.TrimSpace(value)
+		switch key {
+		case "split":
+			if n, err := strconv.Atoi(value); err == nil {
+				l.Split = min(max(n, minSplit), maxSplit)
+			}
+		case "details":
+			l.HideRight = value == "hidden"
+		}
+	}
+	return l
+}
+
+// SaveLayout writes l to file, creating its directory.
+func SaveLayout(file string, l Layout) error {
+	if file == "" {
+		return fmt.Errorf("no config directory")
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	details := "shown"
+	if l.HideRight {
+		details = "hidden"
+	}
+	return os.WriteFile(file, []byte(fmt.Sprintf("# lspath TUI layout, saved by '[', ']' and '\\'\nsplit %d\ndetails %s\n", l.Split, details)), 0o644)
+}
+
+// resizeSplit widens the PATH list by delta percent, within limits, and
+// shows the details pane again if it was collapsed.
+func (m *AppModel) resizeSplit(delta int) {
+	m.Layout.Split = min(max(m.Layout.Split+delta, minSplit), maxSplit)
+	m.Layout.HideRight = false
+}
+
+// minPanelHeight is the least height panelHeight gives the panels.
+const minPanelHeight = 4
+
+// panelHeight returns the height of the panels inside their borders: the
+// window less their borders, and the blank line and key help below them.
+func (m AppModel) panelHeight() int {
+	return max(m.WindowSize.Height-4, minPanelHeight)
+}
+
+// listWidth returns the width of the PATH list panel, inside its border.
+func (m AppModel) listWidth() int {
+	netWidth := max(m.WindowSize.Width-6, 20) // Both panels' borders and a margin
+	if m.rightHidden() {
+		return netWidth + 2 // The details pane's border too
+	}
+	return netWidth * m.Layout.Split / 100
+}
+
+// rightHidden reports whether the details pane is collapsed. Flow Mode
+// always shows its pane.
+func (m AppModel) rightHidden() bool {
+	return m.Layout.HideRight && !m.ShowFlow
+}
@@ -0,0 +1,112 @@
+package tui
+
+import (
+	"lspath/internal/model"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Filters of the PATH list, chosen with keys 1-4; 5 shows everything.
+const (
+	FilterNone       = iota
+	FilterDuplicates // Entries flagged as duplicates
+	FilterMissing    // Directories that do not exist, and broken symlinks
+	FilterSession    // Entries no startup file added
+	FilterSource     // Entries added by FilterFile
+)
+
+// setListEntries shows the PathEntries indices in indices (every entry, or
+// a search's matches), less those Filter hides.
+func (m *AppModel) setListEntries(indices []int) {
+	m.listBase = indices
+	m.FilteredIndices = nil
+	for _, idx := range indices {
+		if m.filterMatches(m.TraceResult.PathEntries[idx]) {
+			m.FilteredIndices = append(m.FilteredIndices, idx)
+		}
+	}
+	m.arrangeList()
+}
+
+// allEntries returns the indices of every PATH entry.
+func (m *AppModel) allEntries() []int {
+	indices := make([]int, len(m.TraceResult.PathEntries))
+	for i := range indices {
+		indices[i] = i
+	}
+	return indices
+}
+
+// filterMatches reports whether Filter shows e.
+func (m *AppModel) filterMatches(e model.PathEntry) bool {
+	switch m.Filter {
+	case FilterDuplicates:
+		return m.TraceResult.DuplicatePolicy.Flagged(e)
+	case FilterMissing:
+		return entryStatusIcon(m.TraceResult, e) == model.IconMissing
+	case FilterSession:
+		return e.IsSessionOnly
+	case FilterSource:
+		return e.SourceFile == m.FilterFile
+	}
+	return true
+}
+
+// chooseFilter applies filter. Choosing a filter again shows everything,
+// except FilterSource, which moves on to the next source file: the first
+// time picks the selected entry's.
+func (m *AppModel) chooseFilter(filter int) tea.Cmd {
+	switch {
+	case filter == FilterSource:
+		m.FilterFile = m.nextFilterFile()
+	case filter == m.Filter:
+		filter = FilterNone
+	}
+	m.Filter = filter
+	m.setListEntries(m.listBase)
+	return m.loadDirectoryListing()
+}
+
+// nextFilterFile returns the source file FilterSource should show next.
+func (m *AppModel) nextFilterFile() string {
+	if m.Filter != FilterSource {
+		if idx, ok := m.selectedEntry(); ok {
+			return m.TraceResult.PathEntries[idx].SourceFile
+		}
+		if group, ok := m.selectedGroup(); ok {
+			return group.Group
+		}
+	}
+	var files []string
+	seen := make(map[string]bool)
+	for _, e := range m.TraceResult.PathEntries {
+		if !seen[e.SourceFile] {
+			seen[e.SourceFile] = true
+			files = append(files, e.SourceFile)
+		}
+	}
+	for i, f := range files {
+		if f == m.FilterFile {
+			return files[(i+1)%len(files)]
+		}
+	}
+	if len(files) == 0 {
+		return ""
+	}
+	return files[0]
+}
+
+// filterTitle describes Filter for the PATH list's title.
+func (m *AppModel) filterTitle() string {
+	switch m.Filter {
+	case FilterDuplicates:
+		return "only duplicates"
+	case FilterMissing:
+		return "only missing"
+	case FilterSession:
+		return "only session-only"
+	case FilterSource:
+		return "only from " + m.FilterFile
+	}
+	return ""
+}
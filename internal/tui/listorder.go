@@ -0,0 +1,174 @@
+package tui
+
+import (
+	"sort"
+	"strings"
+
+	"lspath/internal/model"
+	"lspath/internal/trace"
+)
+
+// Orders of the PATH list, cycled with 's'.
+const (
+	SortPriority = iota // PATH order, the order the shell searches
+	SortSource          // By the startup file that added each entry, then by line
+	SortCategory        // By kind of directory, e.g. "Package Managers"
+	SortStatus          // Problems first: missing, duplicate, relative, session, symlink
+	sortModes
+)
+
+// sortNames describe the sort modes in the panel title.
+var sortNames = []string{"priority", "source file", "category", "status"}
+
+// statusRanks orders status icons for SortStatus, worst first.
+var statusRanks = map[string]int{
+	model.IconMissing:   0,
+	model.IconDuplicate: 1,
+	model.IconRelative:  2,
+	model.IconSession:   3,
+	model.IconSymlink:   4,
+	model.IconOK:        5,
+}
+
+// listRow is a row of the PATH list: an entry, or in grouped mode the header
+// of a source file's entries.
+type listRow struct {
+	Entry int    // PathEntries index, or -1 for a group header
+	Group string // Source file of the entry, or of the header's group
+	Count int    // Entries in the group (headers only)
+}
+
+// entryStatusIcon returns the status icon the PATH list shows for e.
+func entryStatusIcon(res model.AnalysisResult, e model.PathEntry) string {
+	switch {
+	case e.IsSessionOnly:
+		return model.IconSession // Session-only entry (not from config files)
+	case res.DuplicatePolicy.Flagged(e):
+		return model.IconDuplicate
+	case e.SymlinkBroken != "" && !e.IsIgnored(model.IgnoreBroken):
+		return model.IconMissing
+	case e.IsSymlink:
+		return model.IconSymlink
+	case model.IsRelativePath(e.Value) && !e.IsIgnored(model.IgnoreRelative):
+		return model.IconRelative
+	case !e.IsIgnored(model.IgnoreMissing):
+		for _, diag := range e.Diagnostics {
+			if strings.Contains(diag, "does not exist") {
+				return model.IconMissing
+			}
+		}
+	}
+	return model.IconOK
+}
+
+// arrangeList rebuilds ListRows from FilteredIndices in SortMode, grouped
+// under collapsible source file headers when Grouped is set. The selection
+// stays on the same entry or header where it can, moving to the header of
+// an entry whose group was collapsed.
+func (m *AppModel) arrangeList() {
+	entries := m.TraceResult.PathEntries
+	selected, onEntry := m.selectedEntry()
+	group := ""
+	if m.SelectedIdx < len(m.ListRows) {
+		group = m.ListRows[m.SelectedIdx].Group
+	}
+
+	order := append([]int(nil), m.FilteredIndices...)
+	if m.SortMode != SortPriority {
+		less := m.sortLess()
+		sort.SliceStable(order, func(a, b int) bool { return less(order[a], order[b]) })
+	}
+
+	m.ListRows = m.ListRows[:0]
+	if !m.Grouped {
+		for _, idx := range order {
+			m.ListRows = append(m.ListRows, listRow{Entry: idx, Group: entries[idx].SourceFile})
+		}
+	} else {
+		var files []string
+		byFile := make(map[string][]int)
+		for _, idx := range order {
+			file := entries[idx].SourceFile
+			if _, seen := byFile[file]; !seen {
+				files = append(files, file)
+			}
+			byFile[file] = append(byFile[file], idx)
+		}
+		for _, file := range files {
+			m.ListRows = append(m.ListRows, listRow{Entry: -1, Group: file, Count: len(byFile[file])})
+			if m.Collapsed[file] {
+				continue
+			}
+			for _, idx := range byFile[file] {
+				m.ListRows = append(m.ListRows, listRow{Entry: idx, Group: file})
+			}
+		}
+	}
+
+	for i, r := range m.ListRows {
+		if onEntry && r.Entry == selected {
+			m.SelectedIdx = i
+			return
+		}
+	}
+	for i, r := range m.ListRows {
+		if r.Entry < 0 && r.Group == group {
+			m.SelectedIdx = i
+			return
+		}
+	}
+	m.clampSelection()
+}
+
+// sortLess compares two PathEntries indices in SortMode, for a stable sort
+// that keeps PATH order among equals.
+func (m *AppModel) sortLess() func(a, b int) bool {
+	entries := m.TraceResult.PathEntries
+	switch m.SortMode {
+	case SortSource:
+		// Files in the order they first add to PATH, which follows startup
+		rank := make(map[string]int)
+		for _, e := range entries {
+			if _, ok := rank[e.SourceFile]; !ok {
+				rank[e.SourceFile] = len(rank)
+			}
+		}
+		return func(a, b int) bool {
+			ea, eb := entries[a], entries[b]
+			if ea.SourceFile != eb.SourceFile {
+				return rank[ea.SourceFile] < rank[eb.SourceFile]
+			}
+			return ea.LineNumber < eb.LineNumber
+		}
+	case SortCategory:
+		return func(a, b int) bool {
+			return trace.PathCategory(entries[a].Value) < trace.PathCategory(entries[b].Value)
+		}
+	case SortStatus:
+		return func(a, b int) bool {
+			return statusRanks[entryStatusIcon(m.TraceResult, entries[a])] < statusRanks[entryStatusIcon(m.TraceResult, entries[b])]
+		}
+	}
+	return func(a, b int) bool { return a < b }
+}
+
+// selectedGroup returns the group header row selected, if one is.
+func (m *AppModel) selectedGroup() (listRow, bool) {
+	if m.SelectedIdx >= len(m.ListRows) || m.ListRows[m.SelectedIdx].Entry >= 0 {
+		return listRow{}, false
+	}
+	return m.ListRows[m.SelectedIdx], true
+}
+
+// toggleGroup collapses the selected group header's entries, or expands them.
+func (m *AppModel) toggleGroup() {
+	row, ok := m.selectedGroup()
+	if !ok {
+		return
+	}
+	if m.Collapsed == nil {
+		m.Collapsed = make(map[string]bool)
+	}
+	m.Collapsed[row.Group] = !m.Collapsed[row.Group]
+	m.arrangeList()
+}
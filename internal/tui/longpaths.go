@@ -0,0 +1,109 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/x/ansi"
+
+	"lspath/internal/model"
+)
+
+// How the selected PATH list row shows a path too long for the panel,
+// cycled with 'L'. Other rows are always truncated.
+const (
+	LongPathsTruncate = iota // End it with "..."
+	LongPathsWrap            // Continue it on the lines below
+	LongPathsScroll          // Scroll it sideways with ←/→
+	longPathModes
+)
+
+// longPathNames describe the long path modes in the panel title.
+var longPathNames = []string{"truncate", "wrap", "scroll"}
+
+// scrollStep is how far ←/→ scroll the selected row.
+const scrollStep = 10
+
+// wrapIndent starts the continuation lines of a wrapped row, under its path.
+const wrapIndent = "      "
+
+// truncate shortens s to width terminal cells, ending in "..." when it is
+// cut. It counts cells rather than bytes, so icons and other multibyte
+// characters are neither split nor over-counted, and styling is kept.
+func truncate(s string, width int) string {
+	return ansi.Truncate(s, max(width, 3), "...")
+}
+
+// entryRow returns the PATH list row for entry idx, the searched-for
+// executable shown after it (if there is room), and the cells left for the
+// row in a list leftWidth wide.
+func (m AppModel) entryRow(idx, leftWidth int) (line, match string, room int) {
+	entry := m.TraceResult.PathEntries[idx]
+	line = fmt.Sprintf("%2d. %s %s", idx+1, entryStatusIcon(m.TraceResult, entry), model.DisplayPath(entry.Value))
+	if entry.IsSessionOnly {
+		line += " (session)"
+	} else if entry.IsDuplicate {
+		line += " (duplicate)"
+	} else if entry.SymlinkPointsTo >= 0 && !m.TraceResult.DuplicatePolicy.IgnoreSymlinks {
+		line += " (duplicate, symlink)"
+	} else if entry.SymlinkBroken != "" {
+		line += " (broken symlink)"
+	} else if entry.IsSymlink {
+		line += " (symlink)"
+	}
+
+	// Priority indicators
+	if idx == 0 {
+		line += " (highest priority " + model.IconPriorityHigh + ")"
+	} else if idx == len(m.TraceResult.PathEntries)-1 {
+		line += " (lowest priority " + model.IconPriorityLow + ")"
+	}
+
+	// The searched-for executable, shown with its matched characters
+	if m.SearchActive {
+		match = m.SearchMatches[idx]
+	}
+	room = leftWidth - 2
+	if w := ansi.StringWidth(match); match != "" && room-w-2 >= 10 {
+		room -= w + 2
+	} else {
+		match = ""
+	}
+	return line, match, room
+}
+
+// selectedRowLines lays out the selected row, line, in room cells under
+// LongPaths.
+func (m AppModel) selectedRowLines(line string, room int) []string {
+	width := ansi.StringWidth(line)
+	if width <= room {
+		return []string{line}
+	}
+	switch m.LongPaths {
+	case LongPathsWrap:
+		lines := []string{ansi.Cut(line, 0, room)}
+		step := max(room-len(wrapIndent), 10)
+		for from := room; from < width; from += step {
+			lines = append(lines, wrapIndent+ansi.Cut(line, from, from+step))
+		}
+		return lines
+	case LongPathsScroll:
+		// Never scroll past the point where the end of the row shows
+		offset := min(m.RowScrollX, width-room)
+		if offset > 0 {
+			line = ansi.TruncateLeft(line, offset+3, "...")
+		}
+		return []string{truncate(line, room)}
+	}
+	return []string{truncate(line, room)}
+}
+
+// scrollRow scrolls the selected row by delta cells in LongPathsScroll,
+// stopping once its end shows.
+func (m *AppModel) scrollRow(delta int) {
+	idx, ok := m.selectedEntry()
+	if !ok || m.LongPaths != LongPathsScroll {
+		return
+	}
+	line, _, room := m.entryRow(idx, m.listWidth())
+	m.RowScrollX = min(max(m.RowScrollX+delta, 0), max(ansi.StringWidth(line)-room, 0))
+}
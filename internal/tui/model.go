@@ -1,28 +1,35 @@
 package tui
 
 import (
+	"context"
+	"lspath/internal/fix"
+	"lspath/internal/help"
 	"lspath/internal/model"
-	"strings"
+	"lspath/internal/trace"
+	"time"
 
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
-
-	_ "embed"
 )
 
-//go:embed help.md
-var helpContent string
-
 // AppModel holds the TUI state.
 type AppModel struct {
 	// Data
-	TraceResult model.AnalysisResult
-	Loading     bool
-	Err         error
+	TraceResult  model.AnalysisResult
+	Variable     string                // PATH-like variable being analyzed (--var)
+	TraceOptions trace.Options         // Settings for each trace (--var, --duplicates, ...)
+	Preloaded    *model.AnalysisResult // Shown instead of tracing, e.g. from a bundle
+	Loading      bool
+	TraceStage   string        // Progress message shown while Loading
+	Retracing    bool          // Re-tracing ('r', or a watched file changed) while showing the last result
+	RetraceError string        // Why the last re-trace failed, until the next key
+	Spinner      spinner.Model // Spins in the footer while Retracing
+	Err          error
 
 	// UI State
-	SelectedIdx     int
+	SelectedIdx     int // Index into ListRows
 	FlowSelectedIdx int // Index of selected flow node in Flow Mode
 	WindowSize      tea.WindowSizeMsg
 
@@ -36,23 +43,69 @@ type AppModel struct {
 	InputMode       bool
 	InputBuffer     textinput.Model
 	FilteredIndices []int          // Indices of PathEntries to show
+	listBase        []int          // Indices FilteredIndices was filtered from: all, or the search's matches
 	SearchMatches   map[int]string // Map of PathEntry Index -> Matched Filename
+	SearchPositions map[int][]int  // Map of PathEntry Index -> rune positions of the term in its match
+	SearchMode      int            // One of the Search* constants (Tab while typing)
+	GlobalSearch    bool           // The search input lists every match in the results pane ('/')
 	SearchActive    bool
+	ShellResolution *MsgShellResolution // The shell's own answer for the search term, once known
+
+	// List Order State
+	ListRows  []listRow       // FilteredIndices as shown: sorted, and grouped under headers
+	SortMode  int             // One of the Sort* constants ('s')
+	Grouped   bool            // Group entries under their source file ('z')
+	Collapsed map[string]bool // Source files whose group is collapsed (Enter on its header)
+
+	// Filter State (keys 1-5)
+	Filter     int    // One of the Filter* constants
+	FilterFile string // Source file FilterSource shows
 
 	// Flow Preview State
-	RightPanelFocus int // 0 = Flow List, 1 = File Preview
+	RightPanelFocus int // FocusFlowList, FocusFilePreview or FocusPathList
 	PreviewContent  string
 	PreviewScrollY  int
 	PreviewPath     string
+	PreviewLine     int            // Line that added the selected PATH entry, highlighted in the preview (0 if none)
 	ScrollPositions map[string]int // Map of file path -> scroll position
 
 	// Components
 	DetailsViewport  viewport.Model
 	DirectoryListing string
+	ListingIdx       int               // PathEntries index DirectoryListing belongs to (-1 if none)
+	LineContext      model.LineContext // Source line context for ListingIdx
+	FoundBinary      *binaryInfo       // Searched-for binary in ListingIdx, if any
 	DetailsScrollY   int
 	NormalRightFocus bool
-	FileCount        int
-	DirCount         int
+	DirStats         trace.DirStats     // Counts, sizes and kinds of file in ListingIdx
+	BinaryIndex      *trace.BinaryIndex // Executables per entry, built once per trace
+	RemovalImpact    []string           // Binaries that stop resolving if the selected entry is removed
+
+	// Deep Scan State
+	Scanning     bool
+	ScanDone     int
+	ScanTotal    int
+	ScanBudget   time.Duration
+	scanCancel   context.CancelFunc
+	scanProgress chan MsgScanProgress
+	scanGen      int // Number of the current scan, in its messages
+
+	// Watch Mode State
+	Watch        bool   // Re-trace when a config file changes (--watch)
+	WatchStatus  string // Shown in the footer while watching
+	CopyStatus   string // Result of the last 'y'/'Y' copy, until the next key
+	LayoutStatus string // Failure to save the layout, until the next key
+	watchCancel  context.CancelFunc
+
+	// Layout State ('[', ']', '\\')
+	Layout     Layout
+	LongPaths  int // How the selected row shows a long path: one of the LongPaths* constants ('L')
+	RowScrollX int // Cells the selected row is scrolled by in LongPathsScroll
+
+	// Tour State (--tour)
+	Tour      bool       // Walk through the results once they are ready; cleared when the tour ends
+	TourSteps []tourStep // Built from the first trace
+	TourStep  int
 
 	// Help State
 	ShowHelp    bool
@@ -64,11 +117,36 @@ type AppModel struct {
 	DiagnosticsScrollY   int
 	DiagnosticsReport    string
 	DiagnosticsVerbose   bool
+	ReportStatus         string // Result of the last 's' save
+
+	// Fix Popup State ('x')
+	ShowFixPopup bool
+	FixScrollY   int
+	FixText      string       // Rendered plan (notes and diffs)
+	FixChanges   []fix.Change // Changes applied on 'y'
+	FixStatus    string
+	FixApplied   bool
+
+	// Executables Explorer State ('e')
+	ShowExplorer    bool
+	ExplorerEntry   int                     // PathEntries index being explored
+	ExplorerRows    []trace.EntryExecutable // Its executables and which entry wins each
+	ExplorerSel     int
+	ExplorerScrollY int
+
+	// Search Results State ('/')
+	ShowResults    bool
+	ResultsTerm    string
+	ResultsLoading bool
+	ResultHits     []searchHit
+	ResultSel      int
+	ResultScrollY  int
 }
 
 const (
 	FocusFlowList    = 0
 	FocusFilePreview = 1
+	FocusPathList    = 2 // The PATH list, its entries shown in the preview
 )
 
 // InitialModel returns the initial state.
@@ -82,7 +160,12 @@ func InitialModel() AppModel {
 		Loading:         true,
 		InputBuffer:     ti,
 		SelectedIdx:     0,
+		ListingIdx:      -1,
 		ScrollPositions: make(map[string]int),
-		HelpContent:     strings.ReplaceAll(helpContent, "{{VERSION}}", model.Version),
+		ScanBudget:      trace.DefaultScanBudget,
+		Variable:        trace.DefaultVariable,
+		HelpContent:     help.Text(help.TUI),
+		Layout:          LoadLayout(LayoutFile()),
+		Spinner:         spinner.New(spinner.WithSpinner(spinner.Dot)),
 	}
 }
@@ -0,0 +1,215 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Theme is the TUI's palette. Colors are lipgloss colors: an ANSI 256-color
+// number ("205") or hex ("#7D56F4"); lipgloss.NoColor{} leaves the
+// terminal's own.
+type Theme struct {
+	Name       string
+	Accent     lipgloss.TerminalColor // Panel titles, the focused panel's border, the selection in a focused list
+	Title      lipgloss.TerminalColor // Dialog titles
+	TitleBg    lipgloss.TerminalColor // Behind dialog titles
+	Border     lipgloss.TerminalColor // Unfocused panels and the help dialog
+	Text       lipgloss.TerminalColor // List rows
+	Muted      lipgloss.TerminalColor // Entries that are not selected, inactive headers
+	Faint      lipgloss.TerminalColor // Line numbers, startup files that did not run
+	Advice     lipgloss.TerminalColor // Warnings, advice and dialog borders
+	Highlight  lipgloss.TerminalColor // The line that added the selected entry
+	Selected   lipgloss.TerminalColor // The selected row
+	SelectedBg lipgloss.TerminalColor // Behind the selected row
+	Added      lipgloss.TerminalColor // Lines a fix adds
+	Removed    lipgloss.TerminalColor // Lines a fix removes
+	Footer     lipgloss.TerminalColor // Key help in dialogs
+}
+
+// Themes are the built-in themes, chosen with --theme or the theme file's
+// "theme" line. "none" disables color, showing the selection in reverse video.
+var Themes = map[string]Theme{
+	"dark": {
+		Accent: lipgloss.Color("205"), Title: lipgloss.Color("#FAFAFA"), TitleBg: lipgloss.Color("#7D56F4"),
+		Border: lipgloss.Color("63"), Text: lipgloss.Color("255"), Muted: lipgloss.Color("240"), Faint: lipgloss.Color("238"),
+		Advice: lipgloss.Color("208"), Highlight: lipgloss.Color("81"), Selected: lipgloss.Color("229"), SelectedBg: lipgloss.Color("57"),
+		Added: lipgloss.Color("42"), Removed: lipgloss.Color("196"), Footer: lipgloss.Color("241"),
+	},
+	"light": {
+		Accent: lipgloss.Color("162"), Title: lipgloss.Color("#FFFFFF"), TitleBg: lipgloss.Color("#5A3FC0"),
+		Border: lipgloss.Color("61"), Text: lipgloss.Color("235"), Muted: lipgloss.Color("243"), Faint: lipgloss.Color("247"),
+		Advice: lipgloss.Color("166"), Highlight: lipgloss.Color("25"), Selected: lipgloss.Color("231"), SelectedBg: lipgloss.Color("61"),
+		Added: lipgloss.Color("28"), Removed: lipgloss.Color("160"), Footer: lipgloss.Color("243"),
+	},
+	"high-contrast": {
+		Accent: lipgloss.Color("11"), Title: lipgloss.Color("0"), TitleBg: lipgloss.Color("15"),
+		Border: lipgloss.Color("15"), Text: lipgloss.Color("15"), Muted: lipgloss.Color("7"), Faint: lipgloss.Color("7"),
+		Advice: lipgloss.Color("11"), Highlight: lipgloss.Color("14"), Selected: lipgloss.Color("0"), SelectedBg: lipgloss.Color("11"),
+		Added: lipgloss.Color("10"), Removed: lipgloss.Color("9"), Footer: lipgloss.Color("7"),
+	},
+	"none": {
+		Accent: lipgloss.NoColor{}, Title: lipgloss.NoColor{}, TitleBg: lipgloss.NoColor{},
+		Border: lipgloss.NoColor{}, Text: lipgloss.NoColor{}, Muted: lipgloss.NoColor{}, Faint: lipgloss.NoColor{},
+		Advice: lipgloss.NoColor{}, Highlight: lipgloss.NoColor{}, Selected: lipgloss.NoColor{}, SelectedBg: lipgloss.NoColor{},
+		Added: lipgloss.NoColor{}, Removed: lipgloss.NoColor{}, Footer: lipgloss.NoColor{},
+	},
+}
+
+// DefaultTheme is used when neither --theme nor the theme file picks one.
+const DefaultTheme = "dark"
+
+// themeColors maps the theme file's color names to their Theme fields.
+var themeColors = map[string]func(*Theme) *lipgloss.TerminalColor{
+	"accent":              func(t *Theme) *lipgloss.TerminalColor { return &t.Accent },
+	"title":               func(t *Theme) *lipgloss.TerminalColor { return &t.Title },
+	"title-background":    func(t *Theme) *lipgloss.TerminalColor { return &t.TitleBg },
+	"border":              func(t *Theme) *lipgloss.TerminalColor { return &t.Border },
+	"text":                func(t *Theme) *lipgloss.TerminalColor { return &t.Text },
+	"muted":               func(t *Theme) *lipgloss.TerminalColor { return &t.Muted },
+	"faint":               func(t *Theme) *lipgloss.TerminalColor { return &t.Faint },
+	"advice":              func(t *Theme) *lipgloss.TerminalColor { return &t.Advice },
+	"highlight":           func(t *Theme) *lipgloss.TerminalColor { return &t.Highlight },
+	"selected":            func(t *Theme) *lipgloss.TerminalColor { return &t.Selected },
+	"selected-background": func(t *Theme) *lipgloss.TerminalColor { return &t.SelectedBg },
+	"added":               func(t *Theme) *lipgloss.TerminalColor { return &t.Added },
+	"removed":             func(t *Theme) *lipgloss.TerminalColor { return &t.Removed },
+	"footer":              func(t *Theme) *lipgloss.TerminalColor { return &t.Footer },
+}
+
+// colorPattern matches the colors a theme file may set.
+var colorPattern = regexp.MustCompile(`^(#[0-9A-Fa-f]{6}|#[0-9A-Fa-f]{3}|[0-9]{1,3}|none)$`)
+
+// ThemeNames lists the built-in themes, for help and errors.
+func ThemeNames() []string {
+	var names []string
+	for name := range Themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ThemeFile returns the user's theme settings, e.g.
+// ~/.config/lspath/theme.
+func ThemeFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "lspath", "theme")
+}
+
+// LoadTheme returns the theme named name (from --theme), or else the one
+// file's "theme" line picks, or DefaultTheme, with the colors file sets on
+// top. Each line of file is a name and a value, e.g. "theme light" or
+// "accent #d7005f"; blank lines and # comments are ignored. A missing file
+// sets nothing, and the "none" theme ignores its colors.
+func LoadTheme(name, file string) (Theme, error) {
+	base := ""
+	overrides := make(map[string]string)
+	var order []string
+	if f, err := os.Open(file); err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for n := 1; scanner.Scan(); n++ {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			key, value, _ := strings.Cut(line, " ")
+			value = strings.TrimSpace(value)
+			switch _, isColor := themeColors[key]; {
+			case key == "theme":
+				base = value
+			case !isColor:
+				return Theme{}, fmt.Errorf("line %d of %s: unknown setting %q", n, file, key)
+			case !colorPattern.MatchString(value):
+				return Theme{}, fmt.Errorf("line %d of %s: %q is not a color (use a number from 0 to 255, #rrggbb or none)", n, file, value)
+			default:
+				if _, seen := overrides[key]; !seen {
+					order = append(order, key)
+				}
+				overrides[key] = value
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return Theme{}, err
+		}
+	} else if !os.IsNotExist(err) {
+		return Theme{}, err
+	}
+
+	if name == "" {
+		name = base
+	}
+	if name == "" {
+		name = DefaultTheme
+	}
+	t, ok := Themes[name]
+	if !ok {
+		return Theme{}, fmt.Errorf("unknown theme %q (use %s)", name, strings.Join(ThemeNames(), ", "))
+	}
+	t.Name = name
+	if name == "none" {
+		return t, nil
+	}
+	for _, key := range order {
+		var c lipgloss.TerminalColor = lipgloss.Color(overrides[key])
+		if overrides[key] == "none" {
+			c = lipgloss.NoColor{}
+		}
+		*themeColors[key](&t) = c
+	}
+	return t, nil
+}
+
+// theme is the palette View draws with; see ApplyTheme.
+var theme = Themes[DefaultTheme]
+
+func init() {
+	ApplyTheme(Themes[DefaultTheme])
+}
+
+// ApplyTheme makes the TUI draw with t from now on.
+func ApplyTheme(t Theme) {
+	theme = t
+	titleStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(t.Title).
+		Background(t.TitleBg).
+		Padding(0, 1)
+	selectedItemStyle = lipgloss.NewStyle().
+		PaddingLeft(2).
+		Foreground(t.Accent)
+	unselectedItemStyle = lipgloss.NewStyle().
+		PaddingLeft(4).
+		Foreground(t.Muted)
+	dimStyle = lipgloss.NewStyle().
+		Foreground(t.Faint)
+	detailStyle = lipgloss.NewStyle().
+		Padding(1, 2).
+		Border(lipgloss.NormalBorder()).
+		BorderForeground(t.Border)
+	adviceStyle = lipgloss.NewStyle().
+		Foreground(t.Advice)
+	pathHighlightStyle = lipgloss.NewStyle().
+		Foreground(t.Highlight).
+		Bold(true)
+}
+
+// selectedRowStyle draws the selected row of a list. Without a background
+// color it falls back to reverse video, so the selection stays visible.
+func selectedRowStyle() lipgloss.Style {
+	style := lipgloss.NewStyle().Foreground(theme.Selected).Background(theme.SelectedBg)
+	if _, none := theme.SelectedBg.(lipgloss.NoColor); none {
+		style = style.Reverse(true)
+	}
+	return style
+}
@@ -0,0 +1,179 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"lspath/internal/model"
+	"lspath/internal/trace"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// tourStep is one stop of the --tour walkthrough: a panel to show and an
+// explanation written from the user's own results.
+type tourStep struct {
+	Title string
+	Text  string
+	Entry int // PathEntries index to select in the PATH list, or -1
+	Flow  int // FlowNodes index to select in Flow Mode, or -1
+}
+
+// buildTour writes the tour for res. Steps about duplicates, missing
+// directories, shadowing and session entries only appear if res has them.
+func buildTour(res model.AnalysisResult) []tourStep {
+	name := res.VariableName()
+	entries := res.PathEntries
+	var steps []tourStep
+	add := func(title string, entry, flow int, format string, args ...any) {
+		steps = append(steps, tourStep{Title: title, Text: fmt.Sprintf(format, args...), Entry: entry, Flow: flow})
+	}
+	if len(entries) == 0 {
+		add("An empty "+name, -1, -1, "%s is empty here, so there is nothing to walk through. Set it in a startup file and run lspath --tour again.", name)
+		return steps
+	}
+	source := func(e model.PathEntry) string {
+		if e.IsSessionOnly {
+			return "this terminal session (no startup file adds it)"
+		}
+		if e.LineNumber == 0 {
+			return e.SourceFile
+		}
+		return fmt.Sprintf("line %d of %s", e.LineNumber, e.SourceFile)
+	}
+
+	add("Your "+name, 0, -1,
+		"The left panel is your %s: %d directories. When you type a command, the shell looks through them from the top down and runs the first match it finds. Nothing after that match is even looked at.",
+		name, len(entries))
+
+	first, last := entries[0], entries[len(entries)-1]
+	add("Priority", 0, -1,
+		"Entry #1, %s, has the highest priority: a command in it beats one of the same name anywhere else. It was added by %s. The last entry, #%d %s, only gets a turn when nothing earlier matches. "+
+			"That is why tools prepend their directories (PATH=\"new:$PATH\") to win, and append them (PATH=\"$PATH:new\") to stay out of the way.",
+		model.DisplayPath(first.Value), source(first), len(entries), model.DisplayPath(last.Value))
+
+	add("The details panel", 0, -1,
+		"The right panel describes the selected entry: the startup file line that added it, what kind of files it holds, and any problems. Use ↑/↓ outside the tour to look at each entry, and Tab to scroll the details.")
+
+	if len(res.Shadows) > 0 {
+		s := res.Shadows[0]
+		var losers []string
+		for _, l := range s.Losers {
+			losers = append(losers, fmt.Sprintf("#%d %s", l+1, model.DisplayPath(entries[l].Value)))
+		}
+		add("Shadowing", s.Winner, -1,
+			"%d command names are in more than one directory. For example, %s is in #%d %s and also in %s. Typing %s runs the copy in #%d; the others are shadowed, which is how a Homebrew or pyenv python takes over from the system one.",
+			len(res.Shadows), s.Name, s.Winner+1, model.DisplayPath(entries[s.Winner].Value), strings.Join(losers, ", "), s.Name, s.Winner+1)
+	}
+
+	for i, e := range entries {
+		if !e.IsDuplicate {
+			continue
+		}
+		orig := entries[e.DuplicateOf]
+		why := fmt.Sprintf("It was added again by %s, after %s had already added it.", source(e), source(orig))
+		switch {
+		case e.IsSessionOnly || e.SourceFile != orig.SourceFile:
+		case e.LineNumber > 0 && e.LineNumber == orig.LineNumber:
+			why = fmt.Sprintf("Both copies come from %s: the line ran twice, usually because the file is read again by a nested shell or sourced twice.", source(e))
+		case e.LineNumber == orig.LineNumber:
+			why = fmt.Sprintf("Both copies come from %s.", source(e))
+		}
+		add("A duplicate", i, -1,
+			"#%d %s is a duplicate of #%d. The shell never gets to the second copy, because anything it holds was found in the first. %s Press x after the tour to see a fix.",
+			i+1, model.DisplayPath(e.Value), e.DuplicateOf+1, why)
+		break
+	}
+
+	for i, e := range entries {
+		missing := false
+		for _, d := range e.Diagnostics {
+			missing = missing || strings.Contains(d, "does not exist")
+		}
+		if !missing {
+			continue
+		}
+		add("A missing directory", i, -1,
+			"#%d %s does not exist. It costs a failed lookup for every command not found earlier, and if it is created later (say, by an installer), its contents will start winning over everything below it. It was added by %s.",
+			i+1, model.DisplayPath(e.Value), source(e))
+		break
+	}
+
+	for i, e := range entries {
+		if !e.IsSessionOnly {
+			continue
+		}
+		add("Session entries", i, -1,
+			"Entries marked %s, such as #%d %s, are in this terminal's %s but no startup file adds them: an activated virtualenv, a manual export, or the program that opened the terminal. A new terminal will not have them.",
+			model.IconSession, i+1, model.DisplayPath(e.Value), name)
+		break
+	}
+
+	if len(res.FlowNodes) > 0 {
+		login := "an INTERACTIVE (non-login) shell, which reads the rc files (~/.zshrc, ~/.bashrc) each time it starts"
+		if trace.IsLoginShell(res.FlowNodes) {
+			login = "a LOGIN shell, which reads the profile files (~/.zprofile, ~/.bash_profile, ~/.profile) once and then the rc files. macOS terminals do this for every new window"
+		}
+		add("Login and interactive shells", -1, 0,
+			"This is Flow Mode (f): the startup files your shell ran, in order. Your terminal starts %s. Which kind you get decides which files run, and so where an export has to go to take effect.",
+			login)
+
+		busiest := 0
+		for i, n := range res.FlowNodes {
+			if len(n.Entries) > len(res.FlowNodes[busiest].Entries) {
+				busiest = i
+			}
+		}
+		n := res.FlowNodes[busiest]
+		add("Following the flow", -1, busiest,
+			"%s ran as step %d and added %d of your entries; they are highlighted on the left. Step through the files with ↑/↓ to see each one's contribution, and press c for the %s as it stood after each step.",
+			n.FilePath, n.Order, len(n.Entries), name)
+	}
+
+	add("Where next", -1, -1,
+		"That's the tour. Press d for the full diagnostics report, x to fix duplicate lines, w to find which directory a command comes from, and ? for help at any time.")
+	return steps
+}
+
+// showTourStep puts the panels in the state the current tour step explains.
+func (m *AppModel) showTourStep() tea.Cmd {
+	step := m.TourSteps[m.TourStep]
+	m.NormalRightFocus = false
+	m.DetailsScrollY = 0
+	if step.Flow >= 0 && step.Flow < len(m.TraceResult.FlowNodes) {
+		m.ShowFlow = true
+		m.CumulativeFlow = false
+		m.RightPanelFocus = FocusFlowList
+		m.FlowSelectedIdx = step.Flow
+		return m.loadSelectedFile()
+	}
+	m.ShowFlow = false
+	if step.Entry < 0 {
+		return nil
+	}
+	for i, r := range m.ListRows {
+		if r.Entry == step.Entry {
+			m.SelectedIdx = i
+			return m.loadDirectoryListing()
+		}
+	}
+	return nil
+}
+
+// renderTour draws the current tour step as a box of the given width, shown
+// below the panels in place of the key help.
+func (m AppModel) renderTour(width int) string {
+	step := m.TourSteps[m.TourStep]
+	title := titleStyle.Render(fmt.Sprintf("Tour %d/%d · %s", m.TourStep+1, len(m.TourSteps), step.Title))
+	keys := helpLine(item("next", keymap.TourNext), item("back", keymap.TourBack), item("end the tour", keymap.TourEnd))
+	if m.TourStep == len(m.TourSteps)-1 {
+		keys = helpLine(item("end the tour", keymap.TourNext, keymap.TourEnd), item("back", keymap.TourBack))
+	}
+	return lipgloss.NewStyle().
+		Width(width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(theme.Accent).
+		Padding(0, 1).
+		Render(title + "\n" + step.Text + "\n" + dimStyle.Render(keys))
+}
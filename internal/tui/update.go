@@ -1,43 +1,131 @@
 package tui
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"os"
-	"path/filepath"
+	"slices"
 	"strings"
-	"text/tabwriter"
-	"time"
 
+	"lspath/internal/fix"
 	"lspath/internal/model"
 	"lspath/internal/trace"
 
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
-// expandTilde expands ~ to the user's home directory
-func expandTilde(path string) string {
-	if strings.HasPrefix(path, "~/") {
-		home, err := os.UserHomeDir()
-		if err == nil {
-			return filepath.Join(home, path[2:])
-		}
-	} else if path == "~" {
-		home, err := os.UserHomeDir()
-		if err == nil {
-			return home
-		}
-	}
-	return path
-}
-
 // MsgTraceReady indicates that the trace has completed.
 type MsgTraceReady model.AnalysisResult
 
 // MsgError indicates an error occurred.
 type MsgError error
 
+// MsgScanProgress reports deep-scan progress over the PATH directories.
+type MsgScanProgress struct {
+	Gen         int // The scan it comes from; see startScan
+	Done, Total int
+}
+
+// MsgScanDone delivers the (possibly partial) binary index.
+type MsgScanDone struct {
+	Gen   int
+	Index *trace.BinaryIndex
+}
+
+// MsgTraceProgress reports the stage the startup trace has reached.
+type MsgTraceProgress struct {
+	Stage string
+	next  chan MsgTraceProgress
+}
+
+// MsgConfigChanged reports that a traced config file changed on disk
+// (watch mode only).
+type MsgConfigChanged struct {
+	File string
+	Err  error
+}
+
+// MsgDirListing delivers the directory listing and source context for the
+// PATH entry at Index.
+type MsgDirListing struct {
+	Index       int
+	Listing     string
+	Stats       trace.DirStats
+	LineContext model.LineContext
+	Binary      *binaryInfo // Searched-for binary in this entry, if any
+}
+
+// MsgSearchResult delivers the entries containing a binary matching Term.
+type MsgSearchResult struct {
+	Term      string
+	Mode      int // One of the Search* constants
+	Indices   []int
+	Matches   map[int]string // PathEntry index -> matched filename
+	Positions map[int][]int  // PathEntry index -> rune positions of Term in the filename
+}
+
+// MsgGlobalSearch delivers every executable matching Term ('/').
+type MsgGlobalSearch struct {
+	Term string
+	Mode int // One of the Search* constants
+	Hits []searchHit
+}
+
+// MsgShellResolution delivers what the interactive shell runs for a searched
+// command name, and how that differs from the analyzed PATH.
+type MsgShellResolution struct {
+	Term       string
+	Resolution trace.ShellResolution
+	Mismatch   string
+	Wrapper    *trace.Wrapper // What the PATH winner runs, if it is a wrapper or shim
+	Err        error
+}
+
+// MsgFileLoaded delivers a config file's contents for the flow preview.
+type MsgFileLoaded struct {
+	Path    string
+	Content string
+}
+
+// MsgReport delivers the generated diagnostics report.
+type MsgReport struct {
+	Verbose bool
+	Text    string
+}
+
+// MsgFixPlan delivers the proposed duplicate-line removals.
+type MsgFixPlan struct {
+	Changes []fix.Change
+	Notes   []string
+	Err     error
+}
+
+// MsgFixApplied reports the outcome of applying the fix plan.
+type MsgFixApplied struct {
+	Backups []string
+	Err     error
+}
+
+// MsgCopied reports the outcome of copying to the clipboard.
+type MsgCopied struct {
+	What  string // Description for the footer, e.g. "line 12 of ~/.zshrc"
+	Err   error
+	osc52 string // Text still to copy with an OSC 52 sequence; see copyToClipboard
+}
+
+// MsgLayoutSaved reports the outcome of saving the pane layout.
+type MsgLayoutSaved struct {
+	Err error
+}
+
+// MsgReportSaved reports the outcome of saving the diagnostics report.
+type MsgReportSaved struct {
+	File string
+	Err  error
+}
+
 // Update handles events.
 func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
@@ -50,23 +138,181 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case MsgTraceReady:
+		refresh := !m.Loading // Re-traced ('r' or watch mode); keep the user's place
 		m.Loading = false
+		m.Retracing = false
+		m.TraceStage = ""
 		m.TraceResult = model.AnalysisResult(msg)
 		// Generate global report
-		m.DiagnosticsReport = trace.GenerateReport(m.TraceResult, m.DiagnosticsVerbose)
+		m.DiagnosticsReport = "Generating report…"
+		reportCmd := generateReportCmd(m.TraceResult, m.DiagnosticsVerbose)
+		m.BinaryIndex = nil
+		m.ShowExplorer = false
+
+		// Show every entry the filter keeps
+		m.setListEntries(m.allEntries())
+		if refresh && m.SearchActive {
+			cmd = m.performSearch()
+		} else if len(m.ListRows) > 0 {
+			if refresh {
+				m.clampSelection()
+			} else {
+				m.SelectedIdx = 0
+			}
+			cmd = m.loadDirectoryListing()
+		}
+		if refresh && m.ShowFlow {
+			if m.FlowSelectedIdx >= len(m.TraceResult.FlowNodes) {
+				m.FlowSelectedIdx = len(m.TraceResult.FlowNodes) - 1
+			}
+			m.PreviewPath = "" // Force a re-read of the edited file
+			cmd = tea.Batch(cmd, m.loadSelectedFile())
+		}
+		if m.Tour && m.TourSteps == nil {
+			m.TourSteps = buildTour(m.TraceResult)
+			m.TourStep = 0
+			cmd = tea.Batch(cmd, m.showTourStep())
+		}
+		return m, tea.Batch(cmd, reportCmd, m.startScan(), m.startWatch())
 
-		// Auto-populate filtered indices with all
-		m.FilteredIndices = make([]int, len(m.TraceResult.PathEntries))
-		for i := range m.TraceResult.PathEntries {
-			m.FilteredIndices[i] = i
+	case MsgConfigChanged:
+		if msg.Err != nil {
+			m.WatchStatus = fmt.Sprintf("Watch stopped: %v", msg.Err)
+			return m, nil
 		}
-		if len(m.FilteredIndices) > 0 {
-			m.SelectedIdx = 0
-			m.loadDirectoryListing()
+		m.WatchStatus = fmt.Sprintf("%s changed; re-tracing…", msg.File)
+		return m, m.retrace()
+
+	case MsgTraceProgress:
+		m.TraceStage = msg.Stage
+		return m, waitForTrace(msg.next)
+
+	case MsgDirListing:
+		// Ignore listings for an entry that is no longer selected
+		if idx, ok := m.selectedEntry(); !ok || idx != msg.Index {
+			return m, nil
+		}
+		m.ListingIdx = msg.Index
+		m.DirectoryListing = msg.Listing
+		m.DirStats = msg.Stats
+		m.LineContext = msg.LineContext
+		m.FoundBinary = msg.Binary
+		m.DetailsScrollY = 0 // Reset scroll position when loading new directory
+		return m, nil
+
+	case MsgSearchResult:
+		// Ignore results for a search term that has since changed
+		if !m.SearchActive || msg.Term != strings.ToLower(m.InputBuffer.Value()) || msg.Mode != m.SearchMode {
+			return m, nil
+		}
+		m.SearchMatches = msg.Matches
+		m.SearchPositions = msg.Positions
+		m.setListEntries(msg.Indices)
+		return m, m.loadDirectoryListing()
+
+	case MsgGlobalSearch:
+		if msg.Term != m.ResultsTerm || msg.Mode != m.SearchMode {
+			return m, nil // A search that has since been replaced
+		}
+		m.ResultsLoading = false
+		m.ResultHits = msg.Hits
+		m.clampResults()
+		return m, nil
+
+	case MsgShellResolution:
+		if m.SearchActive && msg.Term == strings.ToLower(m.InputBuffer.Value()) {
+			m.ShellResolution = &msg
+		}
+		return m, nil
+
+	case MsgFileLoaded:
+		if msg.Path == m.PreviewPath {
+			m.PreviewContent = msg.Content
+		}
+		return m, nil
+
+	case MsgReport:
+		if msg.Verbose == m.DiagnosticsVerbose {
+			m.DiagnosticsReport = msg.Text
+		}
+		return m, nil
+
+	case MsgFixPlan:
+		m.FixChanges = msg.Changes
+		m.FixText = formatFixPlan(msg)
+		return m, nil
+
+	case MsgFixApplied:
+		if msg.Err != nil {
+			m.FixStatus = fmt.Sprintf("Failed: %v", msg.Err)
+		} else {
+			m.FixApplied = true
+			m.FixStatus = fmt.Sprintf("Applied (backups: %s). Open a new terminal to pick up the changes.", strings.Join(msg.Backups, ", "))
+		}
+		return m, nil
+
+	case MsgCopied:
+		if msg.osc52 != "" {
+			return m, copyOSC52Cmd(msg.What, msg.osc52)
+		}
+		if msg.Err != nil {
+			m.CopyStatus = fmt.Sprintf("Copy failed: %v", msg.Err)
+		} else {
+			m.CopyStatus = "Copied " + msg.What
 		}
 		return m, nil
 
+	case MsgLayoutSaved:
+		if msg.Err != nil {
+			m.LayoutStatus = fmt.Sprintf("Saving the layout failed: %v", msg.Err)
+		}
+		return m, nil
+
+	case MsgReportSaved:
+		if msg.Err != nil {
+			m.ReportStatus = fmt.Sprintf("Save failed: %v", msg.Err)
+		} else {
+			m.ReportStatus = "Saved to " + msg.File
+		}
+		return m, nil
+
+	case MsgScanProgress:
+		if msg.Gen != m.scanGen {
+			return m, nil // A scan that has since been replaced
+		}
+		m.ScanDone, m.ScanTotal = msg.Done, msg.Total
+		return m, waitForScan(m.scanProgress)
+
+	case MsgScanDone:
+		if msg.Gen != m.scanGen {
+			return m, nil // A scan that has since been replaced
+		}
+		m.Scanning = false
+		m.scanCancel = nil
+		m.BinaryIndex = msg.Index
+		if idx, ok := m.selectedEntry(); ok {
+			m.RemovalImpact = m.BinaryIndex.RemovalImpact(idx)
+		}
+		if m.ShowExplorer {
+			m.refreshExplorer()
+		}
+		return m, nil
+
+	case spinner.TickMsg:
+		if !m.Loading && !m.Retracing {
+			return m, nil // Let the spinner stop
+		}
+		m.Spinner, cmd = m.Spinner.Update(msg)
+		return m, cmd
+
 	case MsgError:
+		if m.Retracing {
+			// Keep showing the last result
+			m.Retracing = false
+			m.TraceStage = ""
+			m.RetraceError = fmt.Sprintf("Re-trace failed: %v", msg)
+			return m, nil
+		}
 		m.Err = msg
 		m.Loading = false
 		return m, nil
@@ -79,48 +325,81 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				// Just exit input mode? Or keep it?
 				// For now, exit input mode but keep search active state.
 				m.InputMode = false
-				m.performSearch()
+				if m.GlobalSearch {
+					return m, m.startGlobalSearch()
+				}
+				cmd = m.performSearch()
+				if m.SearchActive {
+					// Ask the shell too, once the name is complete
+					cmd = tea.Batch(cmd, shellResolveCmd(strings.ToLower(m.InputBuffer.Value()), m.TraceResult))
+				}
+				return m, cmd
+			case tea.KeyTab:
+				// Cycle prefix, fuzzy and exact matching
+				m.SearchMode = (m.SearchMode + 1) % searchModes
 				return m, nil
-			case tea.KeyEsc:
+			}
+			if key.Matches(msg, keymap.Back) {
 				// Exit search mode and clear search
 				m.InputMode = false
+				m.GlobalSearch = false
 				m.InputBuffer.Blur()
 				m.SearchActive = false // Disable search
 				m.InputBuffer.SetValue("")
-				m.performSearch() // Reset filter to all
-				return m, nil
+				cmd = m.performSearch() // Reset filter to all
+				return m, cmd
 			}
 			m.InputBuffer, cmd = m.InputBuffer.Update(msg)
 			return m, cmd
 		}
 
+		if m.Tour && m.TourSteps != nil {
+			next := key.Matches(msg, keymap.TourNext)
+			switch {
+			case next && m.TourStep < len(m.TourSteps)-1:
+				m.TourStep++
+				return m, m.showTourStep()
+			case next, key.Matches(msg, keymap.TourEnd):
+				m.Tour, m.TourSteps = false, nil
+				m.ShowFlow = false
+			case key.Matches(msg, keymap.TourBack):
+				if m.TourStep > 0 {
+					m.TourStep--
+					return m, m.showTourStep()
+				}
+			case msg.String() == "ctrl+c":
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+
 		if m.ShowHelp {
-			switch msg.String() {
-			case "?", "h", "esc", "q":
+			switch {
+			case key.Matches(msg, keymap.Help, keymap.Close):
 				m.ShowHelp = false
 				return m, nil
-			case "up", "k":
+			case key.Matches(msg, keymap.Up):
 				if m.HelpScrollY > 0 {
 					m.HelpScrollY--
 				}
-			case "down", "j":
+			case key.Matches(msg, keymap.Down):
 				m.HelpScrollY++
-			case "pgdown", "ctrl+d", "ctrl+f", " ":
+			case key.Matches(msg, keymap.PageDown):
 				m.HelpScrollY += 10
-			case "pgup", "ctrl+u", "ctrl+b", "b":
+			case key.Matches(msg, keymap.PageUp):
 				if m.HelpScrollY > 10 {
 					m.HelpScrollY -= 10
 				} else {
 					m.HelpScrollY = 0
 				}
-			case "home", "g":
+			case key.Matches(msg, keymap.Top):
 				m.HelpScrollY = 0
-			case "end", "G":
+			case key.Matches(msg, keymap.Bottom):
 				m.HelpScrollY = 1000 // Just a high number, we cap below
 			}
 
 			// Robust Capping for Help
-			helpLines := strings.Split(m.HelpContent, "\n")
+			helpLines := m.helpLines()
 			maxHelpScroll := len(helpLines) - (m.WindowSize.Height - 8)
 			if maxHelpScroll < 0 {
 				maxHelpScroll = 0
@@ -135,36 +414,133 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
+		if m.ShowFixPopup {
+			switch {
+			case key.Matches(msg, keymap.Fix, keymap.Close, keymap.Cancel):
+				m.ShowFixPopup = false
+				return m, nil
+			case key.Matches(msg, keymap.Apply):
+				if len(m.FixChanges) > 0 && !m.FixApplied {
+					m.FixStatus = "Applying…"
+					cmd = applyFixCmd(m.FixChanges)
+				}
+			case key.Matches(msg, keymap.Up):
+				m.FixScrollY--
+			case key.Matches(msg, keymap.Down):
+				m.FixScrollY++
+			case key.Matches(msg, keymap.PageUp):
+				m.FixScrollY -= 10
+			case key.Matches(msg, keymap.PageDown):
+				m.FixScrollY += 10
+			case key.Matches(msg, keymap.Top):
+				m.FixScrollY = 0
+			case key.Matches(msg, keymap.Bottom):
+				m.FixScrollY = 1000 // High number, capped below
+			}
+
+			maxFixScroll := len(strings.Split(m.FixText, "\n")) - (m.WindowSize.Height - 10)
+			if m.FixScrollY > maxFixScroll {
+				m.FixScrollY = maxFixScroll
+			}
+			if m.FixScrollY < 0 {
+				m.FixScrollY = 0
+			}
+			return m, cmd
+		}
+
+		if m.ShowResults {
+			switch {
+			case key.Matches(msg, keymap.Search, keymap.Close):
+				m.ShowResults = false
+				return m, nil
+			case key.Matches(msg, keymap.Up):
+				m.ResultSel--
+			case key.Matches(msg, keymap.Down):
+				m.ResultSel++
+			case key.Matches(msg, keymap.PageUp):
+				m.ResultSel -= 10
+			case key.Matches(msg, keymap.PageDown):
+				m.ResultSel += 10
+			case key.Matches(msg, keymap.Top):
+				m.ResultSel = 0
+			case key.Matches(msg, keymap.Bottom):
+				m.ResultSel = len(m.ResultHits) - 1
+			case msg.Type == tea.KeyEnter:
+				// Jump to the entry the executable is in
+				if m.ResultSel < len(m.ResultHits) {
+					m.ShowResults = false
+					m.ShowFlow = false
+					m.NormalRightFocus = false
+					return m, m.selectEntry(m.ResultHits[m.ResultSel].Entry)
+				}
+			}
+			m.clampResults()
+			return m, nil
+		}
+
+		if m.ShowExplorer {
+			switch {
+			case key.Matches(msg, keymap.Executables, keymap.Close):
+				m.ShowExplorer = false
+				return m, nil
+			case key.Matches(msg, keymap.Up):
+				m.ExplorerSel--
+			case key.Matches(msg, keymap.Down):
+				m.ExplorerSel++
+			case key.Matches(msg, keymap.PageUp):
+				m.ExplorerSel -= 10
+			case key.Matches(msg, keymap.PageDown):
+				m.ExplorerSel += 10
+			case key.Matches(msg, keymap.Top):
+				m.ExplorerSel = 0
+			case key.Matches(msg, keymap.Bottom):
+				m.ExplorerSel = len(m.ExplorerRows) - 1
+			case msg.Type == tea.KeyEnter:
+				// Jump to the entry that shadows this one's copy, or from a
+				// winner to the first copy it hides
+				if m.ExplorerSel < len(m.ExplorerRows) {
+					x := m.ExplorerRows[m.ExplorerSel]
+					target := x.Winner
+					if target == m.ExplorerEntry && len(x.Hides) > 0 {
+						target = x.Hides[0]
+					}
+					if target != m.ExplorerEntry {
+						cmd = m.openExplorer(target, x.Name)
+					}
+				}
+			}
+			m.clampExplorer()
+			return m, cmd
+		}
+
 		if m.ShowDiagnosticsPopup {
-			switch msg.String() {
-			case "d", "esc", "q":
+			switch {
+			case key.Matches(msg, keymap.Diagnostics, keymap.Close):
 				m.ShowDiagnosticsPopup = false
 				return m, nil
-			case "up", "k":
+			case key.Matches(msg, keymap.Up):
 				if m.DiagnosticsScrollY > 0 {
 					m.DiagnosticsScrollY--
 				}
-			case "down", "j":
+			case key.Matches(msg, keymap.Down):
 				m.DiagnosticsScrollY++
-			case "pgup", "ctrl+u", "ctrl+b", "b":
+			case key.Matches(msg, keymap.PageUp):
 				if m.DiagnosticsScrollY > 10 {
 					m.DiagnosticsScrollY -= 10
 				} else {
 					m.DiagnosticsScrollY = 0
 				}
-			case "pgdown", "ctrl+d", "ctrl+f", " ":
+			case key.Matches(msg, keymap.PageDown):
 				m.DiagnosticsScrollY += 10
-			case "home", "g":
+			case key.Matches(msg, keymap.Top):
 				m.DiagnosticsScrollY = 0
-			case "end", "G":
+			case key.Matches(msg, keymap.Bottom):
 				m.DiagnosticsScrollY = 1000 // High number, capped below
-			case "v":
+			case key.Matches(msg, keymap.Verbose):
 				m.DiagnosticsVerbose = !m.DiagnosticsVerbose
-				m.DiagnosticsReport = trace.GenerateReport(m.TraceResult, m.DiagnosticsVerbose)
-			case "s":
-				timestamp := time.Now().Format("2006-01-02-15-04-05")
-				filename := fmt.Sprintf("lspath-report-%s.txt", timestamp)
-				_ = os.WriteFile(filename, []byte(m.DiagnosticsReport), 0644)
+				cmd = generateReportCmd(m.TraceResult, m.DiagnosticsVerbose)
+			case key.Matches(msg, keymap.Save):
+				cmd = saveReportCmd(m.DiagnosticsReport)
 			}
 
 			// Robust Capping for Diagnostics
@@ -180,55 +556,54 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.DiagnosticsScrollY = 0
 			}
 
-			return m, nil
+			return m, cmd
 		}
 
-		switch msg.String() {
-		case "ctrl+c", "q":
+		m.CopyStatus = ""
+		m.LayoutStatus = ""
+		m.RetraceError = ""
+		switch {
+		case key.Matches(msg, keymap.Quit):
 			return m, tea.Quit
-		case " ":
-			// Spacebar global page down logic
-			if m.ShowFlow {
-				if m.RightPanelFocus == FocusFilePreview {
-					m.PreviewScrollY += 10
-				} else {
-					m.FlowSelectedIdx += 10
-					if m.FlowSelectedIdx >= len(m.TraceResult.FlowNodes) {
-						m.FlowSelectedIdx = len(m.TraceResult.FlowNodes) - 1
-					}
-				}
-			} else if m.NormalRightFocus {
-				m.DetailsScrollY += 10
-			} else {
-				// PATH list paging
-				m.SelectedIdx += 10
-				if m.SelectedIdx >= len(m.FilteredIndices) {
-					m.SelectedIdx = len(m.FilteredIndices) - 1
-				}
-				m.loadDirectoryListing()
+		case key.Matches(msg, keymap.Copy):
+			if idx, ok := m.selectedEntry(); ok {
+				return m, copyEntryCmd(m.TraceResult.PathEntries[idx])
 			}
-			return m, nil
-		case "?", "h":
+		case key.Matches(msg, keymap.CopyLine):
+			if idx, ok := m.selectedEntry(); ok {
+				return m, copySourceLineCmd(m.TraceResult.PathEntries[idx])
+			}
+		case key.Matches(msg, keymap.Help):
 			m.ShowHelp = true
 			m.HelpScrollY = 0
 			return m, nil
-		case "esc":
-			// Global ESC handler
+		case key.Matches(msg, keymap.Back):
+			// Clear the search, leave Flow Mode or stop the scan
 			if m.SearchActive {
 				m.InputMode = false
 				m.InputBuffer.Blur()
 				m.SearchActive = false
 				m.InputBuffer.SetValue("")
-				m.performSearch()
-				return m, nil
+				cmd = m.performSearch()
+				return m, cmd
 			}
 			if m.ShowFlow {
 				m.ShowFlow = false
 				m.CumulativeFlow = false
 				return m, nil
 			}
-		case "up", "k":
-			if m.ShowFlow {
+			if m.Scanning && m.scanCancel != nil {
+				// Stop the deep scan; whatever was scanned so far is kept
+				m.scanCancel()
+				return m, nil
+			}
+		case key.Matches(msg, keymap.Up):
+			if m.ShowFlow && m.RightPanelFocus == FocusPathList {
+				if m.SelectedIdx > 0 {
+					m.SelectedIdx--
+					cmd = tea.Batch(m.loadDirectoryListing(), m.showEntrySource())
+				}
+			} else if m.ShowFlow {
 				if m.RightPanelFocus == FocusFilePreview {
 					// Scroll preview up
 					if m.PreviewScrollY > 0 {
@@ -238,7 +613,7 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					// Navigate flow list
 					if m.FlowSelectedIdx > 0 {
 						m.FlowSelectedIdx--
-						m.loadSelectedFile()
+						cmd = m.loadSelectedFile()
 					}
 				}
 			} else {
@@ -249,12 +624,17 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				} else {
 					if m.SelectedIdx > 0 {
 						m.SelectedIdx--
-						m.loadDirectoryListing()
+						cmd = m.loadDirectoryListing()
 					}
 				}
 			}
-		case "down", "j":
-			if m.ShowFlow {
+		case key.Matches(msg, keymap.Down):
+			if m.ShowFlow && m.RightPanelFocus == FocusPathList {
+				if m.SelectedIdx < len(m.ListRows)-1 {
+					m.SelectedIdx++
+					cmd = tea.Batch(m.loadDirectoryListing(), m.showEntrySource())
+				}
+			} else if m.ShowFlow {
 				if m.RightPanelFocus == FocusFilePreview {
 					// Scroll preview down
 					m.PreviewScrollY++
@@ -262,20 +642,20 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					// Navigate flow list
 					if m.FlowSelectedIdx < len(m.TraceResult.FlowNodes)-1 {
 						m.FlowSelectedIdx++
-						m.loadSelectedFile()
+						cmd = m.loadSelectedFile()
 					}
 				}
 			} else {
 				if m.NormalRightFocus {
 					m.DetailsScrollY++
 				} else {
-					if m.SelectedIdx < len(m.FilteredIndices)-1 {
+					if m.SelectedIdx < len(m.ListRows)-1 {
 						m.SelectedIdx++
-						m.loadDirectoryListing()
+						cmd = m.loadDirectoryListing()
 					}
 				}
 			}
-		case "pgup", "ctrl+u", "ctrl+b", "b":
+		case key.Matches(msg, keymap.PageUp):
 			// Page up
 			if m.ShowFlow && m.RightPanelFocus == FocusFilePreview {
 				if m.PreviewScrollY > 10 {
@@ -295,37 +675,45 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if m.SelectedIdx < 0 {
 					m.SelectedIdx = 0
 				}
-				m.loadDirectoryListing()
+				cmd = m.loadDirectoryListing()
 			}
-		case "pgdown", "ctrl+d", "ctrl+f":
+		case key.Matches(msg, keymap.PageDown):
 			// Page down
-			if m.ShowFlow && m.RightPanelFocus == FocusFilePreview {
-				m.PreviewScrollY += 10
-			} else if !m.ShowFlow && m.NormalRightFocus {
+			if m.ShowFlow {
+				if m.RightPanelFocus == FocusFilePreview {
+					m.PreviewScrollY += 10
+				} else {
+					m.FlowSelectedIdx += 10
+					if m.FlowSelectedIdx >= len(m.TraceResult.FlowNodes) {
+						m.FlowSelectedIdx = len(m.TraceResult.FlowNodes) - 1
+					}
+				}
+			} else if m.NormalRightFocus {
 				m.DetailsScrollY += 10
-			} else if !m.ShowFlow && !m.NormalRightFocus {
-				// Page down LHS PATH list
+			} else {
+				// PATH list paging
 				m.SelectedIdx += 10
-				if m.SelectedIdx >= len(m.FilteredIndices) {
-					m.SelectedIdx = len(m.FilteredIndices) - 1
+				if m.SelectedIdx >= len(m.ListRows) {
+					m.SelectedIdx = len(m.ListRows) - 1
 				}
-				m.loadDirectoryListing()
+				cmd = m.loadDirectoryListing()
 			}
-		case "home", "g":
+			return m, cmd
+		case key.Matches(msg, keymap.Top):
 			// Jump to top of preview
 			if m.ShowFlow && m.RightPanelFocus == FocusFilePreview {
 				m.PreviewScrollY = 0
 			} else if !m.ShowFlow && m.NormalRightFocus {
 				m.DetailsScrollY = 0
 			}
-		case "end", "G":
+		case key.Matches(msg, keymap.Bottom):
 			// Jump to end of preview - show last page
 			if m.ShowFlow && m.RightPanelFocus == FocusFilePreview {
 				// Calculate the actual number of lines
 				lines := strings.Split(m.PreviewContent, "\n")
 				if len(lines) > 0 {
 					// Calculate visible height (matches view.go logic)
-					contentHeight := m.WindowSize.Height - 10
+					contentHeight := m.panelHeight()
 					topH := contentHeight / 2
 					botH := contentHeight - topH
 					visibleHeight := botH - 1 // -1 for header
@@ -344,29 +732,77 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			} else if !m.ShowFlow && m.NormalRightFocus {
 				lines := strings.Split(m.DirectoryListing, "\n")
 				totalLines := len(lines) + 12 // Approx overhead
-				interiorHeight := m.WindowSize.Height - 8
+				interiorHeight := m.panelHeight()
 				max := totalLines - interiorHeight
 				if max < 0 {
 					max = 0
 				}
 				m.DetailsScrollY = max
 			}
-		case "tab":
+		case key.Matches(msg, keymap.Focus):
 			// Tab switches focus
 			if m.ShowFlow {
-				if m.RightPanelFocus == FocusFlowList {
-					m.RightPanelFocus = FocusFilePreview
-				} else {
-					m.RightPanelFocus = FocusFlowList
+				// Flow list, then preview, then the PATH list
+				m.RightPanelFocus = (m.RightPanelFocus + 1) % 3
+				if m.RightPanelFocus == FocusPathList {
+					cmd = m.showEntrySource()
 				}
-			} else {
+			} else if !m.rightHidden() {
 				m.NormalRightFocus = !m.NormalRightFocus
 			}
-		case "d":
+		case key.Matches(msg, keymap.Retrace):
+			// Re-trace, e.g. after editing a config file elsewhere
+			if m.Preloaded != nil {
+				m.RetraceError = "Nothing to re-trace: this result was loaded, not traced here"
+			} else if !m.Retracing {
+				return m, m.retrace()
+			}
+		case key.Matches(msg, keymap.LongPaths):
+			// Cycle truncating, wrapping and scrolling the selected row
+			m.LongPaths = (m.LongPaths + 1) % longPathModes
+			m.RowScrollX = 0
+		case key.Matches(msg, keymap.ScrollLeft, keymap.ScrollRight):
+			// Scroll the selected row sideways
+			if !m.ShowFlow && !m.NormalRightFocus {
+				if key.Matches(msg, keymap.ScrollLeft) {
+					m.scrollRow(-scrollStep)
+				} else {
+					m.scrollRow(scrollStep)
+				}
+			}
+		case key.Matches(msg, keymap.Narrow, keymap.Widen):
+			// Narrow or widen the PATH list
+			delta := splitStep
+			if key.Matches(msg, keymap.Narrow) {
+				delta = -splitStep
+			}
+			m.resizeSplit(delta)
+			return m, saveLayoutCmd(m.Layout)
+		case key.Matches(msg, keymap.HideDetails):
+			// Collapse the details pane, or bring it back
+			m.Layout.HideRight = !m.Layout.HideRight
+			if m.Layout.HideRight {
+				m.NormalRightFocus = false
+			}
+			return m, saveLayoutCmd(m.Layout)
+		case key.Matches(msg, keymap.Fix):
+			m.ShowFixPopup = true
+			m.FixScrollY = 0
+			m.FixApplied = false
+			m.FixChanges = nil
+			m.FixStatus = ""
+			m.FixText = "Looking for duplicate PATH lines…"
+			return m, planFixCmd(m.TraceResult)
+		case key.Matches(msg, keymap.Executables):
+			if idx, ok := m.selectedEntry(); ok {
+				return m, m.openExplorer(idx, "")
+			}
+		case key.Matches(msg, keymap.Diagnostics):
 			m.ShowDiagnosticsPopup = true
 			m.DiagnosticsScrollY = 0
+			m.ReportStatus = ""
 			return m, nil
-		case "f":
+		case key.Matches(msg, keymap.Flow):
 			m.ShowFlow = !m.ShowFlow
 			m.CumulativeFlow = m.ShowFlow // Default to cumulative when entering flow mode
 			m.ShowDiagnostics = false
@@ -374,28 +810,68 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.FlowSelectedIdx = 0
 			}
 			if m.ShowFlow {
-				m.loadSelectedFile()
+				// Start at the line that added the selected entry
+				if cmd = m.showEntrySource(); m.PreviewLine == 0 {
+					cmd = m.loadSelectedFile()
+				}
 			}
-		case "c":
+		case key.Matches(msg, keymap.Cumulative):
 			// Toggle Cumulative Mode
 			m.CumulativeFlow = !m.CumulativeFlow
 			if m.CumulativeFlow {
 				m.ShowFlow = true
 				m.ShowDiagnostics = false
-				m.loadSelectedFile()
+				cmd = m.loadSelectedFile()
+			}
+		case key.Matches(msg, keymap.Search):
+			// Search every executable; the results replace Which Mode's
+			if m.SearchActive {
+				m.SearchActive = false
+				m.InputBuffer.SetValue("")
+				cmd = m.performSearch()
 			}
-		case "w":
+			m.GlobalSearch = true
+			m.InputMode = true
+			m.InputBuffer.Focus()
+			m.InputBuffer.SetValue("")
+			return m, tea.Batch(cmd, textinput.Blink)
+		case key.Matches(msg, keymap.Which):
+			m.GlobalSearch = false
 			m.InputMode = true
 			m.InputBuffer.Focus()
 			m.InputBuffer.SetValue("")
 			return m, textinput.Blink
+		case key.Matches(msg, keymap.FilterDuplicates):
+			cmd = m.chooseFilter(FilterDuplicates)
+		case key.Matches(msg, keymap.FilterMissing):
+			cmd = m.chooseFilter(FilterMissing)
+		case key.Matches(msg, keymap.FilterSession):
+			cmd = m.chooseFilter(FilterSession)
+		case key.Matches(msg, keymap.FilterSource):
+			cmd = m.chooseFilter(FilterSource)
+		case key.Matches(msg, keymap.FilterNone):
+			cmd = m.chooseFilter(FilterNone)
+		case key.Matches(msg, keymap.Sort):
+			// Cycle the PATH list's order
+			m.SortMode = (m.SortMode + 1) % sortModes
+			m.arrangeList()
+		case key.Matches(msg, keymap.Group):
+			// Group the PATH list by source file, or stop
+			m.Grouped = !m.Grouped
+			m.arrangeList()
+			cmd = m.loadDirectoryListing()
+		case key.Matches(msg, keymap.ToggleGroup):
+			// Collapse or expand the selected source file group
+			if !m.ShowFlow && !m.NormalRightFocus {
+				m.toggleGroup()
+			}
 		}
 	}
 
 	// GLOBAL SCROLL CAPPING
 	// Help
 	if m.ShowHelp {
-		lines := strings.Split(m.HelpContent, "\n")
+		lines := m.helpLines()
 		max := len(lines) - (m.WindowSize.Height - 8)
 		if max < 0 {
 			max = 0
@@ -426,7 +902,7 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	// Preview
 	if m.ShowFlow && m.RightPanelFocus == FocusFilePreview {
 		lines := strings.Split(m.PreviewContent, "\n")
-		contentHeight := m.WindowSize.Height - 10
+		contentHeight := m.panelHeight()
 		visibleHeight := (contentHeight - contentHeight/2) - 1
 		max := len(lines) - visibleHeight
 		if max < 0 {
@@ -444,7 +920,7 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	if !m.ShowFlow && m.NormalRightFocus {
 		lines := strings.Split(m.DirectoryListing, "\n")
 		totalLines := len(lines) + 12 // Approx overhead
-		interiorHeight := m.WindowSize.Height - 8
+		interiorHeight := m.panelHeight()
 		max := totalLines - interiorHeight
 		if max < 0 {
 			max = 0
@@ -460,178 +936,198 @@ func (m *AppModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
-func (m *AppModel) performSearch() {
+// performSearch starts a binary search over the PATH directories. Clearing
+// the search restores the full list immediately.
+func (m *AppModel) performSearch() tea.Cmd {
 	term := strings.ToLower(m.InputBuffer.Value())
-	if term == "" {
-		// Reset
-		m.SearchActive = false
-		m.FilteredIndices = make([]int, len(m.TraceResult.PathEntries))
-		for i := range m.TraceResult.PathEntries {
-			m.FilteredIndices[i] = i
-		}
-	} else {
+	m.ShellResolution = nil
+	if term != "" {
 		m.SearchActive = true
-		m.SearchMatches = make(map[int]string)
-		seenDirs := make(map[string]bool)
-
-		var result []int
-		for i, entry := range m.TraceResult.PathEntries {
-			dir := entry.Value
-
-			// Deduplication: Only show unique directories in search results
-			if seenDirs[dir] {
-				continue
-			}
-
-			// Filesystem Scan
-			files, err := os.ReadDir(dir)
-			if err != nil {
-				continue
-			}
-
-			// Find *best* match (exact matches preferred over prefix)
-			// Or just first one? User said "first path entry that finds that binary".
-			// If we have multiple matches in the directory, we need to pick one to show details for.
-			// Let's store the first one we find, but prefer exact term match.
-
-			var matchedFile string
-			found := false
-
-			// First pass: Exact match check (if we had efficient lookup).
-			// Since we are iterating, let's just find first prefix match,
-			// but if we find exact match later, swap it?
-
-			for _, f := range files {
-				if f.IsDir() {
-					continue
-				}
-				name := strings.ToLower(f.Name())
-
-				if strings.HasPrefix(name, term) {
-					matchedFile = f.Name() // Store original case
-					found = true
-
-					// Optimisation: If exact match, we can stop looking in this dir.
-					if name == term {
-						break
-					}
-					// Continue to see if there is a better (exact) match?
-					// Example: term="py", matches "python", "pypi".
-					// If we find "python" first, that's good.
-				}
-			}
-
-			if found {
-				seenDirs[dir] = true
-				result = append(result, i)
-				m.SearchMatches[i] = matchedFile
-			}
-		}
-		m.FilteredIndices = result
+		return searchCmd(term, m.SearchMode, m.TraceResult.PathEntries)
 	}
 
-	// Bounds check
-	if m.SelectedIdx >= len(m.FilteredIndices) {
-		if len(m.FilteredIndices) > 0 {
-			m.SelectedIdx = len(m.FilteredIndices) - 1
+	// Reset
+	m.SearchActive = false
+	m.setListEntries(m.allEntries())
+	return m.loadDirectoryListing()
+}
+
+// clampSelection keeps SelectedIdx within the list.
+func (m *AppModel) clampSelection() {
+	if m.SelectedIdx >= len(m.ListRows) {
+		if len(m.ListRows) > 0 {
+			m.SelectedIdx = len(m.ListRows) - 1
 		} else {
 			m.SelectedIdx = 0
 		}
 	}
-
-	m.loadDirectoryListing()
 }
 
-func (m *AppModel) loadDirectoryListing() {
-	if len(m.FilteredIndices) == 0 || m.SelectedIdx >= len(m.FilteredIndices) {
-		m.DirectoryListing = ""
-		return
+// selectedEntry returns the PathEntries index of the selected list row,
+// unless it is a group header.
+func (m *AppModel) selectedEntry() (int, bool) {
+	if m.SelectedIdx >= len(m.ListRows) || m.ListRows[m.SelectedIdx].Entry < 0 {
+		return 0, false
 	}
+	return m.ListRows[m.SelectedIdx].Entry, true
+}
 
-	idx := m.FilteredIndices[m.SelectedIdx]
-	dir := m.TraceResult.PathEntries[idx].Value
-	dir = expandTilde(dir)
-
-	files, err := os.ReadDir(dir)
-	if err != nil {
-		// Provide user-friendly error messages
-		if os.IsNotExist(err) {
-			m.DirectoryListing = fmt.Sprintf("Directory does not exist:\n\n%s", dir)
-		} else if os.IsPermission(err) {
-			m.DirectoryListing = fmt.Sprintf("⚠️ Permission denied: Cannot read directory\n\n%s", dir)
-		} else {
-			m.DirectoryListing = fmt.Sprintf("⚠️ Cannot access directory:\n\n%s\n\nError: %v", dir, err)
+// openExplorer lists the executables of entry idx, selecting name if it has
+// one, and moves the PATH list selection to the entry when it is shown.
+func (m *AppModel) openExplorer(idx int, name string) tea.Cmd {
+	m.ShowExplorer = true
+	m.ExplorerEntry = idx
+	m.ExplorerSel = 0
+	m.ExplorerScrollY = 0
+	m.ExplorerRows = nil
+	m.refreshExplorer()
+	for i, x := range m.ExplorerRows {
+		if x.Name == name {
+			m.ExplorerSel = i
+			break
 		}
-		m.FileCount = 0
-		m.DirCount = 0
-		return
 	}
+	m.clampExplorer()
 
-	m.FileCount = 0
-	m.DirCount = 0
-
-	// Handle empty directory
-	if len(files) == 0 {
-		m.DirectoryListing = "Directory is empty"
-		m.DetailsScrollY = 0
-		return
+	for row, r := range m.ListRows {
+		if r.Entry == idx && row != m.SelectedIdx {
+			m.SelectedIdx = row
+			return m.loadDirectoryListing()
+		}
 	}
+	return nil
+}
 
-	var sb strings.Builder
-	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+// startGlobalSearch ends the search input and lists every executable
+// matching it in the results pane.
+func (m *AppModel) startGlobalSearch() tea.Cmd {
+	m.GlobalSearch = false
+	m.InputBuffer.Blur()
+	term := strings.ToLower(m.InputBuffer.Value())
+	m.InputBuffer.SetValue("")
+	if term == "" {
+		return nil
+	}
+	m.ShowResults = true
+	m.ResultsTerm = term
+	m.ResultsLoading = true
+	m.ResultHits = nil
+	m.ResultSel, m.ResultScrollY = 0, 0
+	return globalSearchCmd(term, m.SearchMode, m.TraceResult.PathEntries)
+}
 
-	for _, f := range files {
-		info, err := f.Info()
-		if err != nil {
-			continue
+// selectEntry selects entry idx in the PATH list, first clearing the filter
+// and expanding its group if they hide it.
+func (m *AppModel) selectEntry(idx int) tea.Cmd {
+	if !slices.ContainsFunc(m.ListRows, func(r listRow) bool { return r.Entry == idx }) {
+		m.Filter = FilterNone
+		delete(m.Collapsed, m.TraceResult.PathEntries[idx].SourceFile)
+		m.setListEntries(m.listBase)
+	}
+	for row, r := range m.ListRows {
+		if r.Entry == idx {
+			m.SelectedIdx = row
+			return m.loadDirectoryListing()
 		}
+	}
+	return nil
+}
 
-		if f.IsDir() {
-			m.DirCount++
-		} else {
-			m.FileCount++
+// clampResults keeps the results pane's selection in range and scrolled
+// into view.
+func (m *AppModel) clampResults() {
+	if m.ResultSel >= len(m.ResultHits) {
+		m.ResultSel = len(m.ResultHits) - 1
+	}
+	if m.ResultSel < 0 {
+		m.ResultSel = 0
+	}
+	height := m.explorerHeight()
+	if m.ResultSel < m.ResultScrollY {
+		m.ResultScrollY = m.ResultSel
+	}
+	if m.ResultSel >= m.ResultScrollY+height {
+		m.ResultScrollY = m.ResultSel - height + 1
+	}
+}
+
+// refreshExplorer rebuilds the explorer's rows, e.g. once the scan finishes,
+// keeping the selected executable.
+func (m *AppModel) refreshExplorer() {
+	name := ""
+	if m.ExplorerSel < len(m.ExplorerRows) {
+		name = m.ExplorerRows[m.ExplorerSel].Name
+	}
+	m.ExplorerRows = trace.EntryExecutables(m.TraceResult, m.BinaryIndex, m.ExplorerEntry)
+	for i, x := range m.ExplorerRows {
+		if x.Name == name {
+			m.ExplorerSel = i
 		}
+	}
+	m.clampExplorer()
+}
 
-		// Permissions
-		mode := info.Mode().String()
+// explorerHeight is the number of rows the explorer popup shows at once.
+func (m *AppModel) explorerHeight() int {
+	// popupHeight (h-6) minus border, heading and footer
+	return max(1, m.WindowSize.Height-6-6)
+}
 
-		// Size
-		size := info.Size()
-		sizeStr := fmt.Sprintf("%d", size)
-		if size > 1024*1024 {
-			sizeStr = fmt.Sprintf("%.1fM", float64(size)/(1024*1024))
-		} else if size > 1024 {
-			sizeStr = fmt.Sprintf("%.1fK", float64(size)/1024)
-		}
+// clampExplorer keeps the explorer's selection in range and scrolled into
+// view.
+func (m *AppModel) clampExplorer() {
+	if m.ExplorerSel >= len(m.ExplorerRows) {
+		m.ExplorerSel = len(m.ExplorerRows) - 1
+	}
+	if m.ExplorerSel < 0 {
+		m.ExplorerSel = 0
+	}
+	height := m.explorerHeight()
+	if m.ExplorerSel < m.ExplorerScrollY {
+		m.ExplorerScrollY = m.ExplorerSel
+	}
+	if m.ExplorerSel >= m.ExplorerScrollY+height {
+		m.ExplorerScrollY = m.ExplorerSel - height + 1
+	}
+}
 
-		// Time
-		modTime := info.ModTime().Format("Jan 02 15:04")
+// loadDirectoryListing updates in-memory details for the selected entry and
+// requests its directory listing, which arrives as MsgDirListing.
+func (m *AppModel) loadDirectoryListing() tea.Cmd {
+	m.RowScrollX = 0 // The selection moved, or the list changed
+	idx, ok := m.selectedEntry()
+	if !ok {
+		m.DirectoryListing = ""
+		m.ListingIdx = -1
+		return nil
+	}
 
-		// Icon and Name
-		icon := "📄"
-		if f.IsDir() {
-			icon = "📁"
-		} else if info.Mode().Perm()&0111 != 0 {
-			icon = "🚀"
-		}
+	m.RemovalImpact = nil
+	if m.BinaryIndex != nil {
+		m.RemovalImpact = m.BinaryIndex.RemovalImpact(idx)
+	}
 
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s %s\n", mode, sizeStr, modTime, icon, f.Name())
+	match := ""
+	if m.SearchActive {
+		match = m.SearchMatches[idx]
 	}
-	w.Flush()
-	m.DirectoryListing = sb.String()
-	m.DetailsScrollY = 0 // Reset scroll position when loading new directory
+	return loadDirListingCmd(idx, m.TraceResult.PathEntries[idx], match)
 }
 
-func (m *AppModel) loadSelectedFile() {
+// loadSelectedFile shows the selected flow node in the preview. Built-in
+// nodes are described inline; files are read by loadFileCmd.
+func (m *AppModel) loadSelectedFile() tea.Cmd {
 	// Save current scroll position before switching files
 	if m.PreviewPath != "" {
 		m.ScrollPositions[m.PreviewPath] = m.PreviewScrollY
 	}
 
+	m.PreviewLine = 0
+
 	if m.FlowSelectedIdx < 0 || m.FlowSelectedIdx >= len(m.TraceResult.FlowNodes) {
 		m.PreviewContent = ""
 		m.PreviewPath = ""
-		return
+		return nil
 	}
 
 	node := m.TraceResult.FlowNodes[m.FlowSelectedIdx]
@@ -642,24 +1138,28 @@ func (m *AppModel) loadSelectedFile() {
 		m.PreviewContent = "Session paths ◆ are added manually or by runtime\ntools, not from shell configuration files.\n\nThese paths exist only in the current shell session\nand will not persist after the shell is closed unless\nadded to a configuration file."
 		m.PreviewPath = path
 		m.PreviewScrollY = 0
-		return
+		return nil
 	}
 
 	if path == "System (Default)" {
 		m.PreviewContent = "System default paths are inherited from system-wide\nconfiguration:\n\n• /etc/paths\n• /etc/paths.d/*\n• Built-in shell defaults\n\nThis is normal and expected behavior on Unix-like\nsystems. These paths are set before any user\nconfiguration files are processed."
 		m.PreviewPath = path
 		m.PreviewScrollY = 0
-		return
+		return nil
 	}
 
-	// Expand ~
-	if strings.HasPrefix(path, "~") {
-		if home, err := os.UserHomeDir(); err == nil {
-			path = filepath.Join(home, path[1:])
-		}
+	if path == trace.SystemRegistrySource || path == trace.UserRegistrySource {
+		m.PreviewContent = fmt.Sprintf("Registry Path value:\n%s\n\nWindows builds a new process's PATH from the system\nPath followed by the user Path. Edit them in\nSettings > System > About > Advanced system settings\n> Environment Variables, then open a new terminal.", path)
+		m.PreviewPath = path
+		m.PreviewScrollY = 0
+		return nil
 	}
 
+	// Expand ~
+	path = model.ExpandTilde(path)
+
 	m.PreviewPath = path
+	m.PreviewContent = "Loading…"
 
 	// Restore previous scroll position if we've viewed this file before
 	if savedScroll, exists := m.ScrollPositions[path]; exists {
@@ -668,62 +1168,131 @@ func (m *AppModel) loadSelectedFile() {
 		m.PreviewScrollY = 0 // Reset scroll for new files
 	}
 
-	// Check if file was not executed (placeholder node)
-	if node.NotExecuted {
-		// Check if file exists
-		if _, err := os.Stat(path); err != nil {
-			if os.IsNotExist(err) {
-				m.PreviewContent = fmt.Sprintf("File not executed during this shell session.\n\nFile does not exist:\n%s", path)
-			} else {
-				m.PreviewContent = fmt.Sprintf("File not executed during this shell session.\n\nCannot access file:\n%v", err)
-			}
-		} else {
-			m.PreviewContent = fmt.Sprintf("File not executed during this shell session.\n\nFile exists at:\n%s", path)
-		}
-		return
-	}
+	return loadFileCmd(path, node.NotExecuted)
+}
 
-	content, err := os.ReadFile(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			m.PreviewContent = fmt.Sprintf("File not found: %s\n(This file does not exist on your system)", path)
-		} else {
-			m.PreviewContent = fmt.Sprintf("Error reading file: %v", err)
+// retrace runs the trace again, showing the last result with a spinner
+// until the new one arrives as MsgTraceReady.
+func (m *AppModel) retrace() tea.Cmd {
+	m.Retracing = true
+	m.TraceStage = ""
+	return tea.Batch(InitTraceCmd(m.TraceOptions), m.Spinner.Tick)
+}
+
+// showEntrySource selects the flow node of the selected PATH entry and
+// scrolls the preview to the line that added it, which View highlights.
+func (m *AppModel) showEntrySource() tea.Cmd {
+	idx, ok := m.selectedEntry()
+	if !ok {
+		return nil
+	}
+	e := m.TraceResult.PathEntries[idx]
+	for i, node := range m.TraceResult.FlowNodes {
+		if node.ID != e.FlowID {
+			continue
 		}
-	} else {
-		m.PreviewContent = string(content)
+		var cmd tea.Cmd
+		if i != m.FlowSelectedIdx || m.PreviewPath == "" {
+			m.FlowSelectedIdx = i
+			cmd = m.loadSelectedFile()
+		}
+		if e.LineNumber > 0 {
+			m.PreviewLine = e.LineNumber
+			m.PreviewScrollY = max(e.LineNumber-1-m.previewHeight()/2, 0) // Centered
+		}
+		return cmd
 	}
+	return nil
 }
 
-// InitTraceCmd runs unified analysis (session + trace).
-func InitTraceCmd() tea.Cmd {
-	return func() tea.Msg {
-		analyzer := trace.NewAnalyzer()
-		sessionPath := os.Getenv("PATH")
+// previewHeight is the number of file lines the flow preview shows at once.
+func (m *AppModel) previewHeight() int {
+	contentHeight := m.panelHeight()
+	return max((contentHeight-contentHeight/2)-1, 1)
+}
 
-		// Run shell trace
-		shell := trace.DetectShell(os.Getenv("SHELL"))
-		stderr, err := trace.RunTrace(shell, trace.SandboxInitialPath)
-		if err != nil {
-			return MsgError(err)
-		}
-		defer stderr.Close()
+// startScan builds the binary index in the background within ScanBudget,
+// streaming progress messages until it finishes or is cancelled. Each scan
+// gets the next generation number, and messages from earlier ones are
+// dropped, so a cancelled scan can't finish over its replacement.
+func (m *AppModel) startScan() tea.Cmd {
+	if m.scanCancel != nil {
+		m.scanCancel()
+	}
+	m.scanGen++
+	gen := m.scanGen
+	ctx, cancel := context.WithTimeout(context.Background(), m.ScanBudget)
+	m.scanCancel = cancel
+	m.Scanning = true
+	m.ScanDone, m.ScanTotal = 0, len(m.TraceResult.PathEntries)
+
+	progress := make(chan MsgScanProgress)
+	m.scanProgress = progress
+	entries := m.TraceResult.PathEntries
+
+	done := func() tea.Msg {
+		defer cancel()
+		defer close(progress)
+		idx := trace.ScanBinaryIndex(ctx, entries, func(d, t int) {
+			select {
+			case progress <- MsgScanProgress{Gen: gen, Done: d, Total: t}:
+			default: // Don't block the scan if the UI is busy
+			}
+		})
+		return MsgScanDone{Gen: gen, Index: idx}
+	}
+	return tea.Batch(done, waitForScan(progress))
+}
 
-		parser := trace.NewParser(shell)
-		events, errs := parser.Parse(stderr)
+// startWatch waits for the next change to the traced config files when
+// watch mode is on. Each change re-traces, which starts a fresh watch.
+func (m *AppModel) startWatch() tea.Cmd {
+	if !m.Watch {
+		return nil
+	}
+	if m.watchCancel != nil {
+		m.watchCancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.watchCancel = cancel
+	files := trace.ConfigFiles(m.TraceResult)
+	m.WatchStatus = fmt.Sprintf("Watching %d config files", len(files))
+	return watchConfigCmd(ctx, files)
+}
 
-		var allEvents []model.TraceEvent
-		for ev := range events {
-			allEvents = append(allEvents, ev)
+// waitForScan returns the next progress message, or nothing once the scan ends.
+func waitForScan(progress chan MsgScanProgress) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-progress
+		if !ok {
+			return nil
 		}
+		return msg
+	}
+}
 
-		// Wait for errs
-		if e := <-errs; e != nil {
-			log.Printf("Parser warning: %v", e)
+// formatFixPlan renders the proposed edits as unified diffs with reasons.
+func formatFixPlan(plan MsgFixPlan) string {
+	var sb strings.Builder
+	if plan.Err != nil {
+		sb.WriteString(fmt.Sprintf("Cannot fix: %v\n", plan.Err))
+	}
+	for _, n := range plan.Notes {
+		sb.WriteString("Note: " + n + "\n")
+	}
+	if plan.Err == nil && len(plan.Changes) == 0 {
+		sb.WriteString("No duplicate PATH lines to remove.\n")
+	}
+	for _, c := range plan.Changes {
+		sb.WriteString("\n")
+		for _, e := range c.Edits {
+			if e.Action == fix.ActionAppend {
+				sb.WriteString(fmt.Sprintf("# Append: %s\n", e.Reason))
+			} else {
+				sb.WriteString(fmt.Sprintf("# Line %d: %s\n", e.Line, e.Reason))
+			}
 		}
-
-		// Run unified analysis
-		res := analyzer.AnalyzeUnified(sessionPath, allEvents)
-		return MsgTraceReady(res)
+		sb.WriteString(c.Diff())
 	}
+	return sb.String()
 }
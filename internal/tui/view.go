@@ -5,56 +5,41 @@ import (
 	"os"
 	"strings"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
 	"lspath/internal/model"
+	"lspath/internal/trace"
 )
 
+// Styles shared by the views, set from the theme by ApplyTheme
 var (
-	titleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("#FAFAFA")).
-			Background(lipgloss.Color("#7D56F4")).
-			Padding(0, 1)
-
-	selectedItemStyle = lipgloss.NewStyle().
-				PaddingLeft(2).
-				Foreground(lipgloss.Color("205")) // Pinkish
-
-	unselectedItemStyle = lipgloss.NewStyle().
-				PaddingLeft(4).
-				Foreground(lipgloss.Color("240")) // Grey
-
-	dimStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("238"))
-
-	detailStyle = lipgloss.NewStyle().
-			Padding(1, 2).
-			Border(lipgloss.NormalBorder()).
-			BorderForeground(lipgloss.Color("63"))
-
-	adviceStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("208")) // Orange
-
-	pathHighlightStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("81")). // Sky Blue/Cyan
-				Bold(true)
+	titleStyle          lipgloss.Style
+	selectedItemStyle   lipgloss.Style
+	unselectedItemStyle lipgloss.Style
+	dimStyle            lipgloss.Style
+	detailStyle         lipgloss.Style
+	adviceStyle         lipgloss.Style
+	pathHighlightStyle  lipgloss.Style
 )
 
 func (m AppModel) View() string {
 	if m.Loading {
-		return "\n  Scanning PATH trace... please wait.\n"
+		stage := m.TraceStage
+		if stage == "" {
+			stage = "Starting the PATH trace…"
+		}
+		// The spinner's frames end in a space
+		return fmt.Sprintf("\n  %s%s please wait.\n", m.Spinner.View(), stage)
 	}
 	if m.Err != nil {
 		return fmt.Sprintf("\n  Error: %v\n", m.Err)
 	}
 
-	// Layout dimensions
 	// Layout dimensions
 	// Subtracting 6 for horizontal margin (borders x2 + buffer)
-	// Subtracting 8 for vertical margin (title, footer, borders + buffer)
 	width := m.WindowSize.Width
 	height := m.WindowSize.Height
 
@@ -63,36 +48,50 @@ func (m AppModel) View() string {
 		netWidth = 20
 	}
 
-	leftWidth := netWidth / 2
-	rightWidth := netWidth - leftWidth
+	rightWidth := netWidth - netWidth*m.Layout.Split/100
+	leftWidth := m.listWidth()
 
-	// Total box height (including borders)
-	boxHeight := height - 6
-	if boxHeight < 6 {
-		boxHeight = 6
+	// The tour replaces the key help below the panels and needs more room
+	tour := ""
+	if m.Tour && m.TourSteps != nil {
+		tour = m.renderTour(width - 2)
 	}
 
 	// Interior height (excluding borders)
-	interiorHeight := boxHeight - 2
-	if interiorHeight < 2 {
-		interiorHeight = 2
+	interiorHeight := m.panelHeight()
+	if tour != "" {
+		// The tour replaces the blank line and key help below the panels
+		interiorHeight = max(height-2-lipgloss.Height(tour), minPanelHeight)
 	}
 
 	// Styles
-	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("205"))
-	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("229")).Background(lipgloss.Color("57"))
-	dimmedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
-	normalStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
-	highlightStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("255")).Bold(true) // For matching flow items
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(theme.Accent)
+	selectedStyle := selectedRowStyle()
+	dimmedStyle := lipgloss.NewStyle().Foreground(theme.Muted)
+	normalStyle := lipgloss.NewStyle().Foreground(theme.Text)
+	highlightStyle := lipgloss.NewStyle().Foreground(theme.Text).Bold(true) // For matching flow items
 
 	// Colors for split view
-	dimColor := lipgloss.Color("240")
-	activeColor := lipgloss.Color("205")
-	borderColor := lipgloss.Color("63")
+	dimColor := theme.Muted
+	activeColor := theme.Accent
+	borderColor := theme.Border
 
 	// LEFT PANEL: PATH List
 	var leftView strings.Builder
-	leftView.WriteString(titleStyle.Render("PATH Entries"))
+	listTitle := m.Variable + " Entries"
+	if m.SortMode != SortPriority {
+		listTitle += " · by " + sortNames[m.SortMode]
+	}
+	if m.Grouped {
+		listTitle += " · grouped"
+	}
+	if f := m.filterTitle(); f != "" {
+		listTitle += " · " + f
+	}
+	if m.LongPaths != LongPathsTruncate {
+		listTitle += " · " + longPathNames[m.LongPaths]
+	}
+	leftView.WriteString(titleStyle.Render(truncate(listTitle, leftWidth)))
 	leftView.WriteString("\n\n") // 2 newlines = 3 lines total (Title + blank + blank)
 
 	// Determine Highlighting Context
@@ -118,68 +117,56 @@ func (m AppModel) View() string {
 	// Windowing Logic for Left Panel
 	// Header is 2 lines (Title + 1 blank line)
 	visibleItems := interiorHeight - 2
+	if idx, ok := m.selectedEntry(); ok && !m.ShowFlow {
+		// A wrapped selected row takes more than one line
+		line, _, room := m.entryRow(idx, leftWidth)
+		visibleItems -= len(m.selectedRowLines(line, room)) - 1
+	}
 	if visibleItems < 1 {
 		visibleItems = 1
 	}
 	startIdx := 0
-	endIdx := len(m.FilteredIndices)
+	endIdx := len(m.ListRows)
 
-	if len(m.FilteredIndices) > visibleItems {
+	if len(m.ListRows) > visibleItems {
 		if m.SelectedIdx >= visibleItems/2 {
 			startIdx = m.SelectedIdx - (visibleItems / 2)
 		}
 		if startIdx < 0 {
 			startIdx = 0
 		}
-		if startIdx+visibleItems > len(m.FilteredIndices) {
-			startIdx = len(m.FilteredIndices) - visibleItems
+		if startIdx+visibleItems > len(m.ListRows) {
+			startIdx = len(m.ListRows) - visibleItems
 		}
 		endIdx = startIdx + visibleItems
 	}
 
 	for i := startIdx; i < endIdx; i++ {
-		idx := m.FilteredIndices[i]
-		entry := m.TraceResult.PathEntries[idx]
-
-		// Determine status icon
-		statusIcon := model.IconOK
-		if entry.IsSessionOnly {
-			statusIcon = model.IconSession // Session-only entry (not from config files)
-		} else if entry.IsDuplicate || entry.SymlinkPointsTo >= 0 {
-			statusIcon = model.IconDuplicate
-		} else if entry.IsSymlink {
-			statusIcon = model.IconSymlink
-		} else {
-			// Check if missing by looking at diagnostics or just use OK
-			for _, diag := range entry.Diagnostics {
-				if strings.Contains(diag, "does not exist") {
-					statusIcon = model.IconMissing
-					break
-				}
+		row := m.ListRows[i]
+		if row.Entry < 0 {
+			// Group header: source file and entry count
+			arrow := "▾"
+			if m.Collapsed[row.Group] {
+				arrow = "▸"
 			}
+			line := truncate(fmt.Sprintf("%s %s (%d)", arrow, row.Group, row.Count), leftWidth-2)
+			style := titleStyle
+			if i == m.SelectedIdx && !m.ShowFlow {
+				style = selectedStyle
+			}
+			leftView.WriteString(style.Render(line))
+			leftView.WriteString("\n")
+			continue
 		}
-
-		line := fmt.Sprintf("%2d. %s %s", idx+1, statusIcon, entry.Value)
-		if entry.IsSessionOnly {
-			line += " (session)"
-		} else if entry.IsDuplicate {
-			line += " (duplicate)"
-		} else if entry.SymlinkPointsTo >= 0 {
-			line += " (duplicate, symlink)"
-		} else if entry.IsSymlink {
-			line += " (symlink)"
-		}
-
-		// Priority indicators
-		if idx == 0 {
-			line += " (highest priority " + model.IconPriorityHigh + ")"
-		} else if idx == len(m.TraceResult.PathEntries)-1 {
-			line += " (lowest priority " + model.IconPriorityLow + ")"
-		}
-
-		// Truncate
-		if len(line) > leftWidth-2 {
-			line = line[:leftWidth-5] + "..."
+		idx := row.Entry
+		entry := m.TraceResult.PathEntries[idx]
+		line, match, room := m.entryRow(idx, leftWidth)
+		lines := []string{truncate(line, room)}
+		if i == m.SelectedIdx && !m.ShowFlow {
+			lines = m.selectedRowLines(line, room)
+			if fit := max(interiorHeight-2, 1); len(lines) > fit {
+				lines = lines[:fit] // Too short a window to wrap the whole path
+			}
 		}
 
 		// Styling logic
@@ -204,7 +191,9 @@ func (m AppModel) View() string {
 				}
 			}
 
-			if highlight {
+			if isRowSelected && m.RightPanelFocus == FocusPathList {
+				style = selectedStyle
+			} else if highlight {
 				// Don't show selection in flow mode, just highlight
 				style = highlightStyle
 			} else {
@@ -220,12 +209,17 @@ func (m AppModel) View() string {
 			}
 		}
 
-		leftView.WriteString(style.Render(line))
-		leftView.WriteString("\n")
+		for n, line := range lines {
+			leftView.WriteString(style.Render(line))
+			if match != "" && n == 0 {
+				leftView.WriteString(style.Render("  ") + highlightMatch(match, m.SearchPositions[idx], style))
+			}
+			leftView.WriteString("\n")
+		}
 	}
 
 	lBorderColor := borderColor
-	if !m.ShowFlow && !m.NormalRightFocus {
+	if !m.ShowFlow && !m.NormalRightFocus || m.ShowFlow && m.RightPanelFocus == FocusPathList {
 		lBorderColor = activeColor
 	}
 
@@ -245,7 +239,7 @@ func (m AppModel) View() string {
 		topH := interiorHeight / 2
 		botH := interiorHeight - topH
 
-		rightView.WriteString(titleStyle.Render("Configuration Flow"))
+		rightView.WriteString(titleStyle.Render(truncate("Configuration Flow", rightWidth)))
 		rightView.WriteString("\n\n") // 2 lines overhead (Title + blank line)
 
 		// Flow mode with unified trace + session data
@@ -393,6 +387,8 @@ func (m AppModel) View() string {
 				statusStr = fmt.Sprintf(" [%d %s]", ownCount, pStr)
 			} else if node.NotExecuted {
 				statusStr = " [Not Executed]"
+			} else if totalCount == 0 && strings.HasSuffix(checkPath, "/etc/zshrc_Apple_Terminal") {
+				statusStr = " [Never Modifies PATH]"
 			} else if totalCount == 0 {
 				statusStr = " [No Change]"
 			} else {
@@ -432,16 +428,14 @@ func (m AppModel) View() string {
 			}
 
 			// Truncate width strictly
-			if len(line) > rightWidth-2 {
-				line = line[:rightWidth-5] + "..."
-			}
+			line = truncate(line, rightWidth-2)
 
 			if i == m.FlowSelectedIdx {
 				// Highlight row
 				rendered := selectedStyle.Render(line)
 				// If focused on List, add extra indicator
 				if m.RightPanelFocus == FocusFlowList {
-					rendered = lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Bold(true).Render(line)
+					rendered = lipgloss.NewStyle().Foreground(theme.Accent).Bold(true).Render(line)
 				}
 				rightView.WriteString(rendered)
 			} else {
@@ -489,6 +483,27 @@ func (m AppModel) View() string {
 
 		// Content space = botH - 1 (header line)
 		previewContentHeight := botH - 1
+
+		// Explanatory note for the selected node (e.g. Apple Terminal integration)
+		// and what of it did not run
+		if m.FlowSelectedIdx < len(m.TraceResult.FlowNodes) {
+			node := m.TraceResult.FlowNodes[m.FlowSelectedIdx]
+			var notes []string
+			if node.Note != "" {
+				notes = append(notes, "ℹ "+node.Note)
+			}
+			for _, s := range node.Skipped {
+				notes = append(notes, "⤼ "+s)
+			}
+			for _, note := range notes {
+				if previewContentHeight <= 2 {
+					break
+				}
+				note = truncate(note, rightWidth-4)
+				previewBuilder.WriteString(adviceStyle.Render(note) + "\n")
+				previewContentHeight--
+			}
+		}
 		if previewContentHeight < 1 {
 			previewContentHeight = 1
 		}
@@ -555,14 +570,14 @@ func (m AppModel) View() string {
 				}
 
 				// Truncate
-				displayLine := line
-				if len(displayLine) > contentWidth {
-					displayLine = displayLine[:contentWidth-3] + "..."
-				}
+				displayLine := truncate(line, contentWidth)
 
 				// Render
 				previewBuilder.WriteString(dimStyle.Render(lnPrefix))
-				if isHighlighted {
+				if lineNum == m.PreviewLine {
+					// The line that added the selected PATH entry
+					previewBuilder.WriteString(selectedStyle.Render(displayLine))
+				} else if isHighlighted {
 					previewBuilder.WriteString(pathHighlightStyle.Render(displayLine))
 				} else {
 					previewBuilder.WriteString(displayLine)
@@ -588,24 +603,36 @@ func (m AppModel) View() string {
 		rightView.WriteString(titleStyle.Render("Details"))
 		rightView.WriteString("\n")
 
-		if len(m.FilteredIndices) > 0 && m.SelectedIdx < len(m.FilteredIndices) {
-			idx := m.FilteredIndices[m.SelectedIdx]
+		if idx, ok := m.selectedEntry(); ok {
 			entry := m.TraceResult.PathEntries[idx]
 
 			// Build directory line with optional hint
-			dirLine := fmt.Sprintf("\nDirectory:  %s", entry.Value)
+			dirLine := fmt.Sprintf("\nDirectory:  %s", model.DisplayPath(entry.Value))
 			if !m.ShowDiagnostics {
 				if entry.IsSessionOnly {
 					dirLine += "  (⚡ session-only)"
 				} else if entry.IsDuplicate {
-					dirLine += fmt.Sprintf("  (%s. Press 'd' for details)", entry.DuplicateMessage)
+					dirLine += fmt.Sprintf("  (%s. Press %s for details)", entry.DuplicateMessage, quotedLabel(keymap.Diagnostics))
 				} else if entry.SymlinkPointsTo >= 0 {
-					dirLine += fmt.Sprintf("  (%s. Press 'd' for details)", entry.SymlinkMessage)
-				} else if entry.IsSymlink {
-					dirLine += fmt.Sprintf("  (symlink %s → %s)", model.IconSymlink, entry.SymlinkTarget)
+					dirLine += fmt.Sprintf("  (%s. Press %s for details)", entry.SymlinkMessage, quotedLabel(keymap.Diagnostics))
 				}
 			}
 			rightView.WriteString(dirLine)
+			if chain := trace.SymlinkChain(entry); chain != "" {
+				if entry.SymlinkBroken != "" {
+					chain += " (broken: " + entry.SymlinkBroken + ")"
+				}
+				rightView.WriteString(fmt.Sprintf("\nSymlink:    %s", chain))
+			}
+			if model.IsRelativePath(entry.Value) {
+				for _, d := range entry.Diagnostics {
+					if advice, ok := strings.CutPrefix(d, "Advice: "); ok {
+						rightView.WriteString(fmt.Sprintf("\nAdvice:     %s", advice))
+					} else {
+						rightView.WriteString(fmt.Sprintf("\nWarning:    %s %s", model.IconRelative, d))
+					}
+				}
+			}
 
 			// Show source info - different for session-only entries
 			if entry.IsSessionOnly {
@@ -613,6 +640,7 @@ func (m AppModel) View() string {
 				if entry.SessionNote != "" {
 					rightView.WriteString(fmt.Sprintf("\nNote:       %s", entry.SessionNote))
 				}
+				rightView.WriteString(confidenceLine(entry))
 				rightView.WriteString("\n\n--- Session-Only Entry ---")
 				rightView.WriteString("\nThis path exists in your current terminal but was not")
 				rightView.WriteString("\nadded by any shell configuration file. Common causes:")
@@ -630,10 +658,23 @@ func (m AppModel) View() string {
 				} else {
 					rightView.WriteString(fmt.Sprintf("\nLine:       %d", entry.LineNumber))
 				}
+				if entry.Package != "" {
+					rightView.WriteString(fmt.Sprintf("\nInstalled:  %s", entry.Package))
+				}
+				rightView.WriteString(confidenceLine(entry))
+				if sl, ok := m.TraceResult.SourceLineOf(idx); ok && len(sl.Entries) > 1 {
+					var others []string
+					for _, i := range sl.Entries {
+						if i != idx {
+							others = append(others, fmt.Sprintf("#%d %s", i+1, m.TraceResult.PathEntries[i].Value))
+						}
+					}
+					rightView.WriteString(fmt.Sprintf("\nSame line:  also adds %s", strings.Join(others, ", ")))
+				}
 
 				// Show the actual line from the config file with context
-				lineContext := model.GetLineContext(entry.SourceFile, entry.LineNumber)
-				if lineContext.ErrorMsg == "" && (entry.LineNumber > 0 || entry.SourceFile != "System (Default)") {
+				lineContext := m.LineContext
+				if m.ListingIdx == idx && lineContext.ErrorMsg == "" && (entry.LineNumber > 0 || entry.SourceFile != "System (Default)") {
 					rightView.WriteString(fmt.Sprintf("\n\n--- Source Line Context (%s) ---", entry.SourceFile))
 					if lineContext.HasBefore2 {
 						rightView.WriteString(fmt.Sprintf("\n  %4d  %s", lineContext.LineNumber-2, lineContext.Before2))
@@ -651,68 +692,178 @@ func (m AppModel) View() string {
 				}
 			}
 
-			// Search Match Details
-			if m.SearchActive {
-				if filename, ok := m.SearchMatches[idx]; ok {
-					// Get File Info
-					fullPath := fmt.Sprintf("%s/%s", entry.Value, filename) // Simple join
-					// os.Join is better but this works for unix
-
-					info, err := os.Lstat(fullPath)
-					if err == nil {
-						rightView.WriteString("\n\n--- Found Binary ---")
-						rightView.WriteString(fmt.Sprintf("\nName:       %s", filename))
-						rightView.WriteString(fmt.Sprintf("\nPath:       %s", fullPath))
-						rightView.WriteString(fmt.Sprintf("\nSize:       %d bytes", info.Size()))
-						rightView.WriteString(fmt.Sprintf("\nMode:       %s", info.Mode()))
-						rightView.WriteString(fmt.Sprintf("\nModified:   %s", info.ModTime().Format("2006-01-02 15:04:05")))
-
-						// Check for Symlink
-						if info.Mode()&os.ModeSymlink != 0 {
-							target, err := os.Readlink(fullPath)
-							if err == nil {
-								rightView.WriteString(fmt.Sprintf("\n\n🔗 Symlink -> %s", target))
-								// Maybe Stat the target too?
-								if tInfo, err := os.Stat(fullPath); err == nil {
-									rightView.WriteString(fmt.Sprintf("\nTarget Mode: %s", tInfo.Mode()))
-								} else {
-									rightView.WriteString(" (Broken Link)")
-								}
-							}
-						}
+			// How a version manager decides what this entry runs
+			if l := trace.VersionManagerLabel(entry); l != "" {
+				rightView.WriteString(fmt.Sprintf("\n\n--- Version Manager: %s ---", l))
+				for _, line := range trace.ExplainVersionManager(entry) {
+					rightView.WriteString("\n" + line)
+				}
+			}
 
-						// Check Executable
-						// Check bit 0100 (User Exec), 0010 (Group), 0001 (Other)
-						perm := info.Mode().Perm()
-						isExec := (perm&0100) != 0 || (perm&0010) != 0 || (perm&0001) != 0
-						if isExec {
-							rightView.WriteString("\n✅ Executable")
-						} else {
-							rightView.WriteString("\n❌ Not Executable")
-						}
+			// The tool whose eval'd code added this entry
+			if l := trace.HookLabel(entry); l != "" {
+				rightView.WriteString(fmt.Sprintf("\n\n--- Added By: %s ---", l))
+				for _, line := range trace.ExplainHook(entry) {
+					rightView.WriteString("\n" + line)
+				}
+			}
+
+			// Search Match Details
+			if b := m.FoundBinary; m.SearchActive && m.ListingIdx == idx && b != nil {
+				rightView.WriteString("\n\n--- Found Binary ---")
+				rightView.WriteString(fmt.Sprintf("\nName:       %s", highlightMatch(b.Name, m.SearchPositions[idx], lipgloss.NewStyle())))
+				rightView.WriteString(fmt.Sprintf("\nPath:       %s", b.Path))
+				rightView.WriteString(fmt.Sprintf("\nSize:       %d bytes", b.Size))
+				rightView.WriteString(fmt.Sprintf("\nMode:       %s", b.Mode))
+				rightView.WriteString(fmt.Sprintf("\nModified:   %s", b.ModTime.Format("2006-01-02 15:04:05")))
+
+				// Check for Symlink
+				if b.LinkTarget != "" {
+					rightView.WriteString(fmt.Sprintf("\n\n🔗 Symlink -> %s", b.LinkTarget))
+					if b.Broken {
+						rightView.WriteString(" (Broken Link)")
+					} else {
+						rightView.WriteString(fmt.Sprintf("\nTarget Mode: %s", b.TargetMode))
 					}
 				}
+
+				// Check Executable
+				// Check bit 0100 (User Exec), 0010 (Group), 0001 (Other)
+				perm := b.Mode.Perm()
+				isExec := (perm&0100) != 0 || (perm&0010) != 0 || (perm&0001) != 0
+				if isExec {
+					rightView.WriteString("\n✅ Executable")
+				} else {
+					rightView.WriteString("\n❌ Not Executable")
+				}
+			}
+
+			// What the interactive shell itself runs for the search term
+			if sr := m.ShellResolution; m.SearchActive && sr != nil {
+				rightView.WriteString(fmt.Sprintf("\n\n--- Shell Resolution (%s) ---", sr.Resolution.Shell))
+				switch {
+				case sr.Err != nil:
+					rightView.WriteString(fmt.Sprintf("\nCould not ask the shell: %v", sr.Err))
+				case sr.Resolution.Output != "":
+					rightView.WriteString("\n" + sr.Resolution.Output)
+				default:
+					rightView.WriteString(fmt.Sprintf("\n%s: not found", sr.Term))
+				}
+				if sr.Mismatch != "" {
+					rightView.WriteString(adviceStyle.Render("\n⚠️ " + sr.Mismatch))
+				} else if sr.Err == nil {
+					rightView.WriteString("\n" + model.IconOK + " Matches PATH")
+				}
+				if sr.Wrapper != nil {
+					rightView.WriteString("\n↪ " + trace.FormatWrapper(*sr.Wrapper))
+				}
 			}
 
 			if m.ShowDiagnostics {
+				if g, ok := m.TraceResult.DuplicateGroupOf(idx); ok {
+					// Every copy together, rather than this one and the first
+					rightView.WriteString(adviceStyle.Render(fmt.Sprintf("\n\n⚠️ DUPLICATE %s: %d copies, only #%d is searched", model.IconDuplicate, len(g.Copies)+1, g.First+1)))
+					for _, i := range g.Entries() {
+						marker := "  "
+						if i == idx {
+							marker = "▸ "
+						}
+						rightView.WriteString(fmt.Sprintf("\n%s#%-3d %s", marker, i+1, trace.DuplicateSource(m.TraceResult.PathEntries[i])))
+					}
+				}
 				if entry.IsDuplicate {
-					rightView.WriteString(adviceStyle.Render(fmt.Sprintf("\n\n⚠️ DUPLICATE %s detected!\n%s", model.IconDuplicate, entry.DuplicateMessage)))
+					if sl, ok := m.TraceResult.SourceLineOf(idx); ok {
+						if advice := trace.DuplicateLineAdvice(m.TraceResult, sl); advice != "" {
+							rightView.WriteString(adviceStyle.Render("\nAdvice: " + advice))
+						}
+					}
+				} else if _, ok := m.TraceResult.DuplicateGroupOf(idx); ok {
+					rightView.WriteString("\n" + model.IconOK + " This copy is the one searched; the later ones can be removed.")
 				} else if entry.SymlinkPointsTo >= 0 {
 					rightView.WriteString(adviceStyle.Render(fmt.Sprintf("\n\n🔗 SYMLINK %s%s detected\n%s\n\nThis is normal on modern Linux systems.", model.IconDuplicate, model.IconSymlink, entry.SymlinkMessage)))
+				} else if entry.SymlinkBroken != "" {
+					rightView.WriteString(adviceStyle.Render(fmt.Sprintf("\n\n🔗 BROKEN SYMLINK %s\n%s leads nowhere: %s.\n\nFix the link or remove the entry.", model.IconMissing, model.DisplayPath(entry.Value), entry.SymlinkBroken)))
 				} else {
 					rightView.WriteString("\n\n" + model.IconOK + " No issues detected.")
 				}
 			}
 
 			// Stats
-			rightView.WriteString(fmt.Sprintf("\n\nPath Directory Stats:   %d files, %d directories", m.FileCount, m.DirCount))
+			rightView.WriteString(fmt.Sprintf("\n\nPath Directory Stats:   %d files, %d directories", m.DirStats.FileCount, m.DirStats.DirCount))
+			if m.ListingIdx == idx && m.DirStats.FileCount+m.DirStats.DirCount > 0 {
+				rightView.WriteString(fmt.Sprintf("\n                        %d executables, %s total", m.DirStats.Executables, trace.FormatSize(m.DirStats.Size)))
+				rightView.WriteString(fmt.Sprintf("\nContents:               %s", m.DirStats.Contents.Summary()))
+				rightView.WriteString(fmt.Sprintf("\n                        %s", m.DirStats.Contents.Verdict()))
+			}
+
+			// Executables that clash with other PATH entries
+			if len(entry.Shadows) > 0 || len(entry.ShadowedBy) > 0 {
+				rightView.WriteString(fmt.Sprintf("\n\n--- Shadowed Binaries %s ---", model.IconShadow))
+				if len(entry.ShadowedBy) > 0 {
+					rightView.WriteString(adviceStyle.Render(fmt.Sprintf("\n%d hidden by earlier entries:", len(entry.ShadowedBy))))
+					writeLimited(&rightView, entry.ShadowedBy, 8)
+				}
+				if len(entry.Shadows) > 0 {
+					rightView.WriteString(fmt.Sprintf("\n%d hide copies in later entries:", len(entry.Shadows)))
+					writeLimited(&rightView, entry.Shadows, 8)
+				}
+			}
+
+			// What would break if this entry were removed
+			rightView.WriteString("\n\n--- If Removed ---")
+			if m.BinaryIndex != nil && m.BinaryIndex.Partial() {
+				rightView.WriteString(fmt.Sprintf("\n(partial scan: %d/%d directories - results may be incomplete)", m.BinaryIndex.Scanned, len(m.BinaryIndex.ByEntry)))
+			}
+			switch {
+			case m.BinaryIndex == nil && m.Scanning:
+				rightView.WriteString(fmt.Sprintf("\nScanning %d/%d directories…", m.ScanDone, m.ScanTotal))
+			case m.BinaryIndex == nil || idx >= m.BinaryIndex.Scanned:
+				rightView.WriteString("\nUnknown - this entry was not scanned before the scan stopped.")
+			case len(m.RemovalImpact) == 0:
+				rightView.WriteString("\nNo binaries would stop resolving.")
+			default:
+				shown := m.RemovalImpact
+				more := ""
+				if len(shown) > 12 {
+					more = fmt.Sprintf(" (+%d more)", len(shown)-12)
+					shown = shown[:12]
+				}
+				rightView.WriteString(adviceStyle.Render(fmt.Sprintf("\n%d binaries would stop resolving:", len(m.RemovalImpact))))
+				line := " "
+				for _, name := range shown {
+					if len(line)+len(name)+2 > rightWidth-6 {
+						rightView.WriteString("\n" + strings.TrimSuffix(line, ","))
+						line = " "
+					}
+					line += " " + name + ","
+				}
+				rightView.WriteString("\n" + strings.TrimSuffix(line, ",") + more)
+			}
 
 			// Directory Listing
-			if m.DirectoryListing != "" {
+			if m.ListingIdx != idx {
+				rightView.WriteString("\n\n--- Directory Listing ---")
+				rightView.WriteString("\nLoading…")
+			} else if m.DirectoryListing != "" {
 				rightView.WriteString("\n\n--- Directory Listing ---")
 				rightView.WriteString("\n" + m.DirectoryListing)
 			}
 
+		} else if group, ok := m.selectedGroup(); ok {
+			rightView.WriteString(fmt.Sprintf("\nSource:     %s\n", group.Group))
+			for _, idx := range m.FilteredIndices {
+				if e := m.TraceResult.PathEntries[idx]; e.SourceFile == group.Group {
+					rightView.WriteString(fmt.Sprintf("\n  %2d. %s", idx+1, model.DisplayPath(e.Value)))
+					if e.LineNumber > 0 {
+						rightView.WriteString(dimStyle.Render(fmt.Sprintf("  (line %d)", e.LineNumber)))
+					}
+				}
+			}
+			action := "collapse"
+			if m.Collapsed[group.Group] {
+				action = "expand"
+			}
+			rightView.WriteString("\n\n" + dimStyle.Render(fmt.Sprintf("Press %s to %s this group, %s to stop grouping.", quotedLabel(keymap.ToggleGroup), action, quotedLabel(keymap.Group))))
 		} else {
 			rightView.WriteString("\nNo entries found.")
 		}
@@ -748,9 +899,7 @@ func (m AppModel) View() string {
 		visibleLines := lines[startY:endY]
 		var sb strings.Builder
 		for i, line := range visibleLines {
-			if len(line) > rightWidth {
-				line = line[:rightWidth-4] + "..."
-			}
+			line = truncate(line, rightWidth)
 			sb.WriteString(line)
 			if i < len(visibleLines)-1 {
 				sb.WriteString("\n")
@@ -767,25 +916,81 @@ func (m AppModel) View() string {
 		Render(finalRightViewContent)
 
 	// Footer
-	help := "Help: ↑/↓: Navigate • Tab: Switch Panel • d: Diagnostics • f/c: Flow • w: Which • ?: Help • q: Quit"
+	k := keymap
+	help := "Help: " + helpLine(
+		item("Navigate", k.Up, k.Down), item("Switch Panel", k.Focus), item("Diagnostics", k.Diagnostics),
+		item("Executables", k.Executables), item("Fix", k.Fix), item("Copy Dir/Line", k.Copy, k.CopyLine),
+		item("Flow", k.Flow, k.Cumulative), item("Which", k.Which), item("Search All", k.Search),
+		item("Sort/Group", k.Sort, k.Group),
+		helpItem{desc: "Filter", sep: "-", bindings: []key.Binding{k.FilterDuplicates, k.FilterNone}},
+		helpItem{desc: "Panes", sep: " ", bindings: []key.Binding{k.Narrow, k.Widen, k.HideDetails}},
+		item("Re-trace", k.Retrace), item("Help", k.Help), item("Quit", k.Quit))
 	if m.NormalRightFocus && !m.ShowFlow {
-		help = "Details Mode: ↑/↓: Scroll • Tab: Return to Path List • ?: Help • q: Quit"
+		help = "Details Mode: " + helpLine(item("Scroll", k.Up, k.Down), item("Return to Path List", k.Focus), item("Help", k.Help), item("Quit", k.Quit))
 	} else if m.ShowFlow {
-		help = "Flow Mode: ↑/↓: Select Config File • Tab: Switch Focus • f: Return to Path List • c: Toggle Cumulative • ?: Help • q: Quit"
+		help = "Flow Mode: " + helpLine(item("Select Config File", k.Up, k.Down), item("Switch Focus", k.Focus), item("Return to Path List", k.Flow),
+			item("Toggle Cumulative", k.Cumulative), item("Help", k.Help), item("Quit", k.Quit))
+		if m.RightPanelFocus == FocusPathList {
+			help = "Flow Mode: " + helpLine(item("Select Entry (the preview shows the line that added it)", k.Up, k.Down), item("Switch Focus", k.Focus),
+				item("Return to Path List", k.Flow), item("Help", k.Help), item("Quit", k.Quit))
+		}
 	}
 
-	footer := "\n\n" + help
+	if m.Scanning {
+		help = fmt.Sprintf("Scanning %d/%d directories… (%s to stop) • ", m.ScanDone, m.ScanTotal, bindingLabel(keymap.Back)) + help
+	}
+	if m.Retracing {
+		stage := "Re-tracing…"
+		if m.TraceStage != "" {
+			stage = "Re-tracing: " + m.TraceStage
+		}
+		help = m.Spinner.View() + stage + " • " + help // The spinner's frames end in a space
+	}
+	if m.RetraceError != "" {
+		help = m.RetraceError + " • " + help
+	}
+	if m.WatchStatus != "" {
+		help = m.WatchStatus + " • " + help
+	}
+	if m.CopyStatus != "" {
+		help = m.CopyStatus + " • " + help
+	}
+	if m.LayoutStatus != "" {
+		help = m.LayoutStatus + " • " + help
+	}
+
+	// One line, however many keys there are, so the panels keep their height
+	footer := "\n\n" + truncate(help, width)
 	if m.InputMode {
-		footer = fmt.Sprintf("\n\nSearch: %s", m.InputBuffer.View())
+		prompt := "Search"
+		if m.GlobalSearch {
+			prompt = "Search all executables"
+		}
+		footer = "\n\n" + truncate(fmt.Sprintf("%s (%s, Tab to change): %s", prompt, searchModeNames[m.SearchMode], m.InputBuffer.View()), width)
+	} else if tour != "" {
+		footer = "\n" + tour
 	}
 
-	mainView := lipgloss.JoinHorizontal(lipgloss.Top, left, right) + footer
+	panes := lipgloss.JoinHorizontal(lipgloss.Top, left, right)
+	if m.rightHidden() {
+		panes = left
+	}
+	mainView := panes + footer
 	if m.ShowHelp {
 		return m.renderHelpDialog()
 	}
 	if m.ShowDiagnosticsPopup {
 		return m.renderDiagnosticsPopup()
 	}
+	if m.ShowFixPopup {
+		return m.renderFixPopup()
+	}
+	if m.ShowExplorer {
+		return m.renderExplorerPopup()
+	}
+	if m.ShowResults {
+		return m.renderResultsPopup()
+	}
 	return mainView
 }
 
@@ -828,14 +1033,17 @@ func (m *AppModel) renderDiagnosticsPopup() string {
 	content := strings.Join(visibleLines, "\n")
 
 	title := titleStyle.Render("Global Diagnostics Report")
-	footer := lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render(
-		"\nPress 's' to save, 'v' for verbose, 'd'/Esc to close")
+	footerText := fmt.Sprintf("\nPress %s to save, %s for verbose, %s to close", quotedLabel(keymap.Save), quotedLabel(keymap.Verbose), closeLabel(keymap.Diagnostics))
+	if m.ReportStatus != "" {
+		footerText += "  •  " + m.ReportStatus
+	}
+	footer := lipgloss.NewStyle().Foreground(theme.Footer).Render(footerText)
 
 	dialog := lipgloss.NewStyle().
 		Width(popupWidth).
 		Height(popupHeight).
 		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("208")). // Orange
+		BorderForeground(theme.Advice).
 		Padding(0, 1).
 		Render(title + "\n\n" + content + footer)
 
@@ -845,25 +1053,310 @@ func (m *AppModel) renderDiagnosticsPopup() string {
 	)
 }
 
-func (m *AppModel) renderHelpDialog() string {
+func (m *AppModel) renderFixPopup() string {
+	w, h := m.WindowSize.Width, m.WindowSize.Height
+	if w < 20 || h < 10 {
+		return "Window too small"
+	}
+
+	popupWidth := w * 90 / 100
+	if popupWidth < 40 {
+		popupWidth = 40
+	}
+	if popupWidth > w-4 {
+		popupWidth = w - 4
+	}
+	popupHeight := h - 6
+	if popupHeight < 5 {
+		popupHeight = 5
+	}
+
+	lines := strings.Split(m.FixText, "\n")
+	contentHeight := popupHeight - 4 // minus border and footer
+
+	startY := m.FixScrollY
+	if startY > len(lines)-contentHeight {
+		startY = len(lines) - contentHeight
+	}
+	if startY < 0 {
+		startY = 0
+	}
+	endY := startY + contentHeight
+	if endY > len(lines) {
+		endY = len(lines)
+	}
+
+	var content strings.Builder
+	for i, l := range lines[startY:endY] {
+		if i > 0 {
+			content.WriteString("\n")
+		}
+		switch {
+		case strings.HasPrefix(l, "+") && !strings.HasPrefix(l, "+++"):
+			content.WriteString(lipgloss.NewStyle().Foreground(theme.Added).Render(l))
+		case strings.HasPrefix(l, "-") && !strings.HasPrefix(l, "---"):
+			content.WriteString(lipgloss.NewStyle().Foreground(theme.Removed).Render(l))
+		default:
+			content.WriteString(l)
+		}
+	}
+
+	title := titleStyle.Render("Fix Duplicate PATH Lines and Pins")
+	footerText := fmt.Sprintf("\nPress %s to apply (files are backed up first), %s to cancel", quotedLabel(keymap.Apply), closeLabel(keymap.Fix, keymap.Cancel))
+	if m.FixApplied || len(m.FixChanges) == 0 {
+		footerText = fmt.Sprintf("\nPress %s to close", closeLabel(keymap.Fix))
+	}
+	if m.FixStatus != "" {
+		footerText += "  •  " + m.FixStatus
+	}
+	footer := lipgloss.NewStyle().Foreground(theme.Footer).Render(footerText)
+
+	dialog := lipgloss.NewStyle().
+		Width(popupWidth).
+		Height(popupHeight).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(theme.Advice).
+		Padding(0, 1).
+		Render(title + "\n\n" + content.String() + footer)
+
+	return lipgloss.Place(w, h,
+		lipgloss.Center, lipgloss.Center,
+		dialog,
+	)
+}
+
+func (m *AppModel) renderExplorerPopup() string {
 	w, h := m.WindowSize.Width, m.WindowSize.Height
 	if w < 20 || h < 10 {
 		return "Window too small"
 	}
 
-	helpWidth := w * 80 / 100
-	if helpWidth < 40 {
-		helpWidth = 40
+	popupWidth := w * 90 / 100
+	if popupWidth < 40 {
+		popupWidth = 40
+	}
+	if popupWidth > w-4 {
+		popupWidth = w - 4
 	}
-	if helpWidth > w-4 {
-		helpWidth = w - 4
+	popupHeight := h - 6
+	if popupHeight < 5 {
+		popupHeight = 5
 	}
-	helpHeight := h - 6
-	if helpHeight < 5 {
-		helpHeight = 5
+
+	entries := m.TraceResult.PathEntries
+	idx := m.ExplorerEntry
+	where := func(i int) string {
+		return fmt.Sprintf("#%d %s", i+1, model.DisplayPath(entries[i].Value))
 	}
 
-	lines := strings.Split(m.HelpContent, "\n")
+	var heading string
+	var content strings.Builder
+	switch {
+	case m.BinaryIndex == nil && m.Scanning:
+		heading = fmt.Sprintf("Scanning %d/%d directories…", m.ScanDone, m.ScanTotal)
+	case m.BinaryIndex == nil || idx >= m.BinaryIndex.Scanned:
+		heading = "Unknown - this entry was not scanned before the scan stopped."
+	case len(m.ExplorerRows) == 0:
+		heading = "No executables in this directory."
+	default:
+		wins, shadowed := 0, 0
+		nameWidth := 0
+		for _, x := range m.ExplorerRows {
+			switch {
+			case x.Winner == idx:
+				wins++
+			case x.Shadowed(idx):
+				shadowed++
+			}
+			nameWidth = max(nameWidth, len(x.Name))
+		}
+		nameWidth = min(nameWidth, 28)
+		heading = fmt.Sprintf("%d executables: %d run from here, %d shadowed by earlier entries", len(m.ExplorerRows), wins, shadowed)
+		if same := len(m.ExplorerRows) - wins - shadowed; same > 0 {
+			heading += fmt.Sprintf(", %d the same file as an earlier entry's", same)
+		}
+
+		end := min(m.ExplorerScrollY+m.explorerHeight(), len(m.ExplorerRows))
+		for i := m.ExplorerScrollY; i < end; i++ {
+			x := m.ExplorerRows[i]
+			icon, note := "✓", "runs from here"
+			style := lipgloss.NewStyle()
+			switch {
+			case x.Winner == idx && len(x.Hides) > 0:
+				var hidden []string
+				for _, l := range x.Hides {
+					hidden = append(hidden, where(l))
+				}
+				note = "runs from here, hides " + strings.Join(hidden, ", ")
+			case x.SameFile:
+				icon, note = model.IconDuplicate, "same file as "+where(x.Winner)
+				style = dimStyle
+			case x.Shadowed(idx):
+				icon, note = model.IconShadow, "shadowed by "+where(x.Winner)
+				style = adviceStyle
+			}
+			line := fmt.Sprintf("%s %-*s  %s", icon, nameWidth, x.Name, note)
+			if max(popupWidth-4, 10) < len([]rune(line)) {
+				line = string([]rune(line)[:max(popupWidth-5, 9)]) + "…"
+			}
+			if i > m.ExplorerScrollY {
+				content.WriteString("\n")
+			}
+			if i == m.ExplorerSel {
+				content.WriteString(selectedItemStyle.PaddingLeft(0).Render(line))
+			} else {
+				content.WriteString(style.Render(line))
+			}
+		}
+	}
+	if m.BinaryIndex != nil && m.BinaryIndex.Partial() {
+		heading += fmt.Sprintf(" (partial scan: %d/%d directories)", m.BinaryIndex.Scanned, len(m.BinaryIndex.ByEntry))
+	}
+
+	title := titleStyle.Render("Executables in " + where(idx))
+	footerText := fmt.Sprintf("\nEnter: jump to the shadowing entry (or the first hidden copy)  •  %s to close", closeLabel(keymap.Executables))
+	footer := lipgloss.NewStyle().Foreground(theme.Footer).Render(footerText)
+
+	dialog := lipgloss.NewStyle().
+		Width(popupWidth).
+		Height(popupHeight).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(theme.Advice).
+		Padding(0, 1).
+		Render(title + "\n\n" + heading + "\n\n" + content.String() + footer)
+
+	return lipgloss.Place(w, h,
+		lipgloss.Center, lipgloss.Center,
+		dialog,
+	)
+}
+
+func (m *AppModel) renderResultsPopup() string {
+	w, h := m.WindowSize.Width, m.WindowSize.Height
+	if w < 20 || h < 10 {
+		return "Window too small"
+	}
+
+	popupWidth := w * 90 / 100
+	if popupWidth < 40 {
+		popupWidth = 40
+	}
+	if popupWidth > w-4 {
+		popupWidth = w - 4
+	}
+	popupHeight := h - 6
+	if popupHeight < 5 {
+		popupHeight = 5
+	}
+
+	var heading string
+	var content strings.Builder
+	switch {
+	case m.ResultsLoading:
+		heading = "Searching…"
+	case len(m.ResultHits) == 0:
+		heading = "No executables match."
+	default:
+		runs, shadowed := 0, 0
+		nameWidth := 0
+		for _, x := range m.ResultHits {
+			switch {
+			case x.Winner == x.Entry:
+				runs++
+			case !x.SameFile:
+				shadowed++
+			}
+			nameWidth = max(nameWidth, len([]rune(x.Name)))
+		}
+		nameWidth = min(nameWidth, 24)
+		heading = fmt.Sprintf("%d executables: %d run, %d shadowed by earlier entries", len(m.ResultHits), runs, shadowed)
+		if same := len(m.ResultHits) - runs - shadowed; same > 0 {
+			heading += fmt.Sprintf(", %d the same file as an earlier entry's", same)
+		}
+
+		end := min(m.ResultScrollY+m.explorerHeight(), len(m.ResultHits))
+		for i := m.ResultScrollY; i < end; i++ {
+			x := m.ResultHits[i]
+			icon, status := "✓", "runs"
+			style := lipgloss.NewStyle()
+			switch {
+			case x.SameFile:
+				icon, status = model.IconDuplicate, fmt.Sprintf("same file as #%d", x.Winner+1)
+				style = dimStyle
+			case x.Winner != x.Entry:
+				icon, status = model.IconShadow, fmt.Sprintf("shadowed by #%d", x.Winner+1)
+				style = adviceStyle
+			}
+			name := []rune(x.Name)
+			if len(name) > nameWidth {
+				name = append(name[:nameWidth-1], '…')
+			}
+			pad := strings.Repeat(" ", nameWidth-len(name))
+			rest := fmt.Sprintf("  %9s  %s  %-18s  %s", trace.FormatSize(x.Size), x.ModTime.Format("2006-01-02 15:04"), status, x.Path)
+			if room := max(popupWidth-4-2-nameWidth, 10); len([]rune(rest)) > room {
+				rest = string([]rune(rest)[:room-1]) + "…"
+			}
+			if i > m.ResultScrollY {
+				content.WriteString("\n")
+			}
+			if i == m.ResultSel {
+				content.WriteString(selectedItemStyle.PaddingLeft(0).Render(icon + " " + string(name) + pad + rest))
+			} else {
+				content.WriteString(style.Render(icon+" ") + highlightMatch(string(name), x.Positions, style) + style.Render(pad+rest))
+			}
+		}
+	}
+
+	title := titleStyle.Render(fmt.Sprintf("Executables matching %q (%s)", m.ResultsTerm, searchModeNames[m.SearchMode]))
+	footerText := fmt.Sprintf("\nEnter: select the entry it is in  •  %s to close", closeLabel(keymap.Search))
+	footer := lipgloss.NewStyle().Foreground(theme.Footer).Render(footerText)
+
+	dialog := lipgloss.NewStyle().
+		Width(popupWidth).
+		Height(popupHeight).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(theme.Advice).
+		Padding(0, 1).
+		Render(title + "\n\n" + heading + "\n\n" + content.String() + footer)
+
+	return lipgloss.Place(w, h,
+		lipgloss.Center, lipgloss.Center,
+		dialog,
+	)
+}
+
+// helpSize returns the help dialog's size inside its border.
+func (m AppModel) helpSize() (width, height int) {
+	w, h := m.WindowSize.Width, m.WindowSize.Height
+	width = w * 80 / 100
+	if width < 40 {
+		width = 40
+	}
+	if width > w-4 {
+		width = w - 4
+	}
+	height = h - 6
+	if height < 5 {
+		height = 5
+	}
+	return width, height
+}
+
+// helpLines returns the help wrapped to the dialog's width, so scrolling
+// counts the lines it shows.
+func (m AppModel) helpLines() []string {
+	width, _ := m.helpSize()
+	return strings.Split(lipgloss.NewStyle().Width(width-2).Render(m.HelpContent), "\n")
+}
+
+func (m *AppModel) renderHelpDialog() string {
+	w, h := m.WindowSize.Width, m.WindowSize.Height
+	if w < 20 || h < 10 {
+		return "Window too small"
+	}
+
+	helpWidth, helpHeight := m.helpSize()
+	lines := m.helpLines()
 	// Adjust height for title and border
 	contentHeight := helpHeight - 2
 
@@ -888,7 +1381,7 @@ func (m *AppModel) renderHelpDialog() string {
 		Width(helpWidth).
 		Height(helpHeight).
 		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("63")).
+		BorderForeground(theme.Border).
 		Padding(0, 1).
 		Render(content)
 
@@ -899,5 +1392,33 @@ func (m *AppModel) renderHelpDialog() string {
 }
 
 func (m AppModel) Init() tea.Cmd {
-	return tea.Batch(textinput.Blink, InitTraceCmd())
+	if m.Preloaded != nil {
+		res := *m.Preloaded
+		return tea.Batch(textinput.Blink, func() tea.Msg { return MsgTraceReady(res) })
+	}
+	return tea.Batch(textinput.Blink, InitTraceCmd(m.TraceOptions), m.Spinner.Tick)
+}
+
+// writeLimited writes up to limit items, one per line, noting how many were left out.
+func writeLimited(sb *strings.Builder, items []string, limit int) {
+	for i, item := range items {
+		if i == limit {
+			sb.WriteString(fmt.Sprintf("\n  … +%d more", len(items)-limit))
+			break
+		}
+		sb.WriteString("\n  " + item)
+	}
+}
+
+// confidenceLine describes how sure the attribution of entry is; the
+// reason is only shown when the attribution relies on a heuristic.
+func confidenceLine(entry model.PathEntry) string {
+	if entry.Confidence == "" {
+		return ""
+	}
+	line := fmt.Sprintf("\nConfidence: %s", entry.Confidence)
+	if entry.Confidence != model.ConfidenceHigh {
+		line += " - " + entry.ConfidenceReason
+	}
+	return line
 }
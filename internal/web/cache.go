@@ -0,0 +1,109 @@
+package web
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"lspath/internal/model"
+	"lspath/internal/trace"
+)
+
+// ResultTTL is how long the API answers from the last analysis before
+// tracing the shell again. Tracing starts a login shell, which takes a
+// second or two with a heavy framework, so page loads and searches share
+// one trace; /api/refresh and config file changes (see live.go) replace it
+// sooner.
+const ResultTTL = 30 * time.Second
+
+// lastResult is the analysis most recently sent to the browser. Entry
+// indices in API requests refer to the analysis named by the request's
+// result ID, which is checked against it (see sameResult).
+var (
+	lastResult   *model.AnalysisResult
+	lastResultAt time.Time
+	lastResultMu sync.Mutex
+)
+
+// analysisMu lets one request trace at a time, so requests that arrive
+// together wait for the same trace rather than each starting their own.
+var analysisMu sync.Mutex
+
+// setLastResult records the analysis the browser is looking at.
+func setLastResult(res model.AnalysisResult) {
+	lastResultMu.Lock()
+	defer lastResultMu.Unlock()
+	lastResult = &res
+	lastResultAt = time.Now()
+}
+
+// invalidateResult makes the next request trace again, e.g. after a fix
+// changed a config file.
+func invalidateResult() {
+	lastResultMu.Lock()
+	defer lastResultMu.Unlock()
+	lastResultAt = time.Time{}
+}
+
+// currentResult returns the analysis to serve: the saved one if the server
+// was started with one, else the last analysis if it is younger than
+// ResultTTL, else a new one.
+func currentResult() (model.AnalysisResult, error) {
+	if fixedResult != nil {
+		return *fixedResult, nil
+	}
+	analysisMu.Lock()
+	defer analysisMu.Unlock()
+
+	lastResultMu.Lock()
+	res, fresh := lastResult, time.Since(lastResultAt) < ResultTTL
+	lastResultMu.Unlock()
+	if res != nil && fresh {
+		return *res, nil
+	}
+	result, err := trace.RunAnalysis(traceOptions)
+	if err != nil {
+		return result, err
+	}
+	setLastResult(result)
+	return result, nil
+}
+
+// resultID identifies an analysis by its PATH entries, so a re-trace that
+// finds the same PATH keeps its ID and the browser's indices stay valid.
+func resultID(res model.AnalysisResult) string {
+	data, _ := json.Marshal(res.PathEntries)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// sameResult reports whether id, as sent back by the browser, names res.
+// If not, it answers 409 Conflict: the request's entry indices refer to an
+// analysis that has since been replaced.
+func sameResult(w http.ResponseWriter, res *model.AnalysisResult, id string) bool {
+	if res == nil || id != resultID(*res) {
+		http.Error(w, "the analysis has changed since this page loaded; reload it", http.StatusConflict)
+		return false
+	}
+	return true
+}
+
+// handleRefresh traces again whatever the cache holds and returns the new
+// analysis as /api/trace does.
+func handleRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	invalidateResult()
+	result, err := currentResult()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newTraceReply(result, r))
+}
@@ -0,0 +1,148 @@
+package web
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"lspath/internal/fix"
+	"lspath/internal/model"
+)
+
+// fixToken must accompany every POST /api/fix. It is only handed out in the
+// /api/trace response, which other origins cannot read, and the custom
+// header it travels in cannot be sent cross-origin without a preflight this
+// server never approves.
+var fixToken = newFixToken()
+
+// fixTokenHeader carries fixToken.
+const fixTokenHeader = "X-Lspath-Token"
+
+func newFixToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// isLocalRequest reports whether r comes from this machine and names it as
+// localhost, which also defeats DNS rebinding.
+func isLocalRequest(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return false
+	}
+	name := r.Host
+	if h, _, err := net.SplitHostPort(r.Host); err == nil {
+		name = h
+	}
+	name = strings.Trim(name, "[]")
+	if name == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(name)
+	return ip != nil && ip.IsLoopback()
+}
+
+// FixRequest asks to comment out the config line that added a duplicate.
+type FixRequest struct {
+	Index    int    `json:"Index"`    // PATH entry index in the /api/trace result ResultID names
+	ResultID string `json:"ResultID"` // From that /api/trace result
+	DryRun   bool   `json:"DryRun"`   // Only return the diff
+}
+
+// FixResponse describes the proposed or applied change.
+type FixResponse struct {
+	File    string `json:"File"`
+	Line    int    `json:"Line"`
+	Reason  string `json:"Reason"`
+	Diff    string `json:"Diff"`
+	Backup  string `json:"Backup,omitempty"` // Set once applied
+	Applied bool   `json:"Applied"`
+}
+
+// handleFix comments out the line behind a duplicate entry, using the same
+// checks as --fix: only lines that add nothing but duplicates qualify, and
+// the line must be unchanged since the trace. The file is backed up first.
+func handleFix(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	if !isLocalRequest(r) {
+		http.Error(w, "fixes can only be applied from this machine via localhost", http.StatusForbidden)
+		return
+	}
+	if r.Header.Get(fixTokenHeader) != fixToken {
+		http.Error(w, "missing or invalid token; reload the page", http.StatusForbidden)
+		return
+	}
+	if fixedResult != nil {
+		http.Error(w, "this analysis was loaded from a bundle and is read-only", http.StatusForbidden)
+		return
+	}
+
+	var req FixRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request: "+err.Error(), 400)
+		return
+	}
+	lastResultMu.Lock()
+	res := lastResult
+	lastResultMu.Unlock()
+	if !sameResult(w, res, req.ResultID) {
+		return
+	}
+	if req.Index < 0 || req.Index >= len(res.PathEntries) {
+		http.Error(w, "index out of range; reload the page", 400)
+		return
+	}
+
+	e := res.PathEntries[req.Index]
+	edits, notes := fix.DuplicateRemovals(*res)
+	var edit *fix.Edit
+	for i := range edits {
+		if model.ExpandTilde(edits[i].File) == model.ExpandTilde(e.SourceFile) && edits[i].Line == e.LineNumber {
+			edit = &edits[i]
+			break
+		}
+	}
+	if edit == nil {
+		msg := "this entry's line cannot be fixed automatically"
+		for _, n := range notes {
+			if strings.HasPrefix(n, fmt.Sprintf("%s:%d ", e.SourceFile, e.LineNumber)) {
+				msg = n
+				break
+			}
+		}
+		http.Error(w, msg, http.StatusConflict)
+		return
+	}
+	edit.Action = fix.ActionCommentOut
+
+	changes, err := fix.Plan([]fix.Edit{*edit})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	resp := FixResponse{File: changes[0].File, Line: edit.Line, Reason: edit.Reason, Diff: changes[0].Diff()}
+	if !req.DryRun {
+		backup, err := fix.ApplyChange(changes[0])
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		resp.Backup = backup
+		resp.Applied = true
+		invalidateResult()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,106 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"syscall"
+)
+
+// DefaultPort is tried first when no port is given.
+const DefaultPort = 8080
+
+// DefaultBind keeps the server (which can show and edit config files)
+// private to this machine.
+const DefaultBind = "localhost"
+
+// Config controls where the server listens.
+type Config struct {
+	Port        int    // 0 picks a free port
+	Bind        string // Address or host name to listen on; empty means DefaultBind
+	BindUnsafe  bool   // Allow a Bind other machines can reach
+	FixedPort   bool   // Fail rather than fall back when Port is taken
+	OpenBrowser bool   // Open the URL in the default browser once listening
+}
+
+// listen opens the listener for cfg. If the port is taken and the user did
+// not ask for it explicitly, a random free port is used instead. Binds other
+// machines can reach are refused unless cfg.BindUnsafe: the server has no
+// login and shows any file or directory it is asked for.
+func listen(cfg Config) (net.Listener, error) {
+	bind := cfg.Bind
+	if bind == "" {
+		bind = DefaultBind
+	}
+	loopback, err := isLoopbackBind(bind)
+	if err != nil {
+		return nil, err
+	}
+	if !loopback {
+		if !cfg.BindUnsafe {
+			return nil, fmt.Errorf("refusing to listen on %s: other machines could read any file on this one through the web UI, which has no login (add --bind-unsafe to do it anyway)", bind)
+		}
+		fmt.Printf("WARNING: listening on %s. Anyone who can reach it can read any file and directory on this machine through the web UI.\n", bind)
+	}
+	ln, err := net.Listen("tcp", net.JoinHostPort(bind, strconv.Itoa(cfg.Port)))
+	if errors.Is(err, syscall.EADDRINUSE) && !cfg.FixedPort && cfg.Port != 0 {
+		fmt.Printf("Port %d is in use; picking a free port instead.\n", cfg.Port)
+		ln, err = net.Listen("tcp", net.JoinHostPort(bind, "0"))
+	}
+	return ln, err
+}
+
+// isLoopbackBind reports whether bind, an address or host name, only
+// listens on this machine's loopback interface.
+func isLoopbackBind(bind string) (bool, error) {
+	if bind == "localhost" {
+		return true, nil
+	}
+	if ip := net.ParseIP(bind); ip != nil {
+		return ip.IsLoopback(), nil
+	}
+	ips, err := net.LookupIP(bind)
+	if err != nil {
+		return false, err
+	}
+	for _, ip := range ips {
+		if !ip.IsLoopback() {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// serverURL is the address to browse to for a listener on addr. Wildcard
+// binds are reached through localhost.
+func serverURL(addr net.Addr) string {
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return "http://" + addr.String()
+	}
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() || ip.IsLoopback() {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+// openBrowser opens url in the default browser without waiting for it.
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
@@ -0,0 +1,151 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"lspath/internal/model"
+	"lspath/internal/trace"
+
+	"golang.org/x/net/websocket"
+)
+
+// liveMessage is pushed to the browser over /api/ws.
+type liveMessage struct {
+	Type   string      // "changed" when a config file changes, then "result" or "error"
+	File   string      `json:",omitempty"` // changed: the file
+	Error  string      `json:",omitempty"` // error: why re-analysis failed
+	Result *traceReply `json:",omitempty"` // result: the new analysis, as /api/trace returns it
+}
+
+// liveHub watches the traced config files while at least one browser is
+// connected, re-analyzes when one changes and sends every browser the
+// result, so one trace serves them all.
+type liveHub struct {
+	mu      sync.Mutex
+	clients map[chan liveMessage]*http.Request
+	cancel  context.CancelFunc
+}
+
+var hub = &liveHub{clients: make(map[chan liveMessage]*http.Request)}
+
+// liveServer upgrades /api/ws. Only pages this server served may connect:
+// unlike fetch, a WebSocket from another site is not stopped by the browser,
+// so without the origin check any page could read the analysis.
+var liveServer = websocket.Server{
+	Handshake: func(cfg *websocket.Config, r *http.Request) error {
+		origin, err := websocket.Origin(cfg, r)
+		if err != nil || origin == nil || origin.Host != r.Host {
+			return fmt.Errorf("cross-origin WebSocket refused")
+		}
+		cfg.Origin = origin
+		return nil
+	},
+	Handler: handleLive,
+}
+
+// handleLive sends the browser a message whenever the analysis changes,
+// until it disconnects. A saved analysis never changes, so it gets none.
+func handleLive(ws *websocket.Conn) {
+	defer ws.Close()
+	if fixedResult != nil {
+		return
+	}
+	ch := hub.join(ws.Request())
+	defer hub.leave(ch)
+
+	closed := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, ws) // Returns when the browser goes away
+		close(closed)
+	}()
+	for {
+		select {
+		case msg := <-ch:
+			if websocket.JSON.Send(ws, msg) != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// join adds a browser, starting the watch for the first one.
+func (h *liveHub) join(r *http.Request) chan liveMessage {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch := make(chan liveMessage, 4)
+	h.clients[ch] = r
+	if h.cancel == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		h.cancel = cancel
+		go h.watch(ctx)
+	}
+	return ch
+}
+
+// leave removes a browser, stopping the watch after the last one.
+func (h *liveHub) leave(ch chan liveMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.clients, ch)
+	if len(h.clients) == 0 && h.cancel != nil {
+		h.cancel()
+		h.cancel = nil
+	}
+}
+
+// broadcast sends msg to every browser, with a fix token in results only
+// for those allowed to fix. A browser too slow to keep up misses the
+// message; the next result replaces it anyway.
+func (h *liveHub) broadcast(msg liveMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch, r := range h.clients {
+		m := msg
+		if msg.Result != nil {
+			reply := *msg.Result
+			if !isLocalRequest(r) {
+				reply.FixToken = ""
+			}
+			m.Result = &reply
+		}
+		select {
+		case ch <- m:
+		default:
+		}
+	}
+}
+
+// watch re-analyzes whenever a config file behind the last analysis
+// changes, until ctx is done.
+func (h *liveHub) watch(ctx context.Context) {
+	res, _ := currentResult()
+
+	for {
+		file, err := trace.WaitForChange(ctx, trace.ConfigFiles(res))
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			h.broadcast(liveMessage{Type: "error", Error: "Watch stopped: " + err.Error()})
+			return
+		}
+		h.broadcast(liveMessage{Type: "changed", File: model.DisplayPath(file)})
+
+		invalidateResult()
+		next, err := currentResult()
+		if err != nil {
+			h.broadcast(liveMessage{Type: "error", Error: err.Error()})
+			continue
+		}
+		res = next
+		reply := newTraceReply(res, nil)
+		reply.FixToken = fixToken
+		h.broadcast(liveMessage{Type: "result", Result: &reply})
+	}
+}
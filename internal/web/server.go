@@ -1,6 +1,7 @@
 package web
 
 import (
+	"context"
 	"embed"
 	"encoding/json"
 	"fmt"
@@ -8,37 +9,32 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"path/filepath"
 
+	"lspath/internal/help"
 	"lspath/internal/model"
 	"lspath/internal/trace"
 	"strings"
 )
 
-// expandTilde expands ~ to the user's home directory
-func expandTilde(path string) string {
-	if strings.HasPrefix(path, "~/") {
-		home, err := os.UserHomeDir()
-		if err == nil {
-			return filepath.Join(home, path[2:])
-		}
-	} else if path == "~" {
-		home, err := os.UserHomeDir()
-		if err == nil {
-			return home
-		}
-	}
-	return path
-}
-
 //go:embed static/*
 var staticFS embed.FS
 
-//go:embed help.md
-var helpMD string
+// traceOptions are the analysis settings the server was started with.
+var traceOptions trace.Options
+
+// fixedResult, if set, is served instead of running a new analysis.
+var fixedResult *model.AnalysisResult
+
+// StartServerWithResult serves res, e.g. from a bundle, instead of tracing.
+func StartServerWithResult(res model.AnalysisResult, cfg Config) {
+	fixedResult = &res
+	StartServer(trace.Options{Var: res.VariableName()}, cfg)
+}
+
+// StartServer starts the web server as configured by cfg and prints its URL.
+func StartServer(opts trace.Options, cfg Config) {
+	traceOptions = opts
 
-// StartServer starts the web server on the given port (or default 8080).
-func StartServer() {
 	mux := http.NewServeMux()
 
 	// Serve static files
@@ -50,65 +46,70 @@ func StartServer() {
 	mux.HandleFunc("/api/file", handleFile)
 	mux.HandleFunc("/api/line-context", handleLineContext)
 	mux.HandleFunc("/api/ls", handleLs)
+	mux.HandleFunc("/api/contents", handleContents)
 	mux.HandleFunc("/api/which", handleWhich)
+	mux.HandleFunc("/api/impact", handleImpact)
+	mux.HandleFunc("/api/fix", handleFix)
 	mux.HandleFunc("/api/help", handleHelp)
+	mux.HandleFunc("/api/refresh", handleRefresh)
+	mux.Handle("/api/ws", liveServer)
 
-	port := "8080"
-	fmt.Printf("Starting lspath web server at http://localhost:%s\n", port)
-	fmt.Printf("Go to http://localhost:%s in your browser.\n", port)
+	ln, err := listen(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	url := serverURL(ln.Addr())
+	fmt.Printf("Starting lspath web server at %s\n", url)
+	if cfg.OpenBrowser {
+		if err := openBrowser(url); err != nil {
+			fmt.Printf("Could not open a browser (%v).\n", err)
+		}
+	}
+	fmt.Printf("Go to %s in your browser.\n", url)
 
-	if err := http.ListenAndServe(":"+port, mux); err != nil {
+	if err := http.Serve(ln, mux); err != nil {
 		log.Fatal(err)
 	}
 }
 
 func handleTrace(w http.ResponseWriter, r *http.Request) {
-	sessionPath := os.Getenv("PATH")
-
-	// Run shell trace to find config file sources
-	shell := trace.DetectShell(os.Getenv("SHELL"))
-	stderr, err := trace.RunTrace(shell, trace.SandboxInitialPath)
+	result, err := currentResult()
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 		return
 	}
-	defer stderr.Close()
-
-	parser := trace.NewParser(shell)
-	events, errs := parser.Parse(stderr)
-
-	var allEvents []model.TraceEvent
-	for ev := range events {
-		allEvents = append(allEvents, ev)
-	}
 
-	go func() {
-		for range errs {
-		}
-	}()
-
-	// Unified analysis: merge trace results with session PATH
-	analyzer := trace.NewAnalyzer()
-	result := analyzer.AnalyzeUnified(sessionPath, allEvents)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newTraceReply(result, r))
+}
 
-	// Generate reports for web view
-	report := trace.GenerateReport(result, false)
-	verboseReport := trace.GenerateReport(result, true)
+// traceReply is what /api/trace returns, and what /api/ws pushes when the
+// analysis changes.
+type traceReply struct {
+	model.Document
+	Report        string `json:"Report"`
+	VerboseReport string `json:"VerboseReport"`
+	Version       string `json:"Version"`
+	ResultID      string `json:"ResultID"`           // Sent back with entry indices; see resultID
+	FixToken      string `json:"FixToken,omitempty"` // For POST /api/fix; empty when read-only
+	Live          bool   `json:"Live"`               // /api/ws pushes updates; false for a saved analysis
+}
 
-	response := struct {
-		model.AnalysisResult
-		Report        string `json:"Report"`
-		VerboseReport string `json:"VerboseReport"`
-		Version       string `json:"Version"`
-	}{
-		AnalysisResult: result,
-		Report:         report,
-		VerboseReport:  verboseReport,
-		Version:        model.Version,
+// newTraceReply packages result, with reports for the web view, for the
+// browser that sent r (nil for none, which gets no fix token).
+func newTraceReply(result model.AnalysisResult, r *http.Request) traceReply {
+	reply := traceReply{
+		Document:      model.NewDocument(result),
+		Report:        trace.GenerateReport(result, false),
+		VerboseReport: trace.GenerateReport(result, true),
+		Version:       model.Version,
+		ResultID:      resultID(result),
+		Live:          fixedResult == nil,
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	if fixedResult == nil && r != nil && isLocalRequest(r) {
+		reply.FixToken = fixToken
+	}
+	return reply
 }
 
 func handleFile(w http.ResponseWriter, r *http.Request) {
@@ -160,6 +161,7 @@ type LsEntry struct {
 	Size    int64  `json:"Size"`
 	Mode    string `json:"Mode"`
 	ModTime string `json:"ModTime"`
+	Kind    string `json:"Kind"` // One of the trace.Kind* constants
 }
 
 func handleLs(w http.ResponseWriter, r *http.Request) {
@@ -168,26 +170,27 @@ func handleLs(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "path is required", 400)
 		return
 	}
-	path = expandTilde(path)
+	path = model.ExpandTilde(path)
 
-	files, err := os.ReadDir(path)
+	stats, err := trace.StatDir(path)
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 		return
 	}
 
 	var entries []LsEntry
-	for _, f := range files {
-		info, err := f.Info()
-		if err != nil {
+	for _, f := range stats.Files {
+		info := f.Info
+		if info == nil {
 			continue
 		}
 		entries = append(entries, LsEntry{
-			Name:    f.Name(),
-			IsDir:   f.IsDir(),
+			Name:    f.Entry.Name(),
+			IsDir:   f.Entry.IsDir(),
 			Size:    info.Size(),
 			Mode:    info.Mode().String(),
 			ModTime: info.ModTime().Format("Jan 02 15:04"),
+			Kind:    f.Kind,
 		})
 	}
 
@@ -195,6 +198,39 @@ func handleLs(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(entries)
 }
 
+// handleContents summarizes the kinds of file in a directory.
+func handleContents(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		http.Error(w, "path is required", 400)
+		return
+	}
+
+	stats, err := trace.StatDir(path)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	response := struct {
+		trace.DirContents
+		Summary     string `json:"Summary"`
+		Verdict     string `json:"Verdict"`
+		Executables int    `json:"Executables"`
+		Size        int64  `json:"Size"`
+		SizeText    string `json:"SizeText"`
+	}{
+		DirContents: stats.Contents,
+		Summary:     stats.Contents.Summary(),
+		Verdict:     stats.Contents.Verdict(),
+		Executables: stats.Executables,
+		Size:        stats.Size,
+		SizeText:    trace.FormatSize(stats.Size),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 func handleWhich(w http.ResponseWriter, r *http.Request) {
 	query := strings.ToLower(r.URL.Query().Get("query"))
 	if query == "" {
@@ -202,9 +238,12 @@ func handleWhich(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Use session mode for which - we want to search the current PATH
-	analyzer := trace.NewAnalyzer()
-	result := analyzer.AnalyzeSessionPath(os.Getenv("PATH"))
+	// Indices refer to the entries the browser shows
+	result, err := currentResult()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
 
 	type WhichMatch struct {
 		Index       int    `json:"Index"`
@@ -215,11 +254,11 @@ func handleWhich(w http.ResponseWriter, r *http.Request) {
 	seenDirs := make(map[string]bool)
 
 	for i, entry := range result.PathEntries {
-		dir := entry.Value
-		if seenDirs[dir] {
+		key := model.CanonicalPath(entry.Value, model.CanonOptions{})
+		if seenDirs[key] {
 			continue
 		}
-		expandedDir := expandTilde(dir)
+		expandedDir := model.ExpandTilde(entry.Value)
 
 		files, err := os.ReadDir(expandedDir)
 		if err != nil {
@@ -243,7 +282,7 @@ func handleWhich(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if found {
-			seenDirs[dir] = true
+			seenDirs[key] = true
 			matches = append(matches, WhichMatch{
 				Index:       i,
 				MatchedFile: matchedFile,
@@ -255,10 +294,45 @@ func handleWhich(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(matches)
 }
 
-func handleHelp(w http.ResponseWriter, r *http.Request) {
-	// Use the embedded help content
-	text := strings.ReplaceAll(helpMD, "{{VERSION}}", model.Version)
+func handleImpact(w http.ResponseWriter, r *http.Request) {
+	index := 0
+	if _, err := fmt.Sscanf(r.URL.Query().Get("index"), "%d", &index); err != nil {
+		http.Error(w, "invalid index", 400)
+		return
+	}
 
+	result, err := currentResult()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if !sameResult(w, &result, r.URL.Query().Get("result")) {
+		return
+	}
+	if index < 0 || index >= len(result.PathEntries) {
+		http.Error(w, "index out of range", 400)
+		return
+	}
+
+	// Bounded by the scan budget and cancelled if the browser goes away
+	ctx, cancel := context.WithTimeout(r.Context(), trace.DefaultScanBudget)
+	defer cancel()
+	bins := trace.ScanBinaryIndex(ctx, result.PathEntries, nil)
+	breaks := bins.RemovalImpact(index)
+	if breaks == nil {
+		breaks = []string{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Index   int      `json:"Index"`
+		Breaks  []string `json:"Breaks"`
+		Scanned int      `json:"Scanned"`
+		Total   int      `json:"Total"`
+	}{index, breaks, bins.Scanned, len(bins.ByEntry)})
+}
+
+func handleHelp(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/markdown")
-	w.Write([]byte(text))
+	w.Write([]byte(help.Text(help.Web)))
 }
@@ -30,14 +30,25 @@ async function fetchTrace() {
     try {
         showLoading();
         const response = await fetch('/api/trace');
-        state.data = await response.json();
-
-        state.mainFilteredIndices = state.data.PathEntries.map((_, i) => i);
-
-        document.getElementById('version-display').textContent = 'v' + state.data.Version;
-        updateDiagnostics();
+        applyTrace(await response.json());
+        hideLoading();
+        if (state.data.Live && !state.live) connectLive();
+    } catch (e) {
+        console.error(e);
+        hideLoading();
+        const list = document.getElementById('path-list');
+        if (list) list.textContent = 'Error loading trace: ' + e;
+    }
+}
 
-        renderAll();
+// refreshTrace asks the server to trace again rather than answer from the
+// analysis it keeps for a while (see ResultTTL in cache.go).
+async function refreshTrace() {
+    try {
+        showLoading();
+        const response = await fetch('/api/refresh', { method: 'POST' });
+        if (!response.ok) throw new Error(await response.text());
+        applyTrace(await response.json());
         hideLoading();
     } catch (e) {
         console.error(e);
@@ -47,10 +58,55 @@ async function fetchTrace() {
     }
 }
 
-function showLoading() {
+// applyTrace shows a new analysis, from /api/trace or pushed over /api/ws.
+function applyTrace(data) {
+    state.data = data;
+    state.mainFilteredIndices = state.data.PathEntries.map((_, i) => i);
+    if (state.mainSelectedIndex >= state.mainFilteredIndices.length) {
+        state.mainSelectedIndex = Math.max(0, state.mainFilteredIndices.length - 1);
+    }
+    state.fileCache = {};
+
+    document.getElementById('version-display').textContent = 'v' + state.data.Version;
+    document.getElementById('nav-refresh').style.display = state.data.Live ? '' : 'none';
+    if (state.data.Variable && state.data.Variable !== 'PATH') {
+        document.getElementById('nav-main').innerHTML = '<span>🔍</span> ' + state.data.Variable + ' Explorer';
+    }
+    updateDiagnostics();
+
+    renderAll();
+    if (state.searchTerm) performWhichSearch();
+}
+
+// connectLive listens on /api/ws for re-analyses the server runs when a
+// config file changes, instead of polling /api/trace (a full shell trace
+// each time). It reconnects if the server goes away.
+function connectLive() {
+    const scheme = location.protocol === 'https:' ? 'wss:' : 'ws:';
+    const ws = new WebSocket(`${scheme}//${location.host}/api/ws`);
+    state.live = ws;
+    ws.onmessage = (e) => {
+        const msg = JSON.parse(e.data);
+        if (msg.Type === 'changed') {
+            showLoading(`${msg.File} changed; re-tracing...`);
+        } else if (msg.Type === 'result') {
+            applyTrace(msg.Result);
+            hideLoading();
+        } else if (msg.Type === 'error') {
+            hideLoading();
+            console.error(msg.Error);
+        }
+    };
+    ws.onclose = () => {
+        state.live = null;
+        setTimeout(connectLive, 5000);
+    };
+}
+
+function showLoading(message) {
     const overlay = document.getElementById('loading-overlay');
     const text = overlay.querySelector('.loading-text');
-    text.textContent = 'Analyzing PATH...';
+    text.textContent = message || 'Analyzing PATH...';
     overlay.classList.add('visible');
 }
 
@@ -91,6 +147,10 @@ function setupInputs() {
                 e.preventDefault();
                 switchView(state.currentView === 'diagnostics' ? 'main' : 'diagnostics');
                 return;
+            } else if (e.key === 'r' && !e.shiftKey && state.data && state.data.Live) {
+                e.preventDefault();
+                refreshTrace();
+                return;
             } else if ((e.key === 'h' && !e.shiftKey) || e.key === '?') {
                 e.preventDefault();
                 switchView(state.currentView === 'help' ? 'main' : 'help');
@@ -278,6 +338,7 @@ function renderPathList(containerId, indices, selectedIdx, viewType) {
     const list = document.getElementById(containerId);
     if (!list) return;
     list.innerHTML = '';
+    const dupPolicy = state.data.DuplicatePolicy || {};
 
     indices.forEach((dataIdx, viewIdx) => {
         const entry = state.data.PathEntries[dataIdx];
@@ -326,13 +387,13 @@ function renderPathList(containerId, indices, selectedIdx, viewType) {
         }
 
         const nameSpan = document.createElement('span');
-        let label = `${dataIdx + 1}. ${entry.Value}`;
+        let label = `${dataIdx + 1}. ${displayPath(entry.Value)}`;
 
         // Show matched binary if in search mode
         if (viewType === 'main' && state.whichMatches.length > 0) {
             const match = state.whichMatches.find(m => m.Index === dataIdx);
             if (match) {
-                label = `${dataIdx + 1}. ${match.MatchedFile} (${entry.Value})`;
+                label = `${dataIdx + 1}. ${match.MatchedFile} (${displayPath(entry.Value)})`;
             }
         }
 
@@ -356,23 +417,35 @@ function renderPathList(containerId, indices, selectedIdx, viewType) {
             div.appendChild(priority);
         }
 
+        // Problems the user's ignore rules silence get no pill
+        const ignored = problem => (entry.Ignored || []).includes(problem);
         if (entry.IsSessionOnly) {
             const status = document.createElement('span');
             status.className = 'status-pill';
             status.textContent = `session ${Icons.Session}`;
             div.appendChild(status);
-        } else if (entry.IsDuplicate) {
+        } else if (entry.IsDuplicate && dupPolicy.Severity !== 'harmless' && !ignored('duplicate')) {
             const status = document.createElement('span');
             status.className = 'status-pill';
             status.textContent = `dup ${Icons.Duplicate}`;
             div.appendChild(status);
-        } else if (entry.SymlinkPointsTo >= 0) {
+        } else if (entry.SymlinkPointsTo >= 0 && !dupPolicy.IgnoreSymlinks && dupPolicy.Severity !== 'harmless' && !ignored('duplicate')) {
             const status = document.createElement('span');
             status.className = 'status-pill';
             status.style.background = '#3b82f6';
             status.textContent = `symlink ${Icons.Duplicate}${Icons.Symlink}`;
             div.appendChild(status);
-        } else if (entry.Diagnostics && entry.Diagnostics.some(d => d.includes('does not exist'))) {
+        } else if (entry.Diagnostics && entry.Diagnostics.some(d => d.startsWith('Empty segment:') || d.startsWith('Relative segment:')) && !ignored('relative')) {
+            const status = document.createElement('span');
+            status.className = 'status-pill';
+            status.textContent = `relative ${Icons.Relative}`;
+            div.appendChild(status);
+        } else if (entry.SymlinkBroken && !ignored('broken')) {
+            const status = document.createElement('span');
+            status.className = 'status-pill';
+            status.textContent = `broken ${Icons.Symlink}`;
+            div.appendChild(status);
+        } else if (entry.Diagnostics && entry.Diagnostics.some(d => d.includes('does not exist')) && !ignored('missing')) {
             const status = document.createElement('span');
             status.className = 'status-pill';
             status.textContent = `missing ${Icons.Missing}`;
@@ -430,8 +503,14 @@ async function renderDetails() {
             ` : ''}
             <div class="detail-row">
                 <div class="detail-label">Directory</div>
-                <div class="detail-value">${entry.Value}</div>
+                <div class="detail-value">${escapeHtml(displayPath(entry.Value))}</div>
+            </div>
+            ${(entry.Diagnostics || []).filter(d => d.startsWith('Empty segment:') || d.startsWith('Relative segment:') || d.startsWith('Advice:')).map(d => `
+            <div class="detail-row">
+                <div class="detail-label">${d.startsWith('Advice:') ? 'Advice' : `Warning ${Icons.Relative}`}</div>
+                <div class="detail-value">${escapeHtml(d.replace(/^Advice: /, ''))}</div>
             </div>
+            `).join('')}
             <div class="detail-row">
                 <div class="detail-label">Caused by</div>
                 <div class="detail-value">
@@ -439,6 +518,42 @@ async function renderDetails() {
                     ${entry.Mode !== 'Unknown' ? `<span style="color:var(--text-muted); font-size:0.9em; margin-left:8px;">(Startup Phase: ${entry.Mode})</span>` : ''}
                 </div>
             </div>
+            ${entry.IsSessionOnly && entry.SessionNote ? `
+            <div class="detail-row">
+                <div class="detail-label">Note</div>
+                <div class="detail-value">${escapeHtml(entry.SessionNote)}</div>
+            </div>
+            ` : ''}
+            ${entry.IsSymlink ? `
+            <div class="detail-row">
+                <div class="detail-label">Symlink</div>
+                <div class="detail-value">${[entry.Value, ...(entry.SymlinkChain || [entry.SymlinkTarget])].map(escapeHtml).join(` ${Icons.Symlink} `)}${entry.SymlinkBroken ? ` <span style="color:var(--warning)">(broken: ${escapeHtml(entry.SymlinkBroken)})</span>` : ''}</div>
+            </div>
+            ` : ''}
+            ${entry.Package ? `
+            <div class="detail-row">
+                <div class="detail-label">Installed by</div>
+                <div class="detail-value">${escapeHtml(entry.Package)}</div>
+            </div>
+            ` : ''}
+            ${entry.Hook ? `
+            <div class="detail-row">
+                <div class="detail-label">Added by</div>
+                <div class="detail-value">
+                    ${escapeHtml(entry.Hook.Tool)} hook: the code <code>${escapeHtml(entry.Hook.Command)}</code> prints
+                    <span style="color:var(--text-muted); font-size:0.9em; margin-left:8px;">(${escapeHtml(entry.Hook.Binary || 'tool not found on PATH')})</span>
+                </div>
+            </div>
+            ` : ''}
+            ${entry.Confidence ? `
+            <div class="detail-row">
+                <div class="detail-label">Confidence</div>
+                <div class="detail-value">
+                    ${entry.Confidence}
+                    ${entry.Confidence !== 'high' ? `<span style="color:var(--text-muted); font-size:0.9em; margin-left:8px;">${escapeHtml(entry.ConfidenceReason)}</span>` : ''}
+                </div>
+            </div>
+            ` : ''}
     `;
 
     // Always show source context section (educational for System defaults)
@@ -543,6 +658,11 @@ async function renderDetails() {
             <div class="alert alert-warning">
                 <strong>${Icons.Duplicate} Duplicate detected</strong><br>
                 ${entry.DuplicateMessage}
+                ${state.data.FixToken && entry.LineNumber > 0 ? `
+                <div style="margin-top:10px;">
+                    <button class="fix-button" onclick="commentOutLine(${dataIdx})">Comment out line ${entry.LineNumber} of ${escapeHtml(entry.SourceFile)}</button>
+                </div>
+                ` : ''}
             </div>
         `;
     } else if (entry.SymlinkPointsTo >= 0) {
@@ -555,8 +675,13 @@ async function renderDetails() {
         `;
     }
 
+    html += `<div class="detail-card" id="contents-card"><div class="detail-row"><div class="detail-label">Contents</div><div class="detail-value" style="color:var(--text-muted)">Checking...</div></div></div>`;
+    html += `<div class="detail-card" id="impact-card"><div class="detail-row"><div class="detail-label">If Removed</div><div class="detail-value" style="color:var(--text-muted)">Checking...</div></div></div>`;
+
     html += `</div>`;
     container.innerHTML = html;
+    renderImpact(dataIdx);
+    renderContents(entry.Value);
 
     // Fetch and render LS-like listing
     lsContainer.innerHTML = '<p style="color:var(--text-muted); padding:20px;">Loading directory listing...</p>';
@@ -582,6 +707,74 @@ async function renderDetails() {
     }
 }
 
+// commentOutLine previews, confirms and applies commenting out the config
+// line behind a duplicate entry, like 'x' in the TUI. The file is backed up.
+async function commentOutLine(dataIdx) {
+    const post = async (dryRun) => {
+        const resp = await fetch('/api/fix', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json', 'X-Lspath-Token': state.data.FixToken },
+            body: JSON.stringify({ Index: dataIdx, ResultID: state.data.ResultID, DryRun: dryRun }),
+        });
+        if (!resp.ok) throw new Error(await resp.text());
+        return resp.json();
+    };
+    try {
+        const plan = await post(true);
+        if (!confirm(`${plan.Reason}\n\nComment out line ${plan.Line} of ${plan.File}? A backup is made first.\n\n${plan.Diff}`)) return;
+        const done = await post(false);
+        alert(`Done.${done.Backup ? ` Backup: ${done.Backup}` : ''}\nOpen a new terminal to pick up the change.`);
+        fetchTrace();
+    } catch (e) {
+        alert(`Cannot fix this line: ${e.message}`);
+    }
+}
+
+async function renderContents(dir) {
+    const card = document.getElementById('contents-card');
+    if (!card) return;
+    try {
+        const resp = await fetch(`/api/contents?path=${encodeURIComponent(dir)}`);
+        if (!resp.ok) throw new Error("HTTP " + resp.status);
+        const contents = await resp.json();
+        const value = card.querySelector('.detail-value');
+        value.style.color = '';
+        value.innerHTML = `${escapeHtml(contents.Summary)}<br>${contents.Executables} executables, ${escapeHtml(contents.SizeText)} total<br><em style="color:var(--text-muted);">${escapeHtml(contents.Verdict)}</em>`;
+    } catch (e) {
+        card.remove();
+    }
+}
+
+async function renderImpact(dataIdx) {
+    const card = document.getElementById('impact-card');
+    if (!card) return;
+    try {
+        const resp = await fetch(`/api/impact?index=${dataIdx}&result=${encodeURIComponent(state.data.ResultID)}`);
+        if (resp.status === 409) { // The analysis was re-traced; show the new one
+            fetchTrace();
+            return;
+        }
+        if (!resp.ok) throw new Error("HTTP " + resp.status);
+        const impact = await resp.json();
+        const value = card.querySelector('.detail-value');
+        if (impact.Scanned <= dataIdx) {
+            value.textContent = 'Unknown - scan stopped before reaching this entry.';
+        } else if (impact.Breaks.length === 0) {
+            value.textContent = 'No binaries would stop resolving.';
+        } else {
+            value.style.color = 'var(--warning)';
+            value.innerHTML = `${impact.Breaks.length} binaries would stop resolving:<br><code>${impact.Breaks.map(escapeHtml).join(', ')}</code>`;
+        }
+        if (impact.Scanned < impact.Total) {
+            value.innerHTML += `<br><em>(partial scan: ${impact.Scanned}/${impact.Total} directories)</em>`;
+        }
+    } catch (e) {
+        card.remove();
+    }
+}
+
+const kindIcons = { directory: '📁', binary: '🚀', executable: '🚀', script: '📜', symlink: '🔗' };
+
 function renderLsTable(files) {
     const container = document.getElementById('directory-listing-container');
     if (!container) return;
@@ -613,7 +806,7 @@ function renderLsTable(files) {
                 <td class="col-permissions">${f.Mode}</td>
                 <td class="col-size">${sizeStr}</td>
                 <td class="col-date">${f.ModTime}</td>
-                <td class="col-name ${nameClass}">${f.IsDir ? '📁' : '📄'} ${f.Name}</td>
+                <td class="col-name ${nameClass}">${kindIcons[f.Kind] || '📄'} ${f.Name}</td>
             </tr>
         `;
     });
@@ -674,7 +867,7 @@ function renderFlowNodes() {
         // Don't add first/last symbols in web view - position is obvious from GUI
         
         div.textContent = nodeText;
-        div.title = node.FilePath;
+        div.title = node.Note ? `${node.FilePath}\n${node.Note}` : node.FilePath;
         div.onclick = () => {
             state.flowNodeIndex = idx;
             renderAll();
@@ -769,6 +962,12 @@ async function renderFilePreview() {
         return;
     }
 
+    if (node.FilePath.startsWith('HKLM\\') || node.FilePath.startsWith('HKCU\\')) {
+        preview.classList.remove('file-content');
+        preview.innerHTML = '<div style="color:var(--text-muted); padding:20px; line-height:1.6;"><p style="margin:0 0 12px 0;"><strong style="color:var(--text-color);">Registry Path value</strong> <code style="color:var(--accent-bright);">' + node.FilePath + '</code></p><p style="margin:0;">Windows builds a new process\'s PATH from the system Path followed by the user Path. Edit them under Environment Variables in Advanced system settings, then open a new terminal.</p></div>';
+        return;
+    }
+
     if (node.NotExecuted) {
         preview.classList.remove('file-content');
         // Check if file exists by trying to fetch it
@@ -789,6 +988,7 @@ async function renderFilePreview() {
 
     if (state.fileCache[node.FilePath]) {
         applyPreview(state.fileCache[node.FilePath]);
+        applyPreviewNote(node);
         return;
     }
 
@@ -798,6 +998,7 @@ async function renderFilePreview() {
         const text = await resp.text();
         state.fileCache[node.FilePath] = text;
         applyPreview(text);
+        applyPreviewNote(node);
     } catch (e) {
         preview.textContent = "Error loading file content: " + e.message;
     }
@@ -824,6 +1025,21 @@ function applyPreview(text) {
     });
 }
 
+// Show a flow node's explanatory note (e.g. Apple Terminal integration) and
+// what of it did not run above its source
+function applyPreviewNote(node) {
+    const lines = (node.Skipped || []).map(s => '⤼ ' + s);
+    if (node.Note) lines.unshift('ℹ ' + node.Note);
+    const preview = document.getElementById('file-preview');
+    lines.reverse().forEach(text => {
+        const note = document.createElement('div');
+        note.className = 'preview-line';
+        note.style.color = 'var(--warning)';
+        note.textContent = text;
+        preview.prepend(note);
+    });
+}
+
 function toggleCumulative() {
     const checkbox = document.getElementById('check-cumulative');
     if (checkbox) {
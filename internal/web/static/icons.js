@@ -9,5 +9,11 @@ const Icons = {
     Symlink: '→',
     Missing: '✗',
     OK: ' ',
-    Session: '◆'
+    Session: '◆',
+    Relative: '!'
 };
+
+// Empty PATH segments are invisible, so name them
+function displayPath(value) {
+    return value === '' ? '(empty: current directory)' : value;
+}
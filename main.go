@@ -1,11 +1,25 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"lspath/internal/bundle"
+	"lspath/internal/daemon"
+	"lspath/internal/demo"
+	"lspath/internal/fix"
+	"lspath/internal/fleet"
+	"lspath/internal/help"
+	"lspath/internal/mcp"
 	"lspath/internal/model"
+	"lspath/internal/pager"
 	"lspath/internal/trace"
 	"lspath/internal/tui"
 	"lspath/internal/web"
@@ -36,7 +50,14 @@ func checkUpdate(currentVer string) {
 
 func main() {
 	pflag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: lspath [options]\n\n")
+		for i, s := range help.Synopsis {
+			prefix := "Usage: "
+			if i > 0 {
+				prefix = "       "
+			}
+			fmt.Fprintf(os.Stderr, "%s%s\n", prefix, s)
+		}
+		fmt.Fprintf(os.Stderr, "\n")
 		fmt.Fprintf(os.Stderr, "lspath is a tool for analyzing and debugging your system PATH.\n")
 		fmt.Fprintf(os.Stderr, "It shows your actual PATH with full attribution from shell config files.\n")
 		fmt.Fprintf(os.Stderr, "Session-specific entries (e.g., virtual environments) are clearly marked.\n\n")
@@ -44,127 +65,1038 @@ func main() {
 		pflag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
 		fmt.Fprintf(os.Stderr, "  lspath              # Start TUI mode (unified view)\n")
+		fmt.Fprintf(os.Stderr, "  lspath --tour       # New to PATH? A guided walk through your own results\n")
+		fmt.Fprintf(os.Stderr, "  lspath demo --web   # Try lspath on a made-up messy PATH (nothing is traced)\n")
 		fmt.Fprintf(os.Stderr, "  lspath --report     # Print diagnostic report to stdout\n")
 		fmt.Fprintf(os.Stderr, "  lspath -r -o r.txt  # Save report to file\n")
+		fmt.Fprintf(os.Stderr, "  lspath -r --include-sources -o r.txt  # Self-contained report for support requests\n")
 		fmt.Fprintf(os.Stderr, "  lspath --json       # Output analysis as JSON\n")
+		fmt.Fprintf(os.Stderr, "  lspath --json --json-version 1  # Keep the JSON shape a script was written for\n")
+		fmt.Fprintf(os.Stderr, "  lspath --json --stream | jq -c 'select(.Type == \"change\")'  # PATH changes as they happen\n")
+		fmt.Fprintf(os.Stderr, "  eval \"$(lspath --print-clean --format export)\"  # Drop duplicate and missing entries for this shell\n")
+		fmt.Fprintf(os.Stderr, "  lspath --oneline    # PATH: 23 entries · 2 dup · 1 missing · 310ms startup\n")
+		fmt.Fprintf(os.Stderr, "  lspath --snapshot before.json  # Save the analysis for a later --diff\n")
+		fmt.Fprintf(os.Stderr, "  lspath --diff before.json      # What changed since the snapshot\n")
+		fmt.Fprintf(os.Stderr, "  lspath --format dot | dot -Tsvg > path.svg  # Graph the config flow\n")
+		fmt.Fprintf(os.Stderr, "  lspath --format html -o report.html         # Shareable self-contained report\n")
+		fmt.Fprintf(os.Stderr, "  lspath --format md | pbcopy                 # Report to paste into a GitHub issue\n")
+		fmt.Fprintf(os.Stderr, "  lspath --format csv -o path.csv             # One row per entry for a spreadsheet\n")
+		fmt.Fprintf(os.Stderr, "  lspath bundle export --include-configs me.lspath  # Archive for a support ticket\n")
+		fmt.Fprintf(os.Stderr, "  lspath bundle open --web me.lspath             # Browse a bundle from another machine\n")
+		fmt.Fprintf(os.Stderr, "  lspath fleet --inputs saved/  # Compare --json files and bundles from many hosts\n")
+		fmt.Fprintf(os.Stderr, "  lspath which python  # Every python in PATH, which one runs, and who added it\n")
+		fmt.Fprintf(os.Stderr, "  lspath --explain 3  # Explain PATH entry #3 and what removing it breaks\n")
+		fmt.Fprintf(os.Stderr, "  lspath --advise ~/bin --apply  # Add ~/bin to the right startup file\n")
+		fmt.Fprintf(os.Stderr, "  lspath --var MANPATH -r        # Report on MANPATH instead of PATH\n")
+		fmt.Fprintf(os.Stderr, "  sudo lspath --user root        # Root's PATH, and how it differs from yours\n")
+		fmt.Fprintf(os.Stderr, "  lspath --contexts   # PATH in Terminal.app vs iTerm2 vs tmux vs VS Code\n")
+		fmt.Fprintf(os.Stderr, "  lspath --modes      # Why a command works in the terminal but not in cron or an IDE\n")
+		fmt.Fprintf(os.Stderr, "  lspath --context cron  # The PATH cron jobs really get, and what they lack\n")
+		fmt.Fprintf(os.Stderr, "  lspath --context launchd  # Why an app started from the Dock can't find a command\n")
+		fmt.Fprintf(os.Stderr, "  lspath -r --duplicates=error     # Exit 1 if PATH has duplicates (e.g. in CI)\n")
+		fmt.Fprintf(os.Stderr, "  lspath --check --fail-on error   # CI check: exit 1 on insecure entries or broken pins\n")
+		fmt.Fprintf(os.Stderr, "  lspath -rv --no-heuristic=eval  # Line numbers as traced, not moved to the eval\n")
+		fmt.Fprintf(os.Stderr, "  lspath --sandbox -r # Trace an unfamiliar account's dotfiles with resource limits\n")
+		fmt.Fprintf(os.Stderr, "  lspath --watch      # TUI that re-traces whenever you save a dotfile\n")
+		fmt.Fprintf(os.Stderr, "  lspath --web --open # Web Mode in your browser (any free port if 8080 is taken)\n")
+		fmt.Fprintf(os.Stderr, "  lspath --fix        # Remove config lines that add duplicate PATH entries\n")
+		fmt.Fprintf(os.Stderr, "  lspath -r --pins ~/.config/lspath/pins  # Exit 1 if pinned directories are out of order\n")
 	}
 
 	jsonFlag := pflag.BoolP("json", "j", false, "Output raw analysis data as JSON")
+	jsonVersionFlag := pflag.Int("json-version", model.SchemaVersion, "With --json, write this version of the JSON schema, so scripts written against it keep working")
+	jsonSchemaFlag := pflag.Bool("json-schema", false, "Print the JSON Schema of the --json output")
+	streamFlag := pflag.Bool("stream", false, "With --json, print each trace event and change to the variable as a line of JSON while tracing, instead of the analysis at the end")
+	checkFlag := pflag.Bool("check", false, "Check for problems without a UI (for CI): list them and exit 1 if any reach --fail-on, 2 if the analysis fails")
+	failOnFlag := pflag.String("fail-on", trace.SeverityWarning, "Lowest severity that fails --check: info, warning (missing directories, duplicates) or error (entries others can write, empty segments, broken pins)")
+	printCleanFlag := pflag.Bool("print-clean", false, "Print the value without duplicates, missing directories and empty segments (--format export for an export line)")
+	onelineFlag := pflag.Bool("oneline", false, "Print a one-line summary for prompts and status bars (cached until a config file changes)")
 	reportFlag := pflag.BoolP("report", "r", false, "Generate a detailed diagnostic report (CLI mode)")
-	outputFlag := pflag.StringP("output", "o", "", "Save report to the specified file (combined with --report)")
+	outputFlag := pflag.StringP("output", "o", "", "Save report to the specified file (combined with --report or --format)")
+	formatFlag := pflag.String("format", "", "Output the config flow as a graph (dot, mermaid), the report as a standalone page (html) or Markdown (md), or the data as yaml or csv")
+	snapshotFlag := pflag.String("snapshot", "", "Save the analysis to the specified JSON file for a later --diff")
+	diffFlag := pflag.Bool("diff", false, "Compare a snapshot with another snapshot, or with the current analysis if only one is given")
+	includeConfigsFlag := pflag.Bool("include-configs", false, "With bundle export, include copies of the traced config files")
+	inputsFlag := pflag.String("inputs", "", "With fleet, the directory of saved --json analyses and .lspath bundles to compare")
 	verboseFlag := pflag.BoolP("verbose", "v", false, "Include detailed path entry information in the report")
-	webFlag := pflag.BoolP("web", "w", false, "Start Web Mode on http://localhost:8080")
+	noPagerFlag := pflag.Bool("no-pager", false, "Print the --report directly rather than through $PAGER (less -R) when it is taller than the terminal")
+	colorFlag := pflag.String("color", trace.ColorAuto, "Color the --report: auto (on a terminal, unless NO_COLOR is set), always or never")
+	includeSourcesFlag := pflag.Bool("include-sources", false, "Append annotated excerpts of each contributing config file to the report")
+	adviseFlag := pflag.String("advise", "", "Recommend which startup file a new PATH directory should be exported from")
+	fixFlag := pflag.Bool("fix", false, "Propose removing config lines that add duplicate PATH entries (and restoring --pins order), show a diff, and apply after confirmation")
+	applyFlag := pflag.Bool("apply", false, "With --advise, append the export snippet to the recommended file (backs it up first)")
+	scanBudgetFlag := pflag.Duration("scan-budget", trace.DefaultScanBudget, "Time limit for deep directory scans; partial results are reported when exceeded")
+	varFlag := pflag.String("var", trace.DefaultVariable, "PATH-like variable to analyze (e.g. MANPATH, LD_LIBRARY_PATH, PYTHONPATH)")
+	explainFlag := pflag.StringP("explain", "e", "", "Explain a PATH entry (by number or directory) and what would break if removed")
+	contextsFlag := pflag.Bool("contexts", false, "Trace the startup of each terminal app/launch context on this machine and compare the resulting PATHs")
+	modesFlag := pflag.Bool("modes", false, "Trace login, interactive and non-interactive shells and report the entries only some of them get")
+	contextFlag := pflag.String("context", "", "Reconstruct the PATH cron jobs, macOS GUI apps or systemd services get (cron, launchd, systemd, systemd-user, or systemd:UNIT) and compare it with yours")
+	userFlag := pflag.String("user", "", "Trace another user's startup files (e.g. root, via sudo) and compare their PATH with yours")
+	duplicatesFlag := pflag.String("duplicates", model.DuplicatesWarn, "How to treat duplicate entries: warn, error (first copy wins; --report exits 1) or harmless (counted as OK)")
+	pathCaseFlag := pflag.String("path-case", model.CaseAuto, "How to compare the case of entries when finding duplicates: auto (ignored on volumes that ignore it, e.g. macOS APFS), sensitive or insensitive")
+	symlinkDuplicatesFlag := pflag.Bool("symlink-duplicates", true, "Count symlinks to another entry (e.g. /bin -> /usr/bin) as duplicates; use --symlink-duplicates=false to ignore them")
+	ignoreFlag := pflag.StringArray("ignore", nil, "Accept a known problem so reports and --check stop flagging it: missing, broken, duplicate, relative, session or unsafe, a directory, or both as missing=~/go/bin (repeatable; also read from ~/.config/lspath/ignore)")
+	noHeuristicFlag := pflag.StringSlice("no-heuristic", nil, "Turn off analyzer heuristics to see the raw trace: eval, coalesce, ghost-nodes, noisy-files or all (also read from ~/.config/lspath/no-heuristics)")
+	pluginFlag := pflag.StringArray("plugin", nil, "Run an executable on the analysis (JSON on stdin) and add the diagnostics it prints (repeatable; also runs those in ~/.config/lspath/plugins)")
+	pinsFlag := pflag.String("pins", "", "Pins file listing directories that must appear in this order (default ~/.config/lspath/pins if it exists); --report exits 1 and --fix corrects the order when they don't")
+	sandboxFlag := pflag.Bool("sandbox", false, "Trace with CPU and file size limits, a private TMPDIR and no network where supported (always on with --user)")
+	traceTimeoutFlag := pflag.Duration("trace-timeout", trace.DefaultTraceTimeout, "Kill the traced shell if its startup takes longer, and analyze the trace as far as it got (0 for no limit)")
+	baselineFlag := pflag.String("baseline", "", "PATH the traced shell starts from, before any startup file runs (default: detected for this system, e.g. from getconf PATH)")
+	noSideEffectsFlag := pflag.Bool("no-side-effects", false, "Trace with commands that start daemons or modify files disabled (best effort; fullest in bash)")
+	tourFlag := pflag.Bool("tour", false, "Start the TUI with a guided tour of your own results (priority, duplicates, login shells, ...)")
+	themeFlag := pflag.String("theme", "", fmt.Sprintf("TUI color theme: %s (default %s, or the theme line of ~/.config/lspath/theme, which can also set individual colors)", strings.Join(tui.ThemeNames(), ", "), tui.DefaultTheme))
+	watchFlag := pflag.Bool("watch", false, "Re-run the analysis whenever a traced config file changes (TUI and --report)")
+	webFlag := pflag.BoolP("web", "w", false, "Start Web Mode (http://localhost:8080 unless --port/--bind say otherwise)")
+	portFlag := pflag.Int("port", web.DefaultPort, "Web Mode port; if the default is taken a free port is used (0 always picks a free port)")
+	bindFlag := pflag.String("bind", web.DefaultBind, "Web Mode address to listen on (e.g. 0.0.0.0 to allow other machines, with --bind-unsafe)")
+	bindUnsafeFlag := pflag.Bool("bind-unsafe", false, "Allow a --bind address other machines can reach; they can then read any file on this one through the web UI, which has no login")
+	openFlag := pflag.Bool("open", false, "With --web, open the page in the default browser")
+	versionsFlag := pflag.Bool("versions", false, fmt.Sprintf("With which, run each match with --version (for at most %s each) and compare the versions, e.g. python3: 3.12.1 (/opt/homebrew/bin) vs 3.9.6 (/usr/bin)", trace.VersionTimeout))
+	daemonFlag := pflag.Bool("daemon", false, "Keep the analysis warm, re-tracing when a config file changes, and answer queries (analysis, which, file preview) as JSON-RPC on a Unix socket")
+	socketFlag := pflag.String("socket", "", "With --daemon, the Unix socket to listen on (default daemon.sock in the user cache directory, e.g. ~/.cache/lspath)")
+	mcpFlag := pflag.Bool("mcp", false, "Serve the analysis to AI coding assistants over MCP on stdin/stdout (tools: get_path_analysis, which_binary, get_config_flow, explain_entry)")
 	versionFlag := pflag.BoolP("version", "V", false, "Print version information")
 	updateFlag := pflag.BoolP("update", "u", false, "Check for latest version (not implemented)")
 	helpFlag := pflag.BoolP("help", "h", false, "Show this help message")
+	manFlag := pflag.String("man", "", "Print the manual as a man page (view with: lspath --man | man -l -), or as plain text with --man=text")
+	pflag.Lookup("man").NoOptDefVal = "troff"
 	pflag.Parse()
 
+	analysisOptions.Var = *varFlag
+	analysisOptions.NoSideEffects = *noSideEffectsFlag
+	analysisOptions.Baseline = *baselineFlag
+	analysisOptions.TraceTimeout = *traceTimeoutFlag
+	if *traceTimeoutFlag <= 0 {
+		analysisOptions.TraceTimeout = -1 // No limit
+	}
+	analysisOptions.PinsFile = *pinsFlag
+	if *sandboxFlag {
+		analysisOptions.Sandbox = trace.DefaultSandbox
+	}
+	severity, err := model.ParseDuplicateSeverity(*duplicatesFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	analysisOptions.Duplicates = model.DuplicatePolicy{Severity: severity, IgnoreSymlinks: !*symlinkDuplicatesFlag}
+	analysisOptions.PathCase, err = model.ParseCaseMode(*pathCaseFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	analysisOptions.Ignores, err = trace.ReadIgnoreFile(trace.IgnoreFile())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: reading ignore rules: %v\n", err)
+		os.Exit(2)
+	}
+	for _, s := range *ignoreFlag {
+		rule, err := trace.ParseIgnoreRule(s, "--ignore")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+		analysisOptions.Ignores = append(analysisOptions.Ignores, rule)
+	}
+	noHeuristics, err := trace.ReadHeuristicsFile(trace.HeuristicsFile())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: reading heuristics: %v\n", err)
+		os.Exit(2)
+	}
+	analysisOptions.DisabledHeuristics, err = trace.ParseHeuristics(append(noHeuristics, *noHeuristicFlag...))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	analysisOptions.Plugins = append(trace.FindPlugins(trace.PluginDir()), *pluginFlag...)
+	theme, err := tui.LoadTheme(*themeFlag, tui.ThemeFile())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: theme: %v\n", err)
+		os.Exit(2)
+	}
+	tui.ApplyTheme(theme)
+	keyMap, err := tui.LoadKeyMap(tui.KeysFile())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: keys: %v\n", err)
+		os.Exit(2)
+	}
+	tui.ApplyKeyMap(keyMap)
+	colorMode, err = trace.ParseColorMode(*colorFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	webConfig := web.Config{Port: *portFlag, Bind: *bindFlag, BindUnsafe: *bindUnsafeFlag, FixedPort: pflag.Lookup("port").Changed, OpenBrowser: *openFlag}
+
 	if *helpFlag {
 		pflag.Usage()
 		return
 	}
 
+	switch *manFlag {
+	case "":
+	case "troff":
+		fmt.Print(help.Man(pflag.CommandLine))
+		return
+	case "text":
+		fmt.Print(help.Long(pflag.CommandLine))
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown --man format %q (use troff or text)\n", *manFlag)
+		os.Exit(2)
+	}
+
 	if *versionFlag {
 		fmt.Printf("lspath version %s\n", model.Version)
 		return
 	}
 
+	if *jsonSchemaFlag {
+		data, err := trace.JSONSchema()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+		return
+	}
+	if v := *jsonVersionFlag; v < 1 || v > model.SchemaVersion {
+		fmt.Fprintf(os.Stderr, "Error: unknown --json-version %d (this lspath writes 1 to %d)\n", v, model.SchemaVersion)
+		os.Exit(2)
+	}
+
 	if *updateFlag {
 		checkUpdate(model.Version)
 		return
 	}
 
+	if *diffFlag {
+		args := pflag.Args()
+		if len(args) < 1 || len(args) > 2 {
+			pflag.Usage()
+			os.Exit(2)
+		}
+		runDiffMode(args)
+		return
+	}
+
+	if args := pflag.Args(); len(args) > 0 && args[0] == "bundle" {
+		if len(args) != 3 {
+			pflag.Usage()
+			os.Exit(2)
+		}
+		switch args[1] {
+		case "export":
+			runBundleExport(args[2], *includeConfigsFlag)
+		case "open":
+			runBundleOpen(args[2], *webFlag, webConfig, *reportFlag, *verboseFlag, *noPagerFlag)
+		default:
+			pflag.Usage()
+			os.Exit(2)
+		}
+		return
+	}
+
+	if args := pflag.Args(); len(args) > 0 && args[0] == "fleet" {
+		if len(args) != 1 || *inputsFlag == "" {
+			pflag.Usage()
+			os.Exit(2)
+		}
+		runFleetMode(*inputsFlag, *jsonFlag)
+		return
+	}
+
+	if args := pflag.Args(); len(args) > 0 && args[0] == "prompt-init" {
+		if len(args) != 2 {
+			pflag.Usage()
+			os.Exit(2)
+		}
+		hook, err := trace.PromptInit(args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+		fmt.Print(hook)
+		return
+	}
+
+	if args := pflag.Args(); len(args) == 1 && args[0] == "demo" {
+		if *reportFlag {
+			fmt.Fprintf(os.Stderr, "Error: lspath demo opens the TUI, or Web Mode with --web\n")
+			os.Exit(2)
+		}
+		runDemoMode(*webFlag, webConfig)
+		return
+	}
+
+	if args := pflag.Args(); len(args) > 0 {
+		if args[0] != "which" || len(args) < 2 {
+			pflag.Usage()
+			os.Exit(2)
+		}
+		runWhichMode(args[1:], *versionsFlag)
+		return
+	}
+
 	if *webFlag {
-		web.StartServer()
+		web.StartServer(analysisOptions, webConfig)
+		return
+	}
+
+	if *daemonFlag {
+		runDaemonMode(*socketFlag)
+		return
+	}
+
+	if *mcpFlag {
+		if err := mcp.NewServer(analysisOptions).Serve(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *contextsFlag {
+		runContextsMode()
+		return
+	}
+
+	if *modesFlag {
+		runModesMode()
+		return
+	}
+
+	if *contextFlag != "" {
+		runServiceMode(*contextFlag, *verboseFlag)
+		return
+	}
+
+	if *userFlag != "" {
+		runUserMode(*userFlag, *verboseFlag)
+		return
+	}
+
+	if *checkFlag {
+		runCheckMode(*failOnFlag, *verboseFlag)
+		return
+	}
+
+	if *printCleanFlag {
+		runPrintCleanMode(*formatFlag)
+		return
+	}
+
+	if *formatFlag != "" {
+		runFormatMode(*formatFlag, *outputFlag)
 		return
 	}
 
 	if *reportFlag {
-		runReportMode(*outputFlag, *verboseFlag)
+		runReportMode(*outputFlag, *verboseFlag, *includeSourcesFlag, *watchFlag, *noPagerFlag)
+		return
+	}
+
+	if *snapshotFlag != "" {
+		runSnapshotMode(*snapshotFlag)
+		return
+	}
+
+	if *onelineFlag {
+		runOnelineMode()
+		return
+	}
+
+	if *streamFlag && !*jsonFlag {
+		fmt.Fprintf(os.Stderr, "Error: --stream needs --json\n")
+		os.Exit(2)
+	}
+	if *jsonFlag && *streamFlag {
+		if err := trace.StreamTrace(analysisOptions, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error running trace: %v\n", err)
+			os.Exit(1)
+		}
 		return
 	}
 
 	if *jsonFlag {
-		runJsonMode()
+		runJsonMode(*jsonVersionFlag)
+		return
+	}
+
+	if *explainFlag != "" {
+		runExplainMode(*explainFlag, *scanBudgetFlag)
+		return
+	}
+
+	if *adviseFlag != "" {
+		runAdviseMode(*adviseFlag, *applyFlag)
+		return
+	}
+
+	if *fixFlag {
+		runFixMode()
 		return
 	}
 
 	// Default: TUI
-	runTuiMode()
+	runTuiMode(*varFlag, *watchFlag, *tourFlag)
 }
 
-func runReportMode(outputFile string, verbose bool) {
-	sessionPath := os.Getenv("PATH")
+// analysisOptions holds the command-line settings shared by every mode.
+var analysisOptions trace.Options
 
-	// Run shell trace to find config file sources
-	shell := trace.DetectShell(os.Getenv("SHELL"))
-	stderr, err := trace.RunTrace(shell, trace.SandboxInitialPath)
+// colorMode is --color: whether reports on the terminal are colored.
+var colorMode string
+
+// runUnifiedAnalysis traces the user's shell startup and merges it with the
+// current session PATH.
+func runUnifiedAnalysis() (model.AnalysisResult, error) {
+	return trace.RunAnalysis(analysisOptions)
+}
+
+func runReportMode(outputFile string, verbose, includeSources, watch, noPager bool) {
+	for {
+		result, err := runUnifiedAnalysis()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error running trace: %v\n", err)
+			os.Exit(1)
+		}
+
+		report := trace.GenerateReport(result, verbose)
+		if includeSources {
+			report += "\n" + trace.GenerateSourceExcerpts(result)
+		}
+		if (outputFile == "" && trace.ColorEnabled(colorMode, os.Stdout)) || (outputFile != "" && colorMode == trace.ColorAlways) {
+			report = trace.ColorizeReport(report)
+		}
+
+		if outputFile != "" {
+			err := os.WriteFile(outputFile, []byte(report), 0644)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing report to %s: %v\n", outputFile, err)
+				os.Exit(1)
+			}
+			fmt.Printf("Report saved to %s\n", outputFile)
+		} else if noPager || watch {
+			fmt.Println(report) // Under --watch a pager would hold up the next run
+		} else {
+			pager.Print(report + "\n")
+		}
+
+		if !watch {
+			// Under --duplicates=error, duplicates fail the check like a linter,
+			// as do pins that don't hold
+			if result.DuplicatePolicy.Severity == model.DuplicatesError && result.DuplicateCount() > 0 {
+				os.Exit(1)
+			}
+			if len(result.BrokenPins()) > 0 {
+				os.Exit(1)
+			}
+			return
+		}
+		file, err := waitForConfigChange(result)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error watching config files: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\n=== %s changed at %s; re-running ===\n\n", file, time.Now().Format("15:04:05"))
+	}
+}
+
+// waitForConfigChange blocks until a config file behind result changes.
+// Ctrl+C exits.
+func waitForConfigChange(result model.AnalysisResult) (string, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	files := trace.ConfigFiles(result)
+	fmt.Fprintf(os.Stderr, "Watching %d config files for changes (Ctrl+C to stop)…\n", len(files))
+	file, err := trace.WaitForChange(ctx, files)
+	if ctx.Err() != nil {
+		os.Exit(0)
+	}
+	return file, err
+}
+
+func runFormatMode(format, outputFile string) {
+	var render func(model.AnalysisResult) string
+	kind := "Graph"
+	switch format {
+	case "dot":
+		render = trace.GenerateDOT
+	case "mermaid":
+		render = trace.GenerateMermaid
+	case "html":
+		render = trace.GenerateHTMLReport
+		kind = "Report"
+	case "md", "markdown":
+		render = trace.GenerateMarkdownReport
+		kind = "Report"
+	case "yaml", "yml":
+		render = trace.GenerateYAML
+		kind = "Data"
+	case "csv":
+		render = trace.GenerateCSV
+		kind = "Data"
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q (want dot, mermaid, html, md, yaml or csv)\n", format)
+		os.Exit(1)
+	}
+
+	result, err := runUnifiedAnalysis()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error running trace: %v\n", err)
 		os.Exit(1)
 	}
 
-	parser := trace.NewParser(shell)
-	events, errs := parser.Parse(stderr)
-	var allEvents []model.TraceEvent
-	for ev := range events {
-		allEvents = append(allEvents, ev)
+	out := render(result)
+	if outputFile != "" {
+		if err := os.WriteFile(outputFile, []byte(out), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", outputFile, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s saved to %s\n", kind, outputFile)
+		return
+	}
+	fmt.Print(out)
+}
+
+// runCheckMode lists the problems trace.Check finds and exits 1 if any are
+// at least failOn, so CI can catch PATH regressions in a dotfiles repo.
+func runCheckMode(failOn string, verbose bool) {
+	threshold, err := trace.ParseSeverity(failOn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	result, err := runUnifiedAnalysis()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running trace: %v\n", err)
+		os.Exit(2)
 	}
-	go func() {
-		for range errs {
+
+	counts := make(map[string]int)
+	ignored := 0
+	failed := false
+	for _, f := range trace.Check(result) {
+		if f.Ignored {
+			ignored++
+			if verbose {
+				fmt.Println(f.String(result))
+			}
+			continue
 		}
-	}()
+		fmt.Println(f.String(result))
+		counts[f.Severity]++
+		failed = failed || trace.AtLeast(f.Severity, threshold)
+	}
+	summary := fmt.Sprintf("%s: %d entries · %d errors · %d warnings · %d info", result.VariableName(), len(result.PathEntries),
+		counts[trace.SeverityError], counts[trace.SeverityWarning], counts[trace.SeverityInfo])
+	if ignored > 0 {
+		summary += fmt.Sprintf(" · %d ignored", ignored)
+	}
+	fmt.Printf("%s (failing on %s and above)\n", summary, threshold)
+	if failed {
+		os.Exit(1)
+	}
+}
 
-	// Unified analysis: merge trace results with session PATH
-	analyzer := trace.NewAnalyzer()
-	result := analyzer.AnalyzeUnified(sessionPath, allEvents)
+// runPrintCleanMode prints the cleaned value, plain or as an export line,
+// and lists what was removed on stderr so the output can be eval'd.
+func runPrintCleanMode(format string) {
+	if format != "" && format != "export" {
+		fmt.Fprintf(os.Stderr, "Error: --print-clean supports --format export only\n")
+		os.Exit(2)
+	}
+	result, err := runUnifiedAnalysis()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running trace: %v\n", err)
+		os.Exit(1)
+	}
+	value, removed := trace.CleanValue(result)
+	for _, r := range removed {
+		fmt.Fprintf(os.Stderr, "# Removed %s\n", r)
+	}
+	if format == "export" {
+		fmt.Println(trace.ExportLine(result.VariableName(), value))
+		return
+	}
+	fmt.Println(value)
+}
+
+// runDaemonMode serves queries on socket until interrupted, then removes it.
+func runDaemonMode(socket string) {
+	if socket == "" {
+		socket = daemon.SocketPath()
+	}
+	ln, err := daemon.Listen(socket)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer os.Remove(socket)
+	d, err := daemon.New(analysisOptions) // Clients wait in the socket's queue meanwhile
+	if err != nil {
+		os.Remove(socket)
+		fmt.Fprintf(os.Stderr, "Error running trace: %v\n", err)
+		os.Exit(1)
+	}
 
-	report := trace.GenerateReport(result, verbose)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Fprintf(os.Stderr, "lspath daemon listening on %s (Ctrl+C to stop)\n", socket)
+	if err := d.Serve(ctx, ln); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
 
-	if outputFile != "" {
-		err := os.WriteFile(outputFile, []byte(report), 0644)
+// runOnelineMode prints the --oneline summary, from the cache when no traced
+// config file has changed.
+func runOnelineMode() {
+	if o, ok := trace.CachedOneline(analysisOptions); ok {
+		fmt.Println(o)
+		return
+	}
+	var startup time.Duration
+	opts := analysisOptions
+	opts.StartupTime = &startup
+	result, err := trace.RunAnalysis(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	o := trace.NewOneline(result, opts, startup)
+	trace.SaveOneline(o) // Best effort; the next run just traces again
+	fmt.Println(o)
+}
+
+func runJsonMode(version int) {
+	result, err := runUnifiedAnalysis()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running trace: %v\n", err)
+		os.Exit(1)
+	}
+
+	data, err := trace.MarshalDocument(result, version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(data))
+}
+
+// runContextsMode compares the PATH each available launch context gets.
+func runContextsMode() {
+	opts := analysisOptions
+	if isTerminal(os.Stderr) {
+		opts.Progress = func(stage string) {
+			fmt.Fprintf(os.Stderr, "%s\n", stage)
+		}
+	}
+	results := trace.RunContextMatrix(opts, trace.LaunchContexts())
+	fmt.Print(trace.FormatContextMatrix(results))
+}
+
+// runModesMode compares the PATH of login, interactive and non-interactive
+// shells.
+func runModesMode() {
+	opts := analysisOptions
+	if isTerminal(os.Stderr) {
+		opts.Progress = func(stage string) {
+			fmt.Fprintf(os.Stderr, "%s\n", stage)
+		}
+	}
+	results := trace.RunContextMatrix(opts, trace.ShellModes())
+	fmt.Print(trace.FormatModeComparison(results, trace.DetectShell(os.Getenv("SHELL")).Name()))
+}
+
+// runServiceMode reports on the PATH a service context gives what it runs
+// and compares it with the user's shell's.
+func runServiceMode(name string, verbose bool) {
+	theirs, err := trace.RunServiceAnalysis(name, analysisOptions)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(trace.GenerateReport(theirs, verbose))
+
+	mine, err := runUnifiedAnalysis()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running trace: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println()
+	fmt.Print(trace.FormatServiceComparison(mine, theirs, name))
+}
+
+// runUserMode reports on another user's startup PATH and compares it with the
+// invoking user's, both traced the same way.
+func runUserMode(name string, verbose bool) {
+	me, err := trace.InvokingUser()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	theirs, err := trace.RunUserAnalysis(name, analysisOptions)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error tracing %s: %v\n", name, err)
+		os.Exit(1)
+	}
+	fmt.Print(trace.GenerateReport(theirs, verbose))
+	if me.Name == name {
+		return
+	}
+
+	mine, err := trace.RunUserAnalysis(me.Name, analysisOptions)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error tracing %s: %v\n", me.Name, err)
+		os.Exit(1)
+	}
+	fmt.Println()
+	fmt.Print(trace.FormatUserComparison(mine, theirs, me.Name, name))
+}
+
+func runSnapshotMode(path string) {
+	result, err := runUnifiedAnalysis()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running trace: %v\n", err)
+		os.Exit(1)
+	}
+	if err := trace.SaveSnapshot(path, result); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving snapshot: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Snapshot saved to %s\n", path)
+}
+
+// runBundleExport saves the analysis, raw trace and (optionally) config file
+// copies to path.
+func runBundleExport(path string, includeConfigs bool) {
+	b, err := bundle.Collect(analysisOptions, includeConfigs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running trace: %v\n", err)
+		os.Exit(1)
+	}
+	if err := bundle.Export(path, b); err != nil {
+		fmt.Fprintf(os.Stderr, "Error saving bundle: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Bundle saved to %s (%d entries, %d config files)\n", path, len(b.Result.PathEntries), len(b.Configs))
+	if !includeConfigs {
+		fmt.Println("Config files were not included; add --include-configs to show their contents when the bundle is opened.")
+	}
+}
+
+// runBundleOpen shows a bundle in the TUI, Web Mode or a report, with its
+// config files extracted to a temporary directory.
+func runBundleOpen(path string, webMode bool, webConfig web.Config, report, verbose, noPager bool) {
+	b, err := bundle.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	dir, err := os.MkdirTemp("", "lspath-bundle-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer os.RemoveAll(dir)
+	result, err := b.Extract(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error extracting bundle: %v\n", err)
+		os.Exit(1)
+	}
+
+	if report {
+		out := trace.GenerateReport(result, verbose)
+		if trace.ColorEnabled(colorMode, os.Stdout) {
+			out = trace.ColorizeReport(out)
+		}
+		if noPager {
+			fmt.Print(out)
+		} else {
+			pager.Print(out)
+		}
+		return
+	}
+	showPreloaded(result, webMode, webConfig)
+}
+
+// runFleetMode reports drift across the analyses saved in dir. Files that
+// cannot be read are skipped with a warning; it exits 1 if none can.
+func runFleetMode(dir string, jsonOut bool) {
+	hosts, errs := fleet.Load(dir)
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "Warning: skipped %v\n", err)
+	}
+	if len(hosts) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no lspath --json files or .lspath bundles found in %s\n", dir)
+		os.Exit(1)
+	}
+	report := fleet.Aggregate(hosts)
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+		return
+	}
+	fmt.Print(fleet.Format(report))
+}
+
+// runDemoMode opens the made-up analysis from package demo, so lspath can be
+// tried without tracing anything. Its startup files go in a temporary
+// directory that is removed on exit.
+func runDemoMode(webMode bool, webConfig web.Config) {
+	dir, err := os.MkdirTemp("", "lspath-demo-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer os.RemoveAll(dir)
+	result, err := demo.Result(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing demo files: %v\n", err)
+		os.Exit(1)
+	}
+	showPreloaded(result, webMode, webConfig)
+}
+
+// showPreloaded shows a result that was not traced here in Web Mode or the
+// TUI.
+func showPreloaded(result model.AnalysisResult, webMode bool, webConfig web.Config) {
+	if webMode {
+		web.StartServerWithResult(result, webConfig)
+		return
+	}
+	requireTerminal()
+	m := tui.InitialModel()
+	m.Variable = result.VariableName()
+	m.Preloaded = &result
+	p := tea.NewProgram(&m, tea.WithAltScreen())
+	if _, err := p.Run(); err != nil {
+		fmt.Printf("Alas, there's been an error: %v", err)
+		os.Exit(1)
+	}
+}
+
+// runDiffMode compares two snapshots, or one snapshot with a fresh analysis.
+// It exits 1 if they differ, like diff(1).
+func runDiffMode(files []string) {
+	before, err := trace.LoadSnapshot(files[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var after model.AnalysisResult
+	if len(files) == 2 {
+		after, err = trace.LoadSnapshot(files[1])
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing report to %s: %v\n", outputFile, err)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
-		fmt.Printf("Report saved to %s\n", outputFile)
 	} else {
-		fmt.Println(report)
+		after, err = runUnifiedAnalysis()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error running trace: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	d := trace.DiffResults(before, after)
+	fmt.Print(trace.FormatDiff(before, after, d))
+	if !d.Empty() {
+		os.Exit(1)
+	}
+}
+
+// runWhichMode prints every PATH entry providing each command, with the
+// versions they print if versions is set. It exits 1 if any command is not
+// found, like which(1).
+func runWhichMode(names []string, versions bool) {
+	result, err := runUnifiedAnalysis()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running trace: %v\n", err)
+		os.Exit(1)
+	}
+
+	missing := false
+	for i, name := range names {
+		if i > 0 {
+			fmt.Println()
+		}
+		hits := trace.FindCommand(result.PathEntries, name)
+		if len(hits) == 0 {
+			missing = true
+		}
+		if versions {
+			trace.ProbeVersions(hits)
+		}
+		fmt.Print(trace.FormatWhich(result, name, hits))
+	}
+	if missing {
+		os.Exit(1)
+	}
+}
+
+func runExplainMode(ref string, budget time.Duration) {
+	result, err := runUnifiedAnalysis()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running trace: %v\n", err)
+		os.Exit(1)
+	}
+
+	idx, err := trace.FindEntry(result, ref)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
+
+	bins := scanWithProgress(result, budget)
+	fmt.Print(trace.GenerateExplanation(result, idx, bins))
 }
 
-func runJsonMode() {
-	sessionPath := os.Getenv("PATH")
+func runAdviseMode(dir string, apply bool) {
+	result, err := runUnifiedAnalysis()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running trace: %v\n", err)
+		os.Exit(1)
+	}
 
 	shell := trace.DetectShell(os.Getenv("SHELL"))
-	stderr, err := trace.RunTrace(shell, trace.SandboxInitialPath)
+	advice := trace.AdviseLocation(shell.Name(), result, dir)
+	fmt.Print(trace.FormatLocationAdvice(advice))
+
+	if !apply {
+		return
+	}
+	if advice.AlreadyInPath {
+		fmt.Println("\nNot applying: directory is already in PATH.")
+		return
+	}
+
+	backup, err := fix.Apply(fix.Edit{
+		File:   advice.File,
+		Action: fix.ActionAppend,
+		Text:   "\n# Added by lspath\n" + advice.Snippet,
+		Reason: advice.Reason,
+	})
 	if err != nil {
-		panic(err)
+		fmt.Fprintf(os.Stderr, "Error updating %s: %v\n", advice.File, err)
+		os.Exit(1)
 	}
+	fmt.Printf("\nAppended to %s", advice.File)
+	if backup != "" {
+		fmt.Printf(" (backup: %s)", backup)
+	}
+	fmt.Println("\nOpen a new terminal to pick up the change.")
+}
 
-	parser := trace.NewParser(shell)
-	events, errs := parser.Parse(stderr)
+func runFixMode() {
+	result, err := runUnifiedAnalysis()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running trace: %v\n", err)
+		os.Exit(1)
+	}
 
-	var allEvents []model.TraceEvent
-	for ev := range events {
-		allEvents = append(allEvents, ev)
+	edits, notes := fix.Proposals(result, trace.DetectShell(os.Getenv("SHELL")).Name())
+	for _, n := range notes {
+		fmt.Println("Note: " + n)
+	}
+	if len(edits) == 0 {
+		fmt.Println("No duplicate PATH lines to remove.")
+		return
 	}
 
-	go func() {
-		for range errs {
+	changes, err := fix.Plan(edits)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, c := range changes {
+		fmt.Println()
+		for _, e := range c.Edits {
+			if e.Action == fix.ActionAppend {
+				fmt.Printf("# Append: %s\n", e.Reason)
+			} else {
+				fmt.Printf("# Line %d: %s\n", e.Line, e.Reason)
+			}
 		}
-	}()
+		fmt.Print(c.Diff())
+	}
+
+	// Never wait on a prompt nobody can answer (scripts, cron, CI)
+	if !isTerminal(os.Stdin) {
+		fmt.Println("\nNo changes made: --fix asks before applying, and stdin is not a terminal. Run it from a terminal to apply.")
+		return
+	}
+	fmt.Print("\nApply these changes? Each file is backed up first. [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+		fmt.Println("No changes made.")
+		return
+	}
+
+	for _, c := range changes {
+		backup, err := fix.ApplyChange(c)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error updating %s: %v\n", c.File, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Updated %s", c.File)
+		if backup != "" {
+			fmt.Printf(" (backup: %s)", backup)
+		}
+		fmt.Println()
+	}
+	fmt.Println("Open a new terminal to pick up the changes.")
+}
+
+// scanWithProgress builds the binary index within budget, showing progress on
+// stderr when it is a terminal. Ctrl+C stops the scan early with partial results.
+func scanWithProgress(result model.AnalysisResult, budget time.Duration) *trace.BinaryIndex {
+	ctx, cancel := context.WithTimeout(context.Background(), budget)
+	defer cancel()
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
 
-	analyzer := trace.NewAnalyzer()
-	result := analyzer.AnalyzeUnified(sessionPath, allEvents)
+	var progress trace.ScanProgress
+	if isTerminal(os.Stderr) {
+		progress = func(done, total int) {
+			fmt.Fprintf(os.Stderr, "\rScanning %d/%d directories…", done, total)
+		}
+	}
+	bins := trace.ScanBinaryIndex(ctx, result.PathEntries, progress)
+	if progress != nil {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+	}
+	if bins.Partial() {
+		fmt.Fprintf(os.Stderr, "Scan stopped after %d/%d directories; results are partial.\n", bins.Scanned, len(bins.ByEntry))
+	}
+	return bins
+}
+
+// requireTerminal exits with a usage error when the TUI has no terminal to
+// draw on, as under cron or in a pipe, rather than printing escape codes.
+func requireTerminal() {
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		fmt.Fprintf(os.Stderr, "Error: the TUI needs a terminal. In scripts and CI use --report, --json, --check or lspath which.\n")
+		os.Exit(2)
+	}
+}
 
-	enc := json.NewEncoder(os.Stdout)
-	enc.SetIndent("", "  ")
-	enc.Encode(result)
+// isTerminal reports whether f is attached to a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
 
-func runTuiMode() {
+func runTuiMode(variable string, watch, tour bool) {
+	requireTerminal()
 	m := tui.InitialModel()
+	m.Variable = variable
+	m.TraceOptions = analysisOptions
+	m.Watch = watch
+	m.Tour = tour
 	p := tea.NewProgram(&m, tea.WithAltScreen())
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Alas, there's been an error: %v", err)
@@ -0,0 +1,202 @@
+// Package lspath analyzes PATH and other PATH-like variables the way the
+// lspath command does: it traces the shell's startup files to find the file
+// and line that added each entry, and finds duplicates, missing directories,
+// entries others can write to and shadowed commands. Editors, dotfile
+// managers and other tools can use it instead of running the binary and
+// parsing its --json output, whose document is the same Result.
+//
+// Unlike the command, Analyze reads no configuration of its own (ignore
+// rules, pins, plugins): pass whatever applies in Options.
+package lspath
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"lspath/internal/model"
+	"lspath/internal/trace"
+)
+
+// Version is the version of lspath this package is part of.
+const Version = model.Version
+
+// Result is a finished analysis: the entries in priority order, the startup
+// files that set them, and the problems found.
+type Result = model.AnalysisResult
+
+// Entry is one entry of the variable, attributed to where it was added.
+type Entry = model.PathEntry
+
+// FlowNode is a startup file in the order the shell ran it, with the
+// entries it added.
+type FlowNode = model.ConfigNode
+
+// Finding is a problem Check reports.
+type Finding = trace.Finding
+
+// CommandHit is an executable Which found in an entry.
+type CommandHit = trace.CommandHit
+
+// Severities of a Finding, lowest first.
+const (
+	SeverityInfo    = trace.SeverityInfo
+	SeverityWarning = trace.SeverityWarning
+	SeverityError   = trace.SeverityError
+)
+
+// DefaultTraceTimeout is how long the traced shell may take unless
+// Options.TraceTimeout says otherwise.
+const DefaultTraceTimeout = trace.DefaultTraceTimeout
+
+// Options controls an analysis. The zero value analyzes PATH as the user's
+// login shell sets it up, merged with this process's PATH.
+type Options struct {
+	// Variable is the PATH-like variable to analyze, e.g. "MANPATH". Empty
+	// means PATH.
+	Variable string
+
+	// Value is the value to attribute. Empty means the variable's value in
+	// this process's environment.
+	Value string
+
+	// Shell is the shell whose startup files are traced, e.g. "/bin/zsh".
+	// Empty means $SHELL.
+	Shell string
+
+	// Baseline is the PATH the traced shell starts from, before any startup
+	// file runs. Empty means the one detected for this system.
+	Baseline string
+
+	// TraceTimeout is how long the traced shell may take to start before it
+	// is killed and analyzed as far as it got. 0 means DefaultTraceTimeout;
+	// negative means no limit beyond the context's.
+	TraceTimeout time.Duration
+
+	// NoSideEffects disables commands in the startup files that start
+	// daemons or write files, where the shell allows it.
+	NoSideEffects bool
+
+	// Sandbox traces with CPU and file size limits, a private TMPDIR and no
+	// network where supported.
+	Sandbox bool
+
+	// Duplicates is how duplicate entries are treated: "warn" (the
+	// default), "error" or "harmless".
+	Duplicates string
+
+	// IgnoreSymlinkDuplicates stops symlinks to another entry (e.g. /bin ->
+	// /usr/bin) from counting as duplicates.
+	IgnoreSymlinkDuplicates bool
+
+	// PathCase is how the case of entries is compared when finding
+	// duplicates: "auto" (the default: ignored on volumes that ignore it,
+	// such as macOS's), "sensitive" or "insensitive".
+	PathCase string
+
+	// Ignores are problems to accept, in the command's --ignore syntax:
+	// "missing", "~/go/bin" or "missing=~/go/bin".
+	Ignores []string
+
+	// DisabledHeuristics are analyzer heuristics to leave off, by the
+	// command's --no-heuristic names ("eval", "coalesce", ... or "all").
+	DisabledHeuristics []string
+
+	// PinsFile lists directories that must appear in order. Empty means no
+	// pins are checked.
+	PinsFile string
+
+	// Plugins are executables to run on the result, as with the command's
+	// --plugin.
+	Plugins []string
+
+	// Progress, if set, is called as the analysis moves between stages.
+	Progress func(stage string)
+}
+
+// traceOptions converts o to the options of the analysis itself.
+func (o Options) traceOptions(ctx context.Context) (trace.Options, error) {
+	severity, err := model.ParseDuplicateSeverity(o.Duplicates)
+	if err != nil {
+		return trace.Options{}, err
+	}
+	pathCase, err := model.ParseCaseMode(o.PathCase)
+	if err != nil {
+		return trace.Options{}, err
+	}
+	opts := trace.Options{
+		SessionPath:   o.Value,
+		Var:           o.Variable,
+		Shell:         o.Shell,
+		Context:       ctx,
+		Duplicates:    model.DuplicatePolicy{Severity: severity, IgnoreSymlinks: o.IgnoreSymlinkDuplicates},
+		PathCase:      pathCase,
+		TraceTimeout:  o.TraceTimeout,
+		Baseline:      o.Baseline,
+		NoSideEffects: o.NoSideEffects,
+		PinsFile:      o.PinsFile,
+		Plugins:       o.Plugins,
+		Progress:      o.Progress,
+	}
+	if o.PinsFile == "" {
+		opts.PinsFile = os.DevNull // Not the user's default pins file
+	}
+	if o.Sandbox {
+		opts.Sandbox = trace.DefaultSandbox
+	}
+	for _, s := range o.Ignores {
+		rule, err := trace.ParseIgnoreRule(s, "Ignores")
+		if err != nil {
+			return trace.Options{}, err
+		}
+		opts.Ignores = append(opts.Ignores, rule)
+	}
+	opts.DisabledHeuristics, err = trace.ParseHeuristics(o.DisabledHeuristics)
+	if err != nil {
+		return trace.Options{}, err
+	}
+	return opts, nil
+}
+
+// Analyze traces the shell's startup and attributes each entry of the
+// variable to the file and line that added it. Entries the trace did not
+// add are marked as added in this session. If ctx is done before the trace
+// finishes, the shell is killed and ctx's error returned.
+func Analyze(ctx context.Context, o Options) (Result, error) {
+	opts, err := o.traceOptions(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	res, err := trace.RunAnalysis(opts)
+	if err != nil {
+		return Result{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+	return res, nil
+}
+
+// Check lists the problems in res, worst first, as the command's --check
+// does. Findings marked Ignored were accepted by Options.Ignores.
+func Check(res Result) []Finding {
+	return trace.Check(res)
+}
+
+// Which returns every entry of res with an executable called name, in
+// priority order: the first is the one the shell runs.
+func Which(res Result, name string) []CommandHit {
+	return trace.FindCommand(res.PathEntries, name)
+}
+
+// Report renders res as the command's text report (--report), with every
+// entry's details if verbose.
+func Report(res Result, verbose bool) string {
+	return trace.GenerateReport(res, verbose)
+}
+
+// MarshalJSON renders res as the command's --json document, in the current
+// schema version.
+func MarshalJSON(res Result) ([]byte, error) {
+	return trace.MarshalDocument(res, 0)
+}